@@ -0,0 +1,260 @@
+// Package abtest splits the recipients of a step between its template
+// variants and promotes the winner once the difference is significant.
+package abtest
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/analytics"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+// Variants returns the step's variants in a stable order, loading them if
+// they weren't preloaded
+func Variants(step *models.Step) ([]models.StepVariant, error) {
+	variants := step.Variants
+	if variants == nil {
+		if err := database.DB.Where("step_id = ?", step.ID).Find(&variants).Error; err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(variants, func(i, j int) bool { return variants[i].ID < variants[j].ID })
+	return variants, nil
+}
+
+// Choose returns the variant the customer gets for the step: the promoted
+// winner, or a weighted pick that's always the same for the same customer.
+// It returns nil when the step isn't A/B tested.
+func Choose(step *models.Step, customerID uint) (*models.StepVariant, error) {
+	variants, err := Variants(step)
+	if err != nil || len(variants) == 0 {
+		return nil, err
+	}
+
+	if step.WinningVariantID != 0 {
+		for i := range variants {
+			if variants[i].ID == step.WinningVariantID {
+				return &variants[i], nil
+			}
+		}
+	}
+	return Assign(variants, step.ID, customerID), nil
+}
+
+// Assign deterministically picks a variant for the customer in proportion to
+// the variants' weights. Variants without a positive weight count as 1.
+func Assign(variants []models.StepVariant, stepID, customerID uint) *models.StepVariant {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, variant := range variants {
+		total += weight(variant)
+	}
+
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], uint64(stepID))
+	binary.BigEndian.PutUint64(buf[8:], uint64(customerID))
+	h := fnv.New64a()
+	h.Write(buf[:])
+	point := int(h.Sum64() % uint64(total))
+
+	for i := range variants {
+		point -= weight(variants[i])
+		if point < 0 {
+			return &variants[i]
+		}
+	}
+	return &variants[len(variants)-1]
+}
+
+func weight(variant models.StepVariant) int {
+	if variant.Weight <= 0 {
+		return 1
+	}
+	return variant.Weight
+}
+
+// Results compares the step's variants on its A/B metric
+func Results(step *models.Step) (*models.ABTestResults, error) {
+	variants, err := Variants(step)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := analytics.VariantStats(step.ID, variants)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &models.ABTestResults{
+		StepID:           step.ID,
+		Metric:           metric(step),
+		Confidence:       confidence(step),
+		Variants:         stats,
+		PValue:           1,
+		WinningVariantID: step.WinningVariantID,
+		PromotedAt:       step.PromotedAt,
+	}
+	if len(stats) == 0 {
+		return results, nil
+	}
+
+	ranked := make([]models.VariantStats, len(stats))
+	copy(ranked, stats)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return rate(ranked[i], results.Metric) > rate(ranked[j], results.Metric)
+	})
+	results.LeaderVariantID = ranked[0].VariantID
+	if len(ranked) > 1 {
+		leader, runnerUp := ranked[0], ranked[1]
+		results.PValue = TwoProportionPValue(
+			successes(leader, results.Metric), leader.Sent,
+			successes(runnerUp, results.Metric), runnerUp.Sent,
+		)
+		results.Significant = results.PValue < 1-results.Confidence
+	}
+	return results, nil
+}
+
+// TwoProportionPValue is the two-sided p-value of a z-test for the difference
+// between the success rates x1/n1 and x2/n2
+func TwoProportionPValue(x1, n1, x2, n2 int) float64 {
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+	p1 := float64(x1) / float64(n1)
+	p2 := float64(x2) / float64(n2)
+	pooled := float64(x1+x2) / float64(n1+n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 1
+	}
+	z := math.Abs(p1-p2) / se
+	return math.Erfc(z / math.Sqrt2)
+}
+
+// PromoteWinners checks every step that auto-promotes and hasn't got a winner
+// yet, and promotes its leader once the test is over and the result significant
+func PromoteWinners(now time.Time) error {
+	var steps []models.Step
+	if err := database.DB.Where("ab_auto_promote = ? AND winning_variant_id = 0", true).Find(&steps).Error; err != nil {
+		return err
+	}
+
+	for i := range steps {
+		if err := maybePromote(&steps[i], now); err != nil {
+			log.Printf("Error checking A/B test of step %d: %v", steps[i].ID, err)
+		}
+	}
+	return nil
+}
+
+func maybePromote(step *models.Step, now time.Time) error {
+	results, err := Results(step)
+	if err != nil || len(results.Variants) < 2 {
+		return err
+	}
+
+	if !testOver(step, results, now) || !results.Significant {
+		return nil
+	}
+	log.Printf("Promoting variant %d of step %d (p=%.4f)", results.LeaderVariantID, step.ID, results.PValue)
+	return Promote(step, results.LeaderVariantID, now)
+}
+
+// testOver reports whether every variant reached the sample size, or the
+// test window has passed since the step's first send
+func testOver(step *models.Step, results *models.ABTestResults, now time.Time) bool {
+	if step.ABSampleSize > 0 {
+		reached := true
+		for _, variant := range results.Variants {
+			if variant.Sent < step.ABSampleSize {
+				reached = false
+			}
+		}
+		if reached {
+			return true
+		}
+	}
+
+	if step.ABTestHours > 0 {
+		var first models.EmailLog
+		err := database.DB.Where("step_id = ? AND variant_id <> 0 AND status <> ?", step.ID, models.EmailStatusPending).
+			Order("sent_at asc").First(&first).Error
+		if err == nil && now.Sub(first.SentAt) >= time.Duration(step.ABTestHours)*time.Hour {
+			return true
+		}
+	}
+	return false
+}
+
+// Promote makes variantID the step's winner, sent to every later recipient
+func Promote(step *models.Step, variantID uint, now time.Time) error {
+	step.WinningVariantID = variantID
+	step.PromotedAt = &now
+	return database.DB.Model(step).UpdateColumns(map[string]interface{}{
+		"winning_variant_id": variantID,
+		"promoted_at":        now,
+	}).Error
+}
+
+// ValidateSettings checks a step's A/B test settings
+func ValidateSettings(step *models.Step) []string {
+	var errs []string
+	switch step.ABMetric {
+	case "", models.ABMetricOpen, models.ABMetricClick, models.ABMetricReply:
+	default:
+		errs = append(errs, "ab_metric must be open, click or reply")
+	}
+	if step.ABConfidence != 0 && (step.ABConfidence <= 0.5 || step.ABConfidence >= 1) {
+		errs = append(errs, "ab_confidence must be between 0.5 and 1")
+	}
+	if step.ABSampleSize < 0 || step.ABTestHours < 0 {
+		errs = append(errs, "ab_sample_size and ab_test_hours can't be negative")
+	}
+	if step.ABAutoPromote && step.ABSampleSize == 0 && step.ABTestHours == 0 {
+		errs = append(errs, "auto-promotion needs an ab_sample_size or ab_test_hours")
+	}
+	return errs
+}
+
+func metric(step *models.Step) string {
+	if step.ABMetric == "" {
+		return models.ABMetricOpen
+	}
+	return step.ABMetric
+}
+
+func confidence(step *models.Step) float64 {
+	if step.ABConfidence == 0 {
+		return models.DefaultABConfidence
+	}
+	return step.ABConfidence
+}
+
+func successes(stats models.VariantStats, metric string) int {
+	switch metric {
+	case models.ABMetricClick:
+		return stats.Clicked
+	case models.ABMetricReply:
+		return stats.Replied
+	}
+	return stats.Opened
+}
+
+func rate(stats models.VariantStats, metric string) float64 {
+	switch metric {
+	case models.ABMetricClick:
+		return stats.ClickRate
+	case models.ABMetricReply:
+		return stats.ReplyRate
+	}
+	return stats.OpenRate
+}
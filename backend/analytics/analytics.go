@@ -0,0 +1,251 @@
+// Package analytics aggregates the email log and engagement events into
+// campaign reporting.
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/jinzhu/gorm"
+)
+
+// Time series intervals
+const (
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+)
+
+// sentStatuses are the email log statuses of messages that left the server
+var sentStatuses = []string{
+	models.EmailStatusSent,
+	models.EmailStatusBounced,
+	models.EmailStatusSoftBounced,
+	models.EmailStatusComplained,
+}
+
+// bouncedStatuses are the sent statuses of messages that were not delivered
+var bouncedStatuses = []string{models.EmailStatusBounced, models.EmailStatusSoftBounced}
+
+// CampaignStats builds the enrollment counts, per stage and step funnel and a
+// time series bucketed by interval between from and to
+func CampaignStats(campaignID uint, interval string, from, to time.Time) (*models.CampaignStats, error) {
+	if interval != IntervalDay && interval != IntervalWeek {
+		return nil, fmt.Errorf("invalid interval %q", interval)
+	}
+
+	stats := &models.CampaignStats{CampaignID: campaignID, Interval: interval}
+
+	enrollments, err := enrollmentStats(campaignID)
+	if err != nil {
+		return nil, err
+	}
+	stats.Enrollments = enrollments
+
+	byStep, err := stepFunnels(campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	var stages []models.Stage
+	if err := database.DB.Where("campaign_id = ?", campaignID).Order(`"order" asc, id asc`).Preload("Steps").Find(&stages).Error; err != nil {
+		return nil, err
+	}
+
+	for _, stage := range stages {
+		stageStats := models.StageStats{StageID: stage.ID, Name: stage.Name, Order: stage.Order, Steps: []models.StepStats{}}
+		sort.Slice(stage.Steps, func(i, j int) bool { return stage.Steps[i].ID < stage.Steps[j].ID })
+		for _, step := range stage.Steps {
+			funnel := byStep[step.ID]
+			funnel.ComputeRates()
+			stageStats.Steps = append(stageStats.Steps, models.StepStats{StepID: step.ID, Name: step.Name, FunnelStats: funnel})
+			stageStats.FunnelStats.Add(funnel)
+		}
+		stageStats.ComputeRates()
+		stats.Stages = append(stats.Stages, stageStats)
+	}
+
+	// Totals include sends of steps that have since been deleted
+	for _, funnel := range byStep {
+		stats.Totals.Add(funnel)
+	}
+	stats.Totals.ComputeRates()
+
+	series, err := timeSeries(campaignID, interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+	stats.Series = series
+
+	return stats, nil
+}
+
+func enrollmentStats(campaignID uint) (models.EnrollmentStats, error) {
+	var stats models.EnrollmentStats
+	rows, err := database.DB.Model(&models.CampaignCustomer{}).
+		Select("status, count(*)").
+		Where("campaign_id = ?", campaignID).
+		Group("status").
+		Rows()
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return stats, err
+		}
+		stats.Enrolled += count
+		switch status {
+		case "", models.EnrollmentActive:
+			stats.Active += count
+		case models.EnrollmentPaused:
+			stats.Paused += count
+		case models.EnrollmentCompleted:
+			stats.Completed += count
+		case models.EnrollmentExited:
+			stats.Exited += count
+		}
+	}
+	return stats, rows.Err()
+}
+
+// stepFunnels returns the funnel counts of a campaign keyed by step ID
+func stepFunnels(campaignID uint) (map[uint]models.FunnelStats, error) {
+	funnels := map[uint]models.FunnelStats{}
+
+	sends, err := database.DB.Model(&models.EmailLog{}).
+		Select("step_id, count(*), sum(CASE WHEN status IN (?) THEN 1 ELSE 0 END)", bouncedStatuses).
+		Where("campaign_id = ? AND status IN (?)", campaignID, sentStatuses).
+		Group("step_id").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer sends.Close()
+	for sends.Next() {
+		var stepID uint
+		var sent, bounced int
+		if err := sends.Scan(&stepID, &sent, &bounced); err != nil {
+			return nil, err
+		}
+		funnel := funnels[stepID]
+		funnel.Sent = sent
+		funnel.Bounced = bounced
+		funnel.Delivered = sent - bounced
+		funnels[stepID] = funnel
+	}
+	if err := sends.Err(); err != nil {
+		return nil, err
+	}
+
+	events, err := engagementQuery().
+		Select("step_id, "+engagementColumns).
+		Where("campaign_id = ?", campaignID).
+		Group("step_id").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer events.Close()
+	for events.Next() {
+		var stepID uint
+		var opened, clicked, unsubscribed int
+		if err := events.Scan(&stepID, &opened, &clicked, &unsubscribed); err != nil {
+			return nil, err
+		}
+		funnel := funnels[stepID]
+		funnel.Opened = opened
+		funnel.Clicked = clicked
+		funnel.Unsubscribed = unsubscribed
+		funnels[stepID] = funnel
+	}
+	return funnels, events.Err()
+}
+
+// timeSeries buckets sends by when they were sent and engagement by when it happened
+func timeSeries(campaignID uint, interval string, from, to time.Time) ([]models.StatsBucket, error) {
+	buckets := map[time.Time]*models.StatsBucket{}
+	bucket := func(start time.Time) *models.StatsBucket {
+		start = start.UTC()
+		if buckets[start] == nil {
+			buckets[start] = &models.StatsBucket{Start: start}
+		}
+		return buckets[start]
+	}
+
+	// interval has been validated, so it is safe to inline
+	trunc := "date_trunc('" + interval + "', %s AT TIME ZONE 'UTC')"
+
+	sends, err := database.DB.Model(&models.EmailLog{}).
+		Select(fmt.Sprintf(trunc, "sent_at")+", count(*), sum(CASE WHEN status IN (?) THEN 1 ELSE 0 END)", bouncedStatuses).
+		Where("campaign_id = ? AND status IN (?) AND sent_at >= ? AND sent_at < ?", campaignID, sentStatuses, from, to).
+		Group("1").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer sends.Close()
+	for sends.Next() {
+		var start time.Time
+		var sent, bounced int
+		if err := sends.Scan(&start, &sent, &bounced); err != nil {
+			return nil, err
+		}
+		b := bucket(start)
+		b.Sent = sent
+		b.Bounced = bounced
+		b.Delivered = sent - bounced
+	}
+	if err := sends.Err(); err != nil {
+		return nil, err
+	}
+
+	events, err := engagementQuery().
+		Select(fmt.Sprintf(trunc, "occurred_at")+", "+engagementColumns).
+		Where("campaign_id = ? AND occurred_at >= ? AND occurred_at < ?", campaignID, from, to).
+		Group("1").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer events.Close()
+	for events.Next() {
+		var start time.Time
+		var opened, clicked, unsubscribed int
+		if err := events.Scan(&start, &opened, &clicked, &unsubscribed); err != nil {
+			return nil, err
+		}
+		b := bucket(start)
+		b.Opened = opened
+		b.Clicked = clicked
+		b.Unsubscribed = unsubscribed
+	}
+	if err := events.Err(); err != nil {
+		return nil, err
+	}
+
+	series := make([]models.StatsBucket, 0, len(buckets))
+	for _, b := range buckets {
+		b.ComputeRates()
+		series = append(series, *b)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Start.Before(series[j].Start) })
+	return series, nil
+}
+
+// engagementColumns counts unique opened and clicked emails and unsubscribed customers
+var engagementColumns = fmt.Sprintf(
+	"count(DISTINCT CASE WHEN type IN ('%s', '%s') THEN email_log_id END), "+
+		"count(DISTINCT CASE WHEN type = '%s' THEN email_log_id END), "+
+		"count(DISTINCT CASE WHEN type = '%s' THEN customer_id END)",
+	models.EventOpen, models.EventClick, models.EventClick, models.EventUnsubscribe)
+
+func engagementQuery() *gorm.DB {
+	return database.DB.Model(&models.EngagementEvent{}).Where("bot = ?", false)
+}
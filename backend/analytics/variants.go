@@ -0,0 +1,85 @@
+package analytics
+
+import (
+	"fmt"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+// variantColumns counts unique opened, clicked and replied emails
+var variantColumns = fmt.Sprintf(
+	"count(DISTINCT CASE WHEN engagement_events.type IN ('%s', '%s') THEN engagement_events.email_log_id END), "+
+		"count(DISTINCT CASE WHEN engagement_events.type = '%s' THEN engagement_events.email_log_id END), "+
+		"count(DISTINCT CASE WHEN engagement_events.type = '%s' THEN engagement_events.email_log_id END)",
+	models.EventOpen, models.EventClick, models.EventClick, models.EventReply)
+
+// VariantStats counts the sends, opens, clicks and replies of each of a
+// step's variants, in the order given
+func VariantStats(stepID uint, variants []models.StepVariant) ([]models.VariantStats, error) {
+	byVariant := map[uint]*models.VariantStats{}
+	stats := make([]models.VariantStats, len(variants))
+	for i, variant := range variants {
+		stats[i] = models.VariantStats{
+			VariantID:       variant.ID,
+			Name:            variant.Name,
+			EmailTemplateID: variant.EmailTemplateID,
+			Weight:          variant.Weight,
+		}
+		byVariant[variant.ID] = &stats[i]
+	}
+
+	sends, err := database.DB.Model(&models.EmailLog{}).
+		Select("variant_id, count(*)").
+		Where("step_id = ? AND variant_id <> 0 AND status IN (?)", stepID, sentStatuses).
+		Group("variant_id").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer sends.Close()
+	for sends.Next() {
+		var variantID uint
+		var sent int
+		if err := sends.Scan(&variantID, &sent); err != nil {
+			return nil, err
+		}
+		if s := byVariant[variantID]; s != nil {
+			s.Sent = sent
+		}
+	}
+	if err := sends.Err(); err != nil {
+		return nil, err
+	}
+
+	events, err := engagementQuery().
+		Select("email_logs.variant_id, "+variantColumns).
+		Joins("JOIN email_logs ON email_logs.id = engagement_events.email_log_id").
+		Where("email_logs.step_id = ? AND email_logs.variant_id <> 0", stepID).
+		Group("email_logs.variant_id").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer events.Close()
+	for events.Next() {
+		var variantID uint
+		var opened, clicked, replied int
+		if err := events.Scan(&variantID, &opened, &clicked, &replied); err != nil {
+			return nil, err
+		}
+		if s := byVariant[variantID]; s != nil {
+			s.Opened = opened
+			s.Clicked = clicked
+			s.Replied = replied
+		}
+	}
+	if err := events.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range stats {
+		stats[i].ComputeRates()
+	}
+	return stats, nil
+}
@@ -0,0 +1,327 @@
+// Package assets stores uploaded files that emails attach or show inline.
+// File contents live in a Store, local disk by default, addressed by their
+// SHA-256 so identical uploads are kept once.
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// Store keeps file contents by key. Implementations for object storage
+// such as S3 can replace the local disk with SetStore.
+type Store interface {
+	Put(key string, data io.Reader) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+var (
+	// ErrNotFound is returned for contents that aren't in the store
+	ErrNotFound = errors.New("asset contents not found")
+	// ErrTooLarge is returned for uploads over the size limit
+	ErrTooLarge = errors.New("file is too large")
+	// ErrUnsupportedType is returned for files of a type emails can't carry
+	ErrUnsupportedType = errors.New("file type is not allowed")
+)
+
+var (
+	mu    sync.RWMutex
+	store Store = Local{Dir: "uploads"}
+)
+
+// SetStore replaces the store asset contents are kept in
+func SetStore(s Store) {
+	mu.Lock()
+	defer mu.Unlock()
+	store = s
+}
+
+func current() Store {
+	mu.RLock()
+	defer mu.RUnlock()
+	return store
+}
+
+// Local is a Store on local disk. Files are spread over subdirectories named
+// after the first two characters of their key.
+type Local struct {
+	Dir string
+}
+
+var keyPattern = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+func (l Local) path(key string) (string, error) {
+	if !keyPattern.MatchString(key) {
+		return "", fmt.Errorf("invalid asset key %q", key)
+	}
+	return filepath.Join(l.Dir, key[:2], key), nil
+}
+
+// Put implements Store. The file is written under a temporary name and then
+// renamed, so a partly written file is never read.
+func (l Local) Put(key string, data io.Reader) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get implements Store
+func (l Local) Get(key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete implements Store
+func (l Local) Delete(key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// allowedTypes are the file types that can be uploaded, by their sniffed
+// MIME type. Executables, scripts and SVG, which can carry scripts, aren't.
+var allowedTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+	"text/csv":        true,
+	"text/calendar":   true,
+	"application/zip": true,
+
+	// Office documents
+	"application/msword":            true,
+	"application/vnd.ms-excel":      true,
+	"application/vnd.ms-powerpoint": true,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         true,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": true,
+	"application/vnd.oasis.opendocument.text":                                   true,
+	"application/vnd.oasis.opendocument.spreadsheet":                            true,
+	"application/vnd.oasis.opendocument.presentation":                           true,
+}
+
+// blockedExtensions are rejected whatever their contents, as mail providers
+// such as Gmail refuse messages that attach them
+var blockedExtensions = map[string]bool{
+	".ade": true, ".adp": true, ".apk": true, ".appx": true, ".bat": true, ".cab": true, ".chm": true,
+	".cmd": true, ".com": true, ".cpl": true, ".dll": true, ".dmg": true, ".exe": true, ".hta": true,
+	".ins": true, ".isp": true, ".jar": true, ".js": true, ".jse": true, ".lib": true, ".lnk": true,
+	".mde": true, ".msc": true, ".msi": true, ".msix": true, ".msp": true, ".mst": true, ".nsh": true,
+	".pif": true, ".ps1": true, ".scr": true, ".sct": true, ".sh": true, ".shb": true, ".sys": true,
+	".vb": true, ".vbe": true, ".vbs": true, ".vxd": true, ".wsc": true, ".wsf": true, ".wsh": true,
+}
+
+// Sniff detects a file's type from its contents, not its name or the type
+// the client claimed, and reports whether it may be uploaded
+func Sniff(data []byte) (string, bool) {
+	detected := mimetype.Detect(data)
+	contentType, _, err := mime.ParseMediaType(detected.String())
+	if err != nil {
+		contentType = "application/octet-stream"
+	}
+	return contentType, allowedTypes[contentType]
+}
+
+// IsImage reports whether an asset can be shown inline in an HTML body
+func IsImage(asset *models.Asset) bool {
+	return strings.HasPrefix(asset.ContentType, "image/")
+}
+
+var unsafeFilename = regexp.MustCompile(`[^\p{L}\p{N} ._()-]+`)
+
+// CleanFilename strips directories and characters that would break a
+// Content-Disposition header from an uploaded file's name
+func CleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.TrimSpace(unsafeFilename.ReplaceAllString(name, "_"))
+	if name == "" || name == "." || name == "_" {
+		name = "file"
+	}
+	if len(name) > 200 {
+		ext := filepath.Ext(name)
+		if len(ext) > 20 {
+			ext = ""
+		}
+		name = name[:200-len(ext)] + ext
+	}
+	return name
+}
+
+var (
+	contentIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+(@[A-Za-z0-9.-]+)?$`)
+	contentIDUnsafe  = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// ValidContentID reports whether an inline image's Content-ID can be used
+// in a header and in src="cid:..."
+func ValidContentID(id string) bool {
+	return len(id) <= 200 && contentIDPattern.MatchString(id)
+}
+
+// DefaultContentID derives an inline image's Content-ID from its filename,
+// so logo.png is shown with src="cid:logo.png"
+func DefaultContentID(filename string) string {
+	id := strings.Trim(contentIDUnsafe.ReplaceAllString(filename, "-"), "-")
+	if id == "" {
+		return "image"
+	}
+	return id
+}
+
+// Save stores an uploaded file and records it as an asset. Files over
+// maxBytes, when that's positive, or of a type that isn't allowed are
+// rejected.
+func Save(filename string, data []byte, maxBytes int64, userID uint) (*models.Asset, error) {
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		return nil, ErrTooLarge
+	}
+	filename = CleanFilename(filename)
+	if ext := strings.ToLower(filepath.Ext(filename)); blockedExtensions[ext] {
+		return nil, fmt.Errorf("%w: %s files", ErrUnsupportedType, ext)
+	}
+	contentType, ok := Sniff(data)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:])
+	if err := current().Put(key, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	asset := models.Asset{
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      key,
+		UploadedBy:  userID,
+	}
+	if err := database.DB.Create(&asset).Error; err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// Read returns an asset's contents
+func Read(asset *models.Asset) ([]byte, error) {
+	r, err := current().Get(asset.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Delete removes an asset, and its contents once no other asset has the
+// same contents
+func Delete(asset *models.Asset) error {
+	if err := database.DB.Delete(asset).Error; err != nil {
+		return err
+	}
+	var others int
+	if err := database.DB.Model(&models.Asset{}).Where("sha256 = ?", asset.SHA256).Count(&others).Error; err != nil {
+		return err
+	}
+	if others > 0 {
+		return nil
+	}
+	return current().Delete(asset.SHA256)
+}
+
+// InUse lists the attachments that use an asset
+func InUse(asset *models.Asset) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := database.DB.Where("asset_id = ?", asset.ID).Find(&attachments).Error
+	return attachments, err
+}
+
+// ForEmail lists what an email sending a template from a step attaches: the
+// template's attachments, then the step's. Inline images are left out of
+// plain-text emails, which can't show them.
+func ForEmail(templateID, stepID uint, html bool) ([]models.QueuedAttachment, error) {
+	var attachments []models.Attachment
+	query := database.DB.Where("email_template_id = ?", templateID)
+	if stepID != 0 {
+		query = query.Or("step_id = ?", stepID)
+	}
+	if err := query.Order("step_id asc, id asc").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+
+	var queued []models.QueuedAttachment
+	for _, attachment := range attachments {
+		if attachment.Inline && !html {
+			continue
+		}
+		var asset models.Asset
+		if err := database.DB.First(&asset, attachment.AssetID).Error; err != nil {
+			return nil, err
+		}
+		queued = append(queued, models.QueuedAttachment{
+			AssetID:     asset.ID,
+			Filename:    asset.Filename,
+			ContentType: asset.ContentType,
+			Size:        asset.Size,
+			ContentID:   attachment.ContentID,
+		})
+	}
+	return queued, nil
+}
+
+// TotalSize adds up the size of the attachments
+func TotalSize(attachments []models.QueuedAttachment) int64 {
+	var total int64
+	for _, attachment := range attachments {
+		total += attachment.Size
+	}
+	return total
+}
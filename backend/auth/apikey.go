@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	APIKeyIDKey = "api_key_id"
+
+	apiKeyPrefix       = "dk_"
+	apiKeyHeader       = "X-API-Key"
+	apiKeyTouchEvery   = time.Minute
+	apiKeyPrefixLength = len(apiKeyPrefix) + 8
+)
+
+// Scope actions. A scope is written as "<resource>:<action>", e.g. "customers:write",
+// where the resource is the first path segment after /api/v1. "<resource>:*" grants
+// both actions on a resource and "*" grants everything.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAll   = "*"
+)
+
+// ErrAPIKeyScope is returned when a valid API key lacks the scope for a route
+var ErrAPIKeyScope = errors.New("api key missing required scope")
+
+// apiKeyForbiddenResources can only be used with a user session, never an API key
+var apiKeyForbiddenResources = map[string]bool{
+	"me":       true,
+	"api-keys": true,
+}
+
+// GenerateAPIKey returns a new API key, its display prefix and the hash to store
+func GenerateAPIKey() (string, string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return key, key[:apiKeyPrefixLength], HashToken(key), nil
+}
+
+// ValidateScope checks that a scope is well formed
+func ValidateScope(scope string) error {
+	if scope == ScopeAll {
+		return nil
+	}
+	parts := strings.Split(scope, ":")
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("invalid scope %q, expected <resource>:<read|write|*>", scope)
+	}
+	if apiKeyForbiddenResources[parts[0]] {
+		return fmt.Errorf("scope %q can't be granted to an API key", scope)
+	}
+	switch parts[1] {
+	case ScopeRead, ScopeWrite, ScopeAll:
+		return nil
+	}
+	return fmt.Errorf("invalid scope %q, expected <resource>:<read|write|*>", scope)
+}
+
+// RequiredScope returns the scope needed for the matched route
+func RequiredScope(c *gin.Context) string {
+	path := strings.TrimPrefix(c.FullPath(), "/api/v1/")
+	resource := strings.SplitN(path, "/", 2)[0]
+
+	action := ScopeWrite
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		action = ScopeRead
+	}
+	return resource + ":" + action
+}
+
+// ScopeAllows reports whether any of the granted scopes covers the required one
+func ScopeAllows(granted []string, required string) bool {
+	resource := strings.SplitN(required, ":", 2)[0]
+	if apiKeyForbiddenResources[resource] {
+		return false
+	}
+	for _, scope := range granted {
+		if scope == ScopeAll || scope == required || scope == resource+":"+ScopeAll {
+			return true
+		}
+	}
+	return false
+}
+
+// extractAPIKey returns the API key sent in the X-API-Key header or as a bearer token
+func extractAPIKey(c *gin.Context) string {
+	if key := c.GetHeader(apiKeyHeader); key != "" {
+		return key
+	}
+	if token := ExtractToken(c); strings.HasPrefix(token, apiKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// authenticateAPIKey verifies an API key and its scopes and stores the owning user on the context
+func authenticateAPIKey(c *gin.Context, key string) error {
+	var apiKey models.APIKey
+	if err := database.DB.Where("key_hash = ?", HashToken(key)).First(&apiKey).Error; err != nil {
+		return fmt.Errorf("invalid api key")
+	}
+
+	now := time.Now()
+	if !apiKey.Active(now) {
+		return fmt.Errorf("api key expired or revoked")
+	}
+
+	// Keys stop working once their owner is deleted
+	var owner models.User
+	if err := database.DB.First(&owner, apiKey.UserID).Error; err != nil {
+		return fmt.Errorf("api key owner not found")
+	}
+
+	if !ScopeAllows(apiKey.ScopeList(), RequiredScope(c)) {
+		return ErrAPIKeyScope
+	}
+
+	// Avoid a write on every request for busy integrations
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyTouchEvery {
+		if err := database.DB.Model(&apiKey).UpdateColumn("last_used_at", now).Error; err != nil {
+			log.Println("Error updating api key last used:", err)
+		}
+	}
+
+	c.Set(UserIDKey, apiKey.UserID)
+	c.Set(RoleKey, models.UserRole)
+	c.Set(APIKeyIDKey, apiKey.ID)
+	return nil
+}
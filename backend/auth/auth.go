@@ -4,18 +4,56 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/4cecoder/drip-campaign/config"
+	"github.com/4cecoder/drip-campaign/database"
 	"github.com/4cecoder/drip-campaign/models"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 )
 
-var jwtKey = []byte("your-secret-key")
+// jwtKey signs session tokens and the links in emails. It's set from
+// JWT_SECRET by SetSecret when the application starts.
+var jwtKey []byte
+
+// minSecretLength is the shortest JWT_SECRET accepted, in bytes
+const minSecretLength = 32
+
+// placeholderSecrets are example values of JWT_SECRET, which are public
+var placeholderSecrets = []string{"your-secret-key", "your_jwt_secret"}
+
+// SetSecret sets the key that signs session tokens and email links. It
+// rejects an empty or short secret and the example values, since anyone who
+// knows the key can sign in as an admin and forge links.
+func SetSecret(secret string) error {
+	if secret == "" {
+		return fmt.Errorf("no secret configured, set JWT_SECRET")
+	}
+	for _, placeholder := range placeholderSecrets {
+		if secret == placeholder {
+			return fmt.Errorf("JWT_SECRET is still the example value %q", placeholder)
+		}
+	}
+	if len(secret) < minSecretLength {
+		return fmt.Errorf("JWT_SECRET must be at least %d characters", minSecretLength)
+	}
+	jwtKey = []byte(secret)
+	return nil
+}
+
+// Context keys set by the auth middlewares for downstream handlers
+const (
+	UserIDKey = "user_id"
+	RoleKey   = "role"
+)
 
 func GenerateToken(user *models.User) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"id":   user.ID,
 		"role": "user",
+		"ver":  user.TokenVersion,
+		"exp":  sessionExpiry(),
 	})
 	return token.SignedString(jwtKey)
 }
@@ -24,33 +62,162 @@ func GenerateAdminToken(user *models.User) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"id":   user.ID,
 		"role": "admin",
+		"ver":  user.TokenVersion,
+		"exp":  sessionExpiry(),
+	})
+	return token.SignedString(jwtKey)
+}
+
+// sessionExpiry is when a session token issued now expires, SESSION_HOURS later
+func sessionExpiry() int64 {
+	hours := config.LoadConfig().SessionHours
+	if hours <= 0 {
+		hours = 24
+	}
+	return time.Now().Add(time.Duration(hours) * time.Hour).Unix()
+}
+
+// GenerateUserToken issues the session token matching the user's role
+func GenerateUserToken(user *models.User) (string, error) {
+	if user.Role == models.AdminRole {
+		return GenerateAdminToken(user)
+	}
+	return GenerateToken(user)
+}
+
+// Challenge token purposes for the second login step
+const (
+	ChallengeTwoFactor      = "2fa"
+	ChallengeTwoFactorSetup = "2fa_setup"
+)
+
+const challengeTTL = 5 * time.Minute
+
+// GenerateChallengeToken issues a short-lived token proving the password step of login succeeded
+func GenerateChallengeToken(user *models.User, purpose string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"challenge_id": user.ID,
+		"purpose":      purpose,
+		"ver":          user.TokenVersion,
+		"exp":          time.Now().Add(challengeTTL).Unix(),
 	})
 	return token.SignedString(jwtKey)
 }
 
+// ParseChallengeToken validates a challenge token and returns the user ID and purpose
+func ParseChallengeToken(tokenString string) (uint, string, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return 0, "", err
+	}
+
+	purpose, _ := claims["purpose"].(string)
+	if purpose != ChallengeTwoFactor && purpose != ChallengeTwoFactorSetup {
+		return 0, "", fmt.Errorf("invalid token purpose")
+	}
+
+	id, ok := claims["challenge_id"].(float64)
+	if !ok {
+		return 0, "", fmt.Errorf("invalid user id")
+	}
+
+	return uint(id), purpose, nil
+}
+
+// GenerateInviteToken signs an expiring invite token bound to the invitation ID and email
+func GenerateInviteToken(invitation *models.Invitation) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"invite_id": invitation.ID,
+		"email":     invitation.Email,
+		"purpose":   "invite",
+		"exp":       invitation.ExpiresAt.Unix(),
+	})
+	return token.SignedString(jwtKey)
+}
+
+// ParseInviteToken validates the signature and expiry of an invite token and returns the invitation ID
+func ParseInviteToken(tokenString string) (uint, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return 0, err
+	}
+
+	if purpose, _ := claims["purpose"].(string); purpose != "invite" {
+		return 0, fmt.Errorf("invalid token purpose")
+	}
+
+	id, ok := claims["invite_id"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("invalid invite id")
+	}
+
+	return uint(id), nil
+}
+
 func VerifyToken(c *gin.Context) (string, error) {
-	tokenString := ExtractToken(c)
+	claims, err := ParseToken(c)
+	if err != nil {
+		return "", err
+	}
+
+	role, ok := claims["role"].(string)
+	if !ok {
+		return "", fmt.Errorf("invalid role")
+	}
+
+	return role, nil
+}
+
+// ParseToken validates the bearer token of the request and returns its claims
+func ParseToken(c *gin.Context) (jwt.MapClaims, error) {
+	return parseClaims(ExtractToken(c))
+}
+
+func parseClaims(tokenString string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("invalid signing method")
 		}
+		if len(jwtKey) == 0 {
+			return nil, fmt.Errorf("no secret configured")
+		}
 		return jwtKey, nil
 	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
-		return "", fmt.Errorf("invalid token")
+		return nil, fmt.Errorf("invalid token")
+	}
+	if err := checkTokenVersion(claims); err != nil {
+		return nil, err
 	}
 
-	role, ok := claims["role"].(string)
+	return claims, nil
+}
+
+// checkTokenVersion rejects a session or challenge token issued before its
+// user's password last changed, or for a user who was deleted
+func checkTokenVersion(claims jwt.MapClaims) error {
+	id, ok := claims["id"].(float64)
 	if !ok {
-		return "", fmt.Errorf("invalid role")
+		if id, ok = claims["challenge_id"].(float64); !ok {
+			// Invite tokens aren't for a user yet
+			return nil
+		}
 	}
+	version, _ := claims["ver"].(float64)
 
-	return role, nil
+	var user models.User
+	if err := database.DB.Select("id, token_version").First(&user, uint(id)).Error; err != nil {
+		return fmt.Errorf("invalid user id")
+	}
+	if user.TokenVersion != int(version) {
+		return fmt.Errorf("token has been revoked")
+	}
+	return nil
 }
 
 func ExtractToken(c *gin.Context) string {
@@ -61,8 +228,29 @@ func ExtractToken(c *gin.Context) string {
 	return ""
 }
 
+// CurrentUserID returns the ID of the authenticated user stored by the auth middlewares
+func CurrentUserID(c *gin.Context) uint {
+	return c.GetUint(UserIDKey)
+}
+
+// IsUserOrAdmin accepts a user or admin session token, or an API key with a scope
+// covering the requested route. API keys always act with the user role.
 func IsUserOrAdmin(c *gin.Context) {
-	role, err := VerifyToken(c)
+	if key := extractAPIKey(c); key != "" {
+		if err := authenticateAPIKey(c, key); err == ErrAPIKeyScope {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key is missing scope " + RequiredScope(c)})
+			c.Abort()
+			return
+		} else if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+		c.Next()
+		return
+	}
+
+	role, err := authenticate(c)
 	if err != nil || (role != models.UserRole && role != models.AdminRole) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		c.Abort()
@@ -73,7 +261,7 @@ func IsUserOrAdmin(c *gin.Context) {
 
 func AuthMiddleware(requiredRole string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, err := VerifyToken(c)
+		role, err := authenticate(c)
 		if err != nil || role != requiredRole {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			c.Abort()
@@ -82,3 +270,29 @@ func AuthMiddleware(requiredRole string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// authenticate verifies the bearer token and stores the user ID and role on the context
+func authenticate(c *gin.Context) (string, error) {
+	claims, err := ParseToken(c)
+	if err != nil {
+		return "", err
+	}
+
+	role, ok := claims["role"].(string)
+	if !ok {
+		return "", fmt.Errorf("invalid role")
+	}
+
+	id, ok := claims["id"].(float64)
+	if !ok {
+		return "", fmt.Errorf("invalid user id")
+	}
+	// jwt only checks exp when it's there; sessions must have one
+	if _, ok := claims["exp"].(float64); !ok {
+		return "", fmt.Errorf("token has no expiry")
+	}
+
+	c.Set(UserIDKey, uint(id))
+	c.Set(RoleKey, role)
+	return role, nil
+}
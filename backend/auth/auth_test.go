@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+const testSecret = "test-secret-that-is-long-enough-to-be-accepted"
+
+// setup signs with testSecret and points database.DB at an in-memory
+// database holding one user
+func setup(t *testing.T) *models.User {
+	t.Helper()
+	if err := SetSecret(testSecret); err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		db.Close()
+	})
+
+	user := &models.User{Email: "admin@example.com", Password: "correct horse battery", Role: models.AdminRole}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestSetSecret(t *testing.T) {
+	for _, secret := range []string{"", "your-secret-key", "your_jwt_secret", "too-short"} {
+		if err := SetSecret(secret); err == nil {
+			t.Errorf("SetSecret(%q) was accepted", secret)
+		}
+	}
+}
+
+func TestSessionTokenRevokedByPasswordChange(t *testing.T) {
+	user := setup(t)
+	token, err := GenerateUserToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := GenerateChallengeToken(user, ChallengeTwoFactor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseClaims(token); err != nil {
+		t.Fatalf("fresh token rejected: %v", err)
+	}
+
+	if err := models.UpdatePassword(database.DB, user, "a brand new password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseClaims(token); err == nil {
+		t.Error("session token issued before the password change was accepted")
+	}
+	if _, _, err := ParseChallengeToken(challenge); err == nil {
+		t.Error("challenge token issued before the password change was accepted")
+	}
+
+	fresh, err := GenerateUserToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseClaims(fresh); err != nil {
+		t.Errorf("token issued after the password change rejected: %v", err)
+	}
+}
+
+func TestSessionTokenOfDeletedUser(t *testing.T) {
+	user := setup(t)
+	token, err := GenerateUserToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.DB.Delete(user)
+	if _, err := parseClaims(token); err == nil {
+		t.Error("token of a deleted user was accepted")
+	}
+}
+
+// bearer returns a request context carrying token
+func bearer(token string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/v1/me", nil)
+	c.Request.Header.Set("Authorization", "Bearer "+token)
+	return c
+}
+
+func TestAuthenticate(t *testing.T) {
+	user := setup(t)
+	sign := func(claims jwt.MapClaims, key string) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	session, err := GenerateAdminToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := GenerateChallengeToken(user, ChallengeTwoFactor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"session", session, true},
+		{"expired", sign(jwt.MapClaims{"id": user.ID, "role": "admin", "ver": 0, "exp": time.Now().Add(-time.Minute).Unix()}, testSecret), false},
+		{"no expiry", sign(jwt.MapClaims{"id": user.ID, "role": "admin", "ver": 0}, testSecret), false},
+		{"old default key", sign(jwt.MapClaims{"id": user.ID, "role": "admin", "ver": 0, "exp": later}, "your-secret-key"), false},
+		{"challenge", challenge, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, err := authenticate(bearer(tt.token))
+			if tt.ok && (err != nil || role != models.AdminRole) {
+				t.Errorf("authenticate = %q, %v, want admin", role, err)
+			}
+			if !tt.ok && err == nil {
+				t.Errorf("token accepted with role %q", role)
+			}
+		})
+	}
+}
+
+func TestSessionTokenExpiry(t *testing.T) {
+	user := setup(t)
+	token, err := GenerateToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := parseClaims(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp, _ := claims["exp"].(float64)
+	if remaining := time.Until(time.Unix(int64(exp), 0)); remaining <= 23*time.Hour || remaining > 24*time.Hour {
+		t.Errorf("session expires in %v, want 24h", remaining)
+	}
+}
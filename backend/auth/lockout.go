@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/4cecoder/drip-campaign/config"
+)
+
+// LoginPolicy controls how repeated failed logins are slowed down and locked out
+type LoginPolicy struct {
+	// FreeAttempts is the number of failures allowed before backoff kicks in
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+
+	MaxAccountFailures int
+	LockoutDuration    time.Duration
+
+	MaxIPFailures int
+	IPWindow      time.Duration
+}
+
+// NewLoginPolicy builds the login policy from the application config
+func NewLoginPolicy(cfg *config.Config) LoginPolicy {
+	return LoginPolicy{
+		FreeAttempts:       3,
+		BaseDelay:          time.Second,
+		MaxDelay:           5 * time.Minute,
+		MaxAccountFailures: cfg.LoginMaxFailures,
+		LockoutDuration:    time.Duration(cfg.LoginLockoutMinutes) * time.Minute,
+		MaxIPFailures:      cfg.LoginMaxIPFailures,
+		IPWindow:           time.Duration(cfg.LoginLockoutMinutes) * time.Minute,
+	}
+}
+
+// Backoff returns how long to wait after the given number of consecutive failures.
+// The delay doubles with every failure past FreeAttempts, up to MaxDelay.
+func (p LoginPolicy) Backoff(failures int) time.Duration {
+	if failures < p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts; i < failures; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return delay
+}
+
+// Failures returns the consecutive failures that still count against an account.
+// Once a lockout has expired the account starts again from zero, so a single
+// wrong password doesn't lock it straight back out or inherit the old backoff.
+func (p LoginPolicy) Failures(count int, lockedUntil *time.Time, now time.Time) int {
+	if lockedUntil != nil && !now.Before(*lockedUntil) {
+		return 0
+	}
+	return count
+}
+
+// RetryAfter returns the remaining wait before another attempt is allowed, or zero
+func (p LoginPolicy) RetryAfter(failures int, lastFailure, now time.Time) time.Duration {
+	wait := lastFailure.Add(p.Backoff(failures)).Sub(now)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
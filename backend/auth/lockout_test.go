@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+var testPolicy = LoginPolicy{
+	FreeAttempts:       3,
+	BaseDelay:          time.Second,
+	MaxDelay:           5 * time.Minute,
+	MaxAccountFailures: 10,
+	LockoutDuration:    15 * time.Minute,
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{6, 8 * time.Second},
+		{20, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := testPolicy.Backoff(tt.failures); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestFailures(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	future, past := now.Add(time.Minute), now.Add(-time.Minute)
+
+	tests := []struct {
+		name        string
+		count       int
+		lockedUntil *time.Time
+		want        int
+	}{
+		{"never locked", 4, nil, 4},
+		{"locked", 10, &future, 10},
+		{"lockout expired", 10, &past, 0},
+		{"lockout expires now", 10, &now, 0},
+	}
+	for _, tt := range tests {
+		if got := testPolicy.Failures(tt.count, tt.lockedUntil, now); got != tt.want {
+			t.Errorf("%s: Failures = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// The backoff from before the lockout doesn't carry over
+	lastFailure := past.Add(-testPolicy.LockoutDuration)
+	if wait := testPolicy.RetryAfter(testPolicy.Failures(10, &past, now), lastFailure, now); wait != 0 {
+		t.Errorf("RetryAfter an expired lockout = %s, want 0", wait)
+	}
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// NewOpaqueToken returns a random URL-safe token and the hash that should be stored in its place
+func NewOpaqueToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// HashToken hashes an opaque token for storage and lookup
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign returns a URL-safe HMAC signature of payload, for links that must not be forged
+func Sign(payload string) string {
+	mac := hmac.New(sha256.New, jwtKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// VerifySignature reports whether signature was produced by Sign for payload.
+// Nothing verifies before SetSecret is called.
+func VerifySignature(payload, signature string) bool {
+	if len(jwtKey) == 0 {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(payload)), []byte(signature))
+}
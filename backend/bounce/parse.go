@@ -0,0 +1,277 @@
+// Package bounce parses delivery status notifications (RFC 3464) and ARF
+// feedback-loop reports (RFC 5965) and applies them to the email log.
+package bounce
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// Report kinds
+const (
+	Hard      = "hard"
+	Soft      = "soft"
+	Complaint = "complaint"
+)
+
+// ErrNotReport is returned for messages that are neither a DSN nor a feedback report
+var ErrNotReport = errors.New("message is not a bounce or complaint report")
+
+// Report is a single failed recipient or complaint extracted from a message
+type Report struct {
+	Kind              string `json:"kind"`
+	Recipient         string `json:"recipient"`
+	Action            string `json:"action,omitempty"`
+	Status            string `json:"status,omitempty"`
+	Diagnostic        string `json:"diagnostic,omitempty"`
+	FeedbackType      string `json:"feedback_type,omitempty"`
+	OriginalMessageID string `json:"original_message_id,omitempty"`
+}
+
+// maxPartSize caps how much of a single MIME part is read
+const maxPartSize = 10 << 20
+
+var (
+	statusCodeRegex = regexp.MustCompile(`\b([245]\.\d{1,3}\.\d{1,3})\b`)
+	messageIDRegex  = regexp.MustCompile(`(?im)^message-id:\s*(<[^>\s]+>)`)
+)
+
+// Parse extracts the bounce and complaint reports from a raw RFC 822 message.
+// Reports that aren't failures (delivered, relayed, not-spam...) are left out.
+func Parse(r io.Reader) ([]Report, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var p parser
+	if err := p.walk(textproto.MIMEHeader(msg.Header), msg.Body); err != nil {
+		return nil, err
+	}
+
+	if !p.found {
+		// Some MTAs still send plain-text bounces; trust them only when they
+		// name the failed recipients in a header
+		failed := msg.Header.Get("X-Failed-Recipients")
+		if failed == "" {
+			return nil, ErrNotReport
+		}
+		for _, addr := range strings.Split(failed, ",") {
+			report := Report{Recipient: cleanAddress(addr), Action: "failed"}
+			if m := statusCodeRegex.FindStringSubmatch(p.text); m != nil {
+				report.Status = m[1]
+			}
+			p.reports = append(p.reports, report)
+		}
+	}
+
+	reports := make([]Report, 0, len(p.reports))
+	for _, report := range p.reports {
+		if report.Kind == "" {
+			report.Kind = classify(report.Action, report.Status)
+		}
+		if report.Kind == "" || report.Recipient == "" {
+			continue
+		}
+		if report.OriginalMessageID == "" {
+			report.OriginalMessageID = p.originalMessageID
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// classify returns the kind of a DSN recipient from its action and status code,
+// or "" when the message was actually delivered
+func classify(action, status string) string {
+	switch strings.ToLower(action) {
+	case "delayed":
+		return Soft
+	case "failed":
+	default:
+		return ""
+	}
+
+	// 5.2.2 is a full mailbox, which usually clears up on its own
+	if strings.HasPrefix(status, "4.") || status == "5.2.2" {
+		return Soft
+	}
+	return Hard
+}
+
+// parser accumulates reports while walking a MIME tree
+type parser struct {
+	found             bool
+	reports           []Report
+	originalMessageID string
+	text              string
+}
+
+func (p *parser) walk(header textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := p.walk(part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := io.ReadAll(io.LimitReader(decode(header, body), maxPartSize))
+	if err != nil {
+		return err
+	}
+
+	switch mediaType {
+	case "message/delivery-status", "message/global-delivery-status":
+		p.found = true
+		p.parseDeliveryStatus(content)
+	case "message/feedback-report":
+		p.found = true
+		p.parseFeedbackReport(content)
+	case "message/rfc822", "message/global", "text/rfc822-headers", "message/rfc822-headers":
+		p.parseOriginal(content)
+	case "text/plain":
+		if p.text == "" {
+			p.text = string(content)
+		}
+	}
+	return nil
+}
+
+// parseDeliveryStatus reads the per-recipient field groups of a DSN
+func (p *parser) parseDeliveryStatus(content []byte) {
+	groups := readFieldGroups(content)
+	if len(groups) < 2 {
+		return
+	}
+
+	// The first group holds the per-message fields
+	for _, fields := range groups[1:] {
+		recipient := fields.Get("Final-Recipient")
+		if recipient == "" {
+			recipient = fields.Get("Original-Recipient")
+		}
+
+		status := fields.Get("Status")
+		if m := statusCodeRegex.FindStringSubmatch(status); m != nil {
+			status = m[1]
+		}
+
+		p.reports = append(p.reports, Report{
+			Recipient:  cleanAddress(recipient),
+			Action:     strings.TrimSpace(fields.Get("Action")),
+			Status:     status,
+			Diagnostic: strings.TrimSpace(fields.Get("Diagnostic-Code")),
+		})
+	}
+}
+
+// parseFeedbackReport reads an ARF report, which covers a single message
+func (p *parser) parseFeedbackReport(content []byte) {
+	groups := readFieldGroups(content)
+	if len(groups) == 0 {
+		return
+	}
+	fields := groups[0]
+
+	feedbackType := strings.ToLower(strings.TrimSpace(fields.Get("Feedback-Type")))
+	if feedbackType == "not-spam" {
+		return
+	}
+
+	recipient := fields.Get("Original-Rcpt-To")
+	if recipient == "" {
+		recipient = fields.Get("Removal-Recipient")
+	}
+	p.reports = append(p.reports, Report{
+		Kind:         Complaint,
+		Recipient:    cleanAddress(recipient),
+		FeedbackType: feedbackType,
+	})
+}
+
+// parseOriginal picks the Message-ID and recipient out of the returned message
+func (p *parser) parseOriginal(content []byte) {
+	fields := readFieldGroups(content)
+	if len(fields) == 0 {
+		return
+	}
+
+	if id := strings.TrimSpace(fields[0].Get("Message-Id")); id != "" {
+		p.originalMessageID = id
+	} else if m := messageIDRegex.FindSubmatch(content); m != nil {
+		p.originalMessageID = string(m[1])
+	}
+
+	// Feedback reports often leave out Original-Rcpt-To, the complaint is
+	// then about whoever the original was sent to
+	to := cleanAddress(fields[0].Get("To"))
+	for i := range p.reports {
+		if p.reports[i].Kind == Complaint && p.reports[i].Recipient == "" {
+			p.reports[i].Recipient = to
+		}
+	}
+}
+
+// readFieldGroups parses blank-line separated groups of header fields
+func readFieldGroups(content []byte) []textproto.MIMEHeader {
+	content = bytes.TrimLeft(content, "\r\n")
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(content)))
+
+	var groups []textproto.MIMEHeader
+	for {
+		fields, err := r.ReadMIMEHeader()
+		if len(fields) > 0 {
+			groups = append(groups, fields)
+		}
+		if err != nil {
+			return groups
+		}
+	}
+}
+
+// decode undoes the part's content transfer encoding
+func decode(header textproto.MIMEHeader, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// cleanAddress turns "rfc822; <user@example.com>" into "user@example.com"
+func cleanAddress(value string) string {
+	value = strings.TrimSpace(value)
+	if i := strings.Index(value, ";"); i >= 0 {
+		value = value[i+1:]
+	}
+	if addr, err := mail.ParseAddress(value); err == nil {
+		value = addr.Address
+	}
+	return strings.ToLower(strings.Trim(strings.TrimSpace(value), "<>"))
+}
@@ -0,0 +1,130 @@
+package bounce
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		file    string
+		reports []Report
+	}{
+		{
+			file: "postfix-hard.eml",
+			reports: []Report{{
+				Kind:              Hard,
+				Recipient:         "nobody@customer.test",
+				Action:            "failed",
+				Status:            "5.1.1",
+				Diagnostic:        "smtp; 550 5.1.1 <nobody@customer.test>: Recipient address rejected: User unknown",
+				OriginalMessageID: "<drip-42.1760349700@example.com>",
+			}},
+		},
+		{
+			file: "delayed.eml",
+			reports: []Report{{
+				Kind:              Soft,
+				Recipient:         "slow@customer.test",
+				Action:            "delayed",
+				Status:            "4.4.1",
+				Diagnostic:        "smtp; The recipient server did not accept our requests to connect.",
+				OriginalMessageID: "<drip-43.1760380000@example.com>",
+			}},
+		},
+		{
+			// A full mailbox is soft, delivered recipients are left out and
+			// the delivery status part is base64 encoded
+			file: "mixed-recipients.eml",
+			reports: []Report{
+				{
+					Kind:              Soft,
+					Recipient:         "full@customer.test",
+					Action:            "failed",
+					Status:            "5.2.2",
+					Diagnostic:        "smtp; 552 5.2.2 Mailbox full",
+					OriginalMessageID: "<drip-44.1760390000@example.com>",
+				},
+				{
+					Kind:              Hard,
+					Recipient:         "gone@customer.test",
+					Action:            "failed",
+					Status:            "5.0.0",
+					OriginalMessageID: "<drip-44.1760390000@example.com>",
+				},
+			},
+		},
+		{
+			// Without Original-Rcpt-To the complaint is about the original's
+			// recipient
+			file: "arf-abuse.eml",
+			reports: []Report{{
+				Kind:              Complaint,
+				Recipient:         "angry@customer.test",
+				FeedbackType:      "abuse",
+				OriginalMessageID: "<drip-45.1760433000@example.com>",
+			}},
+		},
+		{
+			file:    "arf-not-spam.eml",
+			reports: []Report{},
+		},
+		{
+			file: "plain-failed-recipients.eml",
+			reports: []Report{
+				{Kind: Hard, Recipient: "old@customer.test", Action: "failed", Status: "5.1.1"},
+				{Kind: Hard, Recipient: "former@customer.test", Action: "failed", Status: "5.1.1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			reports, err := Parse(fixture(t, tt.file))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(reports, tt.reports) {
+				t.Errorf("reports =\n%+v\nwant\n%+v", reports, tt.reports)
+			}
+		})
+	}
+}
+
+func TestParseNotReport(t *testing.T) {
+	if _, err := Parse(fixture(t, "reply.eml")); err != ErrNotReport {
+		t.Errorf("err = %v, want ErrNotReport", err)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		action, status, kind string
+	}{
+		{"failed", "5.1.1", Hard},
+		{"Failed", "5.7.1", Hard},
+		{"failed", "5.2.2", Soft},
+		{"failed", "4.2.2", Soft},
+		{"delayed", "4.4.7", Soft},
+		{"delivered", "2.0.0", ""},
+		{"relayed", "2.0.0", ""},
+		{"expanded", "2.0.0", ""},
+	}
+	for _, tt := range tests {
+		if kind := classify(tt.action, tt.status); kind != tt.kind {
+			t.Errorf("classify(%q, %q) = %q, want %q", tt.action, tt.status, kind, tt.kind)
+		}
+	}
+}
+
+// fixture opens a message in testdata with CRLF line endings, as it arrives
+func fixture(t *testing.T, name string) *strings.Reader {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.NewReader(strings.ReplaceAll(string(raw), "\n", "\r\n"))
+}
@@ -0,0 +1,246 @@
+package bounce
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/config"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/jinzhu/gorm"
+)
+
+// Policy controls when bounces and complaints suppress a customer. A zero
+// limit never suppresses.
+type Policy struct {
+	HardLimit      int
+	SoftLimit      int
+	SoftWindow     time.Duration
+	ComplaintLimit int
+}
+
+// NewPolicy builds the suppression policy from the application config
+func NewPolicy(cfg *config.Config) Policy {
+	return Policy{
+		HardLimit:      cfg.BounceHardLimit,
+		SoftLimit:      cfg.BounceSoftLimit,
+		SoftWindow:     time.Duration(cfg.BounceSoftWindowDays) * 24 * time.Hour,
+		ComplaintLimit: cfg.ComplaintLimit,
+	}
+}
+
+// Result is the outcome of processing a single report
+type Result struct {
+	Report
+	EmailLogID uint `json:"email_log_id,omitempty"`
+	CustomerID uint `json:"customer_id,omitempty"`
+	Matched    bool `json:"matched"`
+	Suppressed bool `json:"suppressed"`
+}
+
+// Handle parses a raw message and processes every report in it. It reports
+// whether the message was a bounce or complaint at all, so mailbox pollers
+// can leave unrelated mail alone.
+func Handle(raw []byte) (bool, error) {
+	reports, err := Parse(bytes.NewReader(raw))
+	if err != nil {
+		// Unparseable mail is left for a human, same as anything else
+		return false, nil
+	}
+
+	policy := NewPolicy(config.LoadConfig())
+	for _, report := range reports {
+		if _, err := Process(report, policy, time.Now()); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// Process applies a report to the matching email log and customer, suppressing
+// the customer once the policy's thresholds are reached
+func Process(report Report, policy Policy, now time.Time) (Result, error) {
+	result := Result{Report: report}
+
+	emailLog, err := findEmailLog(report)
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return result, err
+	}
+
+	var customer models.Customer
+	if emailLog != nil {
+		err = database.DB.First(&customer, emailLog.CustomerID).Error
+	} else {
+		err = database.DB.Where("lower(email) = ?", report.Recipient).First(&customer).Error
+	}
+	if gorm.IsRecordNotFoundError(err) {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	result.Matched = true
+	result.CustomerID = customer.ID
+
+	event := models.EngagementEvent{
+		Type:       eventType(report.Kind),
+		CustomerID: customer.ID,
+		Detail:     detail(report),
+		OccurredAt: now,
+	}
+	if emailLog != nil {
+		result.EmailLogID = emailLog.ID
+		event.EmailLogID = emailLog.ID
+		event.CampaignID = emailLog.CampaignID
+		event.StepID = emailLog.StepID
+
+		if err := updateStatus(emailLog, report.Kind); err != nil {
+			return result, err
+		}
+	}
+	if err := database.DB.Create(&event).Error; err != nil {
+		return result, err
+	}
+
+	if customer.SuppressedAt != nil {
+		return result, nil
+	}
+	reached, err := policy.reached(customer.ID, report.Kind, now)
+	if err != nil || !reached {
+		return result, err
+	}
+	if err := suppress(&customer, event, now); err != nil {
+		return result, err
+	}
+	result.Suppressed = true
+	return result, nil
+}
+
+// findEmailLog matches the report to a sent email by Message-ID, falling back
+// to the latest email sent to the recipient
+func findEmailLog(report Report) (*models.EmailLog, error) {
+	var emailLog models.EmailLog
+	if id := normalizeMessageID(report.OriginalMessageID); id != "" {
+		err := database.DB.Where("message_id = ?", id).First(&emailLog).Error
+		if err == nil || !gorm.IsRecordNotFoundError(err) {
+			return &emailLog, err
+		}
+	}
+
+	err := database.DB.Select("email_logs.*").
+		Joins("JOIN customers ON customers.id = email_logs.customer_id").
+		Where("lower(customers.email) = ? AND email_logs.status <> ?", report.Recipient, models.EmailStatusPending).
+		Order("email_logs.sent_at desc").
+		First(&emailLog).Error
+	if err != nil {
+		return nil, err
+	}
+	return &emailLog, nil
+}
+
+// updateStatus records the report on the email log. A hard bounce is final, so
+// later soft bounces or complaints don't overwrite it.
+func updateStatus(emailLog *models.EmailLog, kind string) error {
+	status := models.EmailStatusBounced
+	switch kind {
+	case Soft:
+		status = models.EmailStatusSoftBounced
+	case Complaint:
+		status = models.EmailStatusComplained
+	}
+
+	return database.DB.Model(emailLog).
+		Where("status <> ?", models.EmailStatusBounced).
+		UpdateColumn("status", status).Error
+}
+
+// reached reports whether the customer's bounces or complaints of the given
+// kind have hit the policy's limit
+func (p Policy) reached(customerID uint, kind string, now time.Time) (bool, error) {
+	limit := p.HardLimit
+	query := database.DB.Model(&models.EngagementEvent{}).Where("customer_id = ? AND type = ?", customerID, eventType(kind))
+	switch kind {
+	case Soft:
+		limit = p.SoftLimit
+		query = query.Where("occurred_at > ?", now.Add(-p.SoftWindow))
+	case Complaint:
+		limit = p.ComplaintLimit
+	}
+	if limit <= 0 {
+		return false, nil
+	}
+
+	var count int
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count >= limit, nil
+}
+
+// suppress unsubscribes the customer and takes them out of every campaign
+func suppress(customer *models.Customer, event models.EngagementEvent, now time.Time) error {
+	reason := event.Type
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(customer).UpdateColumns(map[string]interface{}{
+			"suppressed_at":      now,
+			"suppression_reason": reason,
+			"subscribed":         false,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.CampaignCustomer{}).
+			Where("customer_id = ? AND status IN (?)", customer.ID, []string{"", models.EnrollmentActive, models.EnrollmentPaused}).
+			UpdateColumns(map[string]interface{}{
+				"status":       models.EnrollmentExited,
+				"end_date":     now,
+				"next_step_id": 0,
+				"next_step_at": nil,
+			}).Error; err != nil {
+			return err
+		}
+
+		// A complaint is the recipient asking not to be emailed, so it counts
+		// as an unsubscribe in campaign stats
+		if reason != models.EventComplaint {
+			return nil
+		}
+		unsubscribe := models.EngagementEvent{
+			Type:       models.EventUnsubscribe,
+			EmailLogID: event.EmailLogID,
+			CustomerID: customer.ID,
+			CampaignID: event.CampaignID,
+			StepID:     event.StepID,
+			Detail:     event.Detail,
+			OccurredAt: now,
+		}
+		return tx.Create(&unsubscribe).Error
+	})
+}
+
+func eventType(kind string) string {
+	switch kind {
+	case Soft:
+		return models.EventSoftBounce
+	case Complaint:
+		return models.EventComplaint
+	}
+	return models.EventHardBounce
+}
+
+func detail(report Report) string {
+	if report.Kind == Complaint {
+		return report.FeedbackType
+	}
+	return strings.TrimSpace(report.Status + " " + report.Diagnostic)
+}
+
+// normalizeMessageID wraps a Message-ID in angle brackets the way it's stored
+func normalizeMessageID(id string) string {
+	id = strings.Trim(strings.TrimSpace(id), "<>")
+	if id == "" {
+		return ""
+	}
+	return "<" + id + ">"
+}
@@ -0,0 +1,110 @@
+// Command rotate-keys re-encrypts the secrets stored in the database with the
+// current MASTER_KEY.
+//
+// To rotate keys, generate a new key with -generate, set it as MASTER_KEY, move
+// the old key to MASTER_KEY_PREVIOUS and run this command. Once it completes the
+// old key can be removed. Running it once after first setting MASTER_KEY also
+// encrypts any secrets that were stored in plaintext.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/4cecoder/drip-campaign/config"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/secrets"
+	"github.com/joho/godotenv"
+)
+
+// secretColumns lists every encrypted column, keyed by table
+var secretColumns = []struct {
+	table   string
+	columns []string
+}{
+	{"settings", []string{"crm_api_key", "gmail_password"}},
+	{"users", []string{"totp_secret"}},
+	{"sender_identities", []string{"smtp_password"}},
+	{"dkim_keys", []string{"private_key"}},
+}
+
+func main() {
+	generate := flag.Bool("generate", false, "print a new random master key and exit")
+	flag.Parse()
+
+	if *generate {
+		key, err := secrets.GenerateMasterKey()
+		if err != nil {
+			log.Fatal("Failed to generate master key:", err)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	if err := godotenv.Load(".env"); err != nil {
+		log.Println("Error loading .env file, using environment variables")
+	}
+	if config.LoadConfig().MasterKey == "" {
+		log.Fatal("MASTER_KEY must be set to rotate secrets")
+	}
+
+	config.Init()
+	defer database.DB.Close()
+
+	total := 0
+	for _, target := range secretColumns {
+		for _, column := range target.columns {
+			n, err := rotateColumn(target.table, column)
+			if err != nil {
+				log.Fatalf("Failed to rotate %s.%s: %v", target.table, column, err)
+			}
+			log.Printf("Re-encrypted %d values in %s.%s", n, target.table, column)
+			total += n
+		}
+	}
+	log.Printf("Key rotation completed, %d values re-encrypted", total)
+}
+
+// rotateColumn re-encrypts every value in the column that isn't already
+// encrypted with the primary master key
+func rotateColumn(table, column string) (int, error) {
+	rows, err := database.DB.Table(table).Select("id, " + column).Rows()
+	if err != nil {
+		return 0, err
+	}
+
+	type row struct {
+		id    uint
+		value string
+	}
+	var pending []row
+	for rows.Next() {
+		var r row
+		var value *string
+		if err := rows.Scan(&r.id, &value); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if value != nil && secrets.NeedsRotation(*value) {
+			r.value = *value
+			pending = append(pending, r)
+		}
+	}
+	rows.Close()
+
+	for _, r := range pending {
+		plaintext, err := secrets.Decrypt(r.value)
+		if err != nil {
+			return 0, fmt.Errorf("row %d: %w", r.id, err)
+		}
+		encrypted, err := secrets.Encrypt(plaintext)
+		if err != nil {
+			return 0, fmt.Errorf("row %d: %w", r.id, err)
+		}
+		if err := database.DB.Exec("UPDATE "+table+" SET "+column+" = ? WHERE id = ?", encrypted, r.id).Error; err != nil {
+			return 0, fmt.Errorf("row %d: %w", r.id, err)
+		}
+	}
+	return len(pending), nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/secrets"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+func TestRotateColumn(t *testing.T) {
+	oldKey, _ := secrets.GenerateMasterKey()
+	newKey, _ := secrets.GenerateMasterKey()
+	useKeys := func(primary string, previous ...string) {
+		k, err := secrets.NewKeyring(primary, previous)
+		if err != nil {
+			t.Fatal(err)
+		}
+		secrets.SetKeyring(k)
+	}
+	t.Cleanup(func() { useKeys("") })
+
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		db.Close()
+	})
+	db.Exec("CREATE TABLE sender_identities (id integer primary key, smtp_password text)")
+
+	useKeys(oldKey)
+	encryptedOld, _ := secrets.Encrypt("old key")
+	useKeys(newKey, oldKey)
+	encryptedNew, _ := secrets.Encrypt("new key")
+
+	want := map[uint]string{1: "plaintext", 2: "old key", 3: "new key", 4: "", 5: ""}
+	db.Exec("INSERT INTO sender_identities VALUES (1, ?), (2, ?), (3, ?), (4, ''), (5, NULL)", "plaintext", encryptedOld, encryptedNew)
+
+	n, err := rotateColumn("sender_identities", "smtp_password")
+	if err != nil {
+		t.Fatalf("rotateColumn: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("re-encrypted %d values, want the plaintext and the old key's", n)
+	}
+
+	// Everything reads back with the new key alone
+	useKeys(newKey)
+	rows, err := db.Table("sender_identities").Select("id, smtp_password").Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uint
+		var value *string
+		if err := rows.Scan(&id, &value); err != nil {
+			t.Fatal(err)
+		}
+		if value == nil {
+			continue
+		}
+		if *value != "" && secrets.NeedsRotation(*value) {
+			t.Errorf("row %d still needs rotation: %q", id, *value)
+		}
+		if plaintext, err := secrets.Decrypt(*value); err != nil || plaintext != want[id] {
+			t.Errorf("row %d = %q, %v, want %q", id, plaintext, err, want[id])
+		}
+	}
+}
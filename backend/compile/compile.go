@@ -0,0 +1,82 @@
+// Package compile turns templates written in Markdown or MJML-style
+// component markup into responsive, email-client-safe HTML with a
+// plain-text alternative, and inlines CSS so it survives clients that strip
+// <style> blocks.
+package compile
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+// Source content types compiled to HTML
+const (
+	Markdown = "text/markdown"
+	MJML     = "text/mjml"
+)
+
+// IsSource reports whether a template content type is a source format that
+// is compiled to HTML before sending
+func IsSource(contentType string) bool {
+	return format(contentType) != ""
+}
+
+func format(contentType string) string {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	for _, source := range []string{Markdown, MJML} {
+		if strings.HasPrefix(contentType, source) {
+			return source
+		}
+	}
+	return ""
+}
+
+// Template returns a copy of a template with a source body compiled to HTML
+// and a plain-text alternative generated from it when the template has none.
+// Templates in other formats are returned as they are.
+//
+// Partial includes and merge fields are left in place, so the compiled
+// template can still be expanded and rendered.
+func Template(template *models.EmailTemplate) (*models.EmailTemplate, error) {
+	compiled := *template
+	switch format(template.ContentType) {
+	case Markdown:
+		body := MarkdownHTML(template.Body)
+		if template.LayoutID == 0 {
+			// Without a layout to place it in, the content gets a
+			// responsive page of its own
+			body = markdownPage(body)
+		}
+		compiled.Body = body
+	case MJML:
+		if template.LayoutID != 0 {
+			return nil, errors.New("MJML templates are complete documents and can't be placed in a layout")
+		}
+		body, err := MJMLHTML(template.Body)
+		if err != nil {
+			return nil, err
+		}
+		compiled.Body = body
+	default:
+		return &compiled, nil
+	}
+
+	compiled.ContentType = "text/html"
+	if strings.TrimSpace(compiled.TextBody) == "" {
+		compiled.TextBody = Text(compiled.Body)
+	}
+	return &compiled, nil
+}
+
+// InlineCSS moves the rules of an HTML body's <style> blocks into the style
+// attributes of the elements they match. Rules that can't be inlined, such
+// as media queries and :hover, are kept in a single <style> block in the
+// head. Bodies without a <style> block are returned unchanged.
+func InlineCSS(body string) (string, error) {
+	if !strings.Contains(strings.ToLower(body), "<style") {
+		return body, nil
+	}
+	return inline(body)
+}
@@ -0,0 +1,270 @@
+package compile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+func TestMarkdownHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		html     string
+	}{
+		{"heading with a merge field", "# Hello {{first_name}}", "<h1>Hello {{first_name}}</h1>"},
+		{"setext headings", "Title\n===\n\nSub\n---", "<h1>Title</h1>\n<h2>Sub</h2>"},
+		{"emphasis", "Hi **there**, *you* and _me_, ~~old~~, snake_case_name and 2*3*4",
+			"<p>Hi <strong>there</strong>, <em>you</em> and <em>me</em>, <del>old</del>, snake_case_name and 2<em>3</em>4</p>"},
+		{"hard line breaks", "Line one  \nline two\\\nline three", "<p>Line one<br>\nline two<br>\nline three</p>"},
+		{"paragraphs", "One\ncontinued\n\nTwo", "<p>One\ncontinued</p>\n<p>Two</p>"},
+		{"nested list", "- one\n- two\n  - nested\n- three",
+			"<ul>\n<li>one</li>\n<li>two\n<ul>\n<li>nested</li>\n</ul></li>\n<li>three</li>\n</ul>"},
+		{"ordered list", "3. three\n4. four", "<ol start=\"3\">\n<li>three</li>\n<li>four</li>\n</ol>"},
+		{"loose list", "- loose\n\n- list", "<ul>\n<li><p>loose</p></li>\n<li><p>list</p></li>\n</ul>"},
+		{"block quote", "> quoted\n> **text**", "<blockquote>\n<p>quoted\n<strong>text</strong></p>\n</blockquote>"},
+		{"code block", "```\n<b>code</b>\n```", "<pre><code>&lt;b&gt;code&lt;/b&gt;</code></pre>"},
+		{"code span", "Use `a < b` here", "<p>Use <code>a &lt; b</code> here</p>"},
+		{"rule", "***", "<hr>"},
+		{"links and images", `[Pricing](https://example.com/p?a=1&b=2 "Our plans") and ![Logo](https://example.com/l.png)`,
+			`<p><a href="https://example.com/p?a=1&amp;b=2" title="Our plans">Pricing</a> and <img src="https://example.com/l.png" alt="Logo"></p>`},
+		{"link to a merge field", "[**Your** account](https://example.com/{{id}})",
+			`<p><a href="https://example.com/{{id}}"><strong>Your</strong> account</a></p>`},
+		{"bare and angle bracket links", "Visit https://example.com/x. Or <mailto:hi@example.com>",
+			`<p>Visit <a href="https://example.com/x">https://example.com/x</a>. Or <a href="mailto:hi@example.com">hi@example.com</a></p>`},
+		{"escaping", `5 < 6 & 7 > 3, &copy; \*not em\*`, "<p>5 &lt; 6 &amp; 7 &gt; 3, &copy; *not em*</p>"},
+		{"merge field with a filter", `Hi {{first_name | default: "there"}}`, `<p>Hi {{first_name | default: "there"}}</p>`},
+		{"partial include", "{{> footer}}\n\nText", "{{> footer}}\n<p>Text</p>"},
+		{"HTML block", "<table><tr><td>*x*</td></tr></table>\n\nAfter *em*", "<table><tr><td>*x*</td></tr></table>\n<p>After <em>em</em></p>"},
+		{"CRLF and tabs", "- a\r\n\t- b", "<ul>\n<li>a\n<ul>\n<li>b</li>\n</ul></li>\n</ul>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarkdownHTML(tt.markdown); got != tt.html {
+				t.Errorf("MarkdownHTML =\n%q\nwant\n%q", got, tt.html)
+			}
+		})
+	}
+}
+
+const mjmlSource = `<mjml>
+  <mj-head>
+    <mj-title>Welcome</mj-title>
+    <mj-preview>Your account is ready</mj-preview>
+    <mj-attributes>
+      <mj-text color="#555555" />
+      <mj-class name="big" font-size="20px" />
+    </mj-attributes>
+    <mj-style>.note { color: red }</mj-style>
+  </mj-head>
+  <mj-body width="500px">
+    <mj-section>
+      <mj-column>
+        <mj-text mj-class="big">Hi {{first_name}}, <b>welcome</b> & enjoy</mj-text>
+        <mj-button href="https://example.com/start?a=1&b=2">Get started</mj-button>
+      </mj-column>
+      <mj-column>
+        <mj-image src="https://example.com/logo.png" alt="Logo" width="100px" />
+      </mj-column>
+    </mj-section>
+    {{> footer}}
+  </mj-body>
+</mjml>`
+
+func TestMJMLHTML(t *testing.T) {
+	out, err := MJMLHTML(mjmlSource)
+	if err != nil {
+		t.Fatalf("MJMLHTML: %v", err)
+	}
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<title>Welcome</title>",
+		`max-height: 0px; max-width: 0px; opacity: 0; overflow: hidden;">Your account is ready</div>`,
+		// The body width, split between two columns that stack on small screens
+		`<div style="margin: 0px auto; max-width: 500px;">`,
+		"@media only screen and (min-width: 480px) {\n  .mj-column-per-50 { width: 50% !important; max-width: 50%; }",
+		`<!--[if mso | IE]><td style="vertical-align:top;width:250px;"><![endif]-->`,
+		// mj-attributes and mj-class apply, and the text's HTML and merge fields are kept
+		`font-size: 20px; line-height: 1.5; text-align: left; color: #555555;">Hi {{first_name}}, <b>welcome</b> & enjoy</div>`,
+		`<a href="https://example.com/start?a=1&amp;b=2" style="display: inline-block; background: #414141;`,
+		`<td style="width: 100px;"><img alt="Logo" src="https://example.com/logo.png"`,
+		".note { color: red }\n</style>",
+		"{{> footer}}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output doesn't contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMJMLHTMLErrors(t *testing.T) {
+	column := func(content string) string {
+		return "<mjml><mj-body><mj-section><mj-column>" + content + "</mj-column></mj-section></mj-body></mjml>"
+	}
+	tests := []struct {
+		name   string
+		source string
+		err    string
+	}{
+		{"no mjml root", "<mj-body></mj-body>", "must start with <mjml>"},
+		{"no body", "<mjml><mj-head></mj-head></mjml>", "needs an <mj-body>"},
+		{"image without src", column("<mj-image />"), "<mj-image> needs a src"},
+		{"text outside a column", "<mjml><mj-body><mj-section><mj-text>x</mj-text></mj-section></mj-body></mjml>", "must be placed in a <mj-column>"},
+		{"unclosed text", column("<mj-text>x"), "<mj-text> has no </mj-text>"},
+		{"unknown component", column("<mj-carousel />"), "unknown MJML component <mj-carousel>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := MJMLHTML(tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestInlineCSS(t *testing.T) {
+	page := func(style, body string) string {
+		return "<html><head><style>" + style + "</style></head><body>" + body + "</body></html>"
+	}
+	tests := []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{
+			name: "no style block",
+			in:   `<p class="a">unchanged</p>`,
+			out:  `<p class="a">unchanged</p>`,
+		},
+		{
+			name: "type, class and ID selectors by specificity",
+			in:   page("#x { color: blue } p.big { color: green } .big { font-size: 20px } p { color: red; }", `<p>a</p><p class="big">b</p><p id="x" class="big">c</p>`),
+			out:  `<html><head></head><body><p style="color: red;">a</p><p class="big" style="color: green; font-size: 20px;">b</p><p id="x" class="big" style="color: blue; font-size: 20px;">c</p></body></html>`,
+		},
+		{
+			name: "the element's own style wins",
+			in:   page("p { color: red; margin: 0 }", `<p style="color: orange">c</p>`),
+			out:  `<html><head></head><body><p style="color: orange; margin: 0;">c</p></body></html>`,
+		},
+		{
+			name: "important wins over later rules",
+			in:   page("h1, h2 { color: navy !important } h1 { color: red }", `<h1>t</h1><h2>u</h2>`),
+			out:  `<html><head></head><body><h1 style="color: navy !important;">t</h1><h2 style="color: navy !important;">u</h2></body></html>`,
+		},
+		{
+			name: "descendant and child combinators",
+			in:   page("td p { margin: 0 } div > span { font-weight: bold }", `<table><tr><td><p>x</p></td></tr></table><div><span>1</span><p><span>2</span></p></div>`),
+			out:  `<html><head></head><body><table><tbody><tr><td><p style="margin: 0;">x</p></td></tr></tbody></table><div><span style="font-weight: bold;">1</span><p><span>2</span></p></div></body></html>`,
+		},
+		{
+			name: "media queries and pseudo-classes stay in the head",
+			in:   page("p { color: red } a:hover { color: pink } @media (max-width: 600px) { p { color: black } }", `<p>a</p>`),
+			out:  "<html><head><style>\na:hover { color: pink }\n@media (max-width: 600px) { p { color: black } }\n</style></head><body><p style=\"color: red;\">a</p></body></html>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := InlineCSS(tt.in)
+			if err != nil {
+				t.Fatalf("InlineCSS: %v", err)
+			}
+			if out != tt.out {
+				t.Errorf("InlineCSS =\n%s\nwant\n%s", out, tt.out)
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		text string
+	}{
+		{"paragraphs", "<h1>Hi {{first_name}}</h1><p>One\n   two</p><p>Three<br>four</p>", "Hi {{first_name}}\n\nOne two\n\nThree\nfour\n"},
+		{"links", `<p>See <a href="https://example.com/pricing">our plans</a> or <a href="https://example.com">https://example.com</a></p>`,
+			"See our plans (https://example.com/pricing) or https://example.com\n"},
+		{"lists", "<ul><li>one</li><li>two</li></ul><ol><li>first</li><li>second</li></ol>", "- one\n- two\n\n1. first\n2. second\n"},
+		{"hidden content", `<style>p { color: red }</style><div style="display: none">preview</div><p>shown</p>`, "shown\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Text(tt.html); got != tt.text {
+				t.Errorf("Text =\n%q\nwant\n%q", got, tt.text)
+			}
+		})
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template models.EmailTemplate
+		body     string
+		text     string
+		err      string
+	}{
+		{
+			name:     "markdown",
+			template: models.EmailTemplate{ContentType: Markdown, Body: "# Hi {{first_name}}"},
+			body:     "<td class=\"content\">\n<h1>Hi {{first_name}}</h1>",
+			text:     "Hi {{first_name}}\n",
+		},
+		{
+			name:     "markdown in a layout",
+			template: models.EmailTemplate{ContentType: Markdown + "; charset=utf-8", Body: "Hi *there*", LayoutID: 3},
+			body:     "<p>Hi <em>there</em></p>",
+			text:     "Hi there\n",
+		},
+		{
+			name:     "own text body",
+			template: models.EmailTemplate{ContentType: Markdown, Body: "Hi", TextBody: "Plain hi"},
+			body:     "<p>Hi</p>",
+			text:     "Plain hi",
+		},
+		{
+			name:     "mjml",
+			template: models.EmailTemplate{ContentType: MJML, Body: mjmlSource},
+			body:     "<!DOCTYPE html>",
+			text:     "Hi {{first_name}}, welcome & enjoy",
+		},
+		{
+			name:     "mjml in a layout",
+			template: models.EmailTemplate{ContentType: MJML, Body: mjmlSource, LayoutID: 3},
+			err:      "can't be placed in a layout",
+		},
+		{
+			name:     "html",
+			template: models.EmailTemplate{ContentType: "text/html", Body: "<p>*as is*</p>"},
+			body:     "<p>*as is*</p>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compiled, err := Template(&tt.template)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Template: %v", err)
+			}
+			if !strings.Contains(compiled.Body, tt.body) {
+				t.Errorf("body doesn't contain %q:\n%s", tt.body, compiled.Body)
+			}
+			if !strings.Contains(compiled.TextBody, tt.text) {
+				t.Errorf("text body = %q, want it to contain %q", compiled.TextBody, tt.text)
+			}
+			if IsSource(tt.template.ContentType) && compiled.ContentType != "text/html" {
+				t.Errorf("content type = %q, want text/html", compiled.ContentType)
+			}
+			if compiled == &tt.template {
+				t.Error("the template itself was returned")
+			}
+		})
+	}
+}
@@ -0,0 +1,416 @@
+package compile
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// cssRule is one selector of a style rule with the declarations it applies
+type cssRule struct {
+	selector    selector
+	specificity int
+	order       int
+	decls       []declaration
+}
+
+type declaration struct {
+	property  string
+	value     string
+	important bool
+}
+
+// selector is a chain of compound selectors joined by combinators, stored
+// right to left: parts[0] matches the element itself
+type selector struct {
+	parts []compound
+	// child[i] is set when parts[i] must be the parent of the element
+	// matched by parts[i-1], rather than any ancestor
+	child []bool
+}
+
+type compound struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+var (
+	commentPattern   = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	compoundPattern  = regexp.MustCompile(`^(\*|[a-zA-Z][a-zA-Z0-9-]*)?((?:[.#][a-zA-Z0-9_-]+)*)$`)
+	compoundPieces   = regexp.MustCompile(`[.#][a-zA-Z0-9_-]+`)
+	combinatorSpaces = regexp.MustCompile(`\s*>\s*`)
+)
+
+func inline(body string) (string, error) {
+	fragment := !strings.Contains(strings.ToLower(body), "<html")
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	// Gather the stylesheets, removing them from the document
+	var css strings.Builder
+	var styles []*html.Node
+	walk(doc, func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Style {
+			styles = append(styles, n)
+		}
+	})
+	for _, style := range styles {
+		for child := style.FirstChild; child != nil; child = child.NextSibling {
+			css.WriteString(child.Data + "\n")
+		}
+		style.Parent.RemoveChild(style)
+	}
+
+	rules, kept := parseCSS(css.String())
+	walk(doc, func(n *html.Node) {
+		if n.Type == html.ElementNode && !inHead(n) {
+			applyRules(n, rules)
+		}
+	})
+
+	if kept != "" {
+		head := find(doc, atom.Head)
+		if head == nil || fragment {
+			// A fragment has no head of its own, so the rules go first
+			head = find(doc, atom.Body)
+		}
+		style := &html.Node{Type: html.ElementNode, Data: "style", DataAtom: atom.Style}
+		style.AppendChild(&html.Node{Type: html.TextNode, Data: "\n" + kept})
+		head.InsertBefore(style, head.FirstChild)
+	}
+
+	var out bytes.Buffer
+	if fragment {
+		for child := find(doc, atom.Body).FirstChild; child != nil; child = child.NextSibling {
+			if err := html.Render(&out, child); err != nil {
+				return "", err
+			}
+		}
+	} else if err := html.Render(&out, doc); err != nil {
+		return "", err
+	}
+	// Rendering escapes the > of partial includes not yet expanded
+	return strings.ReplaceAll(out.String(), "{{&gt;", "{{>"), nil
+}
+
+func walk(n *html.Node, visit func(*html.Node)) {
+	visit(n)
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		walk(child, visit)
+	}
+}
+
+func find(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := find(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func inHead(n *html.Node) bool {
+	for ; n != nil; n = n.Parent {
+		if n.DataAtom == atom.Head {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCSS splits a stylesheet into the rules that can be inlined and the
+// text of those that can't: at-rules such as media queries, and selectors
+// with pseudo-classes, attributes or sibling combinators
+func parseCSS(css string) ([]cssRule, string) {
+	css = commentPattern.ReplaceAllString(css, "")
+	var rules []cssRule
+	var kept strings.Builder
+	order := 0
+
+	for {
+		css = strings.TrimSpace(css)
+		open := strings.IndexByte(css, '{')
+		if css == "" || open < 0 {
+			break
+		}
+		prelude := strings.TrimSpace(css[:open])
+		end := matchingBrace(css, open)
+		block := css[open+1 : end]
+		css = css[min(end+1, len(css)):]
+
+		if strings.HasPrefix(prelude, "@") {
+			kept.WriteString(prelude + " {" + block + "}\n")
+			continue
+		}
+
+		decls := parseDeclarations(block)
+		var unsupported []string
+		for _, text := range strings.Split(prelude, ",") {
+			text = strings.TrimSpace(text)
+			sel, ok := parseSelector(text)
+			if !ok {
+				unsupported = append(unsupported, text)
+				continue
+			}
+			rules = append(rules, cssRule{selector: sel, specificity: sel.specificity(), order: order, decls: decls})
+		}
+		if len(unsupported) > 0 {
+			kept.WriteString(strings.Join(unsupported, ", ") + " {" + block + "}\n")
+		}
+		order++
+	}
+	return rules, kept.String()
+}
+
+// matchingBrace finds the brace closing the one at open, or the end of css
+func matchingBrace(css string, open int) int {
+	depth := 0
+	for i := open; i < len(css); i++ {
+		switch css[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(css)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// parseDeclarations splits "color: red; background: url(a;b)" into its
+// declarations, respecting parentheses and quotes
+func parseDeclarations(block string) []declaration {
+	var decls []declaration
+	var current strings.Builder
+	depth := 0
+	var quote rune
+	flush := func() {
+		text := strings.TrimSpace(current.String())
+		current.Reset()
+		colon := strings.IndexByte(text, ':')
+		if colon <= 0 {
+			return
+		}
+		decl := declaration{
+			property: strings.ToLower(strings.TrimSpace(text[:colon])),
+			value:    strings.TrimSpace(text[colon+1:]),
+		}
+		if lower := strings.ToLower(decl.value); strings.HasSuffix(lower, "!important") {
+			decl.important = true
+			decl.value = strings.TrimSpace(decl.value[:len(decl.value)-len("!important")])
+		}
+		if decl.value != "" {
+			decls = append(decls, decl)
+		}
+	}
+	for _, r := range block {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ';' && depth == 0:
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	flush()
+	return decls
+}
+
+func parseSelector(text string) (selector, bool) {
+	var sel selector
+	if text == "" || strings.ContainsAny(text, ":[+~") {
+		return sel, false
+	}
+	tokens := strings.Fields(combinatorSpaces.ReplaceAllString(text, " > "))
+	child := false
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i] == ">" {
+			if child || i == len(tokens)-1 || i == 0 {
+				return sel, false
+			}
+			child = true
+			continue
+		}
+		groups := compoundPattern.FindStringSubmatch(tokens[i])
+		if groups == nil || groups[0] == "" {
+			return sel, false
+		}
+		part := compound{tag: strings.ToLower(groups[1])}
+		for _, piece := range compoundPieces.FindAllString(groups[2], -1) {
+			if piece[0] == '#' {
+				part.id = piece[1:]
+			} else {
+				part.classes = append(part.classes, piece[1:])
+			}
+		}
+		if len(sel.parts) > 0 {
+			sel.child = append(sel.child, child)
+		} else {
+			sel.child = append(sel.child, false)
+		}
+		sel.parts = append(sel.parts, part)
+		child = false
+	}
+	return sel, true
+}
+
+// specificity weighs ids over classes over tags
+func (s selector) specificity() int {
+	total := 0
+	for _, part := range s.parts {
+		if part.id != "" {
+			total += 10000
+		}
+		total += 100 * len(part.classes)
+		if part.tag != "" && part.tag != "*" {
+			total++
+		}
+	}
+	return total
+}
+
+func (s selector) matches(n *html.Node) bool {
+	if !s.parts[0].matches(n) {
+		return false
+	}
+	return s.matchAncestors(n.Parent, 1)
+}
+
+func (s selector) matchAncestors(n *html.Node, i int) bool {
+	if i == len(s.parts) {
+		return true
+	}
+	for ; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		if s.parts[i].matches(n) && s.matchAncestors(n.Parent, i+1) {
+			return true
+		}
+		if s.child[i] {
+			return false
+		}
+	}
+	return false
+}
+
+func (c compound) matches(n *html.Node) bool {
+	if c.tag != "" && c.tag != "*" && c.tag != n.Data {
+		return false
+	}
+	if c.id != "" && attribute(n, "id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(attribute(n, "class"))
+		for _, want := range c.classes {
+			found := false
+			for _, class := range classes {
+				if class == want {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func attribute(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// applyRules sets an element's style attribute from the rules matching it.
+// Later and more specific rules win, the element's own style wins over the
+// stylesheet, and !important stylesheet declarations win over both.
+func applyRules(n *html.Node, rules []cssRule) {
+	var matched []cssRule
+	for _, rule := range rules {
+		if rule.selector.matches(n) {
+			matched = append(matched, rule)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].specificity != matched[j].specificity {
+			return matched[i].specificity < matched[j].specificity
+		}
+		return matched[i].order < matched[j].order
+	})
+
+	var properties []string
+	values := map[string]declaration{}
+	set := func(decl declaration) {
+		existing, ok := values[decl.property]
+		if !ok {
+			properties = append(properties, decl.property)
+		} else if existing.important && !decl.important {
+			return
+		}
+		values[decl.property] = decl
+	}
+	for _, rule := range matched {
+		for _, decl := range rule.decls {
+			set(decl)
+		}
+	}
+	for _, decl := range parseDeclarations(attribute(n, "style")) {
+		set(decl)
+	}
+
+	var style []string
+	for _, property := range properties {
+		decl := values[property]
+		value := decl.value
+		if decl.important {
+			value += " !important"
+		}
+		style = append(style, property+": "+value)
+	}
+	setAttribute(n, "style", strings.Join(style, "; ")+";")
+}
+
+func setAttribute(n *html.Node, key, value string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: value})
+}
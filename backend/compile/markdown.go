@@ -0,0 +1,362 @@
+package compile
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	setextPattern  = regexp.MustCompile(`^(=+|-+)\s*$`)
+	rulePattern    = regexp.MustCompile(`^(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	listPattern    = regexp.MustCompile(`^( *)([-*+]|\d{1,9}[.)]) +(.*)$`)
+	// A line that is only a partial include is left for the partials to fill
+	includeLinePattern = regexp.MustCompile(`^\{\{>\s*[a-zA-Z0-9_-]+\s*\}\}$`)
+	// HTML blocks are passed through as they are
+	htmlBlockPattern = regexp.MustCompile(`(?i)^<(?:/?(?:table|thead|tbody|tfoot|tr|td|th|div|p|h[1-6]|ul|ol|li|center|blockquote|pre|section|hr|style)(?:[\s>/]|$)|!--)`)
+)
+
+// MarkdownHTML converts Markdown to an HTML fragment. Headings, paragraphs,
+// emphasis, links, images, lists, block quotes, code and rules are
+// supported, along with HTML blocks and inline tags, which are kept as they
+// are. Merge fields and partial includes are left in place.
+func MarkdownHTML(source string) string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\t", "    ")
+	return strings.TrimSpace(markdownBlocks(strings.Split(source, "\n")))
+}
+
+func markdownBlocks(lines []string) string {
+	var out strings.Builder
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + markdownLines(paragraph) + "</p>\n")
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence := trimmed[:3]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+
+		case len(paragraph) > 0 && setextPattern.MatchString(trimmed):
+			level := 2
+			if trimmed[0] == '=' {
+				level = 1
+			}
+			out.WriteString(heading(level, markdownLines(paragraph)))
+			paragraph = nil
+
+		case headingPattern.MatchString(trimmed):
+			flush()
+			groups := headingPattern.FindStringSubmatch(trimmed)
+			out.WriteString(heading(len(groups[1]), markdownInline(groups[2])))
+
+		case rulePattern.MatchString(trimmed):
+			flush()
+			out.WriteString("<hr>\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quoted []string
+			for ; i < len(lines); i++ {
+				quotedLine := strings.TrimSpace(lines[i])
+				if !strings.HasPrefix(quotedLine, ">") {
+					break
+				}
+				quotedLine = strings.TrimPrefix(quotedLine, ">")
+				quoted = append(quoted, strings.TrimPrefix(quotedLine, " "))
+			}
+			i--
+			out.WriteString("<blockquote>\n" + markdownBlocks(quoted) + "</blockquote>\n")
+
+		case listPattern.MatchString(line):
+			flush()
+			var list string
+			list, i = markdownList(lines, i)
+			out.WriteString(list)
+
+		case includeLinePattern.MatchString(trimmed) || htmlBlockPattern.MatchString(trimmed):
+			flush()
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				out.WriteString(lines[i] + "\n")
+			}
+
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+	return out.String()
+}
+
+func heading(level int, content string) string {
+	tag := "h" + strconv.Itoa(level)
+	return "<" + tag + ">" + content + "</" + tag + ">\n"
+}
+
+// markdownList renders the list starting at lines[start], returning the
+// index of its last line
+func markdownList(lines []string, start int) (string, int) {
+	first := listPattern.FindStringSubmatch(lines[start])
+	indent := len(first[1])
+	ordered := isOrdered(first[2])
+
+	var items [][]string
+	loose := false
+	i := start
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			// A blank line continues the list only if more of it follows
+			next := i + 1
+			for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+				next++
+			}
+			if next == len(lines) || leadingSpaces(lines[next]) <= indent && !sameList(lines[next], indent, ordered) {
+				break
+			}
+			if sameList(lines[next], indent, ordered) {
+				loose = true
+			}
+			items[len(items)-1] = append(items[len(items)-1], "")
+			continue
+		}
+
+		if sameList(line, indent, ordered) {
+			groups := listPattern.FindStringSubmatch(line)
+			items = append(items, []string{groups[3]})
+			continue
+		}
+		if leadingSpaces(line) > indent {
+			// Continuation of the item, perhaps a nested list
+			content := len(first[1]) + len(first[2]) + 1
+			strip := leadingSpaces(line)
+			if strip > content {
+				strip = content
+			}
+			items[len(items)-1] = append(items[len(items)-1], line[strip:])
+			continue
+		}
+		previous := items[len(items)-1]
+		if previous[len(previous)-1] != "" && !listPattern.MatchString(line) {
+			// A lazy continuation of the item's paragraph
+			items[len(items)-1] = append(previous, strings.TrimSpace(line))
+			continue
+		}
+		break
+	}
+
+	tag := "ul"
+	open := "<ul>"
+	if ordered {
+		tag = "ol"
+		open = "<ol>"
+		if n, err := strconv.Atoi(strings.TrimRight(first[2], ".)")); err == nil && n != 1 {
+			open = `<ol start="` + strconv.Itoa(n) + `">`
+		}
+	}
+
+	var out strings.Builder
+	out.WriteString(open + "\n")
+	for _, item := range items {
+		content := strings.TrimSpace(markdownBlocks(item))
+		if !loose && strings.HasPrefix(content, "<p>") {
+			// Tight lists don't wrap their items in paragraphs
+			end := strings.Index(content, "</p>")
+			content = content[len("<p>"):end] + content[end+len("</p>"):]
+		}
+		out.WriteString("<li>" + content + "</li>\n")
+	}
+	out.WriteString("</" + tag + ">\n")
+	return out.String(), i - 1
+}
+
+func isOrdered(marker string) bool {
+	return marker[0] >= '0' && marker[0] <= '9'
+}
+
+func sameList(line string, indent int, ordered bool) bool {
+	groups := listPattern.FindStringSubmatch(line)
+	return groups != nil && len(groups[1]) <= indent+1 && len(groups[1])+1 >= indent && isOrdered(groups[2]) == ordered
+}
+
+func leadingSpaces(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// markdownLines renders the lines of a paragraph. A line ending in two spaces
+// or a backslash is a hard line break.
+func markdownLines(lines []string) string {
+	var joined strings.Builder
+	for i, line := range lines {
+		line = strings.TrimLeft(line, " ")
+		if i < len(lines)-1 && (strings.HasSuffix(line, "  ") || strings.HasSuffix(line, `\`)) {
+			joined.WriteString(strings.TrimRight(strings.TrimSuffix(line, `\`), " ") + "\x01\n")
+			continue
+		}
+		joined.WriteString(strings.TrimRight(line, " "))
+		if i < len(lines)-1 {
+			joined.WriteString("\n")
+		}
+	}
+	return strings.ReplaceAll(markdownInline(joined.String()), "\x01", "<br>")
+}
+
+var (
+	escapedPattern  = regexp.MustCompile("\\\\([\\\\`*_{}\\[\\]()#+\\-.!~<>|])")
+	codeSpanPattern = regexp.MustCompile("`([^`]+)`")
+	mergePattern    = regexp.MustCompile(`\{\{[^{}]*\}\}`)
+	autolinkPattern = regexp.MustCompile(`<((?:https?://|mailto:)[^>\s]+)>`)
+	inlineHTML      = regexp.MustCompile(`<!--[\s\S]*?-->|</?[a-zA-Z][a-zA-Z0-9-]*(?:\s[^<>]*)?/?>`)
+	entityPattern   = regexp.MustCompile(`&(?:[a-zA-Z][a-zA-Z0-9]*|#[0-9]+|#[xX][0-9a-fA-F]+);`)
+	imagePattern    = regexp.MustCompile(`!\[([^\]]*)\]\(\s*([^\s)]+)(?:\s+"([^"]*)")?\s*\)`)
+	linkPattern     = regexp.MustCompile(`\[([^\]]+)\]\(\s*([^\s)]+)(?:\s+"([^"]*)")?\s*\)`)
+	bareURLPattern  = regexp.MustCompile(`https?://[^\s<>"\x00]+`)
+	strongPattern   = regexp.MustCompile(`\*\*(\S(?:[^*]*?\S)?)\*\*|__(\S(?:[^_]*?\S)?)__`)
+	emPattern       = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	underscoreEm    = regexp.MustCompile(`(^|[^\p{L}\p{N}_])_(\S(?:[^_]*?\S)?)_($|[^\p{L}\p{N}_])`)
+	strikePattern   = regexp.MustCompile(`~~(\S(?:[^~]*?\S)?)~~`)
+	placeholder     = regexp.MustCompile("\x00([0-9]+)\x00")
+	textEscaper     = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+)
+
+// inliner renders Markdown's inline syntax. Finished HTML is set aside behind
+// placeholders so later rules don't rewrite it.
+type inliner struct {
+	saved []string
+}
+
+func markdownInline(text string) string {
+	in := &inliner{}
+	return in.restore(in.render(text))
+}
+
+func (in *inliner) save(rendered string) string {
+	in.saved = append(in.saved, rendered)
+	return "\x00" + strconv.Itoa(len(in.saved)-1) + "\x00"
+}
+
+func (in *inliner) restore(text string) string {
+	for placeholder.MatchString(text) {
+		text = placeholder.ReplaceAllStringFunc(text, func(match string) string {
+			n, _ := strconv.Atoi(strings.Trim(match, "\x00"))
+			return in.saved[n]
+		})
+	}
+	return text
+}
+
+func (in *inliner) render(text string) string {
+	text = escapedPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return in.save(textEscaper.Replace(match[1:]))
+	})
+	text = codeSpanPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return in.save("<code>" + html.EscapeString(strings.Trim(match, "`")) + "</code>")
+	})
+	text = mergePattern.ReplaceAllStringFunc(text, in.save)
+	text = autolinkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		url := textEscaper.Replace(match[1 : len(match)-1])
+		return in.save(`<a href="` + url + `">` + strings.TrimPrefix(url, "mailto:") + "</a>")
+	})
+	text = inlineHTML.ReplaceAllStringFunc(text, in.save)
+	text = entityPattern.ReplaceAllStringFunc(text, in.save)
+	text = textEscaper.Replace(text)
+
+	text = imagePattern.ReplaceAllStringFunc(text, func(match string) string {
+		groups := imagePattern.FindStringSubmatch(match)
+		img := `<img src="` + attr(groups[2]) + `" alt="` + attr(groups[1]) + `"`
+		if groups[3] != "" {
+			img += ` title="` + attr(groups[3]) + `"`
+		}
+		return in.save(img + ">")
+	})
+	text = linkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		groups := linkPattern.FindStringSubmatch(match)
+		a := `<a href="` + attr(groups[2]) + `"`
+		if groups[3] != "" {
+			a += ` title="` + attr(groups[3]) + `"`
+		}
+		return in.save(a + ">" + in.emphasis(groups[1]) + "</a>")
+	})
+	text = bareURLPattern.ReplaceAllStringFunc(text, func(match string) string {
+		url := strings.TrimRight(match, ".,;:!?)")
+		return in.save(`<a href="`+url+`">`+url+"</a>") + match[len(url):]
+	})
+	return in.emphasis(text)
+}
+
+func (in *inliner) emphasis(text string) string {
+	text = strongPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return "<strong>" + match[2:len(match)-2] + "</strong>"
+	})
+	text = emPattern.ReplaceAllString(text, "<em>$1</em>")
+	text = underscoreEm.ReplaceAllString(text, "$1<em>$2</em>$3")
+	return strikePattern.ReplaceAllString(text, "<del>$1</del>")
+}
+
+func attr(value string) string {
+	return strings.ReplaceAll(value, `"`, "&quot;")
+}
+
+// markdownPage places compiled Markdown in a centred, single-column page
+// that narrows to fit small screens. Its stylesheet is inlined when sent.
+func markdownPage(content string) string {
+	return `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<style>
+body { margin: 0; padding: 0; background-color: #f4f4f4; }
+.content { font-family: Arial, Helvetica, sans-serif; font-size: 16px; line-height: 1.5; color: #333333; padding: 24px; }
+h1, h2, h3, h4, h5, h6 { font-family: Arial, Helvetica, sans-serif; color: #111111; line-height: 1.25; margin: 0 0 16px 0; }
+h1 { font-size: 28px; }
+h2 { font-size: 22px; }
+h3 { font-size: 18px; }
+p, ul, ol, blockquote, pre { margin: 0 0 16px 0; }
+a { color: #1a73e8; }
+img { max-width: 100%; height: auto; border: 0; }
+blockquote { border-left: 4px solid #dddddd; padding-left: 12px; color: #555555; }
+code { font-family: Consolas, Menlo, monospace; font-size: 14px; background-color: #f0f0f0; }
+pre { padding: 12px; background-color: #f0f0f0; white-space: pre-wrap; }
+hr { border: 0; border-top: 1px solid #dddddd; margin: 24px 0; }
+@media only screen and (max-width: 620px) {
+  .container { width: 100% !important; }
+  .content { padding: 16px !important; }
+}
+</style>
+</head>
+<body>
+<table role="presentation" width="100%" cellpadding="0" cellspacing="0" border="0" style="background-color: #f4f4f4;">
+<tr>
+<td align="center" style="padding: 16px 0;">
+<table role="presentation" class="container" width="600" cellpadding="0" cellspacing="0" border="0" style="width: 600px; max-width: 600px; background-color: #ffffff;">
+<tr>
+<td class="content">
+` + content + `
+</td>
+</tr>
+</table>
+</td>
+</tr>
+</table>
+</body>
+</html>
+`
+}
@@ -0,0 +1,667 @@
+package compile
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mjNode is a component of an MJML document
+type mjNode struct {
+	name     string
+	attrs    map[string]string
+	children []*mjNode
+	// content is the raw HTML inside content components such as mj-text
+	content string
+}
+
+// contentComponents hold HTML rather than other components
+var contentComponents = []string{"mj-text", "mj-button", "mj-raw", "mj-table", "mj-style", "mj-title", "mj-preview"}
+
+// columnComponents can be placed in a column
+var columnComponents = map[string]bool{
+	"mj-text": true, "mj-button": true, "mj-image": true, "mj-divider": true,
+	"mj-spacer": true, "mj-raw": true, "mj-table": true,
+}
+
+// mjDefaults are each component's attributes when the document doesn't set them
+var mjDefaults = map[string]map[string]string{
+	"mj-body":    {"width": "600px"},
+	"mj-wrapper": {"padding": "20px 0", "text-align": "center"},
+	"mj-section": {"padding": "20px 0", "text-align": "center", "direction": "ltr"},
+	"mj-column":  {"vertical-align": "top", "direction": "ltr"},
+	"mj-text": {"align": "left", "color": "#333333", "font-family": "Arial, Helvetica, sans-serif",
+		"font-size": "14px", "line-height": "1.5", "padding": "10px 25px"},
+	"mj-button": {"align": "center", "background-color": "#414141", "color": "#ffffff",
+		"border-radius": "3px", "font-family": "Arial, Helvetica, sans-serif", "font-size": "14px",
+		"font-weight": "normal", "inner-padding": "10px 25px", "line-height": "120%",
+		"padding": "10px 25px", "text-decoration": "none", "vertical-align": "middle"},
+	"mj-image":   {"align": "center", "padding": "10px 25px", "alt": ""},
+	"mj-divider": {"border-color": "#000000", "border-style": "solid", "border-width": "4px", "padding": "10px 25px", "width": "100%"},
+	"mj-spacer":  {"height": "20px"},
+	"mj-table": {"align": "left", "color": "#333333", "font-family": "Arial, Helvetica, sans-serif",
+		"font-size": "14px", "line-height": "22px", "padding": "10px 25px", "width": "100%"},
+	"mj-raw": {},
+}
+
+// MJMLHTML compiles MJML-style component markup to a complete, responsive
+// HTML document. Supported components are mjml, mj-head (mj-title,
+// mj-preview, mj-style, mj-attributes, mj-font), mj-body, mj-wrapper,
+// mj-section, mj-column, mj-text, mj-button, mj-image, mj-divider,
+// mj-spacer, mj-table and mj-raw. Text between components is kept as raw
+// HTML, so partial includes can be placed anywhere.
+func MJMLHTML(source string) (string, error) {
+	root, err := parseMJML(source)
+	if err != nil {
+		return "", err
+	}
+	if root.name != "mjml" {
+		return "", errors.New("an MJML template must start with <mjml>")
+	}
+
+	r := &mjRenderer{attributes: map[string]map[string]string{}, classes: map[string]map[string]string{}}
+	var body *mjNode
+	for _, child := range root.children {
+		switch child.name {
+		case "mj-head":
+			if err := r.head(child); err != nil {
+				return "", err
+			}
+		case "mj-body":
+			body = child
+		case "mj-raw":
+		default:
+			return "", fmt.Errorf("<%s> can't be placed in <mjml>", child.name)
+		}
+	}
+	if body == nil {
+		return "", errors.New("an MJML template needs an <mj-body>")
+	}
+	content, err := r.body(body)
+	if err != nil {
+		return "", err
+	}
+	return r.document(body, content), nil
+}
+
+// parseMJML reads MJML into a tree. The HTML inside content components is
+// set aside first, as it needn't be well-formed XML.
+func parseMJML(source string) (*mjNode, error) {
+	var contents []string
+	for _, name := range contentComponents {
+		var err error
+		if source, err = extractContent(source, name, &contents); err != nil {
+			return nil, err
+		}
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(source))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	root := &mjNode{}
+	stack := []*mjNode{root}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid MJML: %v", err)
+		}
+		parent := stack[len(stack)-1]
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &mjNode{name: strings.ToLower(token.Name.Local), attrs: map[string]string{}}
+			for _, a := range token.Attr {
+				node.attrs[strings.ToLower(a.Name.Local)] = a.Value
+			}
+			if n, ok := node.attrs["data-mj-content"]; ok {
+				i, _ := strconv.Atoi(n)
+				node.content = contents[i]
+				delete(node.attrs, "data-mj-content")
+			}
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			// Stray text is kept as raw HTML
+			if text := strings.TrimSpace(string(token)); text != "" && parent != root {
+				parent.children = append(parent.children, &mjNode{name: "mj-raw", attrs: map[string]string{}, content: text})
+			}
+		}
+	}
+	if len(root.children) == 0 {
+		return nil, errors.New("invalid MJML: the template is empty")
+	}
+	return root.children[0], nil
+}
+
+// extractContent replaces the content of each <name> element with a
+// data-mj-content attribute indexing contents
+func extractContent(source, name string, contents *[]string) (string, error) {
+	var out strings.Builder
+	lower := strings.ToLower(source)
+	open, closing := "<"+name, "</"+name+">"
+	for {
+		start := strings.Index(lower, open)
+		if start < 0 || start+len(open) >= len(lower) {
+			break
+		}
+		next := lower[start+len(open)]
+		if next != ' ' && next != '>' && next != '/' && next != '\n' && next != '\t' && next != '\r' {
+			out.WriteString(source[:start+len(open)])
+			source, lower = source[start+len(open):], lower[start+len(open):]
+			continue
+		}
+		tagEnd := strings.IndexByte(lower[start:], '>')
+		if tagEnd < 0 {
+			return "", fmt.Errorf("invalid MJML: <%s> isn't closed", name)
+		}
+		tagEnd += start
+		tag := strings.TrimSpace(source[start+len(open) : tagEnd])
+		if strings.HasSuffix(tag, "/") {
+			// Self-closing, so it has no content
+			out.WriteString(source[:tagEnd+1])
+			source, lower = source[tagEnd+1:], lower[tagEnd+1:]
+			continue
+		}
+		end := strings.Index(lower[tagEnd:], closing)
+		if end < 0 {
+			return "", fmt.Errorf("invalid MJML: <%s> has no %s", name, closing)
+		}
+		end += tagEnd
+		*contents = append(*contents, strings.TrimSpace(source[tagEnd+1:end]))
+		out.WriteString(source[:start] + open + " " + tag + ` data-mj-content="` + strconv.Itoa(len(*contents)-1) + `"/>`)
+		source, lower = source[end+len(closing):], lower[end+len(closing):]
+	}
+	out.WriteString(source)
+	return out.String(), nil
+}
+
+type mjRenderer struct {
+	title, preview string
+	styles         []string
+	fonts          []string
+	// attributes are the defaults set in mj-attributes, by component, with
+	// mj-all's under "mj-all"
+	attributes map[string]map[string]string
+	classes    map[string]map[string]string
+	// columnClasses are the responsive width classes the columns use
+	columnClasses map[string]string
+}
+
+func (r *mjRenderer) head(head *mjNode) error {
+	for _, child := range head.children {
+		switch child.name {
+		case "mj-title":
+			r.title = child.content
+		case "mj-preview":
+			r.preview = child.content
+		case "mj-style":
+			r.styles = append(r.styles, child.content)
+		case "mj-font":
+			if href := child.attrs["href"]; href != "" {
+				r.fonts = append(r.fonts, href)
+			}
+		case "mj-attributes":
+			for _, attr := range child.children {
+				if attr.name == "mj-class" {
+					r.classes[attr.attrs["name"]] = attr.attrs
+					continue
+				}
+				r.attributes[attr.name] = attr.attrs
+			}
+		case "mj-raw":
+		default:
+			return fmt.Errorf("<%s> can't be placed in <mj-head>", child.name)
+		}
+	}
+	return nil
+}
+
+// attr resolves a component's attribute: its own, then its mj-class, then
+// mj-attributes for the component, then mj-all, then the default
+func (r *mjRenderer) attr(n *mjNode, key string) string {
+	if value, ok := n.attrs[key]; ok {
+		return value
+	}
+	for _, class := range strings.Fields(n.attrs["mj-class"]) {
+		if value, ok := r.classes[class][key]; ok && key != "name" {
+			return value
+		}
+	}
+	if value, ok := r.attributes[n.name][key]; ok {
+		return value
+	}
+	if value, ok := r.attributes["mj-all"][key]; ok {
+		return value
+	}
+	return mjDefaults[n.name][key]
+}
+
+func (r *mjRenderer) body(body *mjNode) (string, error) {
+	width := px(r.attr(body, "width"), 600)
+	var out strings.Builder
+	for _, child := range body.children {
+		var section string
+		var err error
+		switch child.name {
+		case "mj-section":
+			section, err = r.section(child, width)
+		case "mj-wrapper":
+			section, err = r.wrapper(child, width)
+		case "mj-raw":
+			section = child.content
+		default:
+			err = fmt.Errorf("<%s> must be placed in a <mj-section> or <mj-column>", child.name)
+		}
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(section + "\n")
+	}
+
+	style := ""
+	if bg := r.attr(body, "background-color"); bg != "" {
+		style = ` style="background-color: ` + esc(bg) + `;"`
+	}
+	return "<div" + style + ">\n" + out.String() + "</div>", nil
+}
+
+func (r *mjRenderer) wrapper(wrapper *mjNode, width int) (string, error) {
+	inner := width - horizontalPadding(r, wrapper)
+	var sections strings.Builder
+	for _, child := range wrapper.children {
+		if child.name == "mj-raw" {
+			sections.WriteString(child.content + "\n")
+			continue
+		}
+		if child.name != "mj-section" {
+			return "", fmt.Errorf("<%s> can't be placed in <mj-wrapper>", child.name)
+		}
+		section, err := r.section(child, inner)
+		if err != nil {
+			return "", err
+		}
+		sections.WriteString(section + "\n")
+	}
+	return r.box(wrapper, width, sections.String()), nil
+}
+
+// box is the centred, full-width block sections and wrappers are drawn as
+func (r *mjRenderer) box(n *mjNode, width int, content string) string {
+	background := r.attr(n, "background-color")
+	outer := "margin: 0px auto; max-width: " + strconv.Itoa(width) + "px;"
+	table := "width: 100%;"
+	if background != "" {
+		outer += " background: " + esc(background) + "; background-color: " + esc(background) + ";"
+		table += " background: " + esc(background) + "; background-color: " + esc(background) + ";"
+	}
+	if radius := r.attr(n, "border-radius"); radius != "" {
+		outer += " border-radius: " + esc(radius) + "; overflow: hidden;"
+	}
+	cell := "direction: " + esc(orDefault(r.attr(n, "direction"), "ltr")) + "; font-size: 0px; padding: " +
+		esc(r.attr(n, "padding")) + "; text-align: " + esc(r.attr(n, "text-align")) + ";" + paddingSides(r, n)
+
+	return `<!--[if mso | IE]><table align="center" border="0" cellpadding="0" cellspacing="0" role="presentation" style="width:` +
+		strconv.Itoa(width) + `px;" width="` + strconv.Itoa(width) + `"><tr><td style="line-height:0px;font-size:0px;mso-line-height-rule:exactly;"><![endif]-->
+<div style="` + outer + `">
+<table align="center" border="0" cellpadding="0" cellspacing="0" role="presentation" style="` + table + `">
+<tbody>
+<tr>
+<td style="` + cell + `">
+` + content + `</td>
+</tr>
+</tbody>
+</table>
+</div>
+<!--[if mso | IE]></td></tr></table><![endif]-->`
+}
+
+func (r *mjRenderer) section(section *mjNode, width int) (string, error) {
+	inner := width - horizontalPadding(r, section)
+
+	// Columns without a width share what the others leave
+	var columns []*mjNode
+	used, unsized := 0.0, 0
+	for _, child := range section.children {
+		switch child.name {
+		case "mj-column":
+			columns = append(columns, child)
+			if w := r.attr(child, "width"); w == "" {
+				unsized++
+			} else {
+				used += percent(w, inner)
+			}
+		case "mj-raw":
+		default:
+			return "", fmt.Errorf("<%s> must be placed in a <mj-column>", child.name)
+		}
+	}
+	share := 100.0
+	if unsized > 0 {
+		share = (100 - used) / float64(unsized)
+		if share < 0 {
+			share = 0
+		}
+	}
+
+	var out strings.Builder
+	out.WriteString(`<!--[if mso | IE]><table role="presentation" border="0" cellpadding="0" cellspacing="0"><tr><![endif]-->` + "\n")
+	for _, child := range section.children {
+		if child.name == "mj-raw" {
+			out.WriteString(child.content + "\n")
+			continue
+		}
+		pct := share
+		if w := r.attr(child, "width"); w != "" {
+			pct = percent(w, inner)
+		}
+		column, err := r.column(child, pct, inner)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(column + "\n")
+	}
+	out.WriteString(`<!--[if mso | IE]></tr></table><![endif]-->` + "\n")
+	return r.box(section, width, out.String()), nil
+}
+
+func (r *mjRenderer) column(column *mjNode, pct float64, sectionWidth int) (string, error) {
+	width := int(float64(sectionWidth) * pct / 100)
+	inner := width - horizontalPadding(r, column)
+
+	class := "mj-column-per-" + strings.ReplaceAll(strconv.FormatFloat(pct, 'f', -1, 64), ".", "-")
+	if r.columnClasses == nil {
+		r.columnClasses = map[string]string{}
+	}
+	r.columnClasses[class] = strconv.FormatFloat(pct, 'f', -1, 64) + "%"
+
+	var rows strings.Builder
+	for _, child := range column.children {
+		if !columnComponents[child.name] {
+			if _, known := mjDefaults[child.name]; known || child.name == "mj-column" {
+				return "", fmt.Errorf("<%s> can't be placed in <mj-column>", child.name)
+			}
+			return "", fmt.Errorf("unknown MJML component <%s>", child.name)
+		}
+		row, err := r.component(child, inner)
+		if err != nil {
+			return "", err
+		}
+		rows.WriteString(row + "\n")
+	}
+
+	valign := esc(r.attr(column, "vertical-align"))
+	cell := "vertical-align: " + valign + ";"
+	if background := r.attr(column, "background-color"); background != "" {
+		cell += " background-color: " + esc(background) + ";"
+	}
+	if padding := r.attr(column, "padding"); padding != "" {
+		cell += " padding: " + esc(padding) + ";"
+	}
+	cell += paddingSides(r, column)
+	if radius := r.attr(column, "border-radius"); radius != "" {
+		cell += " border-radius: " + esc(radius) + ";"
+	}
+
+	return `<!--[if mso | IE]><td style="vertical-align:` + valign + `;width:` + strconv.Itoa(width) + `px;"><![endif]-->
+<div class="` + class + ` mj-outlook-group-fix" style="font-size: 0px; text-align: left; direction: ` + esc(r.attr(column, "direction")) +
+		`; display: inline-block; vertical-align: ` + valign + `; width: 100%;">
+<table border="0" cellpadding="0" cellspacing="0" role="presentation" width="100%">
+<tbody>
+<tr>
+<td style="` + cell + `">
+<table border="0" cellpadding="0" cellspacing="0" role="presentation" width="100%">
+<tbody>
+` + rows.String() + `</tbody>
+</table>
+</td>
+</tr>
+</tbody>
+</table>
+</div>
+<!--[if mso | IE]></td><![endif]-->`, nil
+}
+
+// component renders a content component as a row of its column. width is
+// the column's content width in pixels.
+func (r *mjRenderer) component(n *mjNode, width int) (string, error) {
+	if n.name == "mj-raw" {
+		return "<tr>\n<td>\n" + n.content + "\n</td>\n</tr>", nil
+	}
+
+	align := esc(orDefault(r.attr(n, "align"), "center"))
+	inner := width - horizontalPadding(r, n)
+	var content string
+	switch n.name {
+	case "mj-text":
+		content = `<div style="font-family: ` + esc(r.attr(n, "font-family")) + `; font-size: ` + esc(r.attr(n, "font-size")) +
+			`; line-height: ` + esc(r.attr(n, "line-height")) + `; text-align: ` + align + `; color: ` + esc(r.attr(n, "color")) + ";" +
+			optional("font-weight", r.attr(n, "font-weight")) + optional("font-style", r.attr(n, "font-style")) +
+			optional("letter-spacing", r.attr(n, "letter-spacing")) + optional("text-transform", r.attr(n, "text-transform")) +
+			`">` + n.content + `</div>`
+
+	case "mj-button":
+		background := esc(r.attr(n, "background-color"))
+		radius := esc(r.attr(n, "border-radius"))
+		innerPadding := esc(r.attr(n, "inner-padding"))
+		label := `display: inline-block; background: ` + background + `; color: ` + esc(r.attr(n, "color")) +
+			`; font-family: ` + esc(r.attr(n, "font-family")) + `; font-size: ` + esc(r.attr(n, "font-size")) +
+			`; font-weight: ` + esc(r.attr(n, "font-weight")) + `; line-height: ` + esc(r.attr(n, "line-height")) +
+			`; margin: 0; text-decoration: ` + esc(r.attr(n, "text-decoration")) + `; text-transform: none; padding: ` + innerPadding +
+			`; mso-padding-alt: 0px; border-radius: ` + radius + `;`
+		button := `<p style="` + label + `">` + n.content + `</p>`
+		if href := r.attr(n, "href"); href != "" {
+			button = `<a href="` + esc(href) + `" style="` + label + `" target="_blank">` + n.content + `</a>`
+		}
+		tableStyle := "border-collapse: separate; line-height: 100%;"
+		if w := r.attr(n, "width"); w != "" {
+			tableStyle += " width: " + esc(w) + ";"
+		}
+		content = `<table border="0" cellpadding="0" cellspacing="0" role="presentation" style="` + tableStyle + `">
+<tbody>
+<tr>
+<td align="center" bgcolor="` + background + `" role="presentation" style="border: none; border-radius: ` + radius +
+			`; cursor: auto; mso-padding-alt: ` + innerPadding + `; background: ` + background + `;" valign="` +
+			esc(r.attr(n, "vertical-align")) + `">
+` + button + `
+</td>
+</tr>
+</tbody>
+</table>`
+
+	case "mj-image":
+		src := r.attr(n, "src")
+		if src == "" {
+			return "", errors.New("<mj-image> needs a src")
+		}
+		imageWidth := inner
+		if w := r.attr(n, "width"); w != "" && px(w, inner) < inner {
+			imageWidth = px(w, inner)
+		}
+		img := `<img alt="` + esc(r.attr(n, "alt")) + `" src="` + esc(src) + `" style="border: 0; display: block; outline: none; text-decoration: none; height: auto; width: 100%; font-size: 13px;" width="` +
+			strconv.Itoa(imageWidth) + `" height="auto">`
+		if title := r.attr(n, "title"); title != "" {
+			img = strings.Replace(img, "<img ", `<img title="`+esc(title)+`" `, 1)
+		}
+		if href := r.attr(n, "href"); href != "" {
+			img = `<a href="` + esc(href) + `" target="_blank">` + img + `</a>`
+		}
+		content = `<table border="0" cellpadding="0" cellspacing="0" role="presentation" style="border-collapse: collapse; border-spacing: 0px;">
+<tbody>
+<tr>
+<td style="width: ` + strconv.Itoa(imageWidth) + `px;">` + img + `</td>
+</tr>
+</tbody>
+</table>`
+
+	case "mj-divider":
+		border := esc(r.attr(n, "border-style")) + " " + esc(r.attr(n, "border-width")) + " " + esc(r.attr(n, "border-color"))
+		content = `<p style="border-top: ` + border + `; font-size: 1px; margin: 0px auto; width: ` + esc(r.attr(n, "width")) + `;"></p>
+<!--[if mso | IE]><table align="center" border="0" cellpadding="0" cellspacing="0" style="border-top:` + border + `;font-size:1px;margin:0px auto;width:` +
+			strconv.Itoa(inner) + `px;" role="presentation" width="` + strconv.Itoa(inner) + `px"><tr><td style="height:0;line-height:0;">&nbsp;</td></tr></table><![endif]-->`
+
+	case "mj-spacer":
+		height := esc(r.attr(n, "height"))
+		content = `<div style="height: ` + height + `; line-height: ` + height + `;">&#8202;</div>`
+
+	case "mj-table":
+		content = `<table cellpadding="` + esc(orDefault(r.attr(n, "cellpadding"), "0")) + `" cellspacing="` +
+			esc(orDefault(r.attr(n, "cellspacing"), "0")) + `" width="` + esc(r.attr(n, "width")) + `" border="0" style="color: ` +
+			esc(r.attr(n, "color")) + `; font-family: ` + esc(r.attr(n, "font-family")) + `; font-size: ` + esc(r.attr(n, "font-size")) +
+			`; line-height: ` + esc(r.attr(n, "line-height")) + `; table-layout: auto; width: ` + esc(r.attr(n, "width")) + `; border: none;">
+` + n.content + `
+</table>`
+	}
+
+	cell := "font-size: 0px;" + optional("padding", r.attr(n, "padding")) + paddingSides(r, n) + " word-break: break-word;"
+	if background := r.attr(n, "container-background-color"); background != "" {
+		cell += " background: " + esc(background) + ";"
+	}
+	return `<tr>
+<td align="` + align + `" style="` + cell + `">
+` + content + `
+</td>
+</tr>`, nil
+}
+
+// document wraps the rendered body in the page, with the styles that make
+// columns stack on small screens
+func (r *mjRenderer) document(body *mjNode, content string) string {
+	var head strings.Builder
+	head.WriteString(`<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office">
+<head>
+<title>` + r.title + `</title>
+<meta http-equiv="Content-Type" content="text/html; charset=UTF-8">
+<meta http-equiv="X-UA-Compatible" content="IE=edge">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+`)
+	for _, href := range r.fonts {
+		head.WriteString(`<link href="` + esc(href) + `" rel="stylesheet" type="text/css">` + "\n")
+	}
+
+	head.WriteString(`<style type="text/css">
+body { margin: 0; padding: 0; -webkit-text-size-adjust: 100%; -ms-text-size-adjust: 100%; }
+img { border: 0; height: auto; line-height: 100%; outline: none; text-decoration: none; -ms-interpolation-mode: bicubic; }
+p { display: block; margin: 13px 0; }
+@media only screen and (min-width: 480px) {
+`)
+	var classes []string
+	for class := range r.columnClasses {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		width := r.columnClasses[class]
+		head.WriteString("  ." + class + " { width: " + width + " !important; max-width: " + width + "; }\n")
+	}
+	head.WriteString(`}
+@media only screen and (max-width: 479px) {
+  table.mj-full-width-mobile { width: 100% !important; }
+  td.mj-full-width-mobile { width: auto !important; }
+}
+`)
+	for _, style := range r.styles {
+		head.WriteString(style + "\n")
+	}
+	head.WriteString("</style>\n</head>\n")
+
+	bodyStyle := "word-spacing: normal;"
+	if bg := r.attr(body, "background-color"); bg != "" {
+		bodyStyle += " background-color: " + esc(bg) + ";"
+	}
+	head.WriteString(`<body style="` + bodyStyle + `">` + "\n")
+	if r.preview != "" {
+		head.WriteString(`<div style="display: none; font-size: 1px; color: #ffffff; line-height: 1px; max-height: 0px; max-width: 0px; opacity: 0; overflow: hidden;">` +
+			r.preview + "</div>\n")
+	}
+	return head.String() + content + "\n</body>\n</html>\n"
+}
+
+// px reads a width such as "300px" or "50%" of whole in pixels
+func px(value string, whole int) int {
+	value = strings.TrimSpace(value)
+	if strings.HasSuffix(value, "%") {
+		n, _ := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		return int(float64(whole) * n / 100)
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(value, "px"))
+	if err != nil {
+		return whole
+	}
+	return n
+}
+
+// percent reads a width such as "300px" or "50%" as a percentage of whole
+func percent(value string, whole int) float64 {
+	value = strings.TrimSpace(value)
+	if strings.HasSuffix(value, "%") {
+		n, _ := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		return n
+	}
+	if whole == 0 {
+		return 0
+	}
+	return float64(px(value, whole)) * 100 / float64(whole)
+}
+
+// horizontalPadding is a component's left and right padding in pixels
+func horizontalPadding(r *mjRenderer, n *mjNode) int {
+	fields := strings.Fields(r.attr(n, "padding"))
+	var right, left string
+	switch len(fields) {
+	case 1:
+		right, left = fields[0], fields[0]
+	case 2, 3:
+		right, left = fields[1], fields[1]
+	case 4:
+		right, left = fields[1], fields[3]
+	}
+	if side := r.attr(n, "padding-right"); side != "" {
+		right = side
+	}
+	if side := r.attr(n, "padding-left"); side != "" {
+		left = side
+	}
+	return px(orDefault(right, "0"), 0) + px(orDefault(left, "0"), 0)
+}
+
+// paddingSides renders the padding-top and similar attributes set on a
+// component
+func paddingSides(r *mjRenderer, n *mjNode) string {
+	var style string
+	for _, side := range []string{"padding-top", "padding-right", "padding-bottom", "padding-left"} {
+		style += optional(side, r.attr(n, side))
+	}
+	return style
+}
+
+func optional(property, value string) string {
+	if value == "" {
+		return ""
+	}
+	return " " + property + ": " + esc(value) + ";"
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func esc(value string) string {
+	return html.EscapeString(value)
+}
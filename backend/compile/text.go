@@ -0,0 +1,183 @@
+package compile
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	spacePattern     = regexp.MustCompile(`[ \t\r\n\f]+`)
+	blankLinePattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// blockElements start on a line of their own in the plain-text version
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Table: true, atom.Tr: true, atom.Ul: true, atom.Ol: true,
+	atom.Blockquote: true, atom.Pre: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.H5: true, atom.H6: true, atom.Hr: true, atom.Center: true, atom.Section: true,
+}
+
+// Text converts an HTML body to a plain-text version: paragraphs separated by
+// blank lines, list items marked with dashes or numbers, and links followed
+// by their address. Merge fields and partial includes are kept.
+func Text(body string) string {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	t := &textWriter{}
+	t.node(doc)
+	lines := strings.Split(t.out.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+	}
+	text := blankLinePattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text) + "\n"
+}
+
+type textWriter struct {
+	out strings.Builder
+	pre bool
+	// lists is how deeply the current list is nested
+	lists int
+}
+
+// newline ends the current line, or adds blank lines up to n line breaks
+func (t *textWriter) newline(n int) {
+	text := t.out.String()
+	have := len(text) - len(strings.TrimRight(text, "\n"))
+	for ; have < n; have++ {
+		t.out.WriteString("\n")
+	}
+}
+
+func (t *textWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		if t.pre {
+			t.out.WriteString(n.Data)
+			return
+		}
+		text := spacePattern.ReplaceAllString(n.Data, " ")
+		if strings.HasSuffix(t.out.String(), "\n") || t.out.Len() == 0 {
+			text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		}
+		t.out.WriteString(text)
+		return
+	case html.CommentNode:
+		return
+	case html.ElementNode:
+	default:
+		t.children(n)
+		return
+	}
+
+	if hidden(n) {
+		return
+	}
+	switch n.DataAtom {
+	case atom.Head, atom.Style, atom.Script, atom.Title:
+		return
+	case atom.Br:
+		t.out.WriteString("\n")
+		return
+	case atom.Img:
+		if alt := strings.TrimSpace(attribute(n, "alt")); alt != "" {
+			t.out.WriteString(alt)
+		}
+		return
+	case atom.Hr:
+		t.newline(2)
+		t.out.WriteString("----------")
+		t.newline(2)
+		return
+	case atom.A:
+		start := t.out.Len()
+		t.children(n)
+		label := strings.TrimSpace(t.out.String()[start:])
+		href := strings.TrimSpace(attribute(n, "href"))
+		if label != "" && href != "" && !strings.HasPrefix(href, "#") && label != href &&
+			label != strings.TrimPrefix(href, "mailto:") {
+			t.out.WriteString(" (" + href + ")")
+		}
+		return
+	case atom.Li:
+		t.newline(1)
+		marker := "- "
+		if n.Parent != nil && n.Parent.DataAtom == atom.Ol {
+			marker = strconv.Itoa(position(n)) + ". "
+		}
+		if t.lists > 1 {
+			marker = strings.Repeat("  ", t.lists-1) + marker
+		}
+		t.out.WriteString(marker)
+		t.children(n)
+		t.newline(1)
+		return
+	case atom.Ul, atom.Ol:
+		// Lists nested in an item start on the item's next line
+		breaks := 2
+		if t.lists > 0 {
+			breaks = 1
+		}
+		t.newline(breaks)
+		t.lists++
+		t.children(n)
+		t.lists--
+		t.newline(breaks)
+		return
+	case atom.Td, atom.Th:
+		if t.out.Len() > 0 && !strings.HasSuffix(t.out.String(), "\n") {
+			t.out.WriteString(" ")
+		}
+		t.children(n)
+		return
+	case atom.Pre:
+		t.newline(2)
+		t.pre = true
+		t.children(n)
+		t.pre = false
+		t.newline(2)
+		return
+	}
+
+	if blockElements[n.DataAtom] {
+		t.newline(2)
+		t.children(n)
+		t.newline(2)
+		return
+	}
+	t.children(n)
+}
+
+// hidden reports whether an element is styled not to display, such as
+// preview text
+func hidden(n *html.Node) bool {
+	style := strings.ReplaceAll(strings.ToLower(attribute(n, "style")), " ", "")
+	return strings.Contains(style, "display:none")
+}
+
+func (t *textWriter) children(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		t.node(child)
+	}
+}
+
+// position is a list item's number within its ordered list
+func position(li *html.Node) int {
+	start := 1
+	if n, err := strconv.Atoi(attribute(li.Parent, "start")); err == nil {
+		start = n
+	}
+	for sibling := li.PrevSibling; sibling != nil; sibling = sibling.PrevSibling {
+		if sibling.Type == html.ElementNode && sibling.DataAtom == atom.Li {
+			start++
+		}
+	}
+	return start
+}
@@ -5,8 +5,12 @@ import (
 	"github.com/4cecoder/drip-campaign/database"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
+	"github.com/4cecoder/drip-campaign/assets"
 	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/secrets"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
 )
@@ -18,6 +22,62 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	JWTSecret  string
+	AppURL     string
+	PublicURL  string
+
+	LoginMaxFailures    int
+	LoginMaxIPFailures  int
+	LoginLockoutMinutes int
+
+	// SessionHours is how long a session token is valid for
+	SessionHours int
+
+	// TrustedProxies lists the proxies allowed to set the client IP via X-Forwarded-For
+	TrustedProxies []string
+
+	// MasterKey encrypts secrets stored in the database. PreviousMasterKeys are
+	// still accepted for decryption while rotating to a new key.
+	MasterKey          string
+	PreviousMasterKeys []string
+
+	// EngineIntervalSeconds is how often the drip engine looks for due steps
+	EngineIntervalSeconds int
+
+	// Bounce thresholds: a customer is suppressed after this many hard bounces,
+	// soft bounces within BounceSoftWindowDays, or spam complaints. Zero disables.
+	BounceHardLimit      int
+	BounceSoftLimit      int
+	BounceSoftWindowDays int
+	ComplaintLimit       int
+
+	// Optional IMAP mailbox that receives bounces and feedback-loop reports
+	BounceIMAPAddr     string
+	BounceIMAPUser     string
+	BounceIMAPPassword string
+	BounceIMAPFolder   string
+	BouncePollSeconds  int
+
+	// Global send rate limits; zero means no limit. SendQueueIntervalSeconds is
+	// how often the send queue is worked through.
+	SendLimitPerMinute       int
+	SendLimitPerHour         int
+	SendLimitPerDay          int
+	SendQueueIntervalSeconds int
+
+	// Failed sends are retried up to SendMaxAttempts times, waiting
+	// SendRetryBaseSeconds and doubling after each attempt
+	SendMaxAttempts      int
+	SendRetryBaseSeconds int
+
+	// Uploaded assets are kept in AssetDir. An upload can be AssetMaxBytes
+	// at most, and the attachments of one email AttachmentMaxBytes together.
+	AssetDir           string
+	AssetMaxBytes      int64
+	AttachmentMaxBytes int64
+
+	// DefaultLocale is the language templates are written in. Customers are
+	// sent a template's localization for their locale when it has one.
+	DefaultLocale string
 }
 
 func Init() {
@@ -25,6 +85,16 @@ func Init() {
 
 	config := LoadConfig()
 
+	keyring, err := secrets.NewKeyring(config.MasterKey, config.PreviousMasterKeys)
+	if err != nil {
+		log.Fatal("Invalid master key:", err)
+	}
+	secrets.SetKeyring(keyring)
+	if config.MasterKey == "" {
+		log.Println("MASTER_KEY is not set, secrets such as mail credentials can't be saved")
+	}
+	assets.SetStore(assets.Local{Dir: config.AssetDir})
+
 	dsn := fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=disable",
 		config.DBHost, config.DBPort, config.DBUser, config.DBName, config.DBPassword)
 
@@ -46,10 +116,35 @@ func Init() {
 		&models.CampaignCustomer{},
 		&models.Settings{},
 		&models.EmailLog{},
+		&models.Invitation{},
+		&models.PasswordResetToken{},
+		&models.RecoveryCode{},
+		&models.LoginAttempt{},
+		&models.APIKey{},
+		&models.EngagementEvent{},
+		&models.InboundMessage{},
+		&models.StepVariant{},
+		&models.CampaignTrigger{},
+		&models.CustomerEvent{},
+		&models.TriggerFiring{},
+		&models.QueuedEmail{},
+		&models.DomainRateLimit{},
+		&models.SenderIdentity{},
+		&models.DKIMKey{},
+		&models.TemplateVersion{},
+		&models.Partial{},
+		&models.Asset{},
+		&models.Attachment{},
+		&models.TemplateLocalization{},
+		&models.LocalizationRevision{},
 
 		// Add other models here
 	)
 
+	// Encrypted values don't fit the varchar(255) columns created before encryption
+	database.DB.Model(&models.Settings{}).ModifyColumn("crm_api_key", "text")
+	database.DB.Model(&models.Settings{}).ModifyColumn("gmail_password", "text")
+
 	log.Println("Database migration completed")
 }
 
@@ -60,7 +155,45 @@ func LoadConfig() *Config {
 		DBUser:     getEnv("DB_USER", "postgres"),
 		DBPassword: getEnv("DB_PASSWORD", "password"),
 		DBName:     getEnv("DB_NAME", "drip_campaign"),
-		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key"),
+		JWTSecret:  getEnv("JWT_SECRET", ""),
+		AppURL:     getEnv("APP_URL", "http://localhost:3000"),
+		PublicURL:  getEnv("PUBLIC_URL", "http://localhost:8080"),
+
+		LoginMaxFailures:    getEnvInt("LOGIN_MAX_FAILURES", 5),
+		LoginMaxIPFailures:  getEnvInt("LOGIN_MAX_IP_FAILURES", 20),
+		LoginLockoutMinutes: getEnvInt("LOGIN_LOCKOUT_MINUTES", 15),
+		SessionHours:        getEnvInt("SESSION_HOURS", 24),
+
+		TrustedProxies: getEnvList("TRUSTED_PROXIES"),
+
+		MasterKey:          getEnv("MASTER_KEY", ""),
+		PreviousMasterKeys: getEnvList("MASTER_KEY_PREVIOUS"),
+
+		EngineIntervalSeconds: getEnvInt("ENGINE_INTERVAL_SECONDS", 60),
+
+		BounceHardLimit:      getEnvInt("BOUNCE_HARD_LIMIT", 1),
+		BounceSoftLimit:      getEnvInt("BOUNCE_SOFT_LIMIT", 3),
+		BounceSoftWindowDays: getEnvInt("BOUNCE_SOFT_WINDOW_DAYS", 30),
+		ComplaintLimit:       getEnvInt("COMPLAINT_LIMIT", 1),
+
+		BounceIMAPAddr:     getEnv("BOUNCE_IMAP_ADDR", ""),
+		BounceIMAPUser:     getEnv("BOUNCE_IMAP_USER", ""),
+		BounceIMAPPassword: getEnv("BOUNCE_IMAP_PASSWORD", ""),
+		BounceIMAPFolder:   getEnv("BOUNCE_IMAP_FOLDER", "INBOX"),
+		BouncePollSeconds:  getEnvInt("BOUNCE_POLL_SECONDS", 300),
+
+		SendLimitPerMinute:       getEnvInt("SEND_LIMIT_PER_MINUTE", 0),
+		SendLimitPerHour:         getEnvInt("SEND_LIMIT_PER_HOUR", 0),
+		SendLimitPerDay:          getEnvInt("SEND_LIMIT_PER_DAY", 0),
+		SendQueueIntervalSeconds: getEnvInt("SEND_QUEUE_INTERVAL_SECONDS", 5),
+		SendMaxAttempts:          getEnvInt("SEND_MAX_ATTEMPTS", 8),
+		SendRetryBaseSeconds:     getEnvInt("SEND_RETRY_BASE_SECONDS", 60),
+
+		AssetDir:           getEnv("ASSET_DIR", "uploads"),
+		AssetMaxBytes:      int64(getEnvInt("ASSET_MAX_BYTES", 10<<20)),
+		AttachmentMaxBytes: int64(getEnvInt("ATTACHMENT_MAX_BYTES", 20<<20)),
+
+		DefaultLocale: getEnv("DEFAULT_LOCALE", "en"),
 	}
 }
 
@@ -70,3 +203,23 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	if value, ok := os.LookupEnv(key); ok {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+		log.Printf("Invalid value for %s, using default %d", key, fallback)
+	}
+	return fallback
+}
+
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
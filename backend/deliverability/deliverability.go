@@ -0,0 +1,692 @@
+// Package deliverability checks the DNS records of sending domains (MX, SPF,
+// DKIM and DMARC) before mail goes out, and says how to fix what's wrong.
+package deliverability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/dkim"
+	"github.com/4cecoder/drip-campaign/models"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Resolver looks up DNS records. *net.Resolver implements it.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// DefaultResolver is the resolver CheckDomain uses. Tests can replace it with
+// a fake.
+var DefaultResolver Resolver = net.DefaultResolver
+
+const (
+	// gmailSPF is the SPF record that authorizes Gmail's servers
+	gmailSPF = "_spf.google.com"
+
+	// googleSelector is the DKIM selector Google Workspace signs with by default
+	googleSelector = "google"
+
+	// maxSPFLookups is how many DNS lookups receivers allow an SPF record
+	maxSPFLookups = 10
+)
+
+// Config is how mail from a domain is sent: the enabled DKIM keys it's
+// signed with, whether any of its senders goes through Gmail and the SMTP
+// relays the others go through
+type Config struct {
+	Keys     []models.DKIMKey
+	ViaGmail bool
+	Relays   []string
+}
+
+// CheckDomain checks a domain with DefaultResolver, using the DKIM keys and
+// sender identities configured for it
+func CheckDomain(ctx context.Context, domain string) (models.DomainCheck, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	cfg, err := domainConfig(domain)
+	if err != nil {
+		return models.DomainCheck{}, err
+	}
+	return Check(ctx, DefaultResolver, domain, cfg), nil
+}
+
+// domainConfig loads how mail from domain is sent
+func domainConfig(domain string) (Config, error) {
+	var cfg Config
+	if err := database.DB.Where("domain = ? AND disabled = ?", domain, false).Order("id asc").Find(&cfg.Keys).Error; err != nil {
+		return cfg, fmt.Errorf("failed to retrieve DKIM keys: %w", err)
+	}
+
+	var settings models.Settings
+	if err := database.DB.First(&settings).Error; err == nil && Domain(settings.GmailEmail) == domain {
+		cfg.ViaGmail = true
+	}
+	var identities []models.SenderIdentity
+	if err := database.DB.Where("lower(from_email) LIKE ?", "%@"+domain).Find(&identities).Error; err != nil {
+		return cfg, fmt.Errorf("failed to retrieve sender identities: %w", err)
+	}
+	relays := map[string]bool{}
+	for _, identity := range identities {
+		host := strings.ToLower(identity.SMTPHost)
+		switch {
+		case host == "" || host == "smtp.gmail.com":
+			cfg.ViaGmail = true
+		case !relays[host]:
+			relays[host] = true
+			cfg.Relays = append(cfg.Relays, host)
+		}
+	}
+	sort.Strings(cfg.Relays)
+	return cfg, nil
+}
+
+// CampaignDomains returns the from-domains a campaign's emails can be sent
+// from: those of its sender identity, its steps' identities and, depending on
+// its sender mode, the identities in rotation or of assigned reps. Identity
+// zero is the Settings account.
+func CampaignDomains(campaign *models.DripCampaign, steps []models.Step) ([]string, error) {
+	ids := map[uint]bool{campaign.SenderIdentityID: true}
+	for _, step := range steps {
+		if step.SenderIdentityID != 0 {
+			ids[step.SenderIdentityID] = true
+		}
+	}
+
+	var identities []models.SenderIdentity
+	query := database.DB
+	switch campaign.SenderMode {
+	case models.SenderModeRotate:
+		query = query.Where("id IN (?) OR in_rotation = ?", idList(ids), true)
+	case models.SenderModeAssignedRep:
+		query = query.Where("id IN (?) OR user_id <> 0", idList(ids))
+	default:
+		query = query.Where("id IN (?)", idList(ids))
+	}
+	if err := query.Find(&identities).Error; err != nil {
+		return nil, err
+	}
+
+	domains := map[string]bool{}
+	for _, identity := range identities {
+		domains[Domain(identity.FromEmail)] = true
+	}
+	if ids[0] {
+		var settings models.Settings
+		if err := database.DB.First(&settings).Error; err == nil {
+			domains[Domain(settings.GmailEmail)] = true
+		}
+	}
+
+	var list []string
+	for domain := range domains {
+		if domain != "" {
+			list = append(list, domain)
+		}
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+func idList(ids map[uint]bool) []uint {
+	list := []uint{0}
+	for id := range ids {
+		list = append(list, id)
+	}
+	return list
+}
+
+// Domain returns the lowercased domain of an email address
+func Domain(addr string) string {
+	i := strings.LastIndex(addr, "@")
+	if i < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.Trim(addr[i+1:], "> "))
+}
+
+// Check inspects the DNS records of a domain with the given resolver
+func Check(ctx context.Context, r Resolver, domain string, cfg Config) models.DomainCheck {
+	result := models.DomainCheck{Domain: domain}
+
+	result.Checks = append(result.Checks, checkMX(ctx, r, domain))
+
+	spf, spfAligned := checkSPF(ctx, r, domain, cfg)
+	result.Checks = append(result.Checks, spf)
+
+	dkimChecks, dkimAligned := checkDKIM(ctx, r, domain, cfg)
+	result.Checks = append(result.Checks, dkimChecks...)
+
+	result.Checks = append(result.Checks, checkDMARC(ctx, r, domain))
+
+	alignment := models.DNSCheck{Record: "alignment"}
+	switch {
+	case dkimAligned || spfAligned:
+		alignment.Status = models.CheckOK
+		var via []string
+		if dkimAligned {
+			via = append(via, "DKIM")
+		}
+		if spfAligned {
+			via = append(via, "SPF")
+		}
+		alignment.Message = fmt.Sprintf("Mail from %s can pass DMARC through aligned %s", domain, strings.Join(via, " and "))
+	case spf.Status == models.CheckUnknown || anyUnknown(dkimChecks):
+		alignment.Status = models.CheckUnknown
+		alignment.Message = "Couldn't tell whether mail from " + domain + " passes DMARC, because some records couldn't be looked up"
+	default:
+		alignment.Status = models.CheckError
+		alignment.Message = "Mail from " + domain + " fails DMARC alignment: it has neither a valid DKIM signature for the domain nor an SPF record that authorizes its servers, so receivers will treat it as unauthenticated"
+		alignment.Fix = "Fix the DKIM or SPF problems above. Publishing the DKIM record of a key generated with POST /api/v1/dkim-keys and enabling the key is enough on its own."
+	}
+	result.Alignment = alignment.Status
+	result.Checks = append(result.Checks, alignment)
+
+	result.Status = models.CheckOK
+	for _, check := range result.Checks {
+		if severity(check.Status) > severity(result.Status) {
+			result.Status = check.Status
+		}
+	}
+	return result
+}
+
+func severity(status string) int {
+	switch status {
+	case models.CheckError:
+		return 3
+	case models.CheckWarning:
+		return 2
+	case models.CheckUnknown:
+		return 1
+	}
+	return 0
+}
+
+func anyUnknown(checks []models.DNSCheck) bool {
+	for _, check := range checks {
+		if check.Status == models.CheckUnknown {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupTXT returns the TXT records at name. A name that doesn't exist has no
+// records rather than an error.
+func lookupTXT(ctx context.Context, r Resolver, name string) ([]string, error) {
+	records, err := r.LookupTXT(ctx, name)
+	if notFound(err) {
+		return nil, nil
+	}
+	return records, err
+}
+
+func notFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// withPrefix returns the records that start with a version tag such as
+// "v=spf1", case-insensitively
+func withPrefix(records []string, prefix string) []string {
+	var found []string
+	for _, record := range records {
+		record = strings.TrimSpace(record)
+		if len(record) >= len(prefix) && strings.EqualFold(record[:len(prefix)], prefix) &&
+			(len(record) == len(prefix) || record[len(prefix)] == ' ' || record[len(prefix)] == ';') {
+			found = append(found, record)
+		}
+	}
+	return found
+}
+
+func checkMX(ctx context.Context, r Resolver, domain string) models.DNSCheck {
+	check := models.DNSCheck{Record: "mx"}
+	records, err := r.LookupMX(ctx, domain)
+	if err != nil && !notFound(err) {
+		check.Status = models.CheckUnknown
+		check.Message = "Couldn't look up MX records: " + err.Error()
+		return check
+	}
+
+	for _, mx := range records {
+		check.Values = append(check.Values, strings.TrimSuffix(mx.Host, "."))
+	}
+	switch {
+	case len(records) == 0:
+		check.Status = models.CheckWarning
+		check.Message = domain + " has no MX records, so bounces and replies to it can't be delivered, and some receivers reject mail from domains that can't receive any"
+		check.Fix = "Add an MX record for " + domain + " pointing at the server that receives its mail"
+	case len(records) == 1 && (records[0].Host == "." || records[0].Host == ""):
+		check.Status = models.CheckWarning
+		check.Message = domain + " publishes a null MX record, saying it accepts no mail, so bounces and replies to it are lost"
+		check.Fix = "Replace the null MX record with one pointing at the server that receives the domain's mail"
+	default:
+		check.Status = models.CheckOK
+		check.Message = domain + " receives mail at " + strings.Join(check.Values, ", ")
+	}
+	return check
+}
+
+// checkSPF inspects the domain's SPF record. It reports whether SPF aligns
+// for DMARC: emails are sent with the from address as the envelope sender,
+// so it does when the record is valid and covers the servers used. A custom
+// relay only counts as covered when every address its hostname resolves to
+// is authorized; relays that send from other addresses need aligned DKIM.
+func checkSPF(ctx context.Context, r Resolver, domain string, cfg Config) (models.DNSCheck, bool) {
+	check := models.DNSCheck{Record: "spf"}
+	suggested := "v=spf1 include:<your SMTP relay's SPF domain> ~all"
+	if cfg.ViaGmail {
+		suggested = "v=spf1 include:" + gmailSPF + " ~all"
+	}
+
+	txt, err := lookupTXT(ctx, r, domain)
+	if err != nil {
+		check.Status = models.CheckUnknown
+		check.Message = "Couldn't look up the SPF record: " + err.Error()
+		return check, false
+	}
+	records := withPrefix(txt, "v=spf1")
+	check.Values = records
+
+	switch len(records) {
+	case 0:
+		check.Status = models.CheckError
+		check.Message = domain + " has no SPF record, so receivers can't tell which servers may send its mail"
+		check.Fix = fmt.Sprintf("Publish a TXT record at %s: %s", domain, suggested)
+		return check, false
+	case 1:
+	default:
+		check.Status = models.CheckError
+		check.Message = fmt.Sprintf("%s has %d SPF records; receivers treat more than one as an error", domain, len(records))
+		check.Fix = "Merge them into a single v=spf1 record"
+		return check, false
+	}
+
+	walk := &spfWalk{ctx: ctx, r: r, seen: map[string]bool{}}
+	walk.record(records[0], domain, 0, true)
+
+	var problems, fixes []string
+	status := models.CheckOK
+	aligned := true
+	fail := func(level, problem, fix string) {
+		if severity(level) > severity(status) {
+			status = level
+		}
+		problems = append(problems, problem)
+		fixes = append(fixes, fix)
+	}
+
+	switch all := allMechanism(records[0]); all {
+	case "+all", "all":
+		fail(models.CheckError, "it ends with "+all+", which lets any server send as "+domain, "End the record with ~all or -all")
+		aligned = false
+	case "?all":
+		fail(models.CheckWarning, "it ends with ?all, which makes no claim about other servers", "End the record with ~all or -all")
+	case "":
+		if !strings.Contains(strings.ToLower(records[0]), "redirect=") {
+			fail(models.CheckWarning, "it has no all mechanism, so mail from other servers isn't marked", "End the record with ~all or -all")
+		}
+	}
+	if walk.lookups > maxSPFLookups {
+		fail(models.CheckError, fmt.Sprintf("it needs %d DNS lookups and receivers stop after %d, failing SPF", walk.lookups, maxSPFLookups),
+			"Remove unused include: mechanisms or replace them with ip4: and ip6: ranges")
+		aligned = false
+	}
+	if cfg.ViaGmail && !walk.seen[gmailSPF] {
+		fail(models.CheckError, "it doesn't authorize Gmail, which some of the domain's senders send through",
+			"Add include:"+gmailSPF+" before the all mechanism")
+		aligned = false
+	}
+	for _, relay := range cfg.Relays {
+		if problem := walk.covers(relay); problem != "" {
+			fail(models.CheckWarning, problem,
+				"Add the include: mechanism your provider documents for "+relay+", or ip4: and ip6: ranges for the addresses it sends from, before the all mechanism")
+			aligned = false
+		}
+	}
+	if !cfg.ViaGmail && len(cfg.Relays) == 0 {
+		// No sender identity uses the domain, so there's no server to check
+		aligned = false
+	}
+
+	check.Status = status
+	if len(problems) == 0 {
+		check.Message = domain + " has a valid SPF record"
+		switch {
+		case len(cfg.Relays) > 0:
+			check.Message += " that covers " + strings.Join(cfg.Relays, ", ")
+		case !cfg.ViaGmail:
+			check.Message += "; make sure it covers your SMTP relay"
+		}
+	} else {
+		check.Message = "The SPF record of " + domain + " has problems: " + strings.Join(problems, "; ")
+		check.Fix = strings.Join(fixes, ". ")
+	}
+	return check, aligned
+}
+
+// allMechanism returns the all mechanism of an SPF record with its qualifier
+func allMechanism(record string) string {
+	for _, term := range strings.Fields(record) {
+		if strings.EqualFold(strings.TrimLeft(term, "+-~?"), "all") {
+			return strings.ToLower(term)
+		}
+	}
+	return ""
+}
+
+// spfWalk counts the DNS lookups an SPF record needs, following include:
+// and redirect= to the records they name, and collects the networks its
+// ip4:, ip6:, a and mx mechanisms authorize
+type spfWalk struct {
+	ctx      context.Context
+	r        Resolver
+	lookups  int
+	seen     map[string]bool
+	networks []*net.IPNet
+}
+
+// record walks an SPF record. authorize is false inside records included by
+// a fail or softfail mechanism, whose networks aren't authorized.
+func (w *spfWalk) record(record, domain string, depth int, authorize bool) {
+	for _, term := range strings.Fields(record)[1:] {
+		qualifier := term[0]
+		term = strings.ToLower(strings.TrimLeft(term, "+-~?"))
+		name, target, _ := strings.Cut(term, ":")
+		if strings.HasPrefix(term, "redirect=") {
+			name, target = "redirect", strings.TrimPrefix(term, "redirect=")
+		}
+		// Only pass mechanisms authorize anything
+		pass := authorize && qualifier != '-' && qualifier != '~' && qualifier != '?'
+
+		switch name {
+		case "ip4", "ip6":
+			if pass {
+				w.allow(target)
+			}
+		case "a", "mx":
+			w.lookups++
+			if pass {
+				w.resolve(name, target, domain)
+			}
+		case "ptr", "exists":
+			w.lookups++
+		case "include", "redirect":
+			w.lookups++
+			if target == "" || w.seen[target] || depth >= maxSPFLookups {
+				continue
+			}
+			w.seen[target] = true
+			txt, err := lookupTXT(w.ctx, w.r, target)
+			if err != nil {
+				continue
+			}
+			if records := withPrefix(txt, "v=spf1"); len(records) == 1 {
+				w.record(records[0], target, depth+1, pass)
+			}
+		}
+	}
+}
+
+// allow adds an ip4: or ip6: network, or a single address
+func (w *spfWalk) allow(network string) {
+	if !strings.Contains(network, "/") {
+		if ip := net.ParseIP(network); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			w.networks = append(w.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+		return
+	}
+	if _, ipNet, err := net.ParseCIDR(network); err == nil {
+		w.networks = append(w.networks, ipNet)
+	}
+}
+
+// resolve adds the addresses of an a or mx mechanism. Prefix lengths aren't
+// applied, so a range is only partly covered, which errs on the side of
+// warning.
+func (w *spfWalk) resolve(mechanism, target, domain string) {
+	target, _, _ = strings.Cut(target, "/")
+	if target == "" {
+		target = domain
+	}
+	hosts := []string{target}
+	if mechanism == "mx" {
+		mxs, err := w.r.LookupMX(w.ctx, target)
+		if err != nil {
+			return
+		}
+		hosts = hosts[:0]
+		for _, mx := range mxs {
+			hosts = append(hosts, strings.TrimSuffix(mx.Host, "."))
+		}
+	}
+	for _, host := range hosts {
+		addrs, err := w.r.LookupIPAddr(w.ctx, host)
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			w.allow(addr.IP.String())
+		}
+	}
+}
+
+// covers checks that every address a relay's hostname resolves to is
+// authorized, and otherwise says why it isn't
+func (w *spfWalk) covers(relay string) string {
+	addrs, err := w.r.LookupIPAddr(w.ctx, relay)
+	if err != nil {
+		return "couldn't resolve " + relay + " to check it's authorized: " + err.Error()
+	}
+	var missing []string
+	for _, addr := range addrs {
+		if !w.allows(addr.IP) {
+			missing = append(missing, addr.IP.String())
+		}
+	}
+	if len(addrs) == 0 {
+		return relay + " doesn't resolve to any address to check it's authorized"
+	}
+	if len(missing) > 0 {
+		return fmt.Sprintf("it doesn't authorize %s (%s), so mail relayed through it may fail SPF", relay, strings.Join(missing, ", "))
+	}
+	return ""
+}
+
+func (w *spfWalk) allows(ip net.IP) bool {
+	for _, network := range w.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDKIM inspects the DKIM records of the domain's keys, and of Google
+// Workspace's default selector when the domain sends through Gmail. It
+// reports whether there's a published key that signs the domain's mail.
+func checkDKIM(ctx context.Context, r Resolver, domain string, cfg Config) ([]models.DNSCheck, bool) {
+	var checks []models.DNSCheck
+	aligned := false
+
+	if domain == "gmail.com" || domain == "googlemail.com" {
+		return []models.DNSCheck{{
+			Record:  "dkim",
+			Status:  models.CheckOK,
+			Message: "Gmail signs mail from " + domain + " itself",
+		}}, true
+	}
+
+	for _, key := range cfg.Keys {
+		name := dkim.RecordName(domain, key.Selector)
+		expected := dkim.Record(key.Algorithm, key.PublicKey)
+		check := models.DNSCheck{Record: "dkim"}
+		txt, err := lookupTXT(ctx, r, name)
+		if err != nil {
+			check.Status = models.CheckUnknown
+			check.Message = "Couldn't look up the DKIM record at " + name + ": " + err.Error()
+			checks = append(checks, check)
+			continue
+		}
+		records := withPrefix(txt, "v=DKIM1")
+		if len(records) == 0 && len(txt) == 1 && strings.Contains(txt[0], "p=") {
+			// v= is optional in key records
+			records = txt
+		}
+		check.Values = records
+
+		switch {
+		case len(records) == 0:
+			check.Status = models.CheckError
+			check.Message = "The DKIM key " + key.Selector + " signs mail from " + domain + ", but its record isn't published, so receivers can't verify the signature"
+			check.Fix = fmt.Sprintf("Publish a TXT record at %s: %s", name, expected)
+		case len(records) > 1:
+			check.Status = models.CheckError
+			check.Message = "There are several DKIM records at " + name
+			check.Fix = fmt.Sprintf("Keep only one TXT record at %s: %s", name, expected)
+		case publicKey(records[0]) == "":
+			check.Status = models.CheckError
+			check.Message = "The DKIM record at " + name + " has an empty key, which revokes it"
+			check.Fix = fmt.Sprintf("Replace it with: %s", expected)
+		case publicKey(records[0]) != key.PublicKey:
+			check.Status = models.CheckError
+			check.Message = "The DKIM record at " + name + " holds a different key from the one mail is signed with, so signatures fail"
+			check.Fix = fmt.Sprintf("Replace it with: %s", expected)
+		default:
+			check.Status = models.CheckOK
+			check.Message = "The DKIM key " + key.Selector + " is published"
+			aligned = true
+			if bits := dkim.BitsOf(records[0]); bits > 0 && bits < 2048 {
+				check.Status = models.CheckWarning
+				check.Message += fmt.Sprintf(", but %d-bit RSA keys are considered weak", bits)
+				check.Fix = "Generate a 2048-bit key with a new selector, publish it, then delete this one"
+			}
+		}
+		checks = append(checks, check)
+	}
+
+	if cfg.ViaGmail && !hasSelector(cfg.Keys, googleSelector) {
+		name := dkim.RecordName(domain, googleSelector)
+		txt, err := lookupTXT(ctx, r, name)
+		switch {
+		case err != nil:
+			checks = append(checks, models.DNSCheck{
+				Record:  "dkim",
+				Status:  models.CheckUnknown,
+				Message: "Couldn't look up the Google Workspace DKIM record at " + name + ": " + err.Error(),
+			})
+		case len(txt) > 0 && publicKey(strings.Join(txt, "")) != "":
+			aligned = true
+			checks = append(checks, models.DNSCheck{
+				Record:  "dkim",
+				Status:  models.CheckOK,
+				Message: "Google Workspace signs the domain's Gmail mail with the " + googleSelector + " selector",
+				Values:  txt,
+			})
+		}
+	}
+
+	if len(checks) == 0 {
+		fix := "Generate a key with POST /api/v1/dkim-keys, publish its DNS record and enable the key"
+		if cfg.ViaGmail {
+			fix += ", or turn on DKIM signing in the Google Workspace admin console"
+		}
+		checks = append(checks, models.DNSCheck{
+			Record:  "dkim",
+			Status:  models.CheckWarning,
+			Message: "Mail from " + domain + " isn't DKIM signed, so it relies on SPF alone, which breaks when mail is forwarded",
+			Fix:     fix,
+		})
+	}
+	return checks, aligned
+}
+
+func hasSelector(keys []models.DKIMKey, selector string) bool {
+	for _, key := range keys {
+		if key.Selector == selector {
+			return true
+		}
+	}
+	return false
+}
+
+// publicKey returns the p= tag of a DKIM record
+func publicKey(record string) string {
+	for _, tag := range strings.Split(record, ";") {
+		name, value, ok := strings.Cut(tag, "=")
+		if ok && strings.TrimSpace(name) == "p" {
+			return strings.Join(strings.Fields(value), "")
+		}
+	}
+	return ""
+}
+
+// checkDMARC inspects the DMARC record of the domain, falling back to its
+// organizational domain's as receivers do
+func checkDMARC(ctx context.Context, r Resolver, domain string) models.DNSCheck {
+	check := models.DNSCheck{Record: "dmarc"}
+	name := "_dmarc." + domain
+	txt, err := lookupTXT(ctx, r, name)
+	records := withPrefix(txt, "v=DMARC1")
+	if err == nil && len(records) == 0 {
+		if org, orgErr := publicsuffix.EffectiveTLDPlusOne(domain); orgErr == nil && org != domain {
+			name = "_dmarc." + org
+			txt, err = lookupTXT(ctx, r, name)
+			records = withPrefix(txt, "v=DMARC1")
+		}
+	}
+	if err != nil {
+		check.Status = models.CheckUnknown
+		check.Message = "Couldn't look up the DMARC record: " + err.Error()
+		return check
+	}
+	check.Values = records
+
+	switch len(records) {
+	case 0:
+		check.Status = models.CheckError
+		check.Message = domain + " has no DMARC record. Gmail and Yahoo require one from bulk senders."
+		check.Fix = fmt.Sprintf("Publish a TXT record at _dmarc.%s: v=DMARC1; p=none; rua=mailto:dmarc@%s, and move p to quarantine once the reports show your mail passing", domain, domain)
+		return check
+	case 1:
+	default:
+		check.Status = models.CheckError
+		check.Message = "There are several DMARC records at " + name + "; receivers ignore them all"
+		check.Fix = "Keep a single v=DMARC1 record"
+		return check
+	}
+
+	policy := ""
+	for _, tag := range strings.Split(records[0], ";") {
+		if key, value, ok := strings.Cut(tag, "="); ok && strings.TrimSpace(key) == "p" {
+			policy = strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+	switch policy {
+	case "quarantine", "reject":
+		check.Status = models.CheckOK
+		check.Message = fmt.Sprintf("The DMARC record at %s has a %s policy", name, policy)
+	case "none":
+		check.Status = models.CheckWarning
+		check.Message = "The DMARC policy at " + name + " is none, which only monitors, so spoofed mail from the domain is still delivered"
+		check.Fix = "Once the aggregate reports show your mail passing, change p=none to p=quarantine, then p=reject"
+	default:
+		check.Status = models.CheckError
+		check.Message = "The DMARC record at " + name + " has no valid p= policy, so receivers ignore it"
+		check.Fix = "Add p=none, p=quarantine or p=reject to the record"
+	}
+	return check
+}
@@ -0,0 +1,490 @@
+package deliverability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+// fakeResolver answers from fixed records. Names without records don't
+// exist, and names in fail time out.
+type fakeResolver struct {
+	txt  map[string][]string
+	mx   map[string][]*net.MX
+	ip   map[string][]net.IPAddr
+	fail map[string]bool
+}
+
+func (r fakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if r.fail[name] {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: name, IsTimeout: true}
+	}
+	if records, ok := r.txt[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if records, ok := r.mx[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if r.fail[host] {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: host, IsTimeout: true}
+	}
+	if addrs, ok := r.ip[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// addresses are the A and AAAA records of the relays and mail servers
+var addresses = map[string][]net.IPAddr{
+	"smtp.relay.test":  {{IP: net.ParseIP("192.0.2.25")}},
+	"smtp.other.test":  {{IP: net.ParseIP("198.51.100.7")}},
+	"smtp6.relay.test": {{IP: net.ParseIP("192.0.2.26")}, {IP: net.ParseIP("2001:db8::25")}},
+	"mx.example.com":   {{IP: net.ParseIP("203.0.113.10")}},
+}
+
+const (
+	testKey  = "MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAtestkey"
+	otherKey = "MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAotherkey"
+)
+
+var testKeys = []models.DKIMKey{{Domain: "example.com", Selector: "drip", Algorithm: "rsa", PublicKey: testKey}}
+
+// records returns the TXT records of a well configured example.com, with
+// overrides. A nil value removes the name.
+func records(overrides map[string][]string) map[string][]string {
+	txt := map[string][]string{
+		"example.com":                  {"v=spf1 include:_spf.relay.test ~all"},
+		"_spf.relay.test":              {"v=spf1 ip4:192.0.2.0/24 -all"},
+		"drip._domainkey.example.com":  {"v=DKIM1; k=rsa; p=" + testKey},
+		"_dmarc.example.com":           {"v=DMARC1; p=quarantine; rua=mailto:dmarc@example.com"},
+		"unrelated.example.com":        {"google-site-verification=abc"},
+		"_dmarc.unrelated.example.com": {"not a dmarc record"},
+	}
+	for name, values := range overrides {
+		if values == nil {
+			delete(txt, name)
+			continue
+		}
+		txt[name] = values
+	}
+	return txt
+}
+
+func find(t *testing.T, result models.DomainCheck, record string) models.DNSCheck {
+	t.Helper()
+	for _, check := range result.Checks {
+		if check.Record == record {
+			return check
+		}
+	}
+	t.Fatalf("no %s check in %+v", record, result.Checks)
+	return models.DNSCheck{}
+}
+
+func TestCheckSPF(t *testing.T) {
+	// manyIncludes needs one lookup for each include of the domain's record
+	// and two for each included record's a and mx
+	manyIncludes := map[string][]string{}
+	var includes []string
+	for i := 0; i < 4; i++ {
+		name := fmt.Sprintf("_spf%d.relay.test", i)
+		includes = append(includes, "include:"+name)
+		manyIncludes[name] = []string{"v=spf1 a mx -all"}
+	}
+	manyIncludes["example.com"] = []string{"v=spf1 " + strings.Join(includes, " ") + " ~all"}
+
+	tests := []struct {
+		name      string
+		txt       map[string][]string
+		cfg       Config
+		status    string
+		message   string
+		alignment string
+	}{
+		{
+			name:      "valid",
+			status:    models.CheckOK,
+			alignment: models.CheckOK,
+		},
+		{
+			name:      "missing",
+			txt:       map[string][]string{"example.com": {"google-site-verification=abc"}},
+			status:    models.CheckError,
+			message:   "has no SPF record",
+			alignment: models.CheckOK,
+		},
+		{
+			name:      "multiple",
+			txt:       map[string][]string{"example.com": {"v=spf1 include:_spf.relay.test ~all", "v=spf1 mx -all"}},
+			status:    models.CheckError,
+			message:   "has 2 SPF records",
+			alignment: models.CheckOK,
+		},
+		{
+			name:      "plus all",
+			txt:       map[string][]string{"example.com": {"v=spf1 include:_spf.relay.test +all"}},
+			status:    models.CheckError,
+			message:   "+all",
+			alignment: models.CheckOK,
+		},
+		{
+			name:      "neutral all",
+			txt:       map[string][]string{"example.com": {"v=spf1 include:_spf.relay.test ?all"}},
+			status:    models.CheckWarning,
+			message:   "?all",
+			alignment: models.CheckOK,
+		},
+		{
+			name:      "too many lookups",
+			txt:       manyIncludes,
+			status:    models.CheckError,
+			message:   "needs 12 DNS lookups",
+			alignment: models.CheckOK,
+		},
+		{
+			name:      "missing Gmail",
+			cfg:       Config{ViaGmail: true},
+			status:    models.CheckError,
+			message:   "doesn't authorize Gmail",
+			alignment: models.CheckOK,
+		},
+		{
+			name:      "relay covered",
+			cfg:       Config{Relays: []string{"smtp.relay.test"}},
+			status:    models.CheckOK,
+			message:   "covers smtp.relay.test",
+			alignment: models.CheckOK,
+		},
+		{
+			name:      "relay not covered",
+			cfg:       Config{Relays: []string{"smtp.other.test"}},
+			status:    models.CheckWarning,
+			message:   "doesn't authorize smtp.other.test (198.51.100.7)",
+			alignment: models.CheckOK,
+		},
+		{
+			name:      "relay partly covered",
+			cfg:       Config{Relays: []string{"smtp6.relay.test"}},
+			status:    models.CheckWarning,
+			message:   "doesn't authorize smtp6.relay.test (2001:db8::25)",
+			alignment: models.CheckOK,
+		},
+		{
+			name:      "relay that doesn't resolve",
+			cfg:       Config{Relays: []string{"smtp.missing.test"}},
+			status:    models.CheckWarning,
+			message:   "couldn't resolve smtp.missing.test",
+			alignment: models.CheckOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Keys = testKeys
+			r := fakeResolver{txt: records(tt.txt), ip: addresses}
+			result := Check(context.Background(), r, "example.com", cfg)
+
+			spf := find(t, result, "spf")
+			if spf.Status != tt.status {
+				t.Errorf("status = %s, want %s: %s", spf.Status, tt.status, spf.Message)
+			}
+			if !strings.Contains(spf.Message, tt.message) {
+				t.Errorf("message = %q, want it to contain %q", spf.Message, tt.message)
+			}
+			if tt.status != models.CheckOK && spf.Fix == "" {
+				t.Error("no fix suggested")
+			}
+			if result.Alignment != tt.alignment {
+				t.Errorf("alignment = %s, want %s", result.Alignment, tt.alignment)
+			}
+		})
+	}
+}
+
+// TestSPFRelayCoverage checks which mechanisms authorize a relay's addresses
+func TestSPFRelayCoverage(t *testing.T) {
+	tests := []struct {
+		name    string
+		record  string
+		relay   string
+		aligned bool
+	}{
+		{"ip4 range", "v=spf1 ip4:192.0.2.0/24 -all", "smtp.relay.test", true},
+		{"ip4 address", "v=spf1 ip4:192.0.2.25 -all", "smtp.relay.test", true},
+		{"other ip4", "v=spf1 ip4:192.0.2.24 -all", "smtp.relay.test", false},
+		{"ip4 and ip6", "v=spf1 ip4:192.0.2.26 ip6:2001:db8::/32 -all", "smtp6.relay.test", true},
+		{"ip4 without ip6", "v=spf1 ip4:192.0.2.26 -all", "smtp6.relay.test", false},
+		{"include", "v=spf1 include:_spf.relay.test ~all", "smtp.relay.test", true},
+		{"redirect", "v=spf1 redirect=_spf.relay.test", "smtp.relay.test", true},
+		{"a", "v=spf1 a:smtp.relay.test -all", "smtp.relay.test", true},
+		{"mx", "v=spf1 mx -all", "mx.example.com", true},
+		{"fail include", "v=spf1 -include:_spf.relay.test ip4:198.51.100.0/24 ~all", "smtp.relay.test", false},
+		{"softfail ip4", "v=spf1 ~ip4:192.0.2.0/24 -all", "smtp.relay.test", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := fakeResolver{
+				txt: records(map[string][]string{"example.com": {tt.record}}),
+				mx:  map[string][]*net.MX{"example.com": {{Host: "mx.example.com.", Pref: 10}}},
+				ip:  addresses,
+			}
+			check, aligned := checkSPF(context.Background(), r, "example.com", Config{Relays: []string{tt.relay}})
+			if aligned != tt.aligned {
+				t.Errorf("aligned = %v, want %v: %s", aligned, tt.aligned, check.Message)
+			}
+			if !aligned && check.Status != models.CheckWarning {
+				t.Errorf("status = %s, want %s", check.Status, models.CheckWarning)
+			}
+		})
+	}
+}
+
+func TestCheckDKIM(t *testing.T) {
+	tests := []struct {
+		name    string
+		txt     map[string][]string
+		keys    []models.DKIMKey
+		status  string
+		message string
+		aligned bool
+	}{
+		{
+			name:    "published",
+			keys:    testKeys,
+			status:  models.CheckOK,
+			aligned: true,
+		},
+		{
+			name:    "without version tag",
+			txt:     map[string][]string{"drip._domainkey.example.com": {"k=rsa; p=" + testKey}},
+			keys:    testKeys,
+			status:  models.CheckOK,
+			aligned: true,
+		},
+		{
+			name:    "missing",
+			txt:     map[string][]string{"drip._domainkey.example.com": nil},
+			keys:    testKeys,
+			status:  models.CheckError,
+			message: "isn't published",
+		},
+		{
+			name:    "mismatched key",
+			txt:     map[string][]string{"drip._domainkey.example.com": {"v=DKIM1; k=rsa; p=" + otherKey}},
+			keys:    testKeys,
+			status:  models.CheckError,
+			message: "different key",
+		},
+		{
+			name:    "revoked",
+			txt:     map[string][]string{"drip._domainkey.example.com": {"v=DKIM1; k=rsa; p="}},
+			keys:    testKeys,
+			status:  models.CheckError,
+			message: "empty key",
+		},
+		{
+			name:    "no keys",
+			status:  models.CheckWarning,
+			message: "isn't DKIM signed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := fakeResolver{txt: records(tt.txt)}
+			checks, aligned := checkDKIM(context.Background(), r, "example.com", Config{Keys: tt.keys})
+			if len(checks) != 1 {
+				t.Fatalf("got %d checks, want 1: %+v", len(checks), checks)
+			}
+			if checks[0].Status != tt.status {
+				t.Errorf("status = %s, want %s: %s", checks[0].Status, tt.status, checks[0].Message)
+			}
+			if !strings.Contains(checks[0].Message, tt.message) {
+				t.Errorf("message = %q, want it to contain %q", checks[0].Message, tt.message)
+			}
+			if aligned != tt.aligned {
+				t.Errorf("aligned = %v, want %v", aligned, tt.aligned)
+			}
+		})
+	}
+}
+
+func TestCheckDMARC(t *testing.T) {
+	tests := []struct {
+		name    string
+		domain  string
+		txt     map[string][]string
+		status  string
+		message string
+	}{
+		{
+			name:    "quarantine",
+			domain:  "example.com",
+			status:  models.CheckOK,
+			message: "_dmarc.example.com has a quarantine policy",
+		},
+		{
+			name:    "organizational domain",
+			domain:  "mail.example.com",
+			txt:     map[string][]string{"_dmarc.example.com": {"v=DMARC1; p=reject"}},
+			status:  models.CheckOK,
+			message: "_dmarc.example.com has a reject policy",
+		},
+		{
+			name:    "subdomain's own record",
+			domain:  "mail.example.com",
+			txt:     map[string][]string{"_dmarc.mail.example.com": {"v=DMARC1; p=none"}},
+			status:  models.CheckWarning,
+			message: "_dmarc.mail.example.com is none",
+		},
+		{
+			name:    "organizational domain when the subdomain's isn't DMARC",
+			domain:  "unrelated.example.com",
+			status:  models.CheckOK,
+			message: "_dmarc.example.com has a quarantine policy",
+		},
+		{
+			name:    "missing",
+			domain:  "mail.example.com",
+			txt:     map[string][]string{"_dmarc.example.com": nil},
+			status:  models.CheckError,
+			message: "has no DMARC record",
+		},
+		{
+			name:    "multiple",
+			domain:  "example.com",
+			txt:     map[string][]string{"_dmarc.example.com": {"v=DMARC1; p=none", "v=DMARC1; p=reject"}},
+			status:  models.CheckError,
+			message: "several DMARC records",
+		},
+		{
+			name:    "no policy",
+			domain:  "example.com",
+			txt:     map[string][]string{"_dmarc.example.com": {"v=DMARC1; rua=mailto:dmarc@example.com"}},
+			status:  models.CheckError,
+			message: "no valid p= policy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := fakeResolver{txt: records(tt.txt)}
+			check := checkDMARC(context.Background(), r, tt.domain)
+			if check.Status != tt.status {
+				t.Errorf("status = %s, want %s: %s", check.Status, tt.status, check.Message)
+			}
+			if !strings.Contains(check.Message, tt.message) {
+				t.Errorf("message = %q, want it to contain %q", check.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestCheckDMARCLookupError(t *testing.T) {
+	r := fakeResolver{txt: records(nil), fail: map[string]bool{"_dmarc.example.com": true}}
+	check := checkDMARC(context.Background(), r, "example.com")
+	if check.Status != models.CheckUnknown {
+		t.Errorf("status = %s, want %s: %s", check.Status, models.CheckUnknown, check.Message)
+	}
+}
+
+// TestCheckAlignment covers what blocks activating a campaign: mail that
+// passes neither aligned DKIM nor aligned SPF
+func TestCheckAlignment(t *testing.T) {
+	tests := []struct {
+		name      string
+		txt       map[string][]string
+		keys      []models.DKIMKey
+		relays    []string
+		fail      map[string]bool
+		alignment string
+	}{
+		{
+			name:      "DKIM and SPF",
+			keys:      testKeys,
+			alignment: models.CheckOK,
+		},
+		{
+			name:      "DKIM only",
+			txt:       map[string][]string{"example.com": {"v=spf1 include:_spf.relay.test +all"}},
+			keys:      testKeys,
+			alignment: models.CheckOK,
+		},
+		{
+			name:      "SPF only",
+			relays:    []string{"smtp.relay.test"},
+			alignment: models.CheckOK,
+		},
+		{
+			name:      "SPF that doesn't cover the relay",
+			relays:    []string{"smtp.relay.test", "smtp.other.test"},
+			alignment: models.CheckError,
+		},
+		{
+			name:      "SPF without a known relay",
+			alignment: models.CheckError,
+		},
+		{
+			name: "neither",
+			txt: map[string][]string{
+				"example.com":                 {"v=spf1 include:_spf.relay.test +all"},
+				"drip._domainkey.example.com": {"v=DKIM1; k=rsa; p=" + otherKey},
+			},
+			keys:      testKeys,
+			alignment: models.CheckError,
+		},
+		{
+			name: "no SPF record and unpublished key",
+			txt: map[string][]string{
+				"example.com":                 nil,
+				"drip._domainkey.example.com": nil,
+			},
+			keys:      testKeys,
+			alignment: models.CheckError,
+		},
+		{
+			name:      "lookup failed",
+			txt:       map[string][]string{"drip._domainkey.example.com": {"v=DKIM1; k=rsa; p=" + otherKey}},
+			keys:      testKeys,
+			fail:      map[string]bool{"example.com": true},
+			alignment: models.CheckUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := fakeResolver{
+				txt:  records(tt.txt),
+				mx:   map[string][]*net.MX{"example.com": {{Host: "mx.example.com.", Pref: 10}}},
+				ip:   addresses,
+				fail: tt.fail,
+			}
+			result := Check(context.Background(), r, "example.com", Config{Keys: tt.keys, Relays: tt.relays})
+			if result.Alignment != tt.alignment {
+				t.Errorf("alignment = %s, want %s: %s", result.Alignment, tt.alignment, find(t, result, "alignment").Message)
+			}
+			if tt.alignment == models.CheckError && result.Status != models.CheckError {
+				t.Errorf("status = %s, want %s", result.Status, models.CheckError)
+			}
+		})
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	if notFound(errors.New("boom")) {
+		t.Error("a plain error was taken for a missing name")
+	}
+	if !notFound(fmt.Errorf("lookup: %w", &net.DNSError{IsNotFound: true})) {
+		t.Error("a wrapped NXDOMAIN wasn't taken for a missing name")
+	}
+}
@@ -0,0 +1,427 @@
+// Package dkim signs and verifies messages with DKIM (RFC 6376), using
+// relaxed/relaxed canonicalization and rsa-sha256 or ed25519-sha256 (RFC 8463).
+package dkim
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Algorithms
+const (
+	RSA     = "rsa"
+	Ed25519 = "ed25519"
+)
+
+// signedHeaders are the headers signed when the message has them
+var signedHeaders = []string{"from", "to", "subject", "date", "message-id", "reply-to", "mime-version", "content-type",
+	"list-unsubscribe", "list-unsubscribe-post"}
+
+// Signer signs messages for one domain and selector
+type Signer struct {
+	Domain   string
+	Selector string
+	key      crypto.Signer
+}
+
+// GenerateKey creates a keypair for algorithm, returning the private key as
+// PKCS #8 PEM and the public key as it goes in the DNS record. bits only
+// applies to RSA.
+func GenerateKey(algorithm string, bits int) (privatePEM, publicKey string, err error) {
+	var key crypto.Signer
+	switch algorithm {
+	case RSA:
+		if key, err = rsa.GenerateKey(rand.Reader, bits); err != nil {
+			return "", "", err
+		}
+	case Ed25519:
+		if _, key, err = ed25519.GenerateKey(rand.Reader); err != nil {
+			return "", "", err
+		}
+	default:
+		return "", "", fmt.Errorf("unknown algorithm %q", algorithm)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	publicKey, err = encodePublicKey(key.Public())
+	return privatePEM, publicKey, err
+}
+
+// encodePublicKey encodes a public key for the p= tag: a SubjectPublicKeyInfo
+// for RSA, the raw key for Ed25519
+func encodePublicKey(key crypto.PublicKey) (string, error) {
+	if edKey, ok := key.(ed25519.PublicKey); ok {
+		return base64.StdEncoding.EncodeToString(edKey), nil
+	}
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(der), nil
+}
+
+// Record returns the TXT record to publish at Selector._domainkey.Domain
+func Record(algorithm, publicKey string) string {
+	return fmt.Sprintf("v=DKIM1; k=%s; p=%s", algorithm, publicKey)
+}
+
+// RecordName returns the DNS name of a selector's key record
+func RecordName(domain, selector string) string {
+	return selector + "._domainkey." + domain
+}
+
+// NewSigner parses a PKCS #8 PEM private key
+func NewSigner(domain, selector, privatePEM string) (*Signer, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, errors.New("no PEM private key found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	switch key.(type) {
+	case *rsa.PrivateKey, ed25519.PrivateKey:
+	default:
+		return nil, errors.New("DKIM keys must be RSA or Ed25519")
+	}
+	return &Signer{Domain: domain, Selector: selector, key: key}, nil
+}
+
+// algorithm returns the a= tag value of the signer's key
+func (s *Signer) algorithm() string {
+	if _, ok := s.key.(ed25519.PrivateKey); ok {
+		return "ed25519-sha256"
+	}
+	return "rsa-sha256"
+}
+
+// Sign returns the message with a DKIM-Signature header prepended. The
+// message must use CRLF line endings, as it's sent over SMTP.
+func (s *Signer) Sign(message []byte, now time.Time) ([]byte, error) {
+	headers, body := split(message)
+
+	var names []string
+	for _, name := range signedHeaders {
+		if len(find(headers, name)) > 0 {
+			names = append(names, name)
+		}
+	}
+
+	bodyHash := sha256.Sum256(canonicalBody(body))
+	value := fmt.Sprintf("v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		s.algorithm(), s.Domain, s.Selector, now.Unix(), strings.Join(names, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]))
+
+	hash := headerHash(headers, names, "DKIM-Signature: "+value)
+	var signature []byte
+	var err error
+	if _, ok := s.key.(ed25519.PrivateKey); ok {
+		signature, err = s.key.Sign(rand.Reader, hash, crypto.Hash(0))
+	} else {
+		signature, err = s.key.Sign(rand.Reader, hash, crypto.SHA256)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := "DKIM-Signature: " + value + base64.StdEncoding.EncodeToString(signature) + "\r\n"
+	return append([]byte(header), message...), nil
+}
+
+// LookupFunc returns the TXT record of a selector's key, such as
+// "v=DKIM1; k=rsa; p=...", so verification can use DNS or a known key
+type LookupFunc func(domain, selector string) (string, error)
+
+// Verify checks the topmost DKIM-Signature of a message, the one added last,
+// looking its key up with lookup
+func Verify(message []byte, lookup LookupFunc) error {
+	headers, body := split(message)
+	signatures := find(headers, "dkim-signature")
+	if len(signatures) == 0 {
+		return errors.New("message has no DKIM-Signature")
+	}
+	signatureHeader := signatures[len(signatures)-1]
+	tags := parseTags(headerValue(signatureHeader))
+
+	if tags["v"] != "1" {
+		return errors.New("unsupported DKIM version")
+	}
+	if c := tags["c"]; c != "relaxed/relaxed" {
+		return fmt.Errorf("unsupported canonicalization %q", c)
+	}
+	record, err := lookup(tags["d"], tags["s"])
+	if err != nil {
+		return fmt.Errorf("looking up key: %w", err)
+	}
+	key, err := ParseRecord(record)
+	if err != nil {
+		return err
+	}
+
+	bodyHash := sha256.Sum256(canonicalBody(body))
+	if base64.StdEncoding.EncodeToString(bodyHash[:]) != stripSpace(tags["bh"]) {
+		return errors.New("body hash doesn't match")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(stripSpace(tags["b"]))
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	var names []string
+	for _, name := range strings.Split(tags["h"], ":") {
+		names = append(names, strings.ToLower(strings.TrimSpace(name)))
+	}
+	unsigned := emptySignature.ReplaceAllString(strings.TrimRight(signatureHeader, "\r\n"), "${1}")
+	hash := headerHash(withoutFirst(headers, signatureHeader), names, unsigned)
+
+	switch tags["a"] {
+	case "rsa-sha256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key doesn't match the signature algorithm")
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, hash, signature); err != nil {
+			return errors.New("signature doesn't match")
+		}
+	case "ed25519-sha256":
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return errors.New("key doesn't match the signature algorithm")
+		}
+		if !ed25519.Verify(edKey, hash, signature) {
+			return errors.New("signature doesn't match")
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm %q", tags["a"])
+	}
+	return nil
+}
+
+// ParseRecord reads the public key of a DKIM TXT record
+func ParseRecord(record string) (crypto.PublicKey, error) {
+	tags := parseTags(record)
+	if v, ok := tags["v"]; ok && v != "DKIM1" {
+		return nil, fmt.Errorf("unsupported record version %q", v)
+	}
+	data, err := base64.StdEncoding.DecodeString(stripSpace(tags["p"]))
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("record has no valid p= public key")
+	}
+
+	switch k := tags["k"]; k {
+	case Ed25519:
+		if len(data) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+		return ed25519.PublicKey(data), nil
+	case "", RSA:
+		key, err := x509.ParsePKIXPublicKey(data)
+		if err != nil {
+			// Some records hold a bare PKCS #1 RSAPublicKey
+			if rsaKey, rsaErr := x509.ParsePKCS1PublicKey(data); rsaErr == nil {
+				return rsaKey, nil
+			}
+			return nil, fmt.Errorf("invalid RSA public key: %w", err)
+		}
+		if _, ok := key.(*rsa.PublicKey); !ok {
+			return nil, errors.New("k=rsa record doesn't hold an RSA key")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k)
+	}
+}
+
+// emptySignature matches the b= tag of a DKIM-Signature, keeping the tag name
+var emptySignature = regexp.MustCompile(`((?:^|;)\s*b\s*=)[^;]*`)
+
+// split separates a message into its header fields, each with its
+// continuation lines and trailing CRLF, and its body
+func split(message []byte) ([]string, []byte) {
+	var headers []string
+	rest := message
+	for len(rest) > 0 {
+		end := bytes.Index(rest, []byte("\r\n"))
+		if end < 0 {
+			end = len(rest)
+		}
+		line := string(rest[:end])
+		next := rest[min(end+2, len(rest)):]
+		if line == "" {
+			return headers, next
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(headers) > 0 {
+			headers[len(headers)-1] += line + "\r\n"
+		} else {
+			headers = append(headers, line+"\r\n")
+		}
+		rest = next
+	}
+	return headers, nil
+}
+
+// find returns the header fields with the given name, last first, which is
+// the order DKIM picks repeated headers in
+func find(headers []string, name string) []string {
+	var found []string
+	for i := len(headers) - 1; i >= 0; i-- {
+		if strings.EqualFold(headerName(headers[i]), name) {
+			found = append(found, headers[i])
+		}
+	}
+	return found
+}
+
+func withoutFirst(headers []string, header string) []string {
+	out := make([]string, 0, len(headers))
+	removed := false
+	for _, h := range headers {
+		if !removed && h == header {
+			removed = true
+			continue
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+func headerName(header string) string {
+	if i := strings.Index(header, ":"); i >= 0 {
+		return strings.TrimSpace(header[:i])
+	}
+	return ""
+}
+
+func headerValue(header string) string {
+	if i := strings.Index(header, ":"); i >= 0 {
+		return header[i+1:]
+	}
+	return ""
+}
+
+// headerHash hashes the signed headers, then the DKIM-Signature header with
+// an empty b= and no trailing CRLF
+func headerHash(headers []string, names []string, signature string) []byte {
+	h := sha256.New()
+	used := map[string]int{}
+	for _, name := range names {
+		instances := find(headers, name)
+		if used[name] < len(instances) {
+			h.Write([]byte(canonicalHeader(instances[used[name]]) + "\r\n"))
+		}
+		used[name]++
+	}
+	h.Write([]byte(canonicalHeader(signature)))
+	return h.Sum(nil)
+}
+
+var whitespace = regexp.MustCompile(`[ \t]+`)
+
+// canonicalHeader applies relaxed header canonicalization, without the CRLF
+func canonicalHeader(header string) string {
+	name := strings.ToLower(headerName(header))
+	value := headerValue(header)
+	value = strings.ReplaceAll(value, "\r\n", "")
+	value = whitespace.ReplaceAllString(value, " ")
+	return name + ":" + strings.TrimSpace(value)
+}
+
+// canonicalBody applies relaxed body canonicalization
+func canonicalBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(whitespace.ReplaceAllString(line, " "), " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// parseTags reads a tag=value list
+func parseTags(list string) map[string]string {
+	tags := map[string]string{}
+	for _, part := range strings.Split(list, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		tags[strings.TrimSpace(name)] = strings.TrimSpace(strings.ReplaceAll(value, "\r\n", ""))
+	}
+	return tags
+}
+
+func stripSpace(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ValidSelector reports whether selector can be used in a DNS name
+func ValidSelector(selector string) bool {
+	if selector == "" || len(selector) > 63 {
+		return false
+	}
+	for _, label := range strings.Split(selector, ".") {
+		if label == "" {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ValidBits reports whether an RSA key size is accepted
+func ValidBits(bits int) bool {
+	return bits == 1024 || bits == 2048 || bits == 3072 || bits == 4096
+}
+
+// BitsOf returns the size of an RSA public key from its DNS record, or zero
+func BitsOf(record string) int {
+	key, err := ParseRecord(record)
+	if err != nil {
+		return 0
+	}
+	if rsaKey, ok := key.(*rsa.PublicKey); ok {
+		return rsaKey.N.BitLen()
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+package dkim
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const message = "From: Drip <hello@example.com>\r\n" +
+	"To: someone@customer.test\r\n" +
+	"Subject: Welcome aboard\r\n" +
+	"Date: Mon, 19 Oct 2026 12:00:00 +0000\r\n" +
+	"Message-ID: <drip-1@example.com>\r\n" +
+	"\r\n" +
+	"Hi there,\r\n" +
+	"\r\n" +
+	"Thanks for signing up.\r\n"
+
+// keyPair generates a key and returns its signer and DNS record
+func keyPair(t *testing.T, algorithm string) (*Signer, string) {
+	t.Helper()
+	privateKey, publicKey, err := GenerateKey(algorithm, 1024)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	signer, err := NewSigner("example.com", "s1", privateKey)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	return signer, Record(algorithm, publicKey)
+}
+
+// lookup answers with record for s1._domainkey.example.com only
+func lookup(record string) LookupFunc {
+	return func(domain, selector string) (string, error) {
+		if RecordName(domain, selector) != "s1._domainkey.example.com" {
+			return "", fmt.Errorf("no record at %s", RecordName(domain, selector))
+		}
+		return record, nil
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	for _, algorithm := range []string{RSA, Ed25519} {
+		t.Run(algorithm, func(t *testing.T) {
+			signer, record := keyPair(t, algorithm)
+			signed, err := signer.Sign([]byte(message), now)
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+
+			header := string(signed[:strings.Index(string(signed), "\r\n")])
+			if !strings.Contains(header, "a="+algorithm+"-sha256;") || !strings.Contains(header, "c=relaxed/relaxed;") {
+				t.Errorf("unexpected signature header %q", header)
+			}
+			if err := Verify(signed, lookup(record)); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+
+			_, otherRecord := keyPair(t, algorithm)
+			if err := Verify(signed, lookup(otherRecord)); err == nil {
+				t.Error("signature verified against another key")
+			}
+		})
+	}
+}
+
+// TestRelaxedCanonicalization checks that changes relaxed canonicalization
+// ignores, as relays make them, keep the signature valid and others don't
+func TestRelaxedCanonicalization(t *testing.T) {
+	signer, record := keyPair(t, Ed25519)
+	signed, err := signer.Sign([]byte(message), time.Now())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		old, new string
+		valid    bool
+	}{
+		{"header name case", "Subject: Welcome", "SUBJECT: Welcome", true},
+		{"header whitespace", "Subject: Welcome aboard", "Subject:   Welcome \t aboard  ", true},
+		{"folded header", "Subject: Welcome aboard", "Subject: Welcome\r\n aboard", true},
+		{"body whitespace", "Thanks for signing up.", "Thanks  for\tsigning up. \t", true},
+		{"trailing empty lines", "signing up.\r\n", "signing up.\r\n\r\n\r\n", true},
+		{"changed header", "Welcome aboard", "Welcome back", false},
+		{"changed body", "Thanks for", "Thanks again for", false},
+		{"whitespace inside a word", "Thanks", "Tha nks", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := strings.Replace(string(signed), tt.old, tt.new, 1)
+			if changed == string(signed) {
+				t.Fatalf("%q isn't in the message", tt.old)
+			}
+			err := Verify([]byte(changed), lookup(record))
+			if tt.valid && err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("signature still verified")
+			}
+		})
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	// The example from RFC 6376 section 3.4.5
+	if got := canonicalHeader("A: X\r\n"); got != "a:X" {
+		t.Errorf("canonicalHeader = %q, want %q", got, "a:X")
+	}
+	if got := canonicalHeader("B : Y\t\r\n\tZ  \r\n"); got != "b:Y Z" {
+		t.Errorf("canonicalHeader = %q, want %q", got, "b:Y Z")
+	}
+	if got := string(canonicalBody([]byte(" C \r\nD \t E\r\n\r\n\r\n"))); got != " C\r\nD E\r\n" {
+		t.Errorf("canonicalBody = %q, want %q", got, " C\r\nD E\r\n")
+	}
+	if got := canonicalBody([]byte("\r\n\r\n")); got != nil {
+		t.Errorf("canonicalBody of an empty body = %q, want nothing", got)
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	_, record := keyPair(t, RSA)
+	tests := []struct {
+		name   string
+		record string
+		valid  bool
+	}{
+		{"rsa", record, true},
+		{"without version", strings.TrimPrefix(record, "v=DKIM1; "), true},
+		{"revoked", "v=DKIM1; k=rsa; p=", false},
+		{"wrong version", strings.Replace(record, "DKIM1", "DKIM2", 1), false},
+		{"unknown key type", strings.Replace(record, "k=rsa", "k=dsa", 1), false},
+		{"ed25519 key of the wrong size", "v=DKIM1; k=ed25519; p=AAAA", false},
+	}
+	for _, tt := range tests {
+		if _, err := ParseRecord(tt.record); (err == nil) != tt.valid {
+			t.Errorf("%s: ParseRecord error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
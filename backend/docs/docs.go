@@ -1,5 +1,4 @@
-// Code generated by swaggo/swag. DO NOT EDIT.
-
+// Package docs Code generated by swaggo/swag. DO NOT EDIT
 package docs
 
 import "github.com/swaggo/swag"
@@ -14,24 +13,25 @@ const docTemplate = `{
         "version": "{{.Version}}"
     },
     "host": "{{.Host}}",
+    "basePath": "{{.BasePath}}",
     "paths": {
-        "/campaign-customers": {
+        "/api-keys": {
             "get": {
-                "description": "Retrieve all campaign customers",
+                "description": "Retrieve the logged-in user's API keys, or all API keys for admins",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CampaignCustomers"
+                    "APIKeys"
                 ],
-                "summary": "Get all campaign customers",
+                "summary": "Get API keys",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.CampaignCustomer"
+                                "$ref": "#/definitions/models.APIKey"
                             }
                         }
                     },
@@ -44,7 +44,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create a new campaign customer",
+                "description": "Create a scoped API key for machine-to-machine access. The key is only returned once.",
                 "consumes": [
                     "application/json"
                 ],
@@ -52,17 +52,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "CampaignCustomers"
+                    "APIKeys"
                 ],
-                "summary": "Create a campaign customer",
+                "summary": "Create an API key",
                 "parameters": [
                     {
-                        "description": "Campaign customer data",
-                        "name": "campaignCustomer",
+                        "description": "API key data",
+                        "name": "apiKey",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CampaignCustomer"
+                            "$ref": "#/definitions/models.CreateAPIKeyRequest"
                         }
                     }
                 ],
@@ -70,7 +70,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.CampaignCustomer"
+                            "$ref": "#/definitions/models.CreateAPIKeyResponse"
                         }
                     },
                     "400": {
@@ -88,110 +88,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/campaign-customers/{id}": {
-            "get": {
-                "description": "Retrieve a specific campaign customer by ID",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "CampaignCustomers"
-                ],
-                "summary": "Get a campaign customer",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Campaign customer ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.CampaignCustomer"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
-                    }
-                }
-            },
-            "put": {
-                "description": "Update a specific campaign customer by ID",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "CampaignCustomers"
-                ],
-                "summary": "Update a campaign customer",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Campaign customer ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Updated campaign customer data",
-                        "name": "campaignCustomer",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.CampaignCustomer"
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.CampaignCustomer"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
-                    }
-                }
-            },
+        "/api-keys/{id}": {
             "delete": {
-                "description": "Delete a specific campaign customer by ID",
+                "description": "Revoke an API key so it can no longer be used",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CampaignCustomers"
+                    "APIKeys"
                 ],
-                "summary": "Delete a campaign customer",
+                "summary": "Revoke an API key",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Campaign customer ID",
+                        "description": "API key ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -219,23 +129,23 @@ const docTemplate = `{
                 }
             }
         },
-        "/campaigns": {
+        "/assets": {
             "get": {
-                "description": "Retrieve all drip campaigns",
+                "description": "Retrieve every uploaded asset, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Campaigns"
+                    "Assets"
                 ],
-                "summary": "Get all campaigns",
+                "summary": "Get all assets",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.DripCampaign"
+                                "$ref": "#/definitions/models.Asset"
                             }
                         }
                     },
@@ -248,33 +158,31 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create a new drip campaign",
+                "description": "Upload a file, in the file field of a multipart form, for templates and steps to attach or show inline. Its type is detected from its contents; images, PDFs, plain text, CSV, calendar invites, zip archives and office documents are allowed.",
                 "consumes": [
-                    "application/json"
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Campaigns"
+                    "Assets"
                 ],
-                "summary": "Create a campaign",
+                "summary": "Upload an asset",
                 "parameters": [
                     {
-                        "description": "Campaign data",
-                        "name": "campaign",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.DripCampaign"
-                        }
+                        "type": "file",
+                        "description": "The file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.DripCampaign"
+                            "$ref": "#/definitions/models.Asset"
                         }
                     },
                     "400": {
@@ -283,6 +191,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -292,20 +212,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/campaigns/{id}": {
+        "/assets/{id}": {
             "get": {
-                "description": "Retrieve a specific drip campaign by ID",
+                "description": "Retrieve a specific asset by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Campaigns"
+                    "Assets"
                 ],
-                "summary": "Get a campaign",
+                "summary": "Get an asset",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Campaign ID",
+                        "description": "Asset ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -315,7 +235,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.DripCampaign"
+                            "$ref": "#/definitions/models.Asset"
                         }
                     },
                     "404": {
@@ -326,51 +246,39 @@ const docTemplate = `{
                     }
                 }
             },
-            "put": {
-                "description": "Update a specific drip campaign by ID",
-                "consumes": [
-                    "application/json"
-                ],
+            "delete": {
+                "description": "Delete an asset that nothing attaches. Queued emails that attach it fail.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Campaigns"
+                    "Assets"
                 ],
-                "summary": "Update a campaign",
+                "summary": "Delete an asset",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Campaign ID",
+                        "description": "Asset ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Updated campaign data",
-                        "name": "campaign",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.DripCampaign"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.DripCampaign"
+                            "$ref": "#/definitions/models.SuccessResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -382,20 +290,22 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete a specific drip campaign by ID",
+            }
+        },
+        "/assets/{id}/content": {
+            "get": {
+                "description": "Download the file an asset was uploaded with",
                 "produces": [
-                    "application/json"
+                    "application/octet-stream"
                 ],
                 "tags": [
-                    "Campaigns"
+                    "Assets"
                 ],
-                "summary": "Delete a campaign",
+                "summary": "Download an asset",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Campaign ID",
+                        "description": "Asset ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -405,7 +315,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SuccessResponse"
+                            "type": "file"
                         }
                     },
                     "404": {
@@ -423,23 +333,23 @@ const docTemplate = `{
                 }
             }
         },
-        "/customers": {
+        "/campaign-customers": {
             "get": {
-                "description": "Retrieve all customers",
+                "description": "Retrieve all campaign customers",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Customers"
+                    "CampaignCustomers"
                 ],
-                "summary": "Get all customers",
+                "summary": "Get all campaign customers",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Customer"
+                                "$ref": "#/definitions/models.CampaignCustomer"
                             }
                         }
                     },
@@ -452,7 +362,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create a new customer",
+                "description": "Create a new campaign customer",
                 "consumes": [
                     "application/json"
                 ],
@@ -460,17 +370,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Customers"
+                    "CampaignCustomers"
                 ],
-                "summary": "Create a customer",
+                "summary": "Create a campaign customer",
                 "parameters": [
                     {
-                        "description": "Customer data",
-                        "name": "customer",
+                        "description": "Campaign customer data",
+                        "name": "campaignCustomer",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Customer"
+                            "$ref": "#/definitions/models.CampaignCustomer"
                         }
                     }
                 ],
@@ -478,7 +388,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Customer"
+                            "$ref": "#/definitions/models.CampaignCustomer"
                         }
                     },
                     "400": {
@@ -496,20 +406,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/customers/{id}": {
+        "/campaign-customers/{id}": {
             "get": {
-                "description": "Retrieve a specific customer by ID",
+                "description": "Retrieve a specific campaign customer by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Customers"
+                    "CampaignCustomers"
                 ],
-                "summary": "Get a customer",
+                "summary": "Get a campaign customer",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Customer ID",
+                        "description": "Campaign customer ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -519,7 +429,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Customer"
+                            "$ref": "#/definitions/models.CampaignCustomer"
                         }
                     },
                     "404": {
@@ -531,7 +441,7 @@ const docTemplate = `{
                 }
             },
             "put": {
-                "description": "Update a specific customer by ID",
+                "description": "Update a specific campaign customer by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -539,24 +449,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Customers"
+                    "CampaignCustomers"
                 ],
-                "summary": "Update a customer",
+                "summary": "Update a campaign customer",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Customer ID",
+                        "description": "Campaign customer ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated customer data",
-                        "name": "customer",
+                        "description": "Updated campaign customer data",
+                        "name": "campaignCustomer",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Customer"
+                            "$ref": "#/definitions/models.CampaignCustomer"
                         }
                     }
                 ],
@@ -564,7 +474,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Customer"
+                            "$ref": "#/definitions/models.CampaignCustomer"
                         }
                     },
                     "400": {
@@ -588,18 +498,18 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete a specific customer by ID",
+                "description": "Delete a specific campaign customer by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Customers"
+                    "CampaignCustomers"
                 ],
-                "summary": "Delete a customer",
+                "summary": "Delete a campaign customer",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Customer ID",
+                        "description": "Campaign customer ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -627,23 +537,23 @@ const docTemplate = `{
                 }
             }
         },
-        "/email-templates": {
+        "/campaigns": {
             "get": {
-                "description": "Retrieve all email templates",
+                "description": "Retrieve all drip campaigns",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "EmailTemplates"
+                    "Campaigns"
                 ],
-                "summary": "Get all email templates",
+                "summary": "Get all campaigns",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.EmailTemplate"
+                                "$ref": "#/definitions/models.DripCampaign"
                             }
                         }
                     },
@@ -656,7 +566,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create a new email template",
+                "description": "Create a new drip campaign",
                 "consumes": [
                     "application/json"
                 ],
@@ -664,17 +574,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "EmailTemplates"
+                    "Campaigns"
                 ],
-                "summary": "Create an email template",
+                "summary": "Create a campaign",
                 "parameters": [
                     {
-                        "description": "Email template data",
-                        "name": "emailTemplate",
+                        "description": "Campaign data",
+                        "name": "campaign",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.EmailTemplate"
+                            "$ref": "#/definitions/models.DripCampaign"
                         }
                     }
                 ],
@@ -682,7 +592,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.EmailTemplate"
+                            "$ref": "#/definitions/models.DripCampaign"
                         }
                     },
                     "400": {
@@ -700,9 +610,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/email-templates/{id}": {
+        "/campaigns/{id}": {
+            "get": {
+                "description": "Retrieve a specific drip campaign by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Campaigns"
+                ],
+                "summary": "Get a campaign",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DripCampaign"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
             "put": {
-                "description": "Update a specific email template by ID",
+                "description": "Update a specific drip campaign by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -710,24 +653,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "EmailTemplates"
+                    "Campaigns"
                 ],
-                "summary": "Update an email template",
+                "summary": "Update a campaign",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Email template ID",
+                        "description": "Campaign ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated email template data",
-                        "name": "emailTemplate",
+                        "description": "Updated campaign data",
+                        "name": "campaign",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.EmailTemplate"
+                            "$ref": "#/definitions/models.DripCampaign"
                         }
                     }
                 ],
@@ -735,7 +678,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.EmailTemplate"
+                            "$ref": "#/definitions/models.DripCampaign"
                         }
                     },
                     "400": {
@@ -759,18 +702,18 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete a specific email template by ID",
+                "description": "Delete a specific drip campaign by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "EmailTemplates"
+                    "Campaigns"
                 ],
-                "summary": "Delete an email template",
+                "summary": "Delete a campaign",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Email template ID",
+                        "description": "Campaign ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -798,55 +741,48 @@ const docTemplate = `{
                 }
             }
         },
-        "/login": {
-            "post": {
-                "description": "Authenticate user credentials and generate a JWT token",
-                "consumes": [
-                    "application/json"
-                ],
+        "/campaigns/{id}/flow": {
+            "get": {
+                "description": "Retrieve the entry step and steps of a campaign with their branches and conditions, and any problems that would stop it from being activated",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Auth"
+                    "Campaigns"
                 ],
-                "summary": "User login",
+                "summary": "Get a campaign's flow",
                 "parameters": [
                     {
-                        "description": "User credentials",
-                        "name": "credentials",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.LoginRequest"
-                        }
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.TokenResponse"
+                            "$ref": "#/definitions/models.CampaignFlow"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/send-email": {
-            "post": {
-                "description": "Send an email using Gmail SMTP with simple authentication",
+            },
+            "put": {
+                "description": "Set the entry step and the type, branches and conditions of a campaign's steps. Steps are matched by ID and must already exist; only their flow fields and wait_time are changed. The whole graph is validated, rejecting cycles and unreachable steps.",
                 "consumes": [
                     "application/json"
                 ],
@@ -854,17 +790,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Email"
+                    "Campaigns"
                 ],
-                "summary": "Send an email",
+                "summary": "Update a campaign's flow",
                 "parameters": [
                     {
-                        "description": "Email request data",
-                        "name": "emailRequest",
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Campaign flow",
+                        "name": "flow",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.EmailRequest"
+                            "$ref": "#/definitions/models.CampaignFlow"
                         }
                     }
                 ],
@@ -872,7 +815,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SuccessResponse"
+                            "$ref": "#/definitions/models.CampaignFlow"
                         }
                     },
                     "400": {
@@ -881,6 +824,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -890,21 +839,54 @@ const docTemplate = `{
                 }
             }
         },
-        "/settings": {
+        "/campaigns/{id}/stats": {
             "get": {
-                "description": "Retrieve the user settings",
+                "description": "Retrieve enrollment counts, per stage and step sent/delivered/bounced/opened/clicked/unsubscribed counts and rates, and a time series for charts",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Settings"
+                    "Campaigns"
+                ],
+                "summary": "Get campaign stats",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Time series bucket: day (default) or week",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the time series, YYYY-MM-DD (default 30 days ago)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the time series, YYYY-MM-DD inclusive (default today)",
+                        "name": "to",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get settings",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Settings"
+                            "$ref": "#/definitions/models.CampaignStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "404": {
@@ -912,37 +894,46 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "put": {
-                "description": "Update the user settings",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/campaigns/{id}/translations": {
+            "get": {
+                "description": "List the templates a campaign sends with the locales of its active and paused customers they aren't translated into. Those customers are sent the default locale's content. Locales can be checked before any customer has them.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Settings"
+                    "Campaigns"
                 ],
-                "summary": "Update settings",
+                "summary": "Get a campaign's missing translations",
                 "parameters": [
                     {
-                        "description": "Updated settings data",
-                        "name": "settings",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Settings"
-                        }
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated locales to check as well, such as es,fr,de",
+                        "name": "locales",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Settings"
+                            "$ref": "#/definitions/models.TranslationReport"
                         }
                     },
                     "400": {
@@ -951,6 +942,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -960,23 +957,32 @@ const docTemplate = `{
                 }
             }
         },
-        "/stages": {
+        "/campaigns/{id}/triggers": {
             "get": {
-                "description": "Retrieve all stages",
+                "description": "Retrieve the enrollment triggers of a campaign",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Stages"
+                    "Campaigns"
+                ],
+                "summary": "Get campaign triggers",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get all stages",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Stage"
+                                "$ref": "#/definitions/models.CampaignTrigger"
                             }
                         }
                     },
@@ -989,7 +995,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create a new stage",
+                "description": "Enroll customers in the campaign automatically when they're created, get a tag, change lead status, have a custom event posted, or on the anniversary of a date field",
                 "consumes": [
                     "application/json"
                 ],
@@ -997,17 +1003,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Stages"
+                    "Campaigns"
                 ],
-                "summary": "Create a stage",
+                "summary": "Create a campaign trigger",
                 "parameters": [
                     {
-                        "description": "Stage data",
-                        "name": "stage",
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Trigger data",
+                        "name": "trigger",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Stage"
+                            "$ref": "#/definitions/models.CampaignTrigger"
                         }
                     }
                 ],
@@ -1015,7 +1028,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Stage"
+                            "$ref": "#/definitions/models.CampaignTrigger"
                         }
                     },
                     "400": {
@@ -1024,6 +1037,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1033,30 +1052,37 @@ const docTemplate = `{
                 }
             }
         },
-        "/stages/{id}": {
-            "get": {
-                "description": "Retrieve a specific stage by ID",
+        "/campaigns/{id}/triggers/{trigger_id}": {
+            "delete": {
+                "description": "Delete an enrollment trigger. Customers it already enrolled stay enrolled.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Stages"
+                    "Campaigns"
                 ],
-                "summary": "Get a stage",
+                "summary": "Delete a campaign trigger",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Stage ID",
+                        "description": "Campaign ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Trigger ID",
+                        "name": "trigger_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Stage"
+                            "$ref": "#/definitions/models.SuccessResponse"
                         }
                     },
                     "404": {
@@ -1064,36 +1090,150 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "put": {
-                "description": "Update a specific stage by ID",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/customers": {
+            "get": {
+                "description": "Retrieve all customers",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Stages"
+                    "Customers"
                 ],
-                "summary": "Update a stage",
-                "parameters": [
+                "summary": "Get all customers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Customer"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new customer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Customers"
+                ],
+                "summary": "Create a customer",
+                "parameters": [
+                    {
+                        "description": "Customer data",
+                        "name": "customer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Customer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Customer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{id}": {
+            "get": {
+                "description": "Retrieve a specific customer by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Customers"
+                ],
+                "summary": "Get a customer",
+                "parameters": [
                     {
                         "type": "integer",
-                        "description": "Stage ID",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Customer"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update a specific customer by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Customers"
+                ],
+                "summary": "Update a customer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated stage data",
-                        "name": "stage",
+                        "description": "Updated customer data",
+                        "name": "customer",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Stage"
+                            "$ref": "#/definitions/models.Customer"
                         }
                     }
                 ],
@@ -1101,7 +1241,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Stage"
+                            "$ref": "#/definitions/models.Customer"
                         }
                     },
                     "400": {
@@ -1125,18 +1265,18 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete a specific stage by ID",
+                "description": "Delete a specific customer by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Stages"
+                    "Customers"
                 ],
-                "summary": "Delete a stage",
+                "summary": "Delete a customer",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Stage ID",
+                        "description": "Customer ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1164,23 +1304,67 @@ const docTemplate = `{
                 }
             }
         },
-        "/steps": {
+        "/customers/{id}/timeline": {
             "get": {
-                "description": "Retrieve all steps",
+                "description": "Retrieve the emails sent to a customer and their opens, clicks, replies, bounces and complaints, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Steps"
+                    "Customers"
+                ],
+                "summary": "Get a customer's timeline",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get all steps",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Step"
+                                "$ref": "#/definitions/models.TimelineEntry"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/dkim-keys": {
+            "get": {
+                "description": "Retrieve all DKIM keys with the DNS records to publish. Private keys are never returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DKIM"
+                ],
+                "summary": "Get all DKIM keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.DKIMKey"
                             }
                         }
                     },
@@ -1193,7 +1377,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create a new step",
+                "description": "Generate an RSA (2048 bits unless bits is given) or Ed25519 keypair for a sending domain and return the DNS TXT record to publish. The key starts disabled; once the record is published, enable it with PUT /dkim-keys/{id} and emails from the domain are signed with it from then on.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1201,17 +1385,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Steps"
+                    "DKIM"
                 ],
-                "summary": "Create a step",
+                "summary": "Generate a DKIM key",
                 "parameters": [
                     {
-                        "description": "Step data",
-                        "name": "step",
+                        "description": "Domain, selector and algorithm",
+                        "name": "key",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Step"
+                            "$ref": "#/definitions/models.DKIMKeyRequest"
                         }
                     }
                 ],
@@ -1219,7 +1403,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Step"
+                            "$ref": "#/definitions/models.DKIMKey"
                         }
                     },
                     "400": {
@@ -1228,6 +1412,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1237,20 +1427,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/steps/{id}": {
+        "/dkim-keys/{id}": {
             "get": {
-                "description": "Retrieve a specific step by ID",
+                "description": "Retrieve a specific DKIM key by ID with the DNS record to publish",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Steps"
+                    "DKIM"
                 ],
-                "summary": "Get a step",
+                "summary": "Get a DKIM key",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Step ID",
+                        "description": "DKIM key ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1260,7 +1450,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Step"
+                            "$ref": "#/definitions/models.DKIMKey"
                         }
                     },
                     "404": {
@@ -1272,7 +1462,7 @@ const docTemplate = `{
                 }
             },
             "put": {
-                "description": "Update a specific step by ID",
+                "description": "Enable or disable signing with a DKIM key. A key is only enabled once a test message signed with it verifies against its published DNS record.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1280,24 +1470,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Steps"
+                    "DKIM"
                 ],
-                "summary": "Update a step",
+                "summary": "Update a DKIM key",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Step ID",
+                        "description": "DKIM key ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated step data",
-                        "name": "step",
+                        "description": "Whether the key is disabled",
+                        "name": "key",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Step"
+                            "$ref": "#/definitions/models.UpdateDKIMKeyRequest"
                         }
                     }
                 ],
@@ -1305,7 +1495,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Step"
+                            "$ref": "#/definitions/models.DKIMKey"
                         }
                     },
                     "400": {
@@ -1320,6 +1510,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1329,18 +1525,18 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete a specific step by ID",
+                "description": "Delete a DKIM key. Emails from its domain are no longer signed with it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Steps"
+                    "DKIM"
                 ],
-                "summary": "Delete a step",
+                "summary": "Delete a DKIM key",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Step ID",
+                        "description": "DKIM key ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1367,127 +1563,5693 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.CampaignCustomer": {
-            "type": "object",
-            "properties": {
-                "campaign_id": {
-                    "type": "integer"
-                },
-                "created_at": {
-                    "type": "string"
-                },
-                "customer_id": {
-                    "type": "integer"
-                },
-                "deleted_at": {
-                    "type": "string"
-                },
-                "end_date": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "start_date": {
-                    "type": "string"
-                },
-                "status": {
-                    "type": "string"
-                },
-                "subscribed": {
-                    "description": "Added field to track subscription status",
-                    "type": "boolean"
-                },
-                "updated_at": {
-                    "type": "string"
-                }
-            }
         },
-        "models.Customer": {
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "deleted_at": {
-                    "type": "string"
-                },
-                "email": {
-                    "type": "string"
-                },
+        "/dkim-keys/{id}/check": {
+            "post": {
+                "description": "Sign a test message with the stored private key and verify the signature against the key's published DNS record",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DKIM"
+                ],
+                "summary": "Check a DKIM key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "DKIM key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DKIMCheck"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/email-templates": {
+            "get": {
+                "description": "Retrieve all email templates",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Get all email templates",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.EmailTemplate"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new email template. The response lists the linter's warnings, if any.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Create an email template",
+                "parameters": [
+                    {
+                        "description": "Email template data",
+                        "name": "emailTemplate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.EmailTemplate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.EmailTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/email-templates/{id}": {
+            "put": {
+                "description": "Update a specific email template by ID. A change saves a new version, so earlier ones can be compared and restored. The response lists the linter's warnings, if any.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Update an email template",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated email template data",
+                        "name": "emailTemplate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.EmailTemplate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.EmailTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a specific email template by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Delete an email template",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/events": {
+            "post": {
+                "description": "Record a custom event such as trial_started for a customer, identified by customer_id or email. Campaigns with a matching event trigger enroll the customer on the next engine run.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Events"
+                ],
+                "summary": "Post a customer event",
+                "parameters": [
+                    {
+                        "description": "Event data",
+                        "name": "event",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PostEventRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.CustomerEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/inbound/bounces": {
+            "post": {
+                "description": "Submit a raw RFC 822 message containing a delivery status notification (RFC 3464) or an ARF feedback report. Matching email logs are marked bounced or complained and customers are suppressed once the configured thresholds are reached. A message with the Message-ID of one already processed, posted or fetched from a polled mailbox, is only acknowledged.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Inbound"
+                ],
+                "summary": "Process a bounce or complaint",
+                "parameters": [
+                    {
+                        "description": "Raw email message",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/bounce.Result"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/inbound/replies": {
+            "post": {
+                "description": "Submit a raw RFC 822 message. When its In-Reply-To or References headers match a sent email, the reply is recorded on the customer's timeline and the campaign's on_reply action is applied to their enrollment. A reply with the Message-ID of one already processed, posted or fetched from the sending account, is only acknowledged.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Inbound"
+                ],
+                "summary": "Process a reply",
+                "parameters": [
+                    {
+                        "description": "Raw email message",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/reply.Result"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/invitations": {
+            "get": {
+                "description": "Retrieve all user invitations",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get all invitations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Invitation"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Email a signed, expiring invite link that lets a new user set their password",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Invite a user",
+                "parameters": [
+                    {
+                        "description": "Invitation data",
+                        "name": "invitation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.InvitationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Invitation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/invitations/accept": {
+            "post": {
+                "description": "Set a password using an invite token and create the invited user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Accept an invitation",
+                "parameters": [
+                    {
+                        "description": "Invite token and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AcceptInvitationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/invitations/{id}": {
+            "delete": {
+                "description": "Revoke a pending invitation so its link can no longer be used",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Revoke an invitation",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Invitation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "Authenticate user credentials and generate a JWT token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "User login",
+                "parameters": [
+                    {
+                        "description": "User credentials",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TokenResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorChallengeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/login-attempts": {
+            "get": {
+                "description": "Retrieve recent login attempts, optionally filtered by email, IP address or user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get login attempts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IP address",
+                        "name": "ip",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of records (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LoginAttempt"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/login/2fa": {
+            "post": {
+                "description": "Exchange a login challenge token and a TOTP or recovery code for a JWT token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Complete two-factor login",
+                "parameters": [
+                    {
+                        "description": "Challenge token and code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorLoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/login/2fa/setup": {
+            "post": {
+                "description": "Generate a TOTP secret for a user whose account requires 2FA but who has not enrolled",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Start required two-factor enrollment",
+                "parameters": [
+                    {
+                        "description": "Challenge token",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorSetupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorEnrollmentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/2fa/confirm": {
+            "post": {
+                "description": "Verify a TOTP code against the pending secret, enable 2FA and return recovery codes",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Confirm two-factor enrollment",
+                "parameters": [
+                    {
+                        "description": "TOTP code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/2fa/disable": {
+            "post": {
+                "description": "Disable 2FA for the authenticated user unless an admin requires it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Disable two-factor authentication",
+                "parameters": [
+                    {
+                        "description": "Password and TOTP code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorDisableRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/2fa/enroll": {
+            "post": {
+                "description": "Generate a TOTP secret and otpauth URI for the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Start two-factor enrollment",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorEnrollmentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/2fa/recovery-codes": {
+            "post": {
+                "description": "Invalidate existing recovery codes and issue a new set",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Regenerate recovery codes",
+                "parameters": [
+                    {
+                        "description": "Current TOTP code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/password": {
+            "put": {
+                "description": "Change the password of the authenticated user. Every session, including the current one, is revoked, so the user logs in again with the new password.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Change password",
+                "parameters": [
+                    {
+                        "description": "Current and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/partials": {
+            "get": {
+                "description": "Retrieve all partials and layouts, optionally only those of one kind",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Partials"
+                ],
+                "summary": "Get all partials",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "partial or layout",
+                        "name": "kind",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Partial"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a named snippet templates include with {{\u003e name}}, or, with kind layout, a layout that wraps templates' content where it says {{\u003e content}}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Partials"
+                ],
+                "summary": "Create a partial",
+                "parameters": [
+                    {
+                        "description": "Partial data",
+                        "name": "partial",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Partial"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Partial"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/partials/{id}": {
+            "get": {
+                "description": "Retrieve a specific partial or layout by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Partials"
+                ],
+                "summary": "Get a partial",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Partial ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Partial"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update a specific partial or layout by ID. Templates pick the change up the next time they're sent. Partials in use can't be renamed or change kind.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Partials"
+                ],
+                "summary": "Update a partial",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Partial ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated partial data",
+                        "name": "partial",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Partial"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Partial"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a partial or layout that no template, pinned template version or other partial uses",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Partials"
+                ],
+                "summary": "Delete a partial",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Partial ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/forgot": {
+            "post": {
+                "description": "Email a single-use password reset link if the account exists",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Request a password reset",
+                "parameters": [
+                    {
+                        "description": "Account email",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ForgotPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/reset": {
+            "post": {
+                "description": "Set a new password using a single-use reset token. Every session and login challenge issued before is revoked.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Reset a password",
+                "parameters": [
+                    {
+                        "description": "Reset token and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/rate-limits": {
+            "get": {
+                "description": "Retrieve the rate limits of recipient domains",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SendQueue"
+                ],
+                "summary": "Get domain rate limits",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.DomainRateLimit"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Limit how many emails are sent to a recipient domain per minute, hour and day. Zero means no limit.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SendQueue"
+                ],
+                "summary": "Create a domain rate limit",
+                "parameters": [
+                    {
+                        "description": "Rate limit data",
+                        "name": "limit",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DomainRateLimit"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.DomainRateLimit"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/rate-limits/{id}": {
+            "put": {
+                "description": "Update a specific domain rate limit by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SendQueue"
+                ],
+                "summary": "Update a domain rate limit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Rate limit ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated rate limit data",
+                        "name": "limit",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DomainRateLimit"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DomainRateLimit"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a specific domain rate limit by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SendQueue"
+                ],
+                "summary": "Delete a domain rate limit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Rate limit ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/send-email": {
+            "post": {
+                "description": "Queue an email to be sent through Gmail SMTP. Temporary failures are retried; the job can be followed under /send-queue/jobs.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Email"
+                ],
+                "summary": "Send an email",
+                "parameters": [
+                    {
+                        "description": "Email request data",
+                        "name": "emailRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.EmailRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.QueuedEmail"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/send-queue": {
+            "get": {
+                "description": "Retrieve how many emails are waiting to be sent, per recipient domain, how much of the global and per-domain rate limits has been used, and the warm-up of each sender identity warming up",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SendQueue"
+                ],
+                "summary": "Get the send queue",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SendQueueStatus"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/send-queue/jobs": {
+            "get": {
+                "description": "Retrieve the most recent send queue jobs, newest first, optionally filtered by status (queued, sent, failed, dead or cancelled)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SendQueue"
+                ],
+                "summary": "Get send jobs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of jobs (default 100, at most 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.QueuedEmail"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/send-queue/jobs/{id}": {
+            "get": {
+                "description": "Retrieve a specific send queue job by ID, including its attempts and last error",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SendQueue"
+                ],
+                "summary": "Get a send job",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.QueuedEmail"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/send-queue/jobs/{id}/cancel": {
+            "post": {
+                "description": "Cancel a job that hasn't been sent yet",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SendQueue"
+                ],
+                "summary": "Cancel a send job",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.QueuedEmail"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/send-queue/jobs/{id}/retry": {
+            "post": {
+                "description": "Queue a failed, dead-lettered or cancelled job again, due right away and with a fresh set of attempts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SendQueue"
+                ],
+                "summary": "Retry a send job",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.QueuedEmail"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sender-identities": {
+            "get": {
+                "description": "Retrieve all sender identities. Passwords are masked.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SenderIdentities"
+                ],
+                "summary": "Get all sender identities",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SenderIdentity"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Add a mailbox campaigns can send from, with its SMTP credentials, signature, daily cap and warm-up schedule. Identities without an SMTP host send through Gmail.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SenderIdentities"
+                ],
+                "summary": "Create a sender identity",
+                "parameters": [
+                    {
+                        "description": "Sender identity data",
+                        "name": "identity",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SenderIdentity"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.SenderIdentity"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sender-identities/{id}": {
+            "get": {
+                "description": "Retrieve a specific sender identity by ID. The password is masked.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SenderIdentities"
+                ],
+                "summary": "Get a sender identity",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sender identity ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SenderIdentity"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update a specific sender identity by ID. Sending the masked password back keeps the stored one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SenderIdentities"
+                ],
+                "summary": "Update a sender identity",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sender identity ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated sender identity data",
+                        "name": "identity",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SenderIdentity"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SenderIdentity"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a sender identity that no campaign or step sends from",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SenderIdentities"
+                ],
+                "summary": "Delete a sender identity",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sender identity ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sending-domains/{domain}/check": {
+            "get": {
+                "description": "Inspect the MX, SPF, DKIM and DMARC records of a from-domain and report misconfigurations with how to fix them, and whether its mail passes DMARC alignment",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Deliverability"
+                ],
+                "summary": "Check a sending domain",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sending domain, such as example.com",
+                        "name": "domain",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DomainCheck"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/settings": {
+            "get": {
+                "description": "Retrieve the user settings. Secret fields are masked.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Settings"
+                ],
+                "summary": "Get settings",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Settings"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update the user settings. Secret fields are write-only: sending back the masked value keeps the stored secret.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Settings"
+                ],
+                "summary": "Update settings",
+                "parameters": [
+                    {
+                        "description": "Updated settings data",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Settings"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Settings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stages": {
+            "get": {
+                "description": "Retrieve all stages",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stages"
+                ],
+                "summary": "Get all stages",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Stage"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new stage",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stages"
+                ],
+                "summary": "Create a stage",
+                "parameters": [
+                    {
+                        "description": "Stage data",
+                        "name": "stage",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Stage"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Stage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stages/{id}": {
+            "get": {
+                "description": "Retrieve a specific stage by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stages"
+                ],
+                "summary": "Get a stage",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Stage ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Stage"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update a specific stage by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stages"
+                ],
+                "summary": "Update a stage",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Stage ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated stage data",
+                        "name": "stage",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Stage"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Stage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a specific stage by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stages"
+                ],
+                "summary": "Delete a stage",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Stage ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/steps": {
+            "get": {
+                "description": "Retrieve all steps",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Steps"
+                ],
+                "summary": "Get all steps",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Step"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new step",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Steps"
+                ],
+                "summary": "Create a step",
+                "parameters": [
+                    {
+                        "description": "Step data",
+                        "name": "step",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Step"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Step"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/steps/{id}": {
+            "get": {
+                "description": "Retrieve a specific step by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Steps"
+                ],
+                "summary": "Get a step",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Step ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Step"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update a specific step by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Steps"
+                ],
+                "summary": "Update a step",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Step ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated step data",
+                        "name": "step",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Step"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Step"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a specific step by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Steps"
+                ],
+                "summary": "Delete a step",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Step ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/steps/{id}/ab-results": {
+            "get": {
+                "description": "Retrieve per-variant sends, opens, clicks and replies with rates, the leader on the step's A/B metric and whether its lead is statistically significant",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Steps"
+                ],
+                "summary": "Get A/B test results",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Step ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ABTestResults"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/steps/{id}/attachments": {
+            "get": {
+                "description": "Retrieve the files and inline images a step attaches, on top of its template's, with their assets",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Steps"
+                ],
+                "summary": "Get a step's attachments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Step ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Attachment"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Attach an uploaded asset to the emails a step sends, on top of its template's attachments, such as a brochure sent only in one step of a sequence. With inline set, an image is instead shown where the HTML body has src=\"cid:\u003ccontent_id\u003e\".",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Steps"
+                ],
+                "summary": "Attach an asset to a step",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Step ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Asset to attach",
+                        "name": "attachment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AttachmentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Attachment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/steps/{id}/attachments/{attachment_id}": {
+            "delete": {
+                "description": "Stop attaching an asset to a step. The asset itself is kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Steps"
+                ],
+                "summary": "Remove a step attachment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Step ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Attachment ID",
+                        "name": "attachment_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/steps/{id}/variants": {
+            "get": {
+                "description": "Retrieve the template variants of a step",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Steps"
+                ],
+                "summary": "Get step variants",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Step ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.StepVariant"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Add a template variant to a step. Once a step has variants, each recipient is deterministically assigned one of them in proportion to their weights.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Steps"
+                ],
+                "summary": "Create a step variant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Step ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Variant data",
+                        "name": "variant",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.StepVariant"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.StepVariant"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/steps/{id}/variants/{variant_id}": {
+            "put": {
+                "description": "Update the name, template or weight of a step variant",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Steps"
+                ],
+                "summary": "Update a step variant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Step ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Variant ID",
+                        "name": "variant_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated variant data",
+                        "name": "variant",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.StepVariant"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.StepVariant"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a step variant. Deleting the promoted winner restarts the test.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Steps"
+                ],
+                "summary": "Delete a step variant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Step ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Variant ID",
+                        "name": "variant_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/steps/{id}/variants/{variant_id}/promote": {
+            "post": {
+                "description": "Make a variant the winner of the step's A/B test, so every later recipient gets it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Steps"
+                ],
+                "summary": "Promote a step variant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Step ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Variant ID",
+                        "name": "variant_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Step"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/t/c/{token}": {
+            "get": {
+                "description": "Record a click event for the link identified by the signed token and redirect to its destination",
+                "tags": [
+                    "Tracking"
+                ],
+                "summary": "Track a link click",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Signed tracking token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/t/o/{token}": {
+            "get": {
+                "description": "Record an open event for the email identified by the signed token and return a 1x1 GIF",
+                "produces": [
+                    "image/gif"
+                ],
+                "tags": [
+                    "Tracking"
+                ],
+                "summary": "Track an email open",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Signed tracking token followed by .gif",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            }
+        },
+        "/t/u/{token}": {
+            "get": {
+                "description": "Show a page confirming the recipient of the email identified by the signed token wants to unsubscribe. Nothing changes until it's submitted, so link scanners that follow the link don't unsubscribe anyone.",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Tracking"
+                ],
+                "summary": "Show the unsubscribe page",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Signed unsubscribe token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Unsubscribe the recipient of the email identified by the signed token and take them out of every campaign. Mail clients post here with List-Unsubscribe=One-Click (RFC 8058).",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Tracking"
+                ],
+                "summary": "Unsubscribe",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Signed unsubscribe token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}/attachments": {
+            "get": {
+                "description": "Retrieve the files and inline images an email template attaches, with their assets",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Get a template's attachments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Attachment"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Attach an uploaded asset to every email sent with a template. With inline set, an image is instead shown where the HTML body has src=\"cid:\u003ccontent_id\u003e\"; content_id defaults to the asset's filename. Attachments aren't versioned: pinned versions send the template's current attachments.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Attach an asset to a template",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Asset to attach",
+                        "name": "attachment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AttachmentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Attachment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}/attachments/{attachment_id}": {
+            "delete": {
+                "description": "Stop attaching an asset to a template. The asset itself is kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Remove a template attachment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Attachment ID",
+                        "name": "attachment_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}/diff": {
+            "get": {
+                "description": "Compare two versions of an email template line by line. to defaults to the current version and from to the one before to.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Diff template versions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Older version",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Newer version",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TemplateDiff"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}/lint": {
+            "post": {
+                "description": "Check a template, with its layout and partials included, for spam-trigger phrases, image-heavy layouts, a missing plain-text alternative or unsubscribe link, broken merge fields, HTML big enough for Gmail to clip, URL shorteners and links whose text shows a different address",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Lint an email template",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TemplateLint"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}/localizations": {
+            "get": {
+                "description": "Retrieve the translations of an email template, by locale",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Get a template's localizations",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TemplateLocalization"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}/localizations/{locale}": {
+            "get": {
+                "description": "Retrieve an email template's translation for a locale",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Get a template localization",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Locale, such as es or fr-CA",
+                        "name": "locale",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TemplateLocalization"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Create or replace an email template's translation for a locale. Customers whose locale or language matches are sent it instead of the template's own content; it has the template's content type and layout. A change saves a new revision, and steps pinned to a template version are sent the revision that was current at that version. The response lists the linter's warnings, if any.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Save a template localization",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Locale, such as es or fr-CA",
+                        "name": "locale",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Translated subject and body",
+                        "name": "localization",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TemplateLocalization"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TemplateLocalization"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.TemplateLocalization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete an email template's translation for a locale and its revisions. Customers with the locale are sent the template's own content.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Delete a template localization",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Locale, such as es or fr-CA",
+                        "name": "locale",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}/localizations/{locale}/revisions": {
+            "get": {
+                "description": "Retrieve every revision of an email template's translation for a locale, newest first, with the template version each was saved at",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Get a template localization's revisions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Locale, such as es or fr-CA",
+                        "name": "locale",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LocalizationRevision"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}/versions": {
+            "get": {
+                "description": "Retrieve every version of an email template, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Get a template's versions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TemplateVersion"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}/versions/{version}": {
+            "get": {
+                "description": "Retrieve a specific version of an email template",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Get a template version",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Version number",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TemplateVersion"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}/versions/{version}/restore": {
+            "post": {
+                "description": "Make an earlier version's content the template's current content. This saves a new version, so the history is kept and the restore can itself be undone.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "EmailTemplates"
+                ],
+                "summary": "Restore a template version",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Email template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Version number to restore",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.EmailTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/2fa": {
+            "put": {
+                "description": "Enforce or relax the 2FA requirement for a user. Enforcing it for a user who hasn't enrolled signs them out, so they next log in with 2FA.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Require two-factor authentication",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Requirement",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorRequirementRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a user's TOTP secret and recovery codes so they can enroll again, and sign them out everywhere",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Reset a user's two-factor authentication",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/unlock": {
+            "post": {
+                "description": "Clear the failed login count and lockout of a user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Unlock a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "bounce.Result": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "diagnostic": {
+                    "type": "string"
+                },
+                "email_log_id": {
+                    "type": "integer"
+                },
+                "feedback_type": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "matched": {
+                    "type": "boolean"
+                },
+                "original_message_id": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "suppressed": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ABTestResults": {
+            "type": "object",
+            "properties": {
+                "confidence": {
+                    "type": "number"
+                },
+                "leader_variant_id": {
+                    "type": "integer"
+                },
+                "metric": {
+                    "type": "string"
+                },
+                "p_value": {
+                    "type": "number"
+                },
+                "promoted_at": {
+                    "type": "string"
+                },
+                "significant": {
+                    "type": "boolean"
+                },
+                "step_id": {
+                    "type": "integer"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.VariantStats"
+                    }
+                },
+                "winning_variant_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AcceptInvitationRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Asset": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "uploaded_by": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Attachment": {
+            "type": "object",
+            "properties": {
+                "asset": {
+                    "$ref": "#/definitions/models.Asset"
+                },
+                "asset_id": {
+                    "type": "integer"
+                },
+                "content_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "email_template_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "inline": {
+                    "type": "boolean"
+                },
+                "step_id": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AttachmentRequest": {
+            "type": "object",
+            "required": [
+                "asset_id"
+            ],
+            "properties": {
+                "asset_id": {
+                    "type": "integer"
+                },
+                "content_id": {
+                    "type": "string"
+                },
+                "inline": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.CampaignCustomer": {
+            "type": "object",
+            "properties": {
+                "campaign_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "current_step_id": {
+                    "description": "Progress through the campaign, maintained by the engine",
+                    "type": "integer"
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_step_at": {
+                    "type": "string"
+                },
+                "next_step_at": {
+                    "type": "string"
+                },
+                "next_step_id": {
+                    "type": "integer"
+                },
+                "sender_identity_id": {
+                    "description": "SenderIdentityID is the identity the customer was given by a campaign\nthat rotates senders, so the whole sequence comes from one mailbox",
+                    "type": "integer"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subscribed": {
+                    "type": "boolean"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "wait_deadline": {
+                    "description": "WaitDeadline is when a wait-until step times out",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CampaignFlow": {
+            "type": "object",
+            "properties": {
+                "entry_step_id": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Step"
+                    }
+                }
+            }
+        },
+        "models.CampaignStats": {
+            "type": "object",
+            "properties": {
+                "campaign_id": {
+                    "type": "integer"
+                },
+                "enrollments": {
+                    "$ref": "#/definitions/models.EnrollmentStats"
+                },
+                "interval": {
+                    "type": "string"
+                },
+                "series": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StatsBucket"
+                    }
+                },
+                "stages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StageStats"
+                    }
+                },
+                "totals": {
+                    "$ref": "#/definitions/models.FunnelStats"
+                }
+            }
+        },
+        "models.CampaignTrigger": {
+            "type": "object",
+            "properties": {
+                "campaign_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChangePasswordRequest": {
+            "type": "object",
+            "properties": {
+                "current_password": {
+                    "type": "string"
+                },
+                "new_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateAPIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.CreateAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "$ref": "#/definitions/models.APIKey"
+                },
+                "key": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Customer": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "assigned_to": {
+                    "type": "integer"
+                },
+                "birthday": {
+                    "description": "Birthday can be used by anniversary triggers",
+                    "type": "string"
+                },
+                "city": {
+                    "type": "string"
+                },
+                "company": {
+                    "type": "string"
+                },
+                "country": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "email_verified": {
+                    "type": "boolean"
+                },
                 "first_name": {
                     "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "last_name": {
-                    "type": "string"
+                "last_contacted": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "lead_source": {
+                    "type": "string"
+                },
+                "lead_status": {
+                    "type": "string"
+                },
+                "locale": {
+                    "description": "Locale is the language the customer is emailed in, such as \"es\" or\n\"fr-CA\". When empty it's derived from Country.",
+                    "type": "string"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "postal_code": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "subscribed": {
+                    "type": "boolean"
+                },
+                "suppressed_at": {
+                    "description": "Set when bounces or complaints pass the suppression thresholds; suppressed\ncustomers are never emailed again",
+                    "type": "string"
+                },
+                "suppression_reason": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "Timezone is the customer's IANA timezone, such as \"America/Chicago\".\nWhen empty it's derived from Country and State.",
+                    "type": "string"
+                },
+                "unsubscribed_at": {
+                    "description": "Set when the customer unsubscribes with an email's unsubscribe link.\nSubscribing them again clears it.",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CustomerEvent": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "processed_at": {
+                    "type": "string"
+                },
+                "properties": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DKIMCheck": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "ok": {
+                    "type": "boolean"
+                },
+                "signature": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DKIMKey": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "type": "string"
+                },
+                "bits": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "disabled": {
+                    "type": "boolean"
+                },
+                "dns_record": {
+                    "description": "DNSRecord is the TXT record to publish for the key",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.DNSRecord"
+                        }
+                    ]
+                },
+                "domain": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "public_key": {
+                    "type": "string"
+                },
+                "selector": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DKIMKeyRequest": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "type": "string"
+                },
+                "bits": {
+                    "type": "integer"
+                },
+                "domain": {
+                    "type": "string"
+                },
+                "selector": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DNSCheck": {
+            "type": "object",
+            "properties": {
+                "fix": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "record": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "values": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.DNSRecord": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DomainCheck": {
+            "type": "object",
+            "properties": {
+                "alignment": {
+                    "type": "string"
+                },
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DNSCheck"
+                    }
+                },
+                "domain": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DomainQueueStatus": {
+            "type": "object",
+            "properties": {
+                "domain": {
+                    "type": "string"
+                },
+                "per_day": {
+                    "type": "integer"
+                },
+                "per_hour": {
+                    "type": "integer"
+                },
+                "per_minute": {
+                    "type": "integer"
+                },
+                "queued": {
+                    "type": "integer"
+                },
+                "sent_last_day": {
+                    "type": "integer"
+                },
+                "sent_last_hour": {
+                    "type": "integer"
+                },
+                "sent_last_minute": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.DomainRateLimit": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "domain": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "per_day": {
+                    "type": "integer"
+                },
+                "per_hour": {
+                    "type": "integer"
+                },
+                "per_minute": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DripCampaign": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "entry_step_id": {
+                    "description": "EntryStepID is the first step of a branching flow. Campaigns without one\nrun their steps in stage order.",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "on_reply": {
+                    "description": "OnReply is what happens to a customer's enrollment when they reply:\nexit (the default), pause or continue",
+                    "type": "string"
+                },
+                "send_days": {
+                    "description": "Sending window in each customer's local time: SendDays is a\ncomma-separated list of days such as \"mon,tue,wed,thu,fri\" and the\nwindow runs from SendWindowStart to SendWindowEnd (\"09:00\" to \"17:00\").\nEmails due outside it wait for the next opening. Timezone is used for\ncustomers whose own timezone isn't known.",
+                    "type": "string"
+                },
+                "send_window_end": {
+                    "type": "string"
+                },
+                "send_window_start": {
+                    "type": "string"
+                },
+                "sender_identity_id": {
+                    "description": "Who the campaign's emails come from: SenderIdentityID, or per customer\nwith a SenderMode of rotate (identities in rotation, in turn) or\nassigned_rep (the identity of the customer's AssignedTo user, falling\nback to SenderIdentityID). Steps can override it.",
+                    "type": "integer"
+                },
+                "sender_mode": {
+                    "type": "string"
+                },
+                "stages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Stage"
+                    }
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "tracking_disabled": {
+                    "description": "TrackingDisabled turns off open and click tracking for privacy-sensitive lists",
+                    "type": "boolean"
+                },
+                "triggers": {
+                    "description": "Triggers enroll customers automatically; see CampaignTrigger",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CampaignTrigger"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "utm_campaign": {
+                    "type": "string"
+                },
+                "utm_medium": {
+                    "type": "string"
+                },
+                "utm_source": {
+                    "description": "UTM parameters appended to links in the campaign's emails, when set",
+                    "type": "string"
+                }
+            }
+        },
+        "models.EmailRequest": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.EmailTemplate": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "compiled_body": {
+                    "description": "CompiledBody and CompiledTextBody are the HTML and plain text a\nMarkdown or MJML body compiles to, with its CSS inlined. They're\ncompiled again when sent, with the layout and partials as they are then.",
+                    "type": "string"
+                },
+                "compiled_text_body": {
+                    "type": "string"
+                },
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "layout_id": {
+                    "description": "LayoutID is the layout Partial the template's content is placed in",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "text_body": {
+                    "description": "TextBody is the plain-text alternative of an HTML body, sent alongside it",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version is the number of the template's current TemplateVersion",
+                    "type": "integer"
+                },
+                "warnings": {
+                    "description": "Warnings are the linter's findings, returned when the template is saved",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LintWarning"
+                    }
+                }
+            }
+        },
+        "models.EnrollmentStats": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "integer"
+                },
+                "completed": {
+                    "type": "integer"
+                },
+                "enrolled": {
+                    "type": "integer"
+                },
+                "exited": {
+                    "type": "integer"
+                },
+                "paused": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ForgotPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FunnelStats": {
+            "type": "object",
+            "properties": {
+                "bounce_rate": {
+                    "type": "number"
+                },
+                "bounced": {
+                    "type": "integer"
+                },
+                "click_rate": {
+                    "type": "number"
+                },
+                "click_to_open_rate": {
+                    "type": "number"
+                },
+                "clicked": {
+                    "type": "integer"
+                },
+                "delivered": {
+                    "type": "integer"
+                },
+                "delivery_rate": {
+                    "type": "number"
+                },
+                "open_rate": {
+                    "type": "number"
+                },
+                "opened": {
+                    "type": "integer"
+                },
+                "sent": {
+                    "type": "integer"
+                },
+                "unsubscribe_rate": {
+                    "type": "number"
+                },
+                "unsubscribed": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Invitation": {
+            "type": "object",
+            "properties": {
+                "accepted_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "invited_by": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.InvitationRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LintWarning": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LocaleAudience": {
+            "type": "object",
+            "properties": {
+                "customers": {
+                    "type": "integer"
+                },
+                "locale": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LocalizationRevision": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "description": "CreatedBy is the user who saved the revision, when known",
+                    "type": "integer"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "email_template_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "locale": {
+                    "type": "string"
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "template_version": {
+                    "description": "TemplateVersion is the template's version when the revision was saved",
+                    "type": "integer"
+                },
+                "text_body": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LoginAttempt": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip_address": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Partial": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "text_body": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PostEventRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "customer_id": {
+                    "type": "integer"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "properties": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "models.QueuedEmail": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "description": "Attachments is the JSON list of QueuedAttachments the email carries",
+                    "type": "string"
+                },
+                "attempts": {
+                    "type": "integer"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "domain": {
+                    "type": "string"
+                },
+                "email_log_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_attempt_at": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "next_attempt": {
+                    "type": "string"
+                },
+                "sender_identity_id": {
+                    "type": "integer"
+                },
+                "sent_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "text_body": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "unsubscribe_url": {
+                    "description": "UnsubscribeURL is the email's List-Unsubscribe link",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RateUsage": {
+            "type": "object",
+            "properties": {
+                "per_day": {
+                    "type": "integer"
+                },
+                "per_hour": {
+                    "type": "integer"
+                },
+                "per_minute": {
+                    "type": "integer"
+                },
+                "sent_last_day": {
+                    "type": "integer"
+                },
+                "sent_last_hour": {
+                    "type": "integer"
+                },
+                "sent_last_minute": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.RecoveryCodesResponse": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.ResetPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SendQueueStatus": {
+            "type": "object",
+            "properties": {
+                "dead": {
+                    "type": "integer"
+                },
+                "domains": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DomainQueueStatus"
+                    }
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "global": {
+                    "$ref": "#/definitions/models.RateUsage"
+                },
+                "oldest_queued_at": {
+                    "type": "string"
+                },
+                "queued": {
+                    "type": "integer"
+                },
+                "warmups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SenderWarmup"
+                    }
+                }
+            }
+        },
+        "models.SenderIdentity": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "daily_cap": {
+                    "description": "DailyCap limits the emails sent from the identity in any 24 hours; zero\nmeans no limit",
+                    "type": "integer"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "from_email": {
+                    "type": "string"
+                },
+                "from_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "in_rotation": {
+                    "description": "InRotation identities share the customers of campaigns that rotate\nsenders, in turn",
+                    "type": "boolean"
+                },
+                "last_assigned_at": {
+                    "type": "string"
+                },
+                "reply_to": {
+                    "type": "string"
+                },
+                "signature": {
+                    "description": "Signature is available to templates as {{signature}}",
+                    "type": "string"
+                },
+                "smtp_host": {
+                    "type": "string"
+                },
+                "smtp_password": {
+                    "type": "string"
+                },
+                "smtp_port": {
+                    "type": "integer"
+                },
+                "smtp_username": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "UserID is the rep who owns the mailbox, for campaigns that send as the\ncustomer's assigned rep",
+                    "type": "integer"
+                },
+                "warmup_schedule": {
+                    "type": "string"
+                },
+                "warmup_started_at": {
+                    "description": "Warm-up of a new mailbox: WarmupSchedule is a comma-separated list of\ndaily send caps, such as \"50,100,200,400\", the first applying on the day\nof WarmupStartedAt. Once the list runs out only DailyCap applies.",
+                    "type": "string"
+                }
+            }
+        },
+        "models.SenderWarmup": {
+            "type": "object",
+            "properties": {
+                "day": {
+                    "type": "integer"
+                },
+                "from_email": {
+                    "type": "string"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "sender_identity_id": {
+                    "type": "integer"
+                },
+                "sent_last_day": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Settings": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "crm_api_key": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "email_polling_seconds": {
+                    "type": "integer"
+                },
+                "gmail_email": {
+                    "type": "string"
+                },
+                "gmail_password": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Stage": {
+            "type": "object",
+            "properties": {
+                "campaign_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "order": {
+                    "type": "integer"
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Step"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.StageStats": {
+            "type": "object",
+            "properties": {
+                "bounce_rate": {
+                    "type": "number"
+                },
+                "bounced": {
+                    "type": "integer"
+                },
+                "click_rate": {
+                    "type": "number"
+                },
+                "click_to_open_rate": {
+                    "type": "number"
+                },
+                "clicked": {
+                    "type": "integer"
+                },
+                "delivered": {
+                    "type": "integer"
+                },
+                "delivery_rate": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "open_rate": {
+                    "type": "number"
+                },
+                "opened": {
+                    "type": "integer"
+                },
+                "order": {
+                    "type": "integer"
+                },
+                "sent": {
+                    "type": "integer"
+                },
+                "stage_id": {
+                    "type": "integer"
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StepStats"
+                    }
+                },
+                "unsubscribe_rate": {
+                    "type": "number"
+                },
+                "unsubscribed": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.StatsBucket": {
+            "type": "object",
+            "properties": {
+                "bounce_rate": {
+                    "type": "number"
+                },
+                "bounced": {
+                    "type": "integer"
+                },
+                "click_rate": {
+                    "type": "number"
+                },
+                "click_to_open_rate": {
+                    "type": "number"
+                },
+                "clicked": {
+                    "type": "integer"
+                },
+                "delivered": {
+                    "type": "integer"
+                },
+                "delivery_rate": {
+                    "type": "number"
+                },
+                "open_rate": {
+                    "type": "number"
+                },
+                "opened": {
+                    "type": "integer"
+                },
+                "sent": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "unsubscribe_rate": {
+                    "type": "number"
+                },
+                "unsubscribed": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Step": {
+            "type": "object",
+            "properties": {
+                "ab_auto_promote": {
+                    "type": "boolean"
+                },
+                "ab_confidence": {
+                    "type": "number"
+                },
+                "ab_metric": {
+                    "type": "string"
+                },
+                "ab_sample_size": {
+                    "type": "integer"
+                },
+                "ab_test_hours": {
+                    "type": "integer"
+                },
+                "condition": {
+                    "description": "Condition checked by condition, wait-until and goal steps. Opened and\nclicked look at ConditionStepID's email, or the customer's latest one.",
+                    "type": "string"
+                },
+                "condition_field": {
+                    "type": "string"
+                },
+                "condition_operator": {
+                    "type": "string"
+                },
+                "condition_step_id": {
+                    "type": "integer"
+                },
+                "condition_value": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "email_template": {
+                    "$ref": "#/definitions/models.EmailTemplate"
+                },
+                "email_template_id": {
+                    "type": "integer"
+                },
+                "email_template_version": {
+                    "description": "EmailTemplateVersion pins the step to a version of its template; zero\nsends the template's current version",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "next_step_id": {
+                    "type": "integer"
+                },
+                "no_step_id": {
+                    "type": "integer"
+                },
+                "promoted_at": {
+                    "type": "string"
+                },
+                "sender_identity_id": {
+                    "description": "SenderIdentityID overrides the campaign's sender for this step",
+                    "type": "integer"
+                },
+                "stage_id": {
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "Flow fields. Email steps continue to NextStepID, condition steps to\nYesStepID or NoStepID. Wait-until steps wait up to WaitTime for their\ncondition and go to NoStepID on timeout. Goal steps aren't part of the\npath; customers who meet their condition leave the campaign. A zero edge\nends the flow, or in campaigns without an EntryStepID continues with the\nnext step in stage order.",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "variants": {
+                    "description": "A/B test. When a step has variants each recipient gets one of them\ninstead of EmailTemplateID. With ABAutoPromote the leader on ABMetric is\npromoted to WinningVariantID, and sent to everyone after that, once every\nvariant has ABSampleSize sends or ABTestHours have passed since the first\nsend, provided the difference is significant at ABConfidence.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StepVariant"
+                    }
+                },
+                "wait_time": {
+                    "type": "integer"
+                },
+                "wait_unit": {
+                    "description": "WaitUnit is the unit of WaitTime: seconds (the default), minutes, hours,\ndays or business_days",
+                    "type": "string"
+                },
+                "winning_variant_id": {
+                    "type": "integer"
+                },
+                "yes_step_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.StepStats": {
+            "type": "object",
+            "properties": {
+                "bounce_rate": {
+                    "type": "number"
+                },
+                "bounced": {
+                    "type": "integer"
+                },
+                "click_rate": {
+                    "type": "number"
+                },
+                "click_to_open_rate": {
+                    "type": "number"
+                },
+                "clicked": {
+                    "type": "integer"
+                },
+                "delivered": {
+                    "type": "integer"
+                },
+                "delivery_rate": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "open_rate": {
+                    "type": "number"
+                },
+                "opened": {
+                    "type": "integer"
+                },
+                "sent": {
+                    "type": "integer"
+                },
+                "step_id": {
+                    "type": "integer"
+                },
+                "unsubscribe_rate": {
+                    "type": "number"
+                },
+                "unsubscribed": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.StepVariant": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "email_template": {
+                    "$ref": "#/definitions/models.EmailTemplate"
+                },
+                "email_template_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "step_id": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "weight": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TemplateDiff": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "changed": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "content_type": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "email_template_id": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "integer"
+                },
+                "layout_id": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "subject": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "text_body": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "to": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.TemplateLint": {
+            "type": "object",
+            "properties": {
+                "size_bytes": {
+                    "type": "integer"
                 },
-                "phone": {
-                    "type": "string"
+                "template_id": {
+                    "type": "integer"
                 },
-                "updated_at": {
-                    "type": "string"
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LintWarning"
+                    }
                 }
             }
         },
-        "models.DripCampaign": {
+        "models.TemplateLocalization": {
             "type": "object",
             "properties": {
-                "created_at": {
+                "body": {
                     "type": "string"
                 },
-                "deleted_at": {
+                "created_at": {
                     "type": "string"
                 },
-                "description": {
+                "deleted_at": {
                     "type": "string"
                 },
-                "end_date": {
-                    "type": "string"
+                "email_template_id": {
+                    "type": "integer"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "name": {
+                "locale": {
                     "type": "string"
                 },
-                "stages": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.Stage"
-                    }
+                "revision": {
+                    "description": "Revision is the number of the localization's current LocalizationRevision",
+                    "type": "integer"
                 },
-                "start_date": {
+                "subject": {
                     "type": "string"
                 },
-                "status": {
+                "text_body": {
                     "type": "string"
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "warnings": {
+                    "description": "Warnings are the linter's findings, returned when the localization is saved",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LintWarning"
+                    }
                 }
             }
         },
-        "models.EmailRequest": {
+        "models.TemplateTranslations": {
             "type": "object",
             "properties": {
-                "body": {
-                    "type": "string"
+                "email_template_id": {
+                    "type": "integer"
                 },
-                "subject": {
-                    "type": "string"
+                "missing": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LocaleAudience"
+                    }
                 },
-                "to": {
+                "name": {
                     "type": "string"
+                },
+                "step_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "translated": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "models.EmailTemplate": {
+        "models.TemplateVersion": {
             "type": "object",
             "properties": {
                 "body": {
@@ -1499,156 +7261,283 @@ const docTemplate = `{
                 "created_at": {
                     "type": "string"
                 },
+                "created_by": {
+                    "description": "CreatedBy is the user who saved the version, when known",
+                    "type": "integer"
+                },
                 "deleted_at": {
                     "type": "string"
                 },
+                "email_template_id": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "integer"
                 },
+                "layout_id": {
+                    "type": "integer"
+                },
                 "name": {
                     "type": "string"
                 },
+                "restored_from": {
+                    "description": "RestoredFrom is the version this one copies, for restores",
+                    "type": "integer"
+                },
                 "subject": {
                     "type": "string"
                 },
+                "text_body": {
+                    "type": "string"
+                },
                 "updated_at": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer"
                 }
             }
         },
-        "models.ErrorResponse": {
+        "models.TimelineEntry": {
             "type": "object",
             "properties": {
-                "error": {
+                "at": {
+                    "type": "string"
+                },
+                "bot": {
+                    "type": "boolean"
+                },
+                "campaign_id": {
+                    "type": "integer"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "email_log_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "step_id": {
+                    "type": "integer"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "url": {
                     "type": "string"
                 }
             }
         },
-        "models.LoginRequest": {
+        "models.TokenResponse": {
             "type": "object",
             "properties": {
-                "email": {
-                    "type": "string"
-                },
-                "password": {
+                "token": {
                     "type": "string"
                 }
             }
         },
-        "models.Settings": {
+        "models.TranslationReport": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "type": "string"
+                "campaign_id": {
+                    "type": "integer"
                 },
-                "crm_api_key": {
-                    "type": "string"
+                "complete": {
+                    "type": "boolean"
                 },
-                "deleted_at": {
+                "default_locale": {
                     "type": "string"
                 },
-                "email_polling_seconds": {
-                    "type": "integer"
-                },
-                "gmail_email": {
-                    "type": "string"
+                "locales": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LocaleAudience"
+                    }
                 },
-                "gmail_password": {
+                "templates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TemplateTranslations"
+                    }
+                }
+            }
+        },
+        "models.TwoFactorChallengeResponse": {
+            "type": "object",
+            "properties": {
+                "challenge_token": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
+                "setup_required": {
+                    "type": "boolean"
                 },
-                "updated_at": {
+                "two_factor_required": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.TwoFactorCodeRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
                     "type": "string"
-                },
-                "user_id": {
-                    "type": "integer"
                 }
             }
         },
-        "models.Stage": {
+        "models.TwoFactorDisableRequest": {
             "type": "object",
             "properties": {
-                "campaign_id": {
-                    "type": "integer"
-                },
-                "created_at": {
+                "code": {
                     "type": "string"
                 },
-                "deleted_at": {
+                "password": {
                     "type": "string"
-                },
-                "description": {
+                }
+            }
+        },
+        "models.TwoFactorEnrollmentResponse": {
+            "type": "object",
+            "properties": {
+                "secret": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
-                },
-                "name": {
+                "uri": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TwoFactorLoginRequest": {
+            "type": "object",
+            "properties": {
+                "challenge_token": {
                     "type": "string"
                 },
-                "order": {
-                    "type": "integer"
+                "code": {
+                    "type": "string"
                 },
-                "steps": {
+                "recovery_code": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TwoFactorRequirementRequest": {
+            "type": "object",
+            "properties": {
+                "required": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.TwoFactorSetupRequest": {
+            "type": "object",
+            "properties": {
+                "challenge_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TwoFactorTokenResponse": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.Step"
+                        "type": "string"
                     }
                 },
-                "updated_at": {
+                "token": {
                     "type": "string"
                 }
             }
         },
-        "models.Step": {
+        "models.UpdateDKIMKeyRequest": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "type": "string"
+                "disabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.VariantStats": {
+            "type": "object",
+            "properties": {
+                "click_rate": {
+                    "type": "number"
                 },
-                "deleted_at": {
-                    "type": "string"
+                "clicked": {
+                    "type": "integer"
                 },
-                "description": {
+                "email_template_id": {
+                    "type": "integer"
+                },
+                "name": {
                     "type": "string"
                 },
-                "email_template": {
-                    "$ref": "#/definitions/models.EmailTemplate"
+                "open_rate": {
+                    "type": "number"
                 },
-                "email_template_id": {
+                "opened": {
                     "type": "integer"
                 },
-                "id": {
+                "replied": {
                     "type": "integer"
                 },
-                "name": {
-                    "type": "string"
+                "reply_rate": {
+                    "type": "number"
                 },
-                "stage_id": {
+                "sent": {
                     "type": "integer"
                 },
-                "updated_at": {
-                    "type": "string"
+                "variant_id": {
+                    "type": "integer"
                 },
-                "wait_time": {
+                "weight": {
                     "type": "integer"
                 }
             }
         },
-        "models.SuccessResponse": {
+        "reply.Result": {
             "type": "object",
             "properties": {
-                "message": {
+                "action": {
                     "type": "string"
-                }
-            }
-        },
-        "models.TokenResponse": {
-            "type": "object",
-            "properties": {
-                "token": {
+                },
+                "auto": {
+                    "type": "boolean"
+                },
+                "campaign_id": {
+                    "type": "integer"
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "email_log_id": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "matched": {
+                    "type": "boolean"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "references": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "subject": {
                     "type": "string"
                 }
             }
@@ -1666,6 +7555,8 @@ var SwaggerInfo = &swag.Spec{
 	Description:      "API documentation for the Drip Campaign application.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
+	LeftDelim:        "{{",
+	RightDelim:       "}}",
 }
 
 func init() {
@@ -0,0 +1,319 @@
+// Package engine sends the steps of active drip campaigns to enrolled customers.
+package engine
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/abtest"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/flow"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/schedule"
+	"github.com/4cecoder/drip-campaign/triggers"
+)
+
+const (
+	// retryDelay is how long to wait before retrying a step that failed to send
+	retryDelay = 15 * time.Minute
+
+	// waitCheckInterval is how often a wait-until step's condition is checked
+	waitCheckInterval = time.Minute
+
+	// claimTimeout is how long an enrollment stays claimed by the instance
+	// advancing it. If that instance dies the enrollment is picked up again
+	// once it runs out.
+	claimTimeout = 10 * time.Minute
+)
+
+// Start runs the engine every interval. It blocks, so call it in a goroutine.
+func Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := RunOnce(time.Now()); err != nil {
+			log.Println("Error running drip engine:", err)
+		}
+		<-ticker.C
+	}
+}
+
+// RunOnce enrolls customers whose triggers fired, exits enrollments whose
+// customers met a goal and promotes A/B test winners, then advances every
+// active enrollment in an active campaign that has a step due.
+//
+// Each enrollment is claimed before it's advanced, so several instances can
+// run the engine without sending a step twice.
+func RunOnce(now time.Time) error {
+	if err := triggers.RunOnce(now); err != nil {
+		log.Println("Error processing campaign triggers:", err)
+	}
+	if err := checkGoals(now); err != nil {
+		log.Println("Error checking campaign goals:", err)
+	}
+	if err := abtest.PromoteWinners(now); err != nil {
+		log.Println("Error checking A/B tests:", err)
+	}
+
+	var enrollments []models.CampaignCustomer
+	err := database.DB.
+		Select("campaign_customers.*").
+		Joins("JOIN drip_campaigns ON drip_campaigns.id = campaign_customers.campaign_id AND drip_campaigns.deleted_at IS NULL").
+		Where("drip_campaigns.status = ?", models.CampaignStatusActive).
+		Where("campaign_customers.status IN (?)", []string{"", models.EnrollmentActive}).
+		Where("campaign_customers.next_step_at IS NULL OR campaign_customers.next_step_at <= ?", now).
+		Find(&enrollments).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range enrollments {
+		claimed, err := claim(&enrollments[i], now)
+		if err != nil {
+			log.Printf("Error claiming campaign customer %d: %v", enrollments[i].ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if err := advance(&enrollments[i], now); err != nil {
+			log.Printf("Error advancing campaign customer %d: %v", enrollments[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// advance runs every step of the enrollment that is due and schedules the next one
+func advance(enrollment *models.CampaignCustomer, now time.Time) error {
+	var campaign models.DripCampaign
+	if err := database.DB.First(&campaign, enrollment.CampaignID).Error; err != nil {
+		return err
+	}
+
+	var customer models.Customer
+	if err := database.DB.First(&customer, enrollment.CustomerID).Error; err != nil {
+		// The customer was deleted, so there is nobody left to email
+		enrollment.Status = models.EnrollmentExited
+		return saveProgress(enrollment)
+	}
+	if customer.OptedOut() {
+		enrollment.Status = models.EnrollmentExited
+		enrollment.EndDate = now
+		return saveProgress(enrollment)
+	}
+
+	steps, err := CampaignSteps(campaign.ID)
+	if err != nil {
+		return err
+	}
+	graph := flow.New(campaign.EntryStepID, steps)
+	at := timing{loc: schedule.Location(&customer, &campaign)}
+	if at.window, err = schedule.CampaignWindow(&campaign); err != nil {
+		log.Printf("Ignoring the sending window of campaign %d: %v", campaign.ID, err)
+		at.window = schedule.Window{}
+	}
+
+	// Condition steps run back to back without waiting, so a cycle that got
+	// past validation could otherwise spin forever
+	for hops := 0; hops <= len(steps); hops++ {
+		if enrollment.NextStepID == 0 {
+			next := graph.Start()
+			if enrollment.CurrentStepID != 0 {
+				next = 0
+				if current := graph.Step(enrollment.CurrentStepID); current != nil {
+					next = graph.Next(current, false)
+				}
+			}
+			if !scheduleStep(enrollment, graph, next, now, at) {
+				return saveProgress(enrollment)
+			}
+		}
+
+		if enrollment.NextStepAt.After(now) {
+			return saveProgress(enrollment)
+		}
+
+		step := graph.Step(enrollment.NextStepID)
+		if step == nil || step.Kind() == models.StepGoal {
+			// The scheduled step was deleted, pick the next one again
+			enrollment.NextStepID = 0
+			continue
+		}
+
+		switch step.Kind() {
+		case models.StepCondition:
+			met, err := flow.Evaluate(step, enrollment, &customer)
+			if err != nil {
+				return err
+			}
+			ran(enrollment, step, now)
+			if !scheduleStep(enrollment, graph, graph.Next(step, met), now, at) {
+				return saveProgress(enrollment)
+			}
+
+		case models.StepWaitUntil:
+			if enrollment.WaitDeadline == nil {
+				deadline := schedule.Delay(now, step.WaitTime, step.WaitUnit, at.loc)
+				enrollment.WaitDeadline = &deadline
+			}
+			met, err := flow.Evaluate(step, enrollment, &customer)
+			if err != nil {
+				return err
+			}
+			if !met && now.Before(*enrollment.WaitDeadline) {
+				check := now.Add(waitCheckInterval)
+				if check.After(*enrollment.WaitDeadline) {
+					check = *enrollment.WaitDeadline
+				}
+				enrollment.NextStepAt = &check
+				return saveProgress(enrollment)
+			}
+			ran(enrollment, step, now)
+			if !scheduleStep(enrollment, graph, graph.Next(step, met), now, at) {
+				return saveProgress(enrollment)
+			}
+
+		default:
+			// Outside the sending window the email waits for the next opening
+			if open := at.window.Next(now, at.loc); open.After(now) {
+				enrollment.NextStepAt = &open
+				return saveProgress(enrollment)
+			}
+			if _, err := SendStep(&campaign, enrollment, &customer, step); err != nil {
+				retry := now.Add(retryDelay)
+				enrollment.NextStepAt = &retry
+				if saveErr := saveProgress(enrollment); saveErr != nil {
+					log.Println("Error saving campaign customer progress:", saveErr)
+				}
+				return err
+			}
+			ran(enrollment, step, now)
+			enrollment.NextStepID = 0
+			enrollment.NextStepAt = nil
+		}
+	}
+
+	return fmt.Errorf("campaign %d has a cycle of condition steps", campaign.ID)
+}
+
+// timing is where an enrollment's customer is and when their campaign may
+// email them
+type timing struct {
+	loc    *time.Location
+	window schedule.Window
+}
+
+// scheduleStep makes id the enrollment's next step, counting its wait from
+// the last step and moving emails into the campaign's sending window. It
+// completes the enrollment and returns false when id is zero.
+func scheduleStep(enrollment *models.CampaignCustomer, graph *flow.Graph, id uint, now time.Time, at timing) bool {
+	step := graph.Step(id)
+	if step == nil {
+		enrollment.Status = models.EnrollmentCompleted
+		enrollment.EndDate = now
+		enrollment.NextStepID = 0
+		enrollment.NextStepAt = nil
+		return false
+	}
+
+	base := scheduleBase(enrollment)
+	enrollment.NextStepID = step.ID
+	enrollment.NextStepAt = &base
+	enrollment.WaitDeadline = nil
+	switch step.Kind() {
+	case models.StepWaitUntil:
+		// Wait-until steps start checking right away; their wait is the timeout
+		deadline := schedule.Delay(base, step.WaitTime, step.WaitUnit, at.loc)
+		enrollment.WaitDeadline = &deadline
+	case models.StepEmail:
+		due := at.window.Next(schedule.Delay(base, step.WaitTime, step.WaitUnit, at.loc), at.loc)
+		enrollment.NextStepAt = &due
+	default:
+		due := schedule.Delay(base, step.WaitTime, step.WaitUnit, at.loc)
+		enrollment.NextStepAt = &due
+	}
+	return true
+}
+
+// ran records that the enrollment has been through step
+func ran(enrollment *models.CampaignCustomer, step *models.Step, now time.Time) {
+	ranAt := now
+	enrollment.Status = models.EnrollmentActive
+	enrollment.CurrentStepID = step.ID
+	enrollment.LastStepAt = &ranAt
+	enrollment.WaitDeadline = nil
+}
+
+// CampaignSteps returns the steps of a campaign in send order: by stage order,
+// then by step creation within a stage
+func CampaignSteps(campaignID uint) ([]models.Step, error) {
+	var stages []models.Stage
+	if err := database.DB.Where("campaign_id = ?", campaignID).Order(`"order" asc, id asc`).Find(&stages).Error; err != nil {
+		return nil, err
+	}
+	if len(stages) == 0 {
+		return nil, nil
+	}
+
+	stageIDs := make([]uint, len(stages))
+	position := map[uint]int{}
+	for i, stage := range stages {
+		stageIDs[i] = stage.ID
+		position[stage.ID] = i
+	}
+
+	var steps []models.Step
+	if err := database.DB.Where("stage_id IN (?)", stageIDs).Preload("Variants").Find(&steps).Error; err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		if position[steps[i].StageID] != position[steps[j].StageID] {
+			return position[steps[i].StageID] < position[steps[j].StageID]
+		}
+		return steps[i].ID < steps[j].ID
+	})
+	return steps, nil
+}
+
+// scheduleBase is the time the wait of the next step counts from
+func scheduleBase(enrollment *models.CampaignCustomer) time.Time {
+	if enrollment.LastStepAt != nil {
+		return *enrollment.LastStepAt
+	}
+	if !enrollment.StartDate.IsZero() {
+		return enrollment.StartDate
+	}
+	return enrollment.CreatedAt
+}
+
+// claim pushes a due enrollment's next_step_at past claimTimeout so other
+// instances skip it. It reports false if another instance got there first.
+// advance saves the real schedule when it's done; if it fails the enrollment
+// is retried once the claim runs out.
+func claim(enrollment *models.CampaignCustomer, now time.Time) (bool, error) {
+	result := database.DB.Model(&models.CampaignCustomer{}).
+		Where("id = ? AND status IN (?)", enrollment.ID, []string{"", models.EnrollmentActive}).
+		Where("next_step_at IS NULL OR next_step_at <= ?", now).
+		UpdateColumn("next_step_at", now.Add(claimTimeout))
+	return result.RowsAffected == 1, result.Error
+}
+
+// saveProgress writes only the columns the engine owns, and only while the
+// enrollment is still active, so a pause or exit made through the API in the
+// meantime isn't overwritten
+func saveProgress(enrollment *models.CampaignCustomer) error {
+	return database.DB.Model(enrollment).
+		Where("status IN (?)", []string{"", models.EnrollmentActive}).
+		UpdateColumns(map[string]interface{}{
+			"status":          enrollment.Status,
+			"end_date":        enrollment.EndDate,
+			"current_step_id": enrollment.CurrentStepID,
+			"last_step_at":    enrollment.LastStepAt,
+			"next_step_id":    enrollment.NextStepID,
+			"next_step_at":    enrollment.NextStepAt,
+			"wait_deadline":   enrollment.WaitDeadline,
+		}).Error
+}
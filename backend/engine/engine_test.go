@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+func TestClaim(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.CampaignCustomer{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		db.Close()
+	})
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	due, later := now.Add(-time.Minute), now.Add(time.Hour)
+	tests := []struct {
+		name       string
+		status     string
+		nextStepAt *time.Time
+		want       bool
+	}{
+		{"due", models.EnrollmentActive, &due, true},
+		{"just enrolled", "", nil, true},
+		{"not due", models.EnrollmentActive, &later, false},
+		{"paused", models.EnrollmentPaused, &due, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enrollment := models.CampaignCustomer{CampaignID: 1, CustomerID: 7, Status: tt.status, NextStepAt: tt.nextStepAt}
+			db.Create(&enrollment)
+
+			claimed, err := claim(&enrollment, now)
+			if err != nil {
+				t.Fatalf("claim: %v", err)
+			}
+			if claimed != tt.want {
+				t.Fatalf("claimed = %v, want %v", claimed, tt.want)
+			}
+			if !claimed {
+				return
+			}
+
+			// A second instance loaded the same row before the claim
+			if claimed, _ := claim(&enrollment, now); claimed {
+				t.Error("enrollment was claimed twice")
+			}
+			// The claim runs out if the instance never saves its progress
+			if claimed, _ := claim(&enrollment, now.Add(claimTimeout)); !claimed {
+				t.Error("enrollment wasn't claimed again after the claim timed out")
+			}
+		})
+	}
+}
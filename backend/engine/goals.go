@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"log"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/flow"
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+// checkGoals completes the enrollments of customers who met one of their
+// campaign's goals, wherever they are in the flow
+func checkGoals(now time.Time) error {
+	var goals []models.Step
+	err := database.DB.
+		Select("steps.*").
+		Joins("JOIN stages ON stages.id = steps.stage_id AND stages.deleted_at IS NULL").
+		Joins("JOIN drip_campaigns ON drip_campaigns.id = stages.campaign_id AND drip_campaigns.deleted_at IS NULL").
+		Where("drip_campaigns.status = ? AND steps.type = ?", models.CampaignStatusActive, models.StepGoal).
+		Find(&goals).Error
+	if err != nil || len(goals) == 0 {
+		return err
+	}
+
+	byCampaign := map[uint][]models.Step{}
+	for _, goal := range goals {
+		var stage models.Stage
+		if err := database.DB.First(&stage, goal.StageID).Error; err != nil {
+			return err
+		}
+		byCampaign[stage.CampaignID] = append(byCampaign[stage.CampaignID], goal)
+	}
+
+	for campaignID, campaignGoals := range byCampaign {
+		var enrollments []models.CampaignCustomer
+		if err := database.DB.
+			Where("campaign_id = ? AND status IN (?)", campaignID, []string{"", models.EnrollmentActive}).
+			Find(&enrollments).Error; err != nil {
+			return err
+		}
+
+		for i := range enrollments {
+			if err := checkEnrollmentGoals(&enrollments[i], campaignGoals, now); err != nil {
+				log.Printf("Error checking goals of campaign customer %d: %v", enrollments[i].ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkEnrollmentGoals(enrollment *models.CampaignCustomer, goals []models.Step, now time.Time) error {
+	var customer models.Customer
+	if err := database.DB.First(&customer, enrollment.CustomerID).Error; err != nil {
+		// advance exits enrollments of deleted customers
+		return nil
+	}
+
+	for i := range goals {
+		met, err := flow.Evaluate(&goals[i], enrollment, &customer)
+		if err != nil {
+			return err
+		}
+		if !met {
+			continue
+		}
+
+		ran(enrollment, &goals[i], now)
+		enrollment.Status = models.EnrollmentCompleted
+		enrollment.EndDate = now
+		enrollment.NextStepID = 0
+		enrollment.NextStepAt = nil
+		return saveProgress(enrollment)
+	}
+	return nil
+}
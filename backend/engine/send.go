@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/4cecoder/drip-campaign/abtest"
+	"github.com/4cecoder/drip-campaign/assets"
+	"github.com/4cecoder/drip-campaign/config"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/locales"
+	"github.com/4cecoder/drip-campaign/mailer"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/render"
+	"github.com/4cecoder/drip-campaign/senders"
+	"github.com/4cecoder/drip-campaign/sendqueue"
+	"github.com/4cecoder/drip-campaign/templates"
+	"github.com/4cecoder/drip-campaign/tracking"
+)
+
+// SendStep renders the step's template for the customer, records it in the
+// email log and queues it for sending from the chosen sender identity
+func SendStep(campaign *models.DripCampaign, enrollment *models.CampaignCustomer, customer *models.Customer, step *models.Step) (*models.EmailLog, error) {
+	variant, err := abtest.Choose(step, customer.ID)
+	if err != nil {
+		return nil, err
+	}
+	templateID, pinned := step.EmailTemplateID, step.EmailTemplateVersion
+	var variantID uint
+	if variant != nil {
+		templateID, pinned = variant.EmailTemplateID, 0
+		variantID = variant.ID
+	}
+
+	template, err := templates.ForStep(templateID, pinned)
+	if err != nil {
+		return nil, err
+	}
+	// Customers are sent the template in their language when it's translated,
+	// as the translation was at the version sent
+	template, locale, localeRevision, err := locales.Localize(template, locales.ForCustomer(customer, config.LoadConfig().DefaultLocale))
+	if err != nil {
+		return nil, err
+	}
+	// Layouts and partials are included as they are now, not as they were
+	// when the template was saved
+	expanded, err := templates.Prepare(template)
+	if err != nil {
+		return nil, err
+	}
+
+	senderID, err := senders.Choose(campaign, step, enrollment, customer)
+	if err != nil {
+		return nil, err
+	}
+	var sender *models.SenderIdentity
+	if senderID != 0 {
+		sender = &models.SenderIdentity{}
+		if err := database.DB.First(sender, senderID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	attachments, err := assets.ForEmail(template.ID, step.ID, render.IsHTML(expanded.ContentType))
+	if err != nil {
+		return nil, err
+	}
+	if limit := config.LoadConfig().AttachmentMaxBytes; limit > 0 && assets.TotalSize(attachments) > limit {
+		return nil, fmt.Errorf("the attachments of template %d and step %d are over the %d byte limit", template.ID, step.ID, limit)
+	}
+
+	// The log is created first because the email's unsubscribe link and
+	// tracking links identify it
+	emailLog := models.EmailLog{
+		CampaignID:      campaign.ID,
+		CustomerID:      customer.ID,
+		EmailTemplateID: template.ID,
+		StepID:          step.ID,
+		VariantID:       variantID,
+		Status:          models.EmailStatusPending,
+
+		SenderIdentityID: senderID,
+		TemplateVersion:  template.Version,
+		Locale:           locale,
+		LocaleRevision:   localeRevision,
+	}
+	if err := database.DB.Create(&emailLog).Error; err != nil {
+		return nil, err
+	}
+
+	unsubscribeURL := tracking.UnsubscribeURL(emailLog.ID)
+	email := render.Template(expanded, customer, sender, unsubscribeURL)
+	if len(email.Missing) > 0 {
+		log.Printf("Template %d is missing merge fields %v for customer %d", template.ID, email.Missing, customer.ID)
+	}
+	emailLog.Subject = email.Subject
+	emailLog.Body = email.Body
+
+	if render.IsHTML(email.ContentType) {
+		emailLog.Body = tracking.RewriteLinks(emailLog.Body, campaign, emailLog.ID)
+		if !campaign.TrackingDisabled {
+			emailLog.Body = tracking.InjectPixel(emailLog.Body, tracking.OpenPixelURL(emailLog.ID))
+		}
+	}
+
+	if err := database.DB.Model(&emailLog).UpdateColumns(map[string]interface{}{
+		"subject": emailLog.Subject,
+		"body":    emailLog.Body,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	// The queue sends the email within the rate limits and marks the log sent
+	if _, err := sendqueue.Enqueue(mailer.Message{
+		To:          customer.Email,
+		Subject:     emailLog.Subject,
+		Body:        emailLog.Body,
+		TextBody:    email.TextBody,
+		ContentType: email.ContentType,
+
+		SenderIdentityID: senderID,
+		UnsubscribeURL:   unsubscribeURL,
+	}, emailLog.ID, attachments); err != nil {
+		emailLog.Status = models.EmailStatusFailed
+		if updateErr := database.DB.Model(&emailLog).UpdateColumn("status", emailLog.Status).Error; updateErr != nil {
+			log.Println("Error updating email log:", updateErr)
+		}
+		return &emailLog, err
+	}
+	return &emailLog, nil
+}
@@ -0,0 +1,159 @@
+package flow
+
+import (
+	"strings"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+// customerFields are the customer fields field conditions can test, by JSON name
+var customerFields = map[string]func(*models.Customer) string{
+	"email":       func(c *models.Customer) string { return c.Email },
+	"first_name":  func(c *models.Customer) string { return c.FirstName },
+	"last_name":   func(c *models.Customer) string { return c.LastName },
+	"phone":       func(c *models.Customer) string { return c.Phone },
+	"company":     func(c *models.Customer) string { return c.Company },
+	"address":     func(c *models.Customer) string { return c.Address },
+	"city":        func(c *models.Customer) string { return c.City },
+	"state":       func(c *models.Customer) string { return c.State },
+	"country":     func(c *models.Customer) string { return c.Country },
+	"postal_code": func(c *models.Customer) string { return c.PostalCode },
+	"notes":       func(c *models.Customer) string { return c.Notes },
+	"tags":        func(c *models.Customer) string { return c.Tags },
+	"lead_source": func(c *models.Customer) string { return c.LeadSource },
+	"lead_status": func(c *models.Customer) string { return c.LeadStatus },
+}
+
+// ValidateStep checks a step's type and condition on their own, without
+// looking at the rest of the graph
+func ValidateStep(step *models.Step) []string {
+	var errs []string
+	switch step.Kind() {
+	case models.StepEmail:
+		if step.EmailTemplateID == 0 && len(step.Variants) == 0 {
+			errs = append(errs, "email steps need an email_template_id or variants")
+		}
+		return errs
+	case models.StepWaitUntil:
+		if step.WaitTime <= 0 {
+			errs = append(errs, "wait_until steps need a wait_time timeout")
+		}
+	case models.StepCondition, models.StepGoal:
+	default:
+		return append(errs, "type must be email, condition, wait_until or goal")
+	}
+
+	switch step.Condition {
+	case models.ConditionOpened, models.ConditionClicked, models.ConditionReplied:
+	case models.ConditionField:
+		if customerFields[step.ConditionField] == nil {
+			errs = append(errs, "unknown condition_field "+step.ConditionField)
+		}
+	case models.ConditionTag:
+		if strings.TrimSpace(step.ConditionValue) == "" {
+			errs = append(errs, "tag conditions need a condition_value")
+		}
+	default:
+		return append(errs, "condition must be opened, clicked, replied, field or tag")
+	}
+
+	switch step.ConditionOperator {
+	case "", models.OperatorEquals, models.OperatorNotEquals, models.OperatorContains,
+		models.OperatorNotContains, models.OperatorEmpty, models.OperatorNotEmpty:
+	default:
+		errs = append(errs, "unknown condition_operator "+step.ConditionOperator)
+	}
+	return errs
+}
+
+// Evaluate reports whether the customer meets the step's condition
+func Evaluate(step *models.Step, enrollment *models.CampaignCustomer, customer *models.Customer) (bool, error) {
+	switch step.Condition {
+	case models.ConditionOpened:
+		// A click implies the email was opened, even if the pixel was blocked
+		return engaged(step, enrollment, []string{models.EventOpen, models.EventClick})
+	case models.ConditionClicked:
+		return engaged(step, enrollment, []string{models.EventClick})
+	case models.ConditionReplied:
+		var count int
+		err := database.DB.Model(&models.EngagementEvent{}).
+			Where("customer_id = ? AND campaign_id = ? AND type = ? AND occurred_at >= ?",
+				enrollment.CustomerID, enrollment.CampaignID, models.EventReply, enrollment.StartDate).
+			Count(&count).Error
+		return count > 0, err
+	case models.ConditionField:
+		get := customerFields[step.ConditionField]
+		if get == nil {
+			return false, nil
+		}
+		return compare(get(customer), step.ConditionOperator, step.ConditionValue), nil
+	case models.ConditionTag:
+		has := hasTag(customer.Tags, step.ConditionValue)
+		switch step.ConditionOperator {
+		case models.OperatorNotEquals, models.OperatorNotContains:
+			return !has, nil
+		}
+		return has, nil
+	}
+	return false, nil
+}
+
+// engaged reports whether the customer did one of the given things with the
+// condition's email: the one sent for ConditionStepID, or their latest one in
+// the campaign. Bot activity doesn't count.
+func engaged(step *models.Step, enrollment *models.CampaignCustomer, eventTypes []string) (bool, error) {
+	query := database.DB.Where("campaign_id = ? AND customer_id = ? AND status <> ?",
+		enrollment.CampaignID, enrollment.CustomerID, models.EmailStatusPending)
+	if step.ConditionStepID != 0 {
+		query = query.Where("step_id = ?", step.ConditionStepID)
+	}
+
+	var emailLogs []models.EmailLog
+	if err := query.Order("sent_at desc").Limit(1).Find(&emailLogs).Error; err != nil {
+		return false, err
+	}
+	if len(emailLogs) == 0 {
+		return false, nil
+	}
+
+	events := database.DB.Model(&models.EngagementEvent{}).
+		Where("email_log_id = ? AND type IN (?) AND bot = ?", emailLogs[0].ID, eventTypes, false)
+	if step.Condition == models.ConditionClicked && step.ConditionValue != "" {
+		events = events.Where("url LIKE ?", "%"+step.ConditionValue+"%")
+	}
+
+	var count int
+	err := events.Count(&count).Error
+	return count > 0, err
+}
+
+// compare applies a condition operator, ignoring case and surrounding space
+func compare(actual, operator, expected string) bool {
+	actual = strings.ToLower(strings.TrimSpace(actual))
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	switch operator {
+	case models.OperatorNotEquals:
+		return actual != expected
+	case models.OperatorContains:
+		return strings.Contains(actual, expected)
+	case models.OperatorNotContains:
+		return !strings.Contains(actual, expected)
+	case models.OperatorEmpty:
+		return actual == ""
+	case models.OperatorNotEmpty:
+		return actual != ""
+	}
+	return actual == expected
+}
+
+// hasTag reports whether a comma-separated tag list contains tag
+func hasTag(tags, tag string) bool {
+	tag = strings.TrimSpace(tag)
+	for _, t := range strings.Split(tags, ",") {
+		if strings.EqualFold(strings.TrimSpace(t), tag) {
+			return true
+		}
+	}
+	return false
+}
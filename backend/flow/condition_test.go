@@ -0,0 +1,125 @@
+package flow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+func TestEvaluateCustomer(t *testing.T) {
+	customer := &models.Customer{Email: "Ada@Example.com", Company: "  Analytical Engines ", Tags: "vip, Early Adopter,beta"}
+	field := func(name, operator, value string) models.Step {
+		return models.Step{Condition: models.ConditionField, ConditionField: name, ConditionOperator: operator, ConditionValue: value}
+	}
+	tag := func(operator, value string) models.Step {
+		return models.Step{Condition: models.ConditionTag, ConditionOperator: operator, ConditionValue: value}
+	}
+
+	tests := []struct {
+		name string
+		step models.Step
+		met  bool
+	}{
+		{"equals ignores case", field("email", "", "ada@example.com"), true},
+		{"equals ignores surrounding space", field("company", models.OperatorEquals, "analytical engines"), true},
+		{"equals", field("company", models.OperatorEquals, "Analytical"), false},
+		{"not equals", field("company", models.OperatorNotEquals, "Difference Engines"), true},
+		{"contains", field("email", models.OperatorContains, "@EXAMPLE"), true},
+		{"not contains", field("email", models.OperatorNotContains, "@example"), false},
+		{"empty", field("phone", models.OperatorEmpty, ""), true},
+		{"not empty", field("phone", models.OperatorNotEmpty, ""), false},
+		{"unknown field", field("password", models.OperatorEmpty, ""), false},
+		{"has tag", tag("", "early adopter"), true},
+		{"has tag is a whole tag", tag(models.OperatorContains, "early"), false},
+		{"doesn't have tag", tag(models.OperatorNotEquals, "churned"), true},
+		{"doesn't have tag but does", tag(models.OperatorNotContains, "VIP"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			met, err := Evaluate(&tt.step, &models.CampaignCustomer{}, customer)
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+			if met != tt.met {
+				t.Errorf("met = %v, want %v", met, tt.met)
+			}
+		})
+	}
+}
+
+func TestEvaluateEngagement(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.EmailLog{}, &models.EngagementEvent{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		db.Close()
+	})
+
+	start := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	enrollment := &models.CampaignCustomer{CampaignID: 1, CustomerID: 7, StartDate: start}
+
+	// Step 1's email was opened and a link in it clicked, step 2's was only
+	// opened by a bot, and step 3's is still waiting to be sent
+	logs := []models.EmailLog{
+		{CampaignID: 1, CustomerID: 7, StepID: 1, Status: models.EmailStatusSent, SentAt: start.Add(time.Hour)},
+		{CampaignID: 1, CustomerID: 7, StepID: 2, Status: models.EmailStatusSent, SentAt: start.Add(48 * time.Hour)},
+		{CampaignID: 1, CustomerID: 7, StepID: 3, Status: models.EmailStatusPending, SentAt: start.Add(96 * time.Hour)},
+	}
+	for i := range logs {
+		db.Create(&logs[i])
+	}
+	for _, event := range []models.EngagementEvent{
+		{Type: models.EventOpen, EmailLogID: logs[0].ID, CustomerID: 7, CampaignID: 1},
+		{Type: models.EventClick, EmailLogID: logs[0].ID, CustomerID: 7, CampaignID: 1, URL: "https://example.com/pricing"},
+		{Type: models.EventOpen, EmailLogID: logs[1].ID, CustomerID: 7, CampaignID: 1, Bot: true},
+		{Type: models.EventReply, CustomerID: 7, CampaignID: 1, OccurredAt: start.Add(-time.Hour)},
+	} {
+		db.Create(&event)
+	}
+
+	engagement := func(condition string, stepID uint, value string) models.Step {
+		return models.Step{Type: models.StepCondition, Condition: condition, ConditionStepID: stepID, ConditionValue: value}
+	}
+	tests := []struct {
+		name string
+		step models.Step
+		met  bool
+	}{
+		{"opened", engagement(models.ConditionOpened, 1, ""), true},
+		{"clicked", engagement(models.ConditionClicked, 1, ""), true},
+		{"clicked a matching link", engagement(models.ConditionClicked, 1, "/pricing"), true},
+		{"clicked another link", engagement(models.ConditionClicked, 1, "/signup"), false},
+		{"opened by a bot", engagement(models.ConditionOpened, 2, ""), false},
+		{"latest email", engagement(models.ConditionOpened, 0, ""), false},
+		{"never sent", engagement(models.ConditionOpened, 3, ""), false},
+		{"replied before enrolling", engagement(models.ConditionReplied, 0, ""), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			met, err := Evaluate(&tt.step, enrollment, &models.Customer{})
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+			if met != tt.met {
+				t.Errorf("met = %v, want %v", met, tt.met)
+			}
+		})
+	}
+
+	db.Create(&models.EngagementEvent{Type: models.EventReply, CustomerID: 7, CampaignID: 1, OccurredAt: start.Add(72 * time.Hour)})
+	replied := engagement(models.ConditionReplied, 0, "")
+	if met, err := Evaluate(&replied, enrollment, &models.Customer{}); err != nil || !met {
+		t.Errorf("replied = %v, %v, want true", met, err)
+	}
+}
@@ -0,0 +1,230 @@
+// Package flow models a campaign's steps as a graph: which step comes first,
+// where each step leads and whether the graph is sound.
+package flow
+
+import (
+	"fmt"
+
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+// Graph is a campaign's steps with their edges
+type Graph struct {
+	entry uint
+	steps []models.Step
+	index map[uint]int
+}
+
+// New builds the graph of a campaign. steps must be in stage order, as
+// returned by engine.CampaignSteps; entryStepID is zero for linear campaigns.
+func New(entryStepID uint, steps []models.Step) *Graph {
+	g := &Graph{entry: entryStepID, steps: steps, index: map[uint]int{}}
+	for i, step := range steps {
+		g.index[step.ID] = i
+	}
+	return g
+}
+
+// Linear reports whether the campaign runs its steps in stage order
+func (g *Graph) Linear() bool {
+	return g.entry == 0
+}
+
+// Step returns the step with the given ID, or nil
+func (g *Graph) Step(id uint) *models.Step {
+	i, ok := g.index[id]
+	if !ok {
+		return nil
+	}
+	return &g.steps[i]
+}
+
+// Goals returns the campaign's goal steps
+func (g *Graph) Goals() []models.Step {
+	var goals []models.Step
+	for _, step := range g.steps {
+		if step.Kind() == models.StepGoal {
+			goals = append(goals, step)
+		}
+	}
+	return goals
+}
+
+// Start returns the ID of the first step, or zero when there are none
+func (g *Graph) Start() uint {
+	if !g.Linear() {
+		return g.entry
+	}
+	return g.after(-1)
+}
+
+// Next returns the ID of the step that follows step, given the outcome of its
+// condition, or zero at the end of the flow. Email steps ignore the outcome.
+func (g *Graph) Next(step *models.Step, met bool) uint {
+	edge := step.NextStepID
+	switch step.Kind() {
+	case models.StepCondition, models.StepWaitUntil:
+		edge = step.NoStepID
+		if met {
+			edge = step.YesStepID
+		}
+	case models.StepGoal:
+		return 0
+	}
+
+	if edge != 0 || !g.Linear() {
+		return edge
+	}
+	i, ok := g.index[step.ID]
+	if !ok {
+		return 0
+	}
+	return g.after(i)
+}
+
+// after returns the first step in stage order after position i that's part
+// of the path, or zero
+func (g *Graph) after(i int) uint {
+	for j := i + 1; j < len(g.steps); j++ {
+		if g.steps[j].Kind() != models.StepGoal {
+			return g.steps[j].ID
+		}
+	}
+	return 0
+}
+
+// successors returns the distinct steps a step can lead to
+func (g *Graph) successors(step *models.Step) []uint {
+	var next []uint
+	for _, id := range []uint{g.Next(step, true), g.Next(step, false)} {
+		if id != 0 && (len(next) == 0 || next[0] != id) {
+			next = append(next, id)
+		}
+	}
+	return next
+}
+
+// Validate checks every step and the shape of the graph, returning a
+// description of each problem found
+func (g *Graph) Validate() []string {
+	var errs []string
+	for _, step := range g.steps {
+		for _, err := range ValidateStep(&step) {
+			errs = append(errs, fmt.Sprintf("step %d: %s", step.ID, err))
+		}
+	}
+
+	if !g.Linear() {
+		entry := g.Step(g.entry)
+		if entry == nil {
+			errs = append(errs, fmt.Sprintf("entry step %d is not part of the campaign", g.entry))
+			return errs
+		}
+		if entry.Kind() == models.StepGoal {
+			errs = append(errs, "the entry step can't be a goal")
+		}
+	}
+
+	// Edges must point at path steps of this campaign
+	valid := true
+	for _, step := range g.steps {
+		for _, edge := range []struct {
+			name string
+			id   uint
+		}{{"next_step_id", step.NextStepID}, {"yes_step_id", step.YesStepID}, {"no_step_id", step.NoStepID}} {
+			if edge.id == 0 {
+				continue
+			}
+			target := g.Step(edge.id)
+			switch {
+			case step.Kind() == models.StepGoal:
+				errs = append(errs, fmt.Sprintf("step %d: goal steps can't have a %s", step.ID, edge.name))
+			case target == nil:
+				errs = append(errs, fmt.Sprintf("step %d: %s %d is not part of the campaign", step.ID, edge.name, edge.id))
+				valid = false
+			case target.Kind() == models.StepGoal:
+				errs = append(errs, fmt.Sprintf("step %d: %s points at goal step %d", step.ID, edge.name, edge.id))
+			}
+		}
+	}
+	if !valid {
+		return errs
+	}
+
+	if cycle := g.findCycle(); cycle != nil {
+		errs = append(errs, fmt.Sprintf("steps %v form a cycle", cycle))
+	}
+
+	reachable := map[uint]bool{}
+	if start := g.Start(); start != 0 {
+		g.walk(start, reachable)
+	}
+	for _, step := range g.steps {
+		if step.Kind() != models.StepGoal && !reachable[step.ID] {
+			errs = append(errs, fmt.Sprintf("step %d is unreachable", step.ID))
+		}
+	}
+	return errs
+}
+
+// walk marks every step reachable from id
+func (g *Graph) walk(id uint, seen map[uint]bool) {
+	if seen[id] {
+		return
+	}
+	seen[id] = true
+	step := g.Step(id)
+	if step == nil {
+		return
+	}
+	for _, next := range g.successors(step) {
+		g.walk(next, seen)
+	}
+}
+
+// findCycle returns the IDs of a cycle in the graph, or nil
+func (g *Graph) findCycle() []uint {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := map[uint]int{}
+	var path []uint
+
+	var visit func(id uint) []uint
+	visit = func(id uint) []uint {
+		switch state[id] {
+		case visiting:
+			for i := range path {
+				if path[i] == id {
+					return append([]uint{}, path[i:]...)
+				}
+			}
+		case done:
+			return nil
+		}
+
+		state[id] = visiting
+		path = append(path, id)
+		if step := g.Step(id); step != nil {
+			for _, next := range g.successors(step) {
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = done
+		return nil
+	}
+
+	for _, step := range g.steps {
+		if state[step.ID] == unvisited {
+			if cycle := visit(step.ID); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
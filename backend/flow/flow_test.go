@@ -0,0 +1,212 @@
+package flow
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+func email(id, next uint) models.Step {
+	return models.Step{Model: models.Model{ID: id}, EmailTemplateID: 1, NextStepID: next}
+}
+
+func condition(id, yes, no uint) models.Step {
+	return models.Step{Model: models.Model{ID: id}, Type: models.StepCondition, Condition: models.ConditionOpened, YesStepID: yes, NoStepID: no}
+}
+
+func goal(id uint) models.Step {
+	return models.Step{Model: models.Model{ID: id}, Type: models.StepGoal, Condition: models.ConditionTag, ConditionValue: "customer"}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry uint
+		steps []models.Step
+		start uint
+		// next maps a step ID to where it leads when its condition is met
+		// and when it isn't
+		next map[uint][2]uint
+	}{
+		{
+			name:  "linear",
+			steps: []models.Step{email(1, 0), goal(2), email(3, 0), condition(4, 0, 0)},
+			start: 1,
+			next:  map[uint][2]uint{1: {3, 3}, 3: {4, 4}, 4: {0, 0}, 2: {0, 0}},
+		},
+		{
+			name:  "linear with an explicit edge",
+			steps: []models.Step{email(1, 3), email(2, 0), email(3, 0)},
+			start: 1,
+			next:  map[uint][2]uint{1: {3, 3}, 2: {3, 3}, 3: {0, 0}},
+		},
+		{
+			name:  "linear condition falls through when a branch is empty",
+			steps: []models.Step{condition(1, 3, 0), email(2, 0), email(3, 0)},
+			start: 1,
+			next:  map[uint][2]uint{1: {3, 2}},
+		},
+		{
+			name:  "branching",
+			entry: 2,
+			steps: []models.Step{email(1, 0), condition(2, 1, 3), email(3, 0)},
+			start: 2,
+			next:  map[uint][2]uint{2: {1, 3}, 1: {0, 0}, 3: {0, 0}},
+		},
+		{
+			name:  "only goals",
+			steps: []models.Step{goal(1)},
+			start: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(tt.entry, tt.steps)
+			if start := g.Start(); start != tt.start {
+				t.Errorf("Start = %d, want %d", start, tt.start)
+			}
+			for id, want := range tt.next {
+				step := g.Step(id)
+				if got := [2]uint{g.Next(step, true), g.Next(step, false)}; got != want {
+					t.Errorf("Next(%d) = %v, want %v", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry uint
+		steps []models.Step
+		errs  []string
+	}{
+		{
+			name:  "linear",
+			steps: []models.Step{email(1, 0), condition(2, 0, 0), email(3, 0), goal(4)},
+		},
+		{
+			name:  "branching",
+			entry: 1,
+			steps: []models.Step{email(1, 2), condition(2, 3, 4), email(3, 0), email(4, 0), goal(5)},
+		},
+		{
+			name:  "diamond",
+			entry: 1,
+			steps: []models.Step{condition(1, 2, 3), email(2, 4), email(3, 4), email(4, 0)},
+		},
+		{
+			name:  "cycle",
+			entry: 1,
+			steps: []models.Step{email(1, 2), condition(2, 3, 1), email(3, 0)},
+			errs:  []string{"steps [1 2] form a cycle"},
+		},
+		{
+			name:  "self loop",
+			entry: 1,
+			steps: []models.Step{condition(1, 1, 2), email(2, 0)},
+			errs:  []string{"steps [1] form a cycle"},
+		},
+		{
+			name:  "cycle in a linear campaign",
+			steps: []models.Step{email(1, 0), email(2, 1)},
+			errs:  []string{"steps [1 2] form a cycle"},
+		},
+		{
+			name:  "unreachable",
+			entry: 1,
+			steps: []models.Step{email(1, 0), email(2, 3), email(3, 0)},
+			errs:  []string{"step 2 is unreachable", "step 3 is unreachable"},
+		},
+		{
+			name:  "unreachable cycle",
+			entry: 1,
+			steps: []models.Step{email(1, 0), email(2, 3), email(3, 2)},
+			errs:  []string{"steps [2 3] form a cycle", "step 2 is unreachable", "step 3 is unreachable"},
+		},
+		{
+			name:  "entry not in the campaign",
+			entry: 9,
+			steps: []models.Step{email(1, 0)},
+			errs:  []string{"entry step 9 is not part of the campaign"},
+		},
+		{
+			name:  "goal entry",
+			entry: 2,
+			steps: []models.Step{email(1, 0), goal(2)},
+			errs:  []string{"the entry step can't be a goal", "step 1 is unreachable"},
+		},
+		{
+			name:  "edge out of the campaign",
+			entry: 1,
+			steps: []models.Step{condition(1, 2, 9), email(2, 0)},
+			errs:  []string{"step 1: no_step_id 9 is not part of the campaign"},
+		},
+		{
+			name:  "edge to a goal",
+			entry: 1,
+			steps: []models.Step{email(1, 2), goal(2)},
+			errs:  []string{"step 1: next_step_id points at goal step 2"},
+		},
+		{
+			name:  "edge from a goal",
+			entry: 1,
+			steps: []models.Step{email(1, 0), func() models.Step { s := goal(2); s.NextStepID = 1; return s }()},
+			errs:  []string{"step 2: goal steps can't have a next_step_id"},
+		},
+		{
+			name:  "invalid step",
+			steps: []models.Step{{Model: models.Model{ID: 1}}},
+			errs:  []string{"step 1: email steps need an email_template_id or variants"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := New(tt.entry, tt.steps).Validate()
+			if !reflect.DeepEqual(errs, tt.errs) {
+				t.Errorf("Validate =\n%s\nwant\n%s", strings.Join(errs, "\n"), strings.Join(tt.errs, "\n"))
+			}
+		})
+	}
+}
+
+func TestValidateStep(t *testing.T) {
+	wait := func(s models.Step) models.Step {
+		s.Type, s.WaitTime = models.StepWaitUntil, 3
+		return s
+	}
+	field := func(name, operator string) models.Step {
+		return models.Step{Type: models.StepCondition, Condition: models.ConditionField, ConditionField: name, ConditionOperator: operator}
+	}
+
+	tests := []struct {
+		name string
+		step models.Step
+		errs []string
+	}{
+		{"email", email(1, 0), nil},
+		{"email with variants", models.Step{Variants: []models.StepVariant{{}}}, nil},
+		{"email without template", models.Step{}, []string{"email steps need an email_template_id or variants"}},
+		{"unknown type", models.Step{Type: "sms"}, []string{"type must be email, condition, wait_until or goal"}},
+		{"condition", condition(1, 0, 0), nil},
+		{"wait until", wait(condition(1, 0, 0)), nil},
+		{"wait until without timeout", func() models.Step { s := wait(condition(1, 0, 0)); s.WaitTime = 0; return s }(),
+			[]string{"wait_until steps need a wait_time timeout"}},
+		{"field", field("company", models.OperatorContains), nil},
+		{"unknown field", field("password", ""), []string{"unknown condition_field password"}},
+		{"unknown operator", field("company", "matches"), []string{"unknown condition_operator matches"}},
+		{"tag without value", models.Step{Type: models.StepGoal, Condition: models.ConditionTag}, []string{"tag conditions need a condition_value"}},
+		{"unknown condition", models.Step{Type: models.StepCondition, Condition: "visited"},
+			[]string{"condition must be opened, clicked, replied, field or tag"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := ValidateStep(&tt.step); !reflect.DeepEqual(errs, tt.errs) {
+				t.Errorf("ValidateStep = %q, want %q", errs, tt.errs)
+			}
+		})
+	}
+}
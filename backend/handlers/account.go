@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/auth"
+	"github.com/4cecoder/drip-campaign/config"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/mailer"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/sendqueue"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	invitationTTL    = 72 * time.Hour
+	passwordResetTTL = time.Hour
+)
+
+// CreateInvitationHandler invites a new user by email
+// @Summary Invite a user
+// @Description Email a signed, expiring invite link that lets a new user set their password
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param invitation body models.InvitationRequest true "Invitation data"
+// @Success 201 {object} models.Invitation
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /invitations [post]
+func CreateInvitationHandler(c *gin.Context) {
+	var req models.InvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required"})
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.UserRole
+	}
+	if req.Role != models.UserRole && req.Role != models.AdminRole {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+
+	existing, err := models.GetUserByEmail(req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A user with this email already exists"})
+		return
+	}
+
+	invitation := models.Invitation{
+		Email:     req.Email,
+		Role:      req.Role,
+		InvitedBy: auth.CurrentUserID(c),
+		ExpiresAt: time.Now().Add(invitationTTL),
+	}
+	if err := database.DB.Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	token, err := auth.GenerateInviteToken(&invitation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invite token"})
+		return
+	}
+	invitation.TokenHash = auth.HashToken(token)
+	if err := database.DB.Save(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	link := fmt.Sprintf("%s/signup?token=%s", config.LoadConfig().AppURL, token)
+	msg := mailer.Message{
+		To:      invitation.Email,
+		Subject: "You're invited to Drip Campaign",
+		Body:    fmt.Sprintf("You have been invited to Drip Campaign.\r\n\r\nSet your password using the link below. It expires on %s.\r\n\r\n%s\r\n", invitation.ExpiresAt.Format(time.RFC1123), link),
+	}
+	// The queue retries the email if sending fails
+	if _, err := sendqueue.Enqueue(msg, 0, nil); err != nil {
+		log.Println("Error queueing invitation email:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue invitation email"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// GetInvitationsHandler retrieves all invitations
+// @Summary Get all invitations
+// @Description Retrieve all user invitations
+// @Tags Users
+// @Produce json
+// @Success 200 {array} models.Invitation
+// @Failure 500 {object} models.ErrorResponse
+// @Router /invitations [get]
+func GetInvitationsHandler(c *gin.Context) {
+	var invitations []models.Invitation
+	if err := database.DB.Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invitations"})
+		return
+	}
+	c.JSON(http.StatusOK, invitations)
+}
+
+// DeleteInvitationHandler revokes a pending invitation
+// @Summary Revoke an invitation
+// @Description Revoke a pending invitation so its link can no longer be used
+// @Tags Users
+// @Produce json
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /invitations/{id} [delete]
+func DeleteInvitationHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var invitation models.Invitation
+	if err := database.DB.First(&invitation, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	if err := database.DB.Delete(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// AcceptInvitationHandler creates the invited user with the chosen password
+// @Summary Accept an invitation
+// @Description Set a password using an invite token and create the invited user
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.AcceptInvitationRequest true "Invite token and new password"
+// @Success 201 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /invitations/accept [post]
+func AcceptInvitationHandler(c *gin.Context) {
+	var req models.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.Password) < models.MinPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Password must be at least %d characters", models.MinPasswordLength)})
+		return
+	}
+
+	id, err := auth.ParseInviteToken(req.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation"})
+		return
+	}
+
+	var invitation models.Invitation
+	if err := database.DB.First(&invitation, id).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation"})
+		return
+	}
+	if invitation.TokenHash != auth.HashToken(req.Token) || invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation"})
+		return
+	}
+
+	tx := database.DB.Begin()
+	user := models.User{
+		Email:    invitation.Email,
+		Password: req.Password,
+		Role:     invitation.Role,
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	now := time.Now()
+	if err := tx.Model(&invitation).UpdateColumn("accepted_at", now).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Invitation accepted successfully"})
+}
+
+// ForgotPasswordHandler emails a password reset link
+// @Summary Request a password reset
+// @Description Email a single-use password reset link if the account exists
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "Account email"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /password/forgot [post]
+func ForgotPasswordHandler(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Always respond the same way so the endpoint can't be used to discover accounts
+	response := gin.H{"message": "If an account exists for that email, a reset link has been sent"}
+
+	user, err := models.GetUserByEmail(strings.TrimSpace(req.Email))
+	if err != nil || user == nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate reset token"})
+		return
+	}
+
+	// Only the most recent reset link stays valid
+	now := time.Now()
+	database.DB.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		UpdateColumn("used_at", now)
+
+	resetToken := models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(passwordResetTTL),
+	}
+	if err := database.DB.Create(&resetToken).Error; err != nil {
+		log.Println("Error creating password reset token:", err)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", config.LoadConfig().AppURL, token)
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Drip Campaign password",
+		Body:    fmt.Sprintf("A password reset was requested for your account.\r\n\r\nUse the link below within the next hour to choose a new password. If you didn't request this, you can ignore this email.\r\n\r\n%s\r\n", link),
+	}
+	if _, err := sendqueue.Enqueue(msg, 0, nil); err != nil {
+		log.Println("Error queueing password reset email:", err)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ResetPasswordHandler sets a new password using a reset token
+// @Summary Reset a password
+// @Description Set a new password using a single-use reset token. Every session and login challenge issued before is revoked.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /password/reset [post]
+func ResetPasswordHandler(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.Password) < models.MinPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Password must be at least %d characters", models.MinPasswordLength)})
+		return
+	}
+
+	var resetToken models.PasswordResetToken
+	if err := database.DB.Where("token_hash = ? AND used_at IS NULL", auth.HashToken(req.Token)).First(&resetToken).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+	if time.Now().After(resetToken.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, resetToken.UserID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	tx := database.DB.Begin()
+	// Mark the token used first so a concurrent request with the same token can't also succeed
+	result := tx.Model(&resetToken).Where("used_at IS NULL").UpdateColumn("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	if err := models.UpdatePassword(tx, &user, req.Password); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// ChangePasswordHandler changes the password of the logged-in user
+// @Summary Change password
+// @Description Change the password of the authenticated user. Every session, including the current one, is revoked, so the user logs in again with the new password.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/password [put]
+func ChangePasswordHandler(c *gin.Context) {
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.NewPassword) < models.MinPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Password must be at least %d characters", models.MinPasswordLength)})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, auth.CurrentUserID(c)).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if !models.CheckPasswordHash(req.CurrentPassword, user.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	if err := models.UpdatePassword(database.DB, &user, req.NewPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
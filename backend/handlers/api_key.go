@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/auth"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIKeyHandler creates a scoped API key for the logged-in user
+// @Summary Create an API key
+// @Description Create a scoped API key for machine-to-machine access. The key is only returned once.
+// @Tags APIKeys
+// @Accept json
+// @Produce json
+// @Param apiKey body models.CreateAPIKeyRequest true "API key data"
+// @Success 201 {object} models.CreateAPIKeyResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api-keys [post]
+func CreateAPIKeyHandler(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+	if len(req.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one scope is required"})
+		return
+	}
+	for i, scope := range req.Scopes {
+		req.Scopes[i] = strings.TrimSpace(scope)
+		if err := auth.ValidateScope(req.Scopes[i]); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+		return
+	}
+
+	key, prefix, keyHash, err := auth.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+
+	apiKey := models.APIKey{
+		UserID:    auth.CurrentUserID(c),
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   keyHash,
+		Scopes:    strings.Join(req.Scopes, ","),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := database.DB.Create(&apiKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.CreateAPIKeyResponse{Key: key, APIKey: apiKey})
+}
+
+// GetAPIKeysHandler retrieves API keys
+// @Summary Get API keys
+// @Description Retrieve the logged-in user's API keys, or all API keys for admins
+// @Tags APIKeys
+// @Produce json
+// @Success 200 {array} models.APIKey
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api-keys [get]
+func GetAPIKeysHandler(c *gin.Context) {
+	query := database.DB
+	if c.GetString(auth.RoleKey) != models.AdminRole {
+		query = query.Where("user_id = ?", auth.CurrentUserID(c))
+	}
+
+	var apiKeys []models.APIKey
+	if err := query.Find(&apiKeys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API keys"})
+		return
+	}
+	c.JSON(http.StatusOK, apiKeys)
+}
+
+// RevokeAPIKeyHandler revokes an API key
+// @Summary Revoke an API key
+// @Description Revoke an API key so it can no longer be used
+// @Tags APIKeys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api-keys/{id} [delete]
+func RevokeAPIKeyHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	query := database.DB
+	if c.GetString(auth.RoleKey) != models.AdminRole {
+		query = query.Where("user_id = ?", auth.CurrentUserID(c))
+	}
+
+	var apiKey models.APIKey
+	if err := query.First(&apiKey, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	if apiKey.RevokedAt == nil {
+		if err := database.DB.Model(&apiKey).UpdateColumn("revoked_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
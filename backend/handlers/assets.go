@@ -0,0 +1,403 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/4cecoder/drip-campaign/assets"
+	"github.com/4cecoder/drip-campaign/auth"
+	"github.com/4cecoder/drip-campaign/config"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/gin-gonic/gin"
+)
+
+// UploadAssetHandler uploads a file
+// @Summary Upload an asset
+// @Description Upload a file, in the file field of a multipart form, for templates and steps to attach or show inline. Its type is detected from its contents; images, PDFs, plain text, CSV, calendar invites, zip archives and office documents are allowed.
+// @Tags Assets
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "The file"
+// @Success 201 {object} models.Asset
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /assets [post]
+func UploadAssetHandler(c *gin.Context) {
+	maxBytes := config.LoadConfig().AssetMaxBytes
+	if maxBytes > 0 {
+		// Leave room for the rest of the multipart form
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+1<<20)
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Files can be %d bytes at most", maxBytes)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload the file in the file field of a multipart form"})
+		return
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if maxBytes > 0 {
+		reader = io.LimitReader(file, maxBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the uploaded file"})
+		return
+	}
+
+	asset, err := assets.Save(header.Filename, data, maxBytes, auth.CurrentUserID(c))
+	switch {
+	case errors.Is(err, assets.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Files can be %d bytes at most", maxBytes)})
+		return
+	case errors.Is(err, assets.ErrUnsupportedType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save the uploaded file"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, asset)
+}
+
+// GetAssetsHandler retrieves all assets
+// @Summary Get all assets
+// @Description Retrieve every uploaded asset, newest first
+// @Tags Assets
+// @Produce json
+// @Success 200 {array} models.Asset
+// @Failure 500 {object} models.ErrorResponse
+// @Router /assets [get]
+func GetAssetsHandler(c *gin.Context) {
+	var all []models.Asset
+	if err := database.DB.Order("id desc").Find(&all).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve assets"})
+		return
+	}
+	c.JSON(http.StatusOK, all)
+}
+
+// GetAssetHandler retrieves a specific asset by ID
+// @Summary Get an asset
+// @Description Retrieve a specific asset by ID
+// @Tags Assets
+// @Produce json
+// @Param id path int true "Asset ID"
+// @Success 200 {object} models.Asset
+// @Failure 404 {object} models.ErrorResponse
+// @Router /assets/{id} [get]
+func GetAssetHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var asset models.Asset
+	if err := database.DB.First(&asset, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return
+	}
+	c.JSON(http.StatusOK, asset)
+}
+
+// DownloadAssetHandler downloads an asset's contents
+// @Summary Download an asset
+// @Description Download the file an asset was uploaded with
+// @Tags Assets
+// @Produce octet-stream
+// @Param id path int true "Asset ID"
+// @Success 200 {file} file
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /assets/{id}/content [get]
+func DownloadAssetHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var asset models.Asset
+	if err := database.DB.First(&asset, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return
+	}
+
+	data, err := assets.Read(&asset)
+	if errors.Is(err, assets.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset contents not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read asset"})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": asset.Filename}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, asset.ContentType, data)
+}
+
+// DeleteAssetHandler deletes a specific asset by ID
+// @Summary Delete an asset
+// @Description Delete an asset that nothing attaches. Queued emails that attach it fail.
+// @Tags Assets
+// @Produce json
+// @Param id path int true "Asset ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /assets/{id} [delete]
+func DeleteAssetHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var asset models.Asset
+	if err := database.DB.First(&asset, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return
+	}
+
+	attachments, err := assets.InUse(&asset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check where the asset is attached"})
+		return
+	}
+	if len(attachments) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The asset is attached to templates or steps, remove those attachments first"})
+		return
+	}
+
+	if err := assets.Delete(&asset); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete asset"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Asset deleted successfully"})
+}
+
+// GetTemplateAttachmentsHandler retrieves a template's attachments
+// @Summary Get a template's attachments
+// @Description Retrieve the files and inline images an email template attaches, with their assets
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path int true "Email template ID"
+// @Success 200 {array} models.Attachment
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /templates/{id}/attachments [get]
+func GetTemplateAttachmentsHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var emailTemplate models.EmailTemplate
+	if err := database.DB.First(&emailTemplate, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+	listAttachments(c, models.Attachment{EmailTemplateID: emailTemplate.ID})
+}
+
+// CreateTemplateAttachmentHandler attaches an asset to a template
+// @Summary Attach an asset to a template
+// @Description Attach an uploaded asset to every email sent with a template. With inline set, an image is instead shown where the HTML body has src="cid:<content_id>"; content_id defaults to the asset's filename. Attachments aren't versioned: pinned versions send the template's current attachments.
+// @Tags EmailTemplates
+// @Accept json
+// @Produce json
+// @Param id path int true "Email template ID"
+// @Param attachment body models.AttachmentRequest true "Asset to attach"
+// @Success 201 {object} models.Attachment
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /templates/{id}/attachments [post]
+func CreateTemplateAttachmentHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var emailTemplate models.EmailTemplate
+	if err := database.DB.First(&emailTemplate, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+	createAttachment(c, models.Attachment{EmailTemplateID: emailTemplate.ID})
+}
+
+// DeleteTemplateAttachmentHandler removes an attachment from a template
+// @Summary Remove a template attachment
+// @Description Stop attaching an asset to a template. The asset itself is kept.
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path int true "Email template ID"
+// @Param attachment_id path int true "Attachment ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /templates/{id}/attachments/{attachment_id} [delete]
+func DeleteTemplateAttachmentHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	deleteAttachment(c, "email_template_id = ?", id)
+}
+
+// GetStepAttachmentsHandler retrieves a step's attachments
+// @Summary Get a step's attachments
+// @Description Retrieve the files and inline images a step attaches, on top of its template's, with their assets
+// @Tags Steps
+// @Produce json
+// @Param id path int true "Step ID"
+// @Success 200 {array} models.Attachment
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /steps/{id}/attachments [get]
+func GetStepAttachmentsHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var step models.Step
+	if err := database.DB.First(&step, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Step not found"})
+		return
+	}
+	listAttachments(c, models.Attachment{StepID: step.ID})
+}
+
+// CreateStepAttachmentHandler attaches an asset to a step
+// @Summary Attach an asset to a step
+// @Description Attach an uploaded asset to the emails a step sends, on top of its template's attachments, such as a brochure sent only in one step of a sequence. With inline set, an image is instead shown where the HTML body has src="cid:<content_id>".
+// @Tags Steps
+// @Accept json
+// @Produce json
+// @Param id path int true "Step ID"
+// @Param attachment body models.AttachmentRequest true "Asset to attach"
+// @Success 201 {object} models.Attachment
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /steps/{id}/attachments [post]
+func CreateStepAttachmentHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var step models.Step
+	if err := database.DB.First(&step, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Step not found"})
+		return
+	}
+	createAttachment(c, models.Attachment{StepID: step.ID})
+}
+
+// DeleteStepAttachmentHandler removes an attachment from a step
+// @Summary Remove a step attachment
+// @Description Stop attaching an asset to a step. The asset itself is kept.
+// @Tags Steps
+// @Produce json
+// @Param id path int true "Step ID"
+// @Param attachment_id path int true "Attachment ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /steps/{id}/attachments/{attachment_id} [delete]
+func DeleteStepAttachmentHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	deleteAttachment(c, "step_id = ?", id)
+}
+
+// listAttachments responds with the attachments of the template or step
+// owner is set for
+func listAttachments(c *gin.Context, owner models.Attachment) {
+	var attachments []models.Attachment
+	if err := database.DB.Where(&owner).Order("id asc").Find(&attachments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attachments"})
+		return
+	}
+	for i := range attachments {
+		var asset models.Asset
+		if err := database.DB.First(&asset, attachments[i].AssetID).Error; err == nil {
+			attachments[i].Asset = &asset
+		}
+	}
+	c.JSON(http.StatusOK, attachments)
+}
+
+// createAttachment attaches the requested asset to the template or step
+// attachment is set up for
+func createAttachment(c *gin.Context, attachment models.Attachment) {
+	var req models.AttachmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var asset models.Asset
+	if err := database.DB.First(&asset, req.AssetID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Asset not found"})
+		return
+	}
+	attachment.AssetID = asset.ID
+	attachment.Inline = req.Inline
+
+	if req.Inline {
+		if !assets.IsImage(&asset) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only images can be shown inline"})
+			return
+		}
+		attachment.ContentID = req.ContentID
+		if attachment.ContentID == "" {
+			attachment.ContentID = assets.DefaultContentID(asset.Filename)
+		}
+		if !assets.ValidContentID(attachment.ContentID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "content_id can only contain letters, digits, dots, dashes and underscores, optionally followed by @domain"})
+			return
+		}
+	}
+
+	var existing []models.Attachment
+	owner := models.Attachment{EmailTemplateID: attachment.EmailTemplateID, StepID: attachment.StepID}
+	if err := database.DB.Where(&owner).Find(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attachments"})
+		return
+	}
+	total := asset.Size
+	for _, other := range existing {
+		if other.AssetID == asset.ID && other.Inline == attachment.Inline {
+			c.JSON(http.StatusConflict, gin.H{"error": "The asset is already attached"})
+			return
+		}
+		if attachment.Inline && other.ContentID == attachment.ContentID {
+			c.JSON(http.StatusConflict, gin.H{"error": "Another inline image already uses content_id " + attachment.ContentID})
+			return
+		}
+		var otherAsset models.Asset
+		if err := database.DB.First(&otherAsset, other.AssetID).Error; err == nil {
+			total += otherAsset.Size
+		}
+	}
+	if limit := config.LoadConfig().AttachmentMaxBytes; limit > 0 && total > limit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Attachments can add up to %d bytes at most", limit)})
+		return
+	}
+
+	if err := database.DB.Create(&attachment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to attach asset"})
+		return
+	}
+	attachment.Asset = &asset
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// deleteAttachment removes the attachment in the attachment_id parameter,
+// which must belong to the owner matched by where
+func deleteAttachment(c *gin.Context, where string, ownerID int) {
+	attachmentID, _ := strconv.Atoi(c.Param("attachment_id"))
+	var attachment models.Attachment
+	if err := database.DB.Where(where, ownerID).First(&attachment, attachmentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+
+	// Unscoped, so a removed attachment no longer keeps its asset in use
+	if err := database.DB.Unscoped().Delete(&attachment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove attachment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/deliverability"
+	"github.com/4cecoder/drip-campaign/engine"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/gin-gonic/gin"
+)
+
+// dnsCheckTimeout bounds the DNS lookups of a domain's pre-flight checks
+const dnsCheckTimeout = 10 * time.Second
+
+// CheckSendingDomainHandler runs the pre-flight checks of a sending domain
+// @Summary Check a sending domain
+// @Description Inspect the MX, SPF, DKIM and DMARC records of a from-domain and report misconfigurations with how to fix them, and whether its mail passes DMARC alignment
+// @Tags Deliverability
+// @Produce json
+// @Param domain path string true "Sending domain, such as example.com"
+// @Success 200 {object} models.DomainCheck
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /sending-domains/{domain}/check [get]
+func CheckSendingDomainHandler(c *gin.Context) {
+	domain := strings.ToLower(strings.TrimSpace(c.Param("domain")))
+	if domain == "" || strings.Contains(domain, "@") || !strings.Contains(domain, ".") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Domain must be a domain name such as example.com"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), dnsCheckTimeout)
+	defer cancel()
+	check, err := deliverability.CheckDomain(ctx, domain)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check sending domain"})
+		return
+	}
+
+	c.JSON(http.StatusOK, check)
+}
+
+// validateSendingDomains rejects activating a campaign when mail from one of
+// its from-domains fails DMARC alignment. Domains whose records couldn't be
+// looked up don't block it.
+func validateSendingDomains(c *gin.Context, campaign *models.DripCampaign) bool {
+	if campaign.Status != models.CampaignStatusActive {
+		return true
+	}
+
+	steps, err := engine.CampaignSteps(campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve steps"})
+		return false
+	}
+	domains, err := deliverability.CampaignDomains(campaign, steps)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sending domains"})
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), dnsCheckTimeout)
+	defer cancel()
+	for _, domain := range domains {
+		check, err := deliverability.CheckDomain(ctx, domain)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check sending domain " + domain})
+			return false
+		}
+		if check.Alignment == models.CheckError {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Sending domain " + domain + " fails DMARC alignment; see GET /api/v1/sending-domains/" + domain + "/check",
+				"checks": check.Checks,
+			})
+			return false
+		}
+	}
+	return true
+}
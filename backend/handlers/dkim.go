@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/dkim"
+	"github.com/4cecoder/drip-campaign/mailer"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/secrets"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateDKIMKeyHandler generates a DKIM keypair for a sending domain
+// @Summary Generate a DKIM key
+// @Description Generate an RSA (2048 bits unless bits is given) or Ed25519 keypair for a sending domain and return the DNS TXT record to publish. The key starts disabled; once the record is published, enable it with PUT /dkim-keys/{id} and emails from the domain are signed with it from then on.
+// @Tags DKIM
+// @Accept json
+// @Produce json
+// @Param key body models.DKIMKeyRequest true "Domain, selector and algorithm"
+// @Success 201 {object} models.DKIMKey
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /dkim-keys [post]
+func CreateDKIMKeyHandler(c *gin.Context) {
+	var req models.DKIMKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key := models.DKIMKey{
+		Domain:    strings.ToLower(strings.TrimSpace(req.Domain)),
+		Selector:  strings.ToLower(strings.TrimSpace(req.Selector)),
+		Algorithm: strings.ToLower(strings.TrimSpace(req.Algorithm)),
+	}
+	if key.Domain == "" || strings.Contains(key.Domain, "@") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Domain must be a domain name such as example.com"})
+		return
+	}
+	if !dkim.ValidSelector(key.Selector) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Selector must be letters, digits, hyphens and dots, such as s1"})
+		return
+	}
+	switch key.Algorithm {
+	case "", dkim.RSA:
+		key.Algorithm = dkim.RSA
+		key.Bits = req.Bits
+		if key.Bits == 0 {
+			key.Bits = 2048
+		}
+		if !dkim.ValidBits(key.Bits) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "RSA keys must be 1024, 2048, 3072 or 4096 bits"})
+			return
+		}
+	case dkim.Ed25519:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Algorithm must be rsa or ed25519"})
+		return
+	}
+
+	var count int
+	if err := database.DB.Model(&models.DKIMKey{}).
+		Where("domain = ? AND selector = ?", key.Domain, key.Selector).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check DKIM keys"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "That domain already has a key with this selector"})
+		return
+	}
+
+	privateKey, publicKey, err := dkim.GenerateKey(key.Algorithm, key.Bits)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate DKIM key"})
+		return
+	}
+	key.PrivateKey = secrets.String(privateKey)
+	key.PublicKey = publicKey
+	// Signing with a key receivers can't find fails DKIM, so wait for the record
+	key.Disabled = true
+
+	if err := database.DB.Create(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create DKIM key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, withDNSRecord(key))
+}
+
+// GetDKIMKeysHandler retrieves all DKIM keys
+// @Summary Get all DKIM keys
+// @Description Retrieve all DKIM keys with the DNS records to publish. Private keys are never returned.
+// @Tags DKIM
+// @Produce json
+// @Success 200 {array} models.DKIMKey
+// @Failure 500 {object} models.ErrorResponse
+// @Router /dkim-keys [get]
+func GetDKIMKeysHandler(c *gin.Context) {
+	var keys []models.DKIMKey
+	if err := database.DB.Order("domain asc, selector asc").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve DKIM keys"})
+		return
+	}
+
+	for i := range keys {
+		keys[i] = withDNSRecord(keys[i])
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// GetDKIMKeyHandler retrieves a specific DKIM key by ID
+// @Summary Get a DKIM key
+// @Description Retrieve a specific DKIM key by ID with the DNS record to publish
+// @Tags DKIM
+// @Produce json
+// @Param id path int true "DKIM key ID"
+// @Success 200 {object} models.DKIMKey
+// @Failure 404 {object} models.ErrorResponse
+// @Router /dkim-keys/{id} [get]
+func GetDKIMKeyHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var key models.DKIMKey
+	if err := database.DB.First(&key, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DKIM key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, withDNSRecord(key))
+}
+
+// UpdateDKIMKeyHandler enables or disables a DKIM key
+// @Summary Update a DKIM key
+// @Description Enable or disable signing with a DKIM key. A key is only enabled once a test message signed with it verifies against its published DNS record.
+// @Tags DKIM
+// @Accept json
+// @Produce json
+// @Param id path int true "DKIM key ID"
+// @Param key body models.UpdateDKIMKeyRequest true "Whether the key is disabled"
+// @Success 200 {object} models.DKIMKey
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /dkim-keys/{id} [put]
+func UpdateDKIMKeyHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var key models.DKIMKey
+	if err := database.DB.First(&key, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DKIM key not found"})
+		return
+	}
+
+	var req models.UpdateDKIMKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if key.Disabled && !req.Disabled {
+		if check := mailer.CheckDKIM(&key); !check.OK {
+			c.JSON(http.StatusConflict, gin.H{"error": "The key's DNS record isn't published or doesn't match: " + check.Error})
+			return
+		}
+	}
+
+	key.Disabled = req.Disabled
+	if err := database.DB.Model(&key).UpdateColumn("disabled", req.Disabled).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update DKIM key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, withDNSRecord(key))
+}
+
+// DeleteDKIMKeyHandler deletes a specific DKIM key by ID
+// @Summary Delete a DKIM key
+// @Description Delete a DKIM key. Emails from its domain are no longer signed with it.
+// @Tags DKIM
+// @Produce json
+// @Param id path int true "DKIM key ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /dkim-keys/{id} [delete]
+func DeleteDKIMKeyHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var key models.DKIMKey
+	if err := database.DB.First(&key, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DKIM key not found"})
+		return
+	}
+
+	// Unscoped so the selector can be reused, and the private key doesn't linger
+	if err := database.DB.Unscoped().Delete(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DKIM key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "DKIM key deleted successfully"})
+}
+
+// CheckDKIMKeyHandler signs a test message with a DKIM key and verifies it
+// @Summary Check a DKIM key
+// @Description Sign a test message with the stored private key and verify the signature against the key's published DNS record
+// @Tags DKIM
+// @Produce json
+// @Param id path int true "DKIM key ID"
+// @Success 200 {object} models.DKIMCheck
+// @Failure 404 {object} models.ErrorResponse
+// @Router /dkim-keys/{id}/check [post]
+func CheckDKIMKeyHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var key models.DKIMKey
+	if err := database.DB.First(&key, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DKIM key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, mailer.CheckDKIM(&key))
+}
+
+// withDNSRecord fills in the TXT record to publish for a key
+func withDNSRecord(key models.DKIMKey) models.DKIMKey {
+	key.DNSRecord = &models.DNSRecord{
+		Name:  dkim.RecordName(key.Domain, key.Selector),
+		Type:  "TXT",
+		Value: dkim.Record(key.Algorithm, key.PublicKey),
+	}
+	return key
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/4cecoder/drip-campaign/abtest"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/engine"
+	"github.com/4cecoder/drip-campaign/flow"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/schedule"
+	"github.com/4cecoder/drip-campaign/templates"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// GetCampaignFlowHandler retrieves a campaign's step graph
+// @Summary Get a campaign's flow
+// @Description Retrieve the entry step and steps of a campaign with their branches and conditions, and any problems that would stop it from being activated
+// @Tags Campaigns
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Success 200 {object} models.CampaignFlow
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /campaigns/{id}/flow [get]
+func GetCampaignFlowHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var campaign models.DripCampaign
+	if err := database.DB.First(&campaign, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	steps, err := engine.CampaignSteps(campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve steps"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CampaignFlow{
+		EntryStepID: campaign.EntryStepID,
+		Steps:       steps,
+		Errors:      flow.New(campaign.EntryStepID, steps).Validate(),
+	})
+}
+
+// UpdateCampaignFlowHandler updates a campaign's step graph in one go
+// @Summary Update a campaign's flow
+// @Description Set the entry step and the type, branches and conditions of a campaign's steps. Steps are matched by ID and must already exist; only their flow fields and wait_time are changed. The whole graph is validated, rejecting cycles and unreachable steps.
+// @Tags Campaigns
+// @Accept json
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Param flow body models.CampaignFlow true "Campaign flow"
+// @Success 200 {object} models.CampaignFlow
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /campaigns/{id}/flow [put]
+func UpdateCampaignFlowHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var campaign models.DripCampaign
+	if err := database.DB.First(&campaign, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	var req models.CampaignFlow
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	steps, err := engine.CampaignSteps(campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve steps"})
+		return
+	}
+
+	index := map[uint]int{}
+	for i, step := range steps {
+		index[step.ID] = i
+	}
+	changed := map[uint]bool{}
+	for _, update := range req.Steps {
+		i, ok := index[update.ID]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Step " + strconv.Itoa(int(update.ID)) + " is not part of the campaign"})
+			return
+		}
+		applyFlowFields(&steps[i], update)
+		changed[update.ID] = true
+	}
+
+	if errs := flow.New(req.EntryStepID, steps).Validate(); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flow: " + strings.Join(errs, "; "), "errors": errs})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, step := range steps {
+			if changed[step.ID] {
+				if err := tx.Save(&step).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Model(&campaign).UpdateColumn("entry_step_id", req.EntryStepID).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update flow"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CampaignFlow{EntryStepID: req.EntryStepID, Steps: steps})
+}
+
+// applyFlowFields copies the fields a flow update may change
+func applyFlowFields(step *models.Step, update models.Step) {
+	step.Type = update.Type
+	step.WaitTime = update.WaitTime
+	step.NextStepID = update.NextStepID
+	step.YesStepID = update.YesStepID
+	step.NoStepID = update.NoStepID
+	step.Condition = update.Condition
+	step.ConditionStepID = update.ConditionStepID
+	step.ConditionField = update.ConditionField
+	step.ConditionOperator = update.ConditionOperator
+	step.ConditionValue = update.ConditionValue
+}
+
+// validateStep rejects unknown senders and wait units, malformed A/B test
+// settings and condition, wait-until and goal steps. Email steps are
+// otherwise only checked as part of the whole flow, when the campaign is
+// activated.
+func validateStep(c *gin.Context, step *models.Step) bool {
+	if !validateSender(c, step.SenderIdentityID) {
+		return false
+	}
+	if step.EmailTemplateVersion < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email template version can't be negative"})
+		return false
+	}
+	if step.EmailTemplateVersion > 0 {
+		if _, err := templates.Version(step.EmailTemplateID, step.EmailTemplateVersion); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The step's template has no such version"})
+			return false
+		}
+	}
+	errs := abtest.ValidateSettings(step)
+	if !schedule.ValidUnit(step.WaitUnit) {
+		errs = append(errs, "wait_unit must be seconds, minutes, hours, days or business_days")
+	}
+	if step.Kind() != models.StepEmail {
+		errs = append(errs, flow.ValidateStep(step)...)
+	}
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid step: " + strings.Join(errs, "; ")})
+		return false
+	}
+	return true
+}
+
+// validateActivation rejects activating a campaign whose flow is invalid
+func validateActivation(c *gin.Context, campaign *models.DripCampaign) bool {
+	if campaign.Status != models.CampaignStatusActive {
+		return true
+	}
+
+	steps, err := engine.CampaignSteps(campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve steps"})
+		return false
+	}
+	if errs := flow.New(campaign.EntryStepID, steps).Validate(); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flow: " + strings.Join(errs, "; "), "errors": errs})
+		return false
+	}
+	return true
+}
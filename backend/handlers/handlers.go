@@ -1,15 +1,22 @@
 package handlers
 
 import (
-	"fmt"
 	"github.com/4cecoder/drip-campaign/auth"
 	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/lint"
+	"github.com/4cecoder/drip-campaign/locales"
+	"github.com/4cecoder/drip-campaign/mailer"
 	"log"
 	"net/http"
-	"net/smtp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/schedule"
+	"github.com/4cecoder/drip-campaign/sendqueue"
+	"github.com/4cecoder/drip-campaign/templates"
+	"github.com/4cecoder/drip-campaign/triggers"
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,8 +28,10 @@ import (
 // @Produce json
 // @Param credentials body models.LoginRequest true "User credentials"
 // @Success 200 {object} models.TokenResponse
+// @Success 202 {object} models.TwoFactorChallengeResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Router /login [post]
 func LoginHandler(c *gin.Context) {
 	var loginReq models.LoginRequest
@@ -37,11 +46,44 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 
-	if user == nil || !models.CheckPasswordHash(loginReq.Password, user.Password) {
+	policy := loginPolicy()
+	if loginThrottled(c, policy, loginReq.Email, user) {
+		return
+	}
+
+	if user == nil {
+		// Hash the password anyway so unknown emails take as long to reject as wrong passwords
+		models.CheckPasswordHash(loginReq.Password, models.DummyPasswordHash())
+		recordLoginFailure(c, policy, loginReq.Email, nil, models.LoginReasonUnknownUser)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+
+	if !models.CheckPasswordHash(loginReq.Password, user.Password) {
+		recordLoginFailure(c, policy, loginReq.Email, user, models.LoginReasonInvalidPassword)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
+	// Accounts with 2FA get a short-lived challenge instead of a session token
+	if user.TOTPEnabled || user.TOTPRequired {
+		purpose := auth.ChallengeTwoFactor
+		if !user.TOTPEnabled {
+			purpose = auth.ChallengeTwoFactorSetup
+		}
+		challenge, err := auth.GenerateChallengeToken(user, purpose)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate challenge token"})
+			return
+		}
+		c.JSON(http.StatusAccepted, models.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			SetupRequired:     !user.TOTPEnabled,
+			ChallengeToken:    challenge,
+		})
+		return
+	}
+
 	var token string
 	if user.Role == "admin" {
 		token, err = auth.GenerateAdminToken(user)
@@ -57,6 +99,7 @@ func LoginHandler(c *gin.Context) {
 		}
 	}
 
+	recordLoginSuccess(c, user)
 	c.JSON(http.StatusOK, gin.H{"token": token})
 }
 
@@ -77,6 +120,24 @@ func CreateCampaignHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !models.ValidReplyAction(campaign.OnReply) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "On reply must be exit, pause or continue"})
+		return
+	}
+	if err := schedule.ValidateCampaign(&campaign); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sending window: " + err.Error()})
+		return
+	}
+	if !models.ValidSenderMode(campaign.SenderMode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sender mode must be rotate or assigned_rep"})
+		return
+	}
+	if !validateSender(c, campaign.SenderIdentityID) {
+		return
+	}
+	if !validateSendingDomains(c, &campaign) {
+		return
+	}
 
 	if err := database.DB.Create(&campaign).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create campaign"})
@@ -144,11 +205,35 @@ func UpdateCampaignHandler(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
 		return
 	}
+	previousStatus := campaign.Status
 
 	if err := c.ShouldBindJSON(&campaign); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !models.ValidReplyAction(campaign.OnReply) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "On reply must be exit, pause or continue"})
+		return
+	}
+	if err := schedule.ValidateCampaign(&campaign); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sending window: " + err.Error()})
+		return
+	}
+	if !models.ValidSenderMode(campaign.SenderMode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sender mode must be rotate or assigned_rep"})
+		return
+	}
+	if !validateSender(c, campaign.SenderIdentityID) {
+		return
+	}
+	if !validateActivation(c, &campaign) {
+		return
+	}
+	// DNS is only checked when the campaign is activated, so a later DNS
+	// outage doesn't lock edits to running campaigns
+	if previousStatus != models.CampaignStatusActive && !validateSendingDomains(c, &campaign) {
+		return
+	}
 
 	if err := database.DB.Save(&campaign).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update campaign"})
@@ -325,6 +410,9 @@ func CreateStepHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !validateStep(c, &step) {
+		return
+	}
 
 	if err := database.DB.Create(&step).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create step"})
@@ -397,6 +485,9 @@ func UpdateStepHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !validateStep(c, &step) {
+		return
+	}
 
 	if err := database.DB.Save(&step).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update step"})
@@ -428,6 +519,9 @@ func DeleteStepHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete step"})
 		return
 	}
+	if err := database.DB.Unscoped().Where("step_id = ?", step.ID).Delete(&models.Attachment{}).Error; err != nil {
+		log.Println("Error removing the step's attachments:", err)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Step deleted successfully"})
 }
@@ -459,6 +553,16 @@ func CreateCustomerHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Email, FirstName, and LastName are required fields"})
 		return
 	}
+	if !schedule.ValidTimezone(customer.Timezone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone " + customer.Timezone})
+		return
+	}
+	locale, ok := locales.Normalize(customer.Locale)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid locale " + customer.Locale})
+		return
+	}
+	customer.Locale = locale
 
 	if err := database.DB.Create(&customer).Error; err != nil {
 		log.Println("Error creating customer in database:", err)
@@ -466,6 +570,10 @@ func CreateCustomerHandler(c *gin.Context) {
 		return
 	}
 
+	if err := triggers.CustomerCreated(&customer); err != nil {
+		log.Println("Error recording customer events:", err)
+	}
+
 	log.Println("Customer created successfully:", customer)
 	c.JSON(http.StatusCreated, customer)
 }
@@ -528,16 +636,36 @@ func UpdateCustomerHandler(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
 		return
 	}
+	before := customer
 	if err := c.ShouldBindJSON(&customer); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !schedule.ValidTimezone(customer.Timezone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone " + customer.Timezone})
+		return
+	}
+	locale, ok := locales.Normalize(customer.Locale)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid locale " + customer.Locale})
+		return
+	}
+	customer.Locale = locale
+	// Subscribing a customer who unsubscribed, with their consent, lets
+	// campaigns email them again
+	if customer.Subscribed && !before.Subscribed {
+		customer.UnsubscribedAt = nil
+	}
 
 	if err := database.DB.Save(&customer).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update customer"})
 		return
 	}
 
+	if err := triggers.CustomerUpdated(&before, &customer); err != nil {
+		log.Println("Error recording customer events:", err)
+	}
+
 	c.JSON(http.StatusOK, customer)
 }
 
@@ -584,6 +712,13 @@ func CreateCampaignCustomerHandler(c *gin.Context) {
 		return
 	}
 
+	if campaignCustomer.Status == "" {
+		campaignCustomer.Status = models.EnrollmentActive
+	}
+	if campaignCustomer.StartDate.IsZero() {
+		campaignCustomer.StartDate = time.Now()
+	}
+
 	if err := database.DB.Create(&campaignCustomer).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create campaign customer"})
 		return
@@ -692,7 +827,7 @@ func DeleteCampaignCustomerHandler(c *gin.Context) {
 
 // GetSettingsHandler retrieves the user settings
 // @Summary Get settings
-// @Description Retrieve the user settings
+// @Description Retrieve the user settings. Secret fields are masked.
 // @Tags Settings
 // @Produce json
 // @Success 200 {object} models.Settings
@@ -710,7 +845,7 @@ func GetSettingsHandler(c *gin.Context) {
 
 // UpdateSettingsHandler updates the user settings
 // @Summary Update settings
-// @Description Update the user settings
+// @Description Update the user settings. Secret fields are write-only: sending back the masked value keeps the stored secret.
 // @Tags Settings
 // @Accept json
 // @Produce json
@@ -739,14 +874,14 @@ func UpdateSettingsHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, settings)
 }
 
-// SendEmailHandler sends an email using Gmail SMTP with simple authentication
+// SendEmailHandler queues an email to be sent through Gmail SMTP
 // @Summary Send an email
-// @Description Send an email using Gmail SMTP with simple authentication
+// @Description Queue an email to be sent through Gmail SMTP. Temporary failures are retried; the job can be followed under /send-queue/jobs.
 // @Tags Email
 // @Accept json
 // @Produce json
 // @Param emailRequest body models.EmailRequest true "Email request data"
-// @Success 200 {object} models.SuccessResponse
+// @Success 202 {object} models.QueuedEmail
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /send-email [post]
@@ -757,38 +892,30 @@ func SendEmailHandler(c *gin.Context) {
 		return
 	}
 
-	to := emailRequest.To
-	subject := emailRequest.Subject
-	body := emailRequest.Body
-
-	// Retrieve email settings from the database
-	var settings models.Settings
-	if err := database.DB.First(&settings).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve email settings"})
+	// Sending is asynchronous now, so catch a missing recipient up front
+	if !strings.Contains(emailRequest.To, "@") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "To must be an email address"})
 		return
 	}
 
-	from := settings.GmailEmail
-	password := settings.GmailPassword
-
-	msg := []byte(fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s", from, to, subject, body))
-
-	smtpServer := "smtp.gmail.com"
-	smtpPort := "587"
-	authEmail := smtp.PlainAuth("", from, password, smtpServer)
+	msg := mailer.Message{
+		To:      emailRequest.To,
+		Subject: emailRequest.Subject,
+		Body:    emailRequest.Body,
+	}
 
-	err := smtp.SendMail(smtpServer+":"+smtpPort, authEmail, from, []string{to}, msg)
+	job, err := sendqueue.Enqueue(msg, 0, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send email"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue email"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Email sent successfully"})
+	c.JSON(http.StatusAccepted, job)
 }
 
 // CreateEmailTemplateHandler creates a new email template
 // @Summary Create an email template
-// @Description Create a new email template
+// @Description Create a new email template. The response lists the linter's warnings, if any.
 // @Tags EmailTemplates
 // @Accept json
 // @Produce json
@@ -804,11 +931,16 @@ func CreateEmailTemplateHandler(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Create(&emailTemplate).Error; err != nil {
+	if !validateTemplateContent(c, &emailTemplate) {
+		return
+	}
+
+	if err := templates.Save(&emailTemplate, auth.CurrentUserID(c)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create email template"})
 		return
 	}
 
+	emailTemplate.Warnings = lintTemplate(&emailTemplate)
 	c.JSON(http.StatusCreated, emailTemplate)
 }
 
@@ -850,7 +982,7 @@ func GetEmailTemplateHandler(c *gin.Context) {
 
 // UpdateEmailTemplateHandler updates a specific email template by ID
 // @Summary Update an email template
-// @Description Update a specific email template by ID
+// @Description Update a specific email template by ID. A change saves a new version, so earlier ones can be compared and restored. The response lists the linter's warnings, if any.
 // @Tags EmailTemplates
 // @Accept json
 // @Produce json
@@ -874,14 +1006,53 @@ func UpdateEmailTemplateHandler(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Save(&emailTemplate).Error; err != nil {
+	if !validateTemplateContent(c, &emailTemplate) {
+		return
+	}
+
+	if err := templates.Save(&emailTemplate, auth.CurrentUserID(c)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update email template"})
 		return
 	}
 
+	emailTemplate.Warnings = lintTemplate(&emailTemplate)
 	c.JSON(http.StatusOK, emailTemplate)
 }
 
+// LintEmailTemplateHandler checks an email template for deliverability problems
+// @Summary Lint an email template
+// @Description Check a template, with its layout and partials included, for spam-trigger phrases, image-heavy layouts, a missing plain-text alternative or unsubscribe link, broken merge fields, HTML big enough for Gmail to clip, URL shorteners and links whose text shows a different address
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path int true "Email template ID"
+// @Success 200 {object} models.TemplateLint
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /templates/{id}/lint [post]
+func LintEmailTemplateHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var emailTemplate models.EmailTemplate
+	if err := database.DB.First(&emailTemplate, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+
+	expanded, err := templates.Prepare(&emailTemplate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template: " + err.Error()})
+		return
+	}
+	warnings := lint.Template(expanded)
+	if warnings == nil {
+		warnings = []models.LintWarning{}
+	}
+	c.JSON(http.StatusOK, models.TemplateLint{
+		TemplateID: emailTemplate.ID,
+		SizeBytes:  len(expanded.Body),
+		Warnings:   warnings,
+	})
+}
+
 // DeleteEmailTemplateHandler deletes a specific email template by ID
 // @Summary Delete an email template
 // @Description Delete a specific email template by ID
@@ -904,6 +1075,12 @@ func DeleteEmailTemplateHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete email template"})
 		return
 	}
+	if err := database.DB.Unscoped().Where("email_template_id = ?", emailTemplate.ID).Delete(&models.Attachment{}).Error; err != nil {
+		log.Println("Error removing the template's attachments:", err)
+	}
+	if err := locales.DeleteForTemplate(emailTemplate.ID); err != nil {
+		log.Println("Error removing the template's localizations:", err)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Email template deleted successfully"})
 }
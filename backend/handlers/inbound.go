@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/bounce"
+	"github.com/4cecoder/drip-campaign/config"
+	"github.com/4cecoder/drip-campaign/inbound"
+	"github.com/4cecoder/drip-campaign/reply"
+	"github.com/gin-gonic/gin"
+)
+
+// maxInboundMessageSize caps the size of a posted raw email
+const maxInboundMessageSize = 10 << 20
+
+// InboundBounceHandler processes a bounce or feedback-loop report
+// @Summary Process a bounce or complaint
+// @Description Submit a raw RFC 822 message containing a delivery status notification (RFC 3464) or an ARF feedback report. Matching email logs are marked bounced or complained and customers are suppressed once the configured thresholds are reached. A message with the Message-ID of one already processed, posted or fetched from a polled mailbox, is only acknowledged.
+// @Tags Inbound
+// @Accept plain
+// @Produce json
+// @Param message body string true "Raw email message"
+// @Success 200 {array} bounce.Result
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /inbound/bounces [post]
+func InboundBounceHandler(c *gin.Context) {
+	raw, err := io.ReadAll(io.LimitReader(c.Request.Body, maxInboundMessageSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read message"})
+		return
+	}
+	if len(raw) > maxInboundMessageSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Message is too large"})
+		return
+	}
+
+	// The message is recorded like a polled one, so a bounce that's posted
+	// again or also fetched from the bounce mailbox isn't counted twice
+	var results []bounce.Result
+	var parseErr error
+	policy := bounce.NewPolicy(config.LoadConfig())
+	now := time.Now()
+	_, err = inbound.Handle("webhook/bounces", raw, now, func(raw []byte) (bool, error) {
+		reports, err := bounce.Parse(bytes.NewReader(raw))
+		if err != nil {
+			parseErr = err
+			return false, nil
+		}
+		results = make([]bounce.Result, 0, len(reports))
+		for _, report := range reports {
+			result, err := bounce.Process(report, policy, now)
+			if err != nil {
+				return false, err
+			}
+			results = append(results, result)
+		}
+		return true, nil
+	})
+	if err == inbound.ErrDuplicate {
+		c.JSON(http.StatusOK, gin.H{"message": "Message was already processed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process report"})
+		return
+	}
+	if parseErr == bounce.ErrNotReport {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Message is not a bounce or complaint report"})
+		return
+	}
+	if parseErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message: " + parseErr.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// InboundReplyHandler processes a reply to a campaign email
+// @Summary Process a reply
+// @Description Submit a raw RFC 822 message. When its In-Reply-To or References headers match a sent email, the reply is recorded on the customer's timeline and the campaign's on_reply action is applied to their enrollment. A reply with the Message-ID of one already processed, posted or fetched from the sending account, is only acknowledged.
+// @Tags Inbound
+// @Accept plain
+// @Produce json
+// @Param message body string true "Raw email message"
+// @Success 200 {object} reply.Result
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /inbound/replies [post]
+func InboundReplyHandler(c *gin.Context) {
+	raw, err := io.ReadAll(io.LimitReader(c.Request.Body, maxInboundMessageSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read message"})
+		return
+	}
+	if len(raw) > maxInboundMessageSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Message is too large"})
+		return
+	}
+
+	// Matched replies are recorded like polled ones, so a reply that's posted
+	// again or also fetched from the sending account is applied once
+	var result reply.Result
+	var parseErr error
+	_, err = inbound.Handle("webhook/replies", raw, time.Now(), func(raw []byte) (bool, error) {
+		parsed, err := reply.Parse(bytes.NewReader(raw))
+		if err != nil {
+			parseErr = err
+			return false, nil
+		}
+		result, err = reply.Process(parsed, time.Now())
+		return result.Matched, err
+	})
+	if err == inbound.ErrDuplicate {
+		c.JSON(http.StatusOK, gin.H{"message": "Message was already processed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process reply"})
+		return
+	}
+	if parseErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message: " + parseErr.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
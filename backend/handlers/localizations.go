@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/4cecoder/drip-campaign/auth"
+	"github.com/4cecoder/drip-campaign/config"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/engine"
+	"github.com/4cecoder/drip-campaign/locales"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetTemplateLocalizationsHandler retrieves the localizations of a template
+// @Summary Get a template's localizations
+// @Description Retrieve the translations of an email template, by locale
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path int true "Email template ID"
+// @Success 200 {array} models.TemplateLocalization
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /templates/{id}/localizations [get]
+func GetTemplateLocalizationsHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var emailTemplate models.EmailTemplate
+	if err := database.DB.First(&emailTemplate, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+
+	var localizations []models.TemplateLocalization
+	if err := database.DB.Where("email_template_id = ?", emailTemplate.ID).Order("locale asc").Find(&localizations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve localizations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, localizations)
+}
+
+// GetTemplateLocalizationHandler retrieves one localization of a template
+// @Summary Get a template localization
+// @Description Retrieve an email template's translation for a locale
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path int true "Email template ID"
+// @Param locale path string true "Locale, such as es or fr-CA"
+// @Success 200 {object} models.TemplateLocalization
+// @Failure 404 {object} models.ErrorResponse
+// @Router /templates/{id}/localizations/{locale} [get]
+func GetTemplateLocalizationHandler(c *gin.Context) {
+	_, localization, ok := findTemplateLocalization(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, localization)
+}
+
+// PutTemplateLocalizationHandler creates or replaces a localization of a template
+// @Summary Save a template localization
+// @Description Create or replace an email template's translation for a locale. Customers whose locale or language matches are sent it instead of the template's own content; it has the template's content type and layout. A change saves a new revision, and steps pinned to a template version are sent the revision that was current at that version. The response lists the linter's warnings, if any.
+// @Tags EmailTemplates
+// @Accept json
+// @Produce json
+// @Param id path int true "Email template ID"
+// @Param locale path string true "Locale, such as es or fr-CA"
+// @Param localization body models.TemplateLocalization true "Translated subject and body"
+// @Success 200 {object} models.TemplateLocalization
+// @Success 201 {object} models.TemplateLocalization
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /templates/{id}/localizations/{locale} [put]
+func PutTemplateLocalizationHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var emailTemplate models.EmailTemplate
+	if err := database.DB.First(&emailTemplate, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+	locale, ok := locales.Normalize(c.Param("locale"))
+	if !ok || locale == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid locale " + c.Param("locale")})
+		return
+	}
+	if defaultLocale, _ := locales.Normalize(config.LoadConfig().DefaultLocale); locale == defaultLocale {
+		c.JSON(http.StatusBadRequest, gin.H{"error": locale + " is the default locale; edit the template itself"})
+		return
+	}
+
+	var localization models.TemplateLocalization
+	status := http.StatusOK
+	if err := database.DB.Where("email_template_id = ? AND locale = ?", emailTemplate.ID, locale).First(&localization).Error; err != nil {
+		status = http.StatusCreated
+	}
+	if err := c.ShouldBindJSON(&localization); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	localization.EmailTemplateID = emailTemplate.ID
+	localization.Locale = locale
+	if strings.TrimSpace(localization.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Body is required"})
+		return
+	}
+
+	// The localization is checked as it will be sent, in the template's
+	// content type and layout
+	localized := emailTemplate
+	localized.Subject = localization.Subject
+	localized.Body = localization.Body
+	localized.TextBody = localization.TextBody
+	if !validateTemplateContent(c, &localized) {
+		return
+	}
+
+	if err := locales.Save(&localization, emailTemplate.Version, auth.CurrentUserID(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save localization"})
+		return
+	}
+
+	localization.Warnings = lintTemplate(&localized)
+	c.JSON(status, localization)
+}
+
+// GetLocalizationRevisionsHandler retrieves the revision history of a localization
+// @Summary Get a template localization's revisions
+// @Description Retrieve every revision of an email template's translation for a locale, newest first, with the template version each was saved at
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path int true "Email template ID"
+// @Param locale path string true "Locale, such as es or fr-CA"
+// @Success 200 {array} models.LocalizationRevision
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /templates/{id}/localizations/{locale}/revisions [get]
+func GetLocalizationRevisionsHandler(c *gin.Context) {
+	_, localization, ok := findTemplateLocalization(c)
+	if !ok {
+		return
+	}
+
+	var revisions []models.LocalizationRevision
+	if err := database.DB.Where("email_template_id = ? AND locale = ?", localization.EmailTemplateID, localization.Locale).
+		Order("revision desc").Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve localization revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// DeleteTemplateLocalizationHandler deletes a localization of a template
+// @Summary Delete a template localization
+// @Description Delete an email template's translation for a locale and its revisions. Customers with the locale are sent the template's own content.
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path int true "Email template ID"
+// @Param locale path string true "Locale, such as es or fr-CA"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /templates/{id}/localizations/{locale} [delete]
+func DeleteTemplateLocalizationHandler(c *gin.Context) {
+	_, localization, ok := findTemplateLocalization(c)
+	if !ok {
+		return
+	}
+
+	if err := locales.Delete(&localization); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete localization"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Localization deleted successfully"})
+}
+
+// GetCampaignTranslationsHandler reports the translations a campaign is missing
+// @Summary Get a campaign's missing translations
+// @Description List the templates a campaign sends with the locales of its active and paused customers they aren't translated into. Those customers are sent the default locale's content. Locales can be checked before any customer has them.
+// @Tags Campaigns
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Param locales query string false "Comma-separated locales to check as well, such as es,fr,de"
+// @Success 200 {object} models.TranslationReport
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /campaigns/{id}/translations [get]
+func GetCampaignTranslationsHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var campaign models.DripCampaign
+	if err := database.DB.First(&campaign, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	var wanted []string
+	for _, text := range strings.Split(c.Query("locales"), ",") {
+		locale, ok := locales.Normalize(text)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid locale " + text})
+			return
+		}
+		if locale != "" {
+			wanted = append(wanted, locale)
+		}
+	}
+
+	steps, err := engine.CampaignSteps(campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve steps"})
+		return
+	}
+
+	defaultLocale, _ := locales.Normalize(config.LoadConfig().DefaultLocale)
+	report, err := locales.Report(campaign.ID, steps, defaultLocale, wanted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build translation report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func findTemplateLocalization(c *gin.Context) (models.EmailTemplate, models.TemplateLocalization, bool) {
+	var localization models.TemplateLocalization
+	id, _ := strconv.Atoi(c.Param("id"))
+	var emailTemplate models.EmailTemplate
+	if err := database.DB.First(&emailTemplate, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return emailTemplate, localization, false
+	}
+
+	locale, _ := locales.Normalize(c.Param("locale"))
+	if err := database.DB.Where("email_template_id = ? AND locale = ?", emailTemplate.ID, locale).First(&localization).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Localization not found"})
+		return emailTemplate, localization, false
+	}
+	return emailTemplate, localization, true
+}
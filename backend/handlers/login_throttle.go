@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/auth"
+	"github.com/4cecoder/drip-campaign/config"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// UnlockUserHandler clears a user's failed login count and lockout
+// @Summary Unlock a user
+// @Description Clear the failed login count and lockout of a user
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /users/{id}/unlock [post]
+func UnlockUserHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var user models.User
+	if err := database.DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := resetLoginFailures(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+}
+
+// GetLoginAttemptsHandler retrieves the login audit log
+// @Summary Get login attempts
+// @Description Retrieve recent login attempts, optionally filtered by email, IP address or user
+// @Tags Users
+// @Produce json
+// @Param email query string false "Email"
+// @Param ip query string false "IP address"
+// @Param user_id query int false "User ID"
+// @Param limit query int false "Maximum number of records (default 100)"
+// @Success 200 {array} models.LoginAttempt
+// @Failure 500 {object} models.ErrorResponse
+// @Router /login-attempts [get]
+func GetLoginAttemptsHandler(c *gin.Context) {
+	query := database.DB.Order("created_at desc")
+	if email := c.Query("email"); email != "" {
+		query = query.Where("email = ?", email)
+	}
+	if ip := c.Query("ip"); ip != "" {
+		query = query.Where("ip_address = ?", ip)
+	}
+	if userID, err := strconv.Atoi(c.Query("user_id")); err == nil {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	var attempts []models.LoginAttempt
+	if err := query.Limit(limit).Find(&attempts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve login attempts"})
+		return
+	}
+	c.JSON(http.StatusOK, attempts)
+}
+
+// loginThrottled rejects the request with 429 if the client IP or the account
+// must wait before trying again. user may be nil when the account is unknown.
+func loginThrottled(c *gin.Context, policy auth.LoginPolicy, email string, user *models.User) bool {
+	now := time.Now()
+
+	wait, err := ipRetryAfter(c.ClientIP(), policy, now)
+	if err != nil {
+		log.Println("Error checking login attempts:", err)
+	}
+
+	reason := models.LoginReasonThrottled
+	if user != nil {
+		if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+			reason = models.LoginReasonLocked
+			wait = user.LockedUntil.Sub(now)
+		} else if user.LastFailedLoginAt != nil {
+			failures := policy.Failures(user.FailedLoginCount, user.LockedUntil, now)
+			if accountWait := policy.RetryAfter(failures, *user.LastFailedLoginAt, now); accountWait > wait {
+				wait = accountWait
+			}
+		}
+	}
+
+	if wait <= 0 {
+		return false
+	}
+
+	recordLoginAttempt(c, email, user, false, reason)
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("Too many failed login attempts. Try again in %s", wait.Round(time.Second))})
+	return true
+}
+
+// ipRetryAfter derives the backoff for an IP address from its recent failed attempts
+func ipRetryAfter(ip string, policy auth.LoginPolicy, now time.Time) (time.Duration, error) {
+	var count int
+	var last *time.Time
+	row := database.DB.Model(&models.LoginAttempt{}).
+		Select("count(*), max(created_at)").
+		Where("ip_address = ? AND success = ? AND reason NOT IN (?) AND created_at > ?",
+			ip, false, []string{models.LoginReasonThrottled, models.LoginReasonLocked}, now.Add(-policy.IPWindow)).
+		Row()
+	if err := row.Scan(&count, &last); err != nil {
+		return 0, err
+	}
+	if count == 0 || last == nil {
+		return 0, nil
+	}
+
+	if count >= policy.MaxIPFailures {
+		return last.Add(policy.IPWindow).Sub(now), nil
+	}
+	return policy.RetryAfter(count, *last, now), nil
+}
+
+// recordLoginFailure audits a failed attempt and counts it against the account
+func recordLoginFailure(c *gin.Context, policy auth.LoginPolicy, email string, user *models.User, reason string) {
+	recordLoginAttempt(c, email, user, false, reason)
+	if user == nil {
+		return
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"failed_login_count":   gorm.Expr("failed_login_count + 1"),
+		"last_failed_login_at": now,
+	}
+	failures := policy.Failures(user.FailedLoginCount, user.LockedUntil, now)
+	if failures == 0 {
+		// Start counting again after an expired lockout
+		updates["failed_login_count"] = 1
+		updates["locked_until"] = nil
+	}
+	if failures+1 >= policy.MaxAccountFailures {
+		updates["locked_until"] = now.Add(policy.LockoutDuration)
+		log.Printf("Locking account %d after %d failed login attempts", user.ID, failures+1)
+	}
+	if err := database.DB.Model(user).UpdateColumns(updates).Error; err != nil {
+		log.Println("Error recording failed login:", err)
+	}
+}
+
+// recordLoginSuccess audits a successful login and clears the account's failures
+func recordLoginSuccess(c *gin.Context, user *models.User) {
+	recordLoginAttempt(c, user.Email, user, true, models.LoginReasonSuccess)
+	if user.FailedLoginCount == 0 && user.LockedUntil == nil {
+		return
+	}
+	if err := resetLoginFailures(user); err != nil {
+		log.Println("Error resetting failed logins:", err)
+	}
+}
+
+func recordLoginAttempt(c *gin.Context, email string, user *models.User, success bool, reason string) {
+	attempt := models.LoginAttempt{
+		Email:     email,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Success:   success,
+		Reason:    reason,
+	}
+	if user != nil {
+		attempt.UserID = user.ID
+	}
+	if err := database.DB.Create(&attempt).Error; err != nil {
+		log.Println("Error recording login attempt:", err)
+	}
+}
+
+func resetLoginFailures(user *models.User) error {
+	return database.DB.Model(user).UpdateColumns(map[string]interface{}{
+		"failed_login_count":   0,
+		"last_failed_login_at": nil,
+		"locked_until":         nil,
+	}).Error
+}
+
+func loginPolicy() auth.LoginPolicy {
+	return auth.NewLoginPolicy(config.LoadConfig())
+}
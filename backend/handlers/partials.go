@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/lint"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/partials"
+	"github.com/4cecoder/drip-campaign/templates"
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePartialHandler creates a partial or layout
+// @Summary Create a partial
+// @Description Create a named snippet templates include with {{> name}}, or, with kind layout, a layout that wraps templates' content where it says {{> content}}
+// @Tags Partials
+// @Accept json
+// @Produce json
+// @Param partial body models.Partial true "Partial data"
+// @Success 201 {object} models.Partial
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /partials [post]
+func CreatePartialHandler(c *gin.Context) {
+	var partial models.Partial
+	if err := c.ShouldBindJSON(&partial); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validatePartial(c, &partial) {
+		return
+	}
+
+	if err := database.DB.Create(&partial).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create partial"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, partial)
+}
+
+// GetPartialsHandler retrieves all partials and layouts
+// @Summary Get all partials
+// @Description Retrieve all partials and layouts, optionally only those of one kind
+// @Tags Partials
+// @Produce json
+// @Param kind query string false "partial or layout"
+// @Success 200 {array} models.Partial
+// @Failure 500 {object} models.ErrorResponse
+// @Router /partials [get]
+func GetPartialsHandler(c *gin.Context) {
+	query := database.DB.Order("name asc")
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var all []models.Partial
+	if err := query.Find(&all).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve partials"})
+		return
+	}
+
+	c.JSON(http.StatusOK, all)
+}
+
+// GetPartialHandler retrieves a specific partial by ID
+// @Summary Get a partial
+// @Description Retrieve a specific partial or layout by ID
+// @Tags Partials
+// @Produce json
+// @Param id path int true "Partial ID"
+// @Success 200 {object} models.Partial
+// @Failure 404 {object} models.ErrorResponse
+// @Router /partials/{id} [get]
+func GetPartialHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var partial models.Partial
+	if err := database.DB.First(&partial, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Partial not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, partial)
+}
+
+// UpdatePartialHandler updates a specific partial by ID
+// @Summary Update a partial
+// @Description Update a specific partial or layout by ID. Templates pick the change up the next time they're sent. Partials in use can't be renamed or change kind.
+// @Tags Partials
+// @Accept json
+// @Produce json
+// @Param id path int true "Partial ID"
+// @Param partial body models.Partial true "Updated partial data"
+// @Success 200 {object} models.Partial
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /partials/{id} [put]
+func UpdatePartialHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var partial models.Partial
+	if err := database.DB.First(&partial, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Partial not found"})
+		return
+	}
+	stored := partial
+
+	if err := c.ShouldBindJSON(&partial); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	partial.ID = stored.ID
+
+	if partial.Name != stored.Name || partial.Kind != stored.Kind {
+		usage, err := partials.UsageOf(&stored)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check where the partial is used"})
+			return
+		}
+		if usage.InUse() {
+			c.JSON(http.StatusConflict, gin.H{"error": "The partial is in use, so it can't be renamed or change kind", "used_by": usage})
+			return
+		}
+	}
+	if !validatePartial(c, &partial) {
+		return
+	}
+
+	if err := database.DB.Save(&partial).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update partial"})
+		return
+	}
+
+	c.JSON(http.StatusOK, partial)
+}
+
+// DeletePartialHandler deletes a specific partial by ID
+// @Summary Delete a partial
+// @Description Delete a partial or layout that no template, pinned template version or other partial uses
+// @Tags Partials
+// @Produce json
+// @Param id path int true "Partial ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /partials/{id} [delete]
+func DeletePartialHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var partial models.Partial
+	if err := database.DB.First(&partial, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Partial not found"})
+		return
+	}
+
+	usage, err := partials.UsageOf(&partial)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check where the partial is used"})
+		return
+	}
+	if usage.InUse() {
+		c.JSON(http.StatusConflict, gin.H{"error": "The partial is still in use", "used_by": usage})
+		return
+	}
+
+	// Unscoped so the name can be reused
+	if err := database.DB.Unscoped().Delete(&partial).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete partial"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Partial deleted successfully"})
+}
+
+// validatePartial checks a partial's name, kind and includes
+func validatePartial(c *gin.Context, partial *models.Partial) bool {
+	partial.Name = strings.TrimSpace(partial.Name)
+	if partial.Kind == "" {
+		partial.Kind = models.PartialSnippet
+	}
+	if !partials.ValidName(partial.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name must be letters, digits, hyphens and underscores, such as signature"})
+		return false
+	}
+	if partial.Name == models.ContentSlot {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content is reserved for layouts' content slot"})
+		return false
+	}
+	if partial.Kind != models.PartialSnippet && partial.Kind != models.PartialLayout {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kind must be partial or layout"})
+		return false
+	}
+
+	var count int
+	if err := database.DB.Model(&models.Partial{}).Where("name = ? AND id <> ?", partial.Name, partial.ID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check partials"})
+		return false
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A partial with that name already exists"})
+		return false
+	}
+
+	set, err := partials.Load()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve partials"})
+		return false
+	}
+	if err := set.Check(partial); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid partial: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+// validateTemplateContent rejects a template whose Markdown or MJML source
+// doesn't compile, or whose layout or partials don't exist or include each
+// other
+func validateTemplateContent(c *gin.Context, template *models.EmailTemplate) bool {
+	if _, err := templates.Prepare(template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+// lintTemplate lints a template as it will be sent, compiled and with its
+// layout and partials included
+func lintTemplate(template *models.EmailTemplate) []models.LintWarning {
+	expanded, err := templates.Prepare(template)
+	if err != nil {
+		expanded = template
+	}
+	return lint.Template(expanded)
+}
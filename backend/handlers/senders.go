@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/sendqueue"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSenderIdentityHandler creates a new sender identity
+// @Summary Create a sender identity
+// @Description Add a mailbox campaigns can send from, with its SMTP credentials, signature, daily cap and warm-up schedule. Identities without an SMTP host send through Gmail.
+// @Tags SenderIdentities
+// @Accept json
+// @Produce json
+// @Param identity body models.SenderIdentity true "Sender identity data"
+// @Success 201 {object} models.SenderIdentity
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /sender-identities [post]
+func CreateSenderIdentityHandler(c *gin.Context) {
+	var identity models.SenderIdentity
+	if err := c.ShouldBindJSON(&identity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validateSenderIdentity(c, &identity) {
+		return
+	}
+
+	if err := database.DB.Create(&identity).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sender identity"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, identity)
+}
+
+// GetSenderIdentitiesHandler retrieves all sender identities
+// @Summary Get all sender identities
+// @Description Retrieve all sender identities. Passwords are masked.
+// @Tags SenderIdentities
+// @Produce json
+// @Success 200 {array} models.SenderIdentity
+// @Failure 500 {object} models.ErrorResponse
+// @Router /sender-identities [get]
+func GetSenderIdentitiesHandler(c *gin.Context) {
+	var identities []models.SenderIdentity
+	if err := database.DB.Order("id asc").Find(&identities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sender identities"})
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+// GetSenderIdentityHandler retrieves a specific sender identity by ID
+// @Summary Get a sender identity
+// @Description Retrieve a specific sender identity by ID. The password is masked.
+// @Tags SenderIdentities
+// @Produce json
+// @Param id path int true "Sender identity ID"
+// @Success 200 {object} models.SenderIdentity
+// @Failure 404 {object} models.ErrorResponse
+// @Router /sender-identities/{id} [get]
+func GetSenderIdentityHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var identity models.SenderIdentity
+	if err := database.DB.First(&identity, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sender identity not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, identity)
+}
+
+// UpdateSenderIdentityHandler updates a specific sender identity by ID
+// @Summary Update a sender identity
+// @Description Update a specific sender identity by ID. Sending the masked password back keeps the stored one.
+// @Tags SenderIdentities
+// @Accept json
+// @Produce json
+// @Param id path int true "Sender identity ID"
+// @Param identity body models.SenderIdentity true "Updated sender identity data"
+// @Success 200 {object} models.SenderIdentity
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /sender-identities/{id} [put]
+func UpdateSenderIdentityHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var identity models.SenderIdentity
+	if err := database.DB.First(&identity, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sender identity not found"})
+		return
+	}
+
+	if err := c.ShouldBindJSON(&identity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validateSenderIdentity(c, &identity) {
+		return
+	}
+
+	if err := database.DB.Save(&identity).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update sender identity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, identity)
+}
+
+// DeleteSenderIdentityHandler deletes a specific sender identity by ID
+// @Summary Delete a sender identity
+// @Description Delete a sender identity that no campaign or step sends from
+// @Tags SenderIdentities
+// @Produce json
+// @Param id path int true "Sender identity ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /sender-identities/{id} [delete]
+func DeleteSenderIdentityHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var identity models.SenderIdentity
+	if err := database.DB.First(&identity, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sender identity not found"})
+		return
+	}
+
+	var campaigns, steps int
+	if err := database.DB.Model(&models.DripCampaign{}).Where("sender_identity_id = ?", identity.ID).Count(&campaigns).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check campaigns"})
+		return
+	}
+	if err := database.DB.Model(&models.Step{}).Where("sender_identity_id = ?", identity.ID).Count(&steps).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check steps"})
+		return
+	}
+	if campaigns > 0 || steps > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Sender identity is still used by a campaign or step"})
+		return
+	}
+
+	if err := database.DB.Delete(&identity).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete sender identity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sender identity deleted successfully"})
+}
+
+// validateSenderIdentity checks the addresses, port, daily cap and warm-up
+// schedule of an identity
+func validateSenderIdentity(c *gin.Context, identity *models.SenderIdentity) bool {
+	identity.FromEmail = strings.TrimSpace(identity.FromEmail)
+	identity.ReplyTo = strings.TrimSpace(identity.ReplyTo)
+	identity.SMTPHost = strings.TrimSpace(identity.SMTPHost)
+
+	if _, err := mail.ParseAddress(identity.FromEmail); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "From email must be an email address"})
+		return false
+	}
+	if identity.ReplyTo != "" {
+		if _, err := mail.ParseAddress(identity.ReplyTo); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reply-to must be an email address"})
+			return false
+		}
+	}
+	if identity.SMTPPort < 0 || identity.SMTPPort > 65535 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "SMTP port must be between 1 and 65535"})
+		return false
+	}
+	if identity.DailyCap < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Daily cap can't be negative"})
+		return false
+	}
+	caps, err := sendqueue.ParseWarmupSchedule(identity.WarmupSchedule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid warm-up schedule: " + err.Error()})
+		return false
+	}
+	if identity.WarmupStartedAt != nil && len(caps) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A warm-up start needs a warm-up schedule"})
+		return false
+	}
+	return true
+}
+
+// validateSender rejects a sender identity ID that doesn't exist. Zero means
+// the Settings account.
+func validateSender(c *gin.Context, identityID uint) bool {
+	if identityID == 0 {
+		return true
+	}
+	var identity models.SenderIdentity
+	if err := database.DB.First(&identity, identityID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sender identity not found"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/config"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/sendqueue"
+	"github.com/gin-gonic/gin"
+)
+
+// GetSendQueueHandler reports the depth of the send queue
+// @Summary Get the send queue
+// @Description Retrieve how many emails are waiting to be sent, per recipient domain, how much of the global and per-domain rate limits has been used, and the warm-up of each sender identity warming up
+// @Tags SendQueue
+// @Produce json
+// @Success 200 {object} models.SendQueueStatus
+// @Failure 500 {object} models.ErrorResponse
+// @Router /send-queue [get]
+func GetSendQueueHandler(c *gin.Context) {
+	status, err := sendqueue.Status(sendqueue.NewLimits(config.LoadConfig()), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve send queue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// CreateDomainRateLimitHandler adds a rate limit for a recipient domain
+// @Summary Create a domain rate limit
+// @Description Limit how many emails are sent to a recipient domain per minute, hour and day. Zero means no limit.
+// @Tags SendQueue
+// @Accept json
+// @Produce json
+// @Param limit body models.DomainRateLimit true "Rate limit data"
+// @Success 201 {object} models.DomainRateLimit
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /rate-limits [post]
+func CreateDomainRateLimitHandler(c *gin.Context) {
+	var limit models.DomainRateLimit
+	if err := c.ShouldBindJSON(&limit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validateDomainRateLimit(c, &limit) {
+		return
+	}
+
+	if err := database.DB.Create(&limit).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rate limit"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, limit)
+}
+
+// GetDomainRateLimitsHandler retrieves the per-domain rate limits
+// @Summary Get domain rate limits
+// @Description Retrieve the rate limits of recipient domains
+// @Tags SendQueue
+// @Produce json
+// @Success 200 {array} models.DomainRateLimit
+// @Failure 500 {object} models.ErrorResponse
+// @Router /rate-limits [get]
+func GetDomainRateLimitsHandler(c *gin.Context) {
+	var limits []models.DomainRateLimit
+	if err := database.DB.Order("domain asc").Find(&limits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve rate limits"})
+		return
+	}
+
+	c.JSON(http.StatusOK, limits)
+}
+
+// UpdateDomainRateLimitHandler updates a recipient domain's rate limit
+// @Summary Update a domain rate limit
+// @Description Update a specific domain rate limit by ID
+// @Tags SendQueue
+// @Accept json
+// @Produce json
+// @Param id path int true "Rate limit ID"
+// @Param limit body models.DomainRateLimit true "Updated rate limit data"
+// @Success 200 {object} models.DomainRateLimit
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /rate-limits/{id} [put]
+func UpdateDomainRateLimitHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var limit models.DomainRateLimit
+	if err := database.DB.First(&limit, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rate limit not found"})
+		return
+	}
+
+	if err := c.ShouldBindJSON(&limit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validateDomainRateLimit(c, &limit) {
+		return
+	}
+
+	if err := database.DB.Save(&limit).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rate limit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, limit)
+}
+
+// DeleteDomainRateLimitHandler removes a recipient domain's rate limit
+// @Summary Delete a domain rate limit
+// @Description Delete a specific domain rate limit by ID
+// @Tags SendQueue
+// @Produce json
+// @Param id path int true "Rate limit ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /rate-limits/{id} [delete]
+func DeleteDomainRateLimitHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var limit models.DomainRateLimit
+	if err := database.DB.First(&limit, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rate limit not found"})
+		return
+	}
+
+	// Unscoped so the domain can be given a new limit later
+	if err := database.DB.Unscoped().Delete(&limit).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rate limit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rate limit deleted successfully"})
+}
+
+// validateDomainRateLimit normalizes the domain and rejects negative limits
+// and a second limit for the same domain
+func validateDomainRateLimit(c *gin.Context, limit *models.DomainRateLimit) bool {
+	limit.Domain = strings.ToLower(strings.TrimSpace(limit.Domain))
+	if limit.Domain == "" || strings.Contains(limit.Domain, "@") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Domain must be a domain name such as gmail.com"})
+		return false
+	}
+	if limit.PerMinute < 0 || limit.PerHour < 0 || limit.PerDay < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Limits can't be negative"})
+		return false
+	}
+
+	var count int
+	if err := database.DB.Model(&models.DomainRateLimit{}).
+		Where("domain = ? AND id <> ?", limit.Domain, limit.ID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check rate limits"})
+		return false
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "That domain already has a rate limit"})
+		return false
+	}
+	return true
+}
+
+// GetSendJobsHandler retrieves the jobs in the send queue
+// @Summary Get send jobs
+// @Description Retrieve the most recent send queue jobs, newest first, optionally filtered by status (queued, sent, failed, dead or cancelled)
+// @Tags SendQueue
+// @Produce json
+// @Param status query string false "Job status"
+// @Param limit query int false "Maximum number of jobs (default 100, at most 1000)"
+// @Success 200 {array} models.QueuedEmail
+// @Failure 500 {object} models.ErrorResponse
+// @Router /send-queue/jobs [get]
+func GetSendJobsHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	query := database.DB.Order("id desc").Limit(limit)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var jobs []models.QueuedEmail
+	if err := query.Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve send jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, jobs)
+}
+
+// GetSendJobHandler retrieves a send queue job
+// @Summary Get a send job
+// @Description Retrieve a specific send queue job by ID, including its attempts and last error
+// @Tags SendQueue
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} models.QueuedEmail
+// @Failure 404 {object} models.ErrorResponse
+// @Router /send-queue/jobs/{id} [get]
+func GetSendJobHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var job models.QueuedEmail
+	if err := database.DB.First(&job, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Send job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// RetrySendJobHandler puts a failed, dead-lettered or cancelled job back on the queue
+// @Summary Retry a send job
+// @Description Queue a failed, dead-lettered or cancelled job again, due right away and with a fresh set of attempts
+// @Tags SendQueue
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} models.QueuedEmail
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /send-queue/jobs/{id}/retry [post]
+func RetrySendJobHandler(c *gin.Context) {
+	updateSendJob(c, sendqueue.Requeue, "Only failed, dead or cancelled jobs can be retried")
+}
+
+// CancelSendJobHandler takes a queued job off the queue
+// @Summary Cancel a send job
+// @Description Cancel a job that hasn't been sent yet
+// @Tags SendQueue
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} models.QueuedEmail
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /send-queue/jobs/{id}/cancel [post]
+func CancelSendJobHandler(c *gin.Context) {
+	updateSendJob(c, sendqueue.Cancel, "Only queued jobs can be cancelled")
+}
+
+// updateSendJob applies a status change to the job in the path and responds
+// with the job as it is afterwards
+func updateSendJob(c *gin.Context, change func(*models.QueuedEmail) error, conflict string) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var job models.QueuedEmail
+	if err := database.DB.First(&job, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Send job not found"})
+		return
+	}
+
+	if err := change(&job); err != nil {
+		if errors.Is(err, sendqueue.ErrWrongStatus) {
+			c.JSON(http.StatusConflict, gin.H{"error": conflict})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update send job"})
+		return
+	}
+
+	if err := database.DB.First(&job, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve send job"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
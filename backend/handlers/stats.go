@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/analytics"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/gin-gonic/gin"
+)
+
+const statsDateLayout = "2006-01-02"
+
+// GetCampaignStatsHandler reports enrollment and per-step funnel metrics for a campaign
+// @Summary Get campaign stats
+// @Description Retrieve enrollment counts, per stage and step sent/delivered/bounced/opened/clicked/unsubscribed counts and rates, and a time series for charts
+// @Tags Campaigns
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Param interval query string false "Time series bucket: day (default) or week"
+// @Param from query string false "Start of the time series, YYYY-MM-DD (default 30 days ago)"
+// @Param to query string false "End of the time series, YYYY-MM-DD inclusive (default today)"
+// @Success 200 {object} models.CampaignStats
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /campaigns/{id}/stats [get]
+func GetCampaignStatsHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var campaign models.DripCampaign
+	if err := database.DB.First(&campaign, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	interval := c.DefaultQuery("interval", analytics.IntervalDay)
+	if interval != analytics.IntervalDay && interval != analytics.IntervalWeek {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Interval must be day or week"})
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to := today
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(statsDateLayout, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -30)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(statsDateLayout, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "From date must be before to date"})
+		return
+	}
+
+	// to is inclusive, so the series runs until the end of that day
+	stats, err := analytics.CampaignStats(campaign.ID, interval, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve campaign stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/4cecoder/drip-campaign/auth"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/templates"
+	"github.com/gin-gonic/gin"
+)
+
+// GetTemplateVersionsHandler retrieves the version history of a template
+// @Summary Get a template's versions
+// @Description Retrieve every version of an email template, newest first
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path int true "Email template ID"
+// @Success 200 {array} models.TemplateVersion
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /templates/{id}/versions [get]
+func GetTemplateVersionsHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var emailTemplate models.EmailTemplate
+	if err := database.DB.First(&emailTemplate, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+
+	var versions []models.TemplateVersion
+	if err := database.DB.Where("email_template_id = ?", emailTemplate.ID).Order("version desc").Find(&versions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve template versions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// GetTemplateVersionHandler retrieves one version of a template
+// @Summary Get a template version
+// @Description Retrieve a specific version of an email template
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path int true "Email template ID"
+// @Param version path int true "Version number"
+// @Success 200 {object} models.TemplateVersion
+// @Failure 404 {object} models.ErrorResponse
+// @Router /templates/{id}/versions/{version} [get]
+func GetTemplateVersionHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	number, _ := strconv.Atoi(c.Param("version"))
+	version, err := templates.Version(uint(id), number)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template version not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+// DiffTemplateVersionsHandler compares two versions of a template
+// @Summary Diff template versions
+// @Description Compare two versions of an email template line by line. to defaults to the current version and from to the one before to.
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path int true "Email template ID"
+// @Param from query int false "Older version"
+// @Param to query int false "Newer version"
+// @Success 200 {object} models.TemplateDiff
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /templates/{id}/diff [get]
+func DiffTemplateVersionsHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var emailTemplate models.EmailTemplate
+	if err := database.DB.First(&emailTemplate, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+
+	to := emailTemplate.Version
+	if value := c.Query("to"); value != "" {
+		var err error
+		if to, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a version number"})
+			return
+		}
+	}
+	from := to - 1
+	if value := c.Query("from"); value != "" {
+		var err error
+		if from, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a version number"})
+			return
+		}
+	}
+
+	toVersion, err := templates.Version(emailTemplate.ID, to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template version " + strconv.Itoa(to) + " not found"})
+		return
+	}
+	fromVersion, err := templates.Version(emailTemplate.ID, from)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template version " + strconv.Itoa(from) + " not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates.Diff(fromVersion, toVersion))
+}
+
+// RestoreTemplateVersionHandler rolls a template back to an earlier version
+// @Summary Restore a template version
+// @Description Make an earlier version's content the template's current content. This saves a new version, so the history is kept and the restore can itself be undone.
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path int true "Email template ID"
+// @Param version path int true "Version number to restore"
+// @Success 200 {object} models.EmailTemplate
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /templates/{id}/versions/{version}/restore [post]
+func RestoreTemplateVersionHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	number, _ := strconv.Atoi(c.Param("version"))
+	var emailTemplate models.EmailTemplate
+	if err := database.DB.First(&emailTemplate, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+
+	// The version may include partials that have changed since
+	version, err := templates.Version(emailTemplate.ID, number)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template version not found"})
+		return
+	}
+	restored := emailTemplate
+	restored.Body, restored.TextBody, restored.LayoutID = version.Body, version.TextBody, version.LayoutID
+	if !validateTemplateContent(c, &restored) {
+		return
+	}
+
+	if err := templates.Restore(&emailTemplate, number, auth.CurrentUserID(c)); err != nil {
+		if errors.Is(err, templates.ErrVersionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template version not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore template version"})
+		return
+	}
+
+	emailTemplate.Warnings = lintTemplate(&emailTemplate)
+	c.JSON(http.StatusOK, emailTemplate)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetCustomerTimelineHandler lists the emails sent to a customer and everything they did with them
+// @Summary Get a customer's timeline
+// @Description Retrieve the emails sent to a customer and their opens, clicks, replies, bounces and complaints, newest first
+// @Tags Customers
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Success 200 {array} models.TimelineEntry
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /customers/{id}/timeline [get]
+func GetCustomerTimelineHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var customer models.Customer
+	if err := database.DB.First(&customer, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return
+	}
+
+	var emailLogs []models.EmailLog
+	if err := database.DB.Where("customer_id = ?", customer.ID).Find(&emailLogs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve emails"})
+		return
+	}
+
+	var events []models.EngagementEvent
+	if err := database.DB.Where("customer_id = ?", customer.ID).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		return
+	}
+
+	timeline := make([]models.TimelineEntry, 0, len(emailLogs)+len(events))
+	for _, emailLog := range emailLogs {
+		at := emailLog.SentAt
+		if at.IsZero() {
+			at = emailLog.CreatedAt
+		}
+		timeline = append(timeline, models.TimelineEntry{
+			Type:       "email",
+			At:         at,
+			EmailLogID: emailLog.ID,
+			CampaignID: emailLog.CampaignID,
+			StepID:     emailLog.StepID,
+			Subject:    emailLog.Subject,
+			Status:     emailLog.Status,
+		})
+	}
+	for _, event := range events {
+		timeline = append(timeline, models.TimelineEntry{
+			Type:       event.Type,
+			At:         event.OccurredAt,
+			EmailLogID: event.EmailLogID,
+			CampaignID: event.CampaignID,
+			StepID:     event.StepID,
+			URL:        event.URL,
+			Detail:     event.Detail,
+			Bot:        event.Bot,
+		})
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].At.After(timeline[j].At)
+	})
+
+	c.JSON(http.StatusOK, timeline)
+}
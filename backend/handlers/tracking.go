@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/tracking"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// TrackOpenHandler records an email open and serves the tracking pixel
+// @Summary Track an email open
+// @Description Record an open event for the email identified by the signed token and return a 1x1 GIF
+// @Tags Tracking
+// @Produce image/gif
+// @Param token path string true "Signed tracking token followed by .gif"
+// @Success 200 {file} binary
+// @Router /t/o/{token} [get]
+func TrackOpenHandler(c *gin.Context) {
+	// Always serve the pixel so broken or forged tokens look the same as valid ones
+	defer func() {
+		c.Header("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+		c.Header("Pragma", "no-cache")
+		c.Data(http.StatusOK, "image/gif", tracking.Pixel)
+	}()
+
+	emailLogID, err := tracking.ParseOpenToken(c.Param("token"))
+	if err != nil {
+		return
+	}
+
+	var emailLog models.EmailLog
+	if err := database.DB.First(&emailLog, emailLogID).Error; err != nil {
+		return
+	}
+
+	now := time.Now()
+	bot, reason := tracking.ClassifyOpen(c.Request.UserAgent(), emailLog.SentAt, now)
+	event := models.EngagementEvent{
+		Type:       models.EventOpen,
+		EmailLogID: emailLog.ID,
+		CustomerID: emailLog.CustomerID,
+		CampaignID: emailLog.CampaignID,
+		StepID:     emailLog.StepID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		Bot:        bot,
+		BotReason:  reason,
+		OccurredAt: now,
+	}
+	if err := database.DB.Create(&event).Error; err != nil {
+		log.Println("Error recording open event:", err)
+	}
+}
+
+// TrackClickHandler records a link click and redirects to the original URL
+// @Summary Track a link click
+// @Description Record a click event for the link identified by the signed token and redirect to its destination
+// @Tags Tracking
+// @Param token path string true "Signed tracking token"
+// @Success 302
+// @Failure 404 {object} models.ErrorResponse
+// @Router /t/c/{token} [get]
+func TrackClickHandler(c *gin.Context) {
+	// Only destinations signed with JWT_SECRET when the email was sent are
+	// redirected to, so this can't be used as an open redirect
+	emailLogID, target, err := tracking.ParseClickToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+		return
+	}
+
+	var emailLog models.EmailLog
+	if err := database.DB.First(&emailLog, emailLogID).Error; err == nil {
+		now := time.Now()
+		bot, reason := tracking.ClassifyClick(c.Request.UserAgent(), emailLog.SentAt, now)
+		event := models.EngagementEvent{
+			Type:       models.EventClick,
+			EmailLogID: emailLog.ID,
+			CustomerID: emailLog.CustomerID,
+			CampaignID: emailLog.CampaignID,
+			StepID:     emailLog.StepID,
+			URL:        target,
+			IPAddress:  c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			Bot:        bot,
+			BotReason:  reason,
+			OccurredAt: now,
+		}
+		if err := database.DB.Create(&event).Error; err != nil {
+			log.Println("Error recording click event:", err)
+		}
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, target)
+}
+
+// UnsubscribePageHandler asks a recipient to confirm they want to unsubscribe
+// @Summary Show the unsubscribe page
+// @Description Show a page confirming the recipient of the email identified by the signed token wants to unsubscribe. Nothing changes until it's submitted, so link scanners that follow the link don't unsubscribe anyone.
+// @Tags Tracking
+// @Produce html
+// @Param token path string true "Signed unsubscribe token"
+// @Success 200 {string} string
+// @Failure 404 {string} string
+// @Router /t/u/{token} [get]
+func UnsubscribePageHandler(c *gin.Context) {
+	if _, err := tracking.ParseUnsubscribeToken(c.Param("token")); err != nil {
+		unsubscribePage(c, http.StatusNotFound, "This unsubscribe link isn't valid.", false)
+		return
+	}
+	unsubscribePage(c, http.StatusOK, "Do you want to stop receiving these emails?", true)
+}
+
+// UnsubscribeHandler unsubscribes the recipient of an email
+// @Summary Unsubscribe
+// @Description Unsubscribe the recipient of the email identified by the signed token and take them out of every campaign. Mail clients post here with List-Unsubscribe=One-Click (RFC 8058).
+// @Tags Tracking
+// @Produce html
+// @Param token path string true "Signed unsubscribe token"
+// @Success 200 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /t/u/{token} [post]
+func UnsubscribeHandler(c *gin.Context) {
+	emailLogID, err := tracking.ParseUnsubscribeToken(c.Param("token"))
+	if err != nil {
+		unsubscribePage(c, http.StatusNotFound, "This unsubscribe link isn't valid.", false)
+		return
+	}
+
+	var emailLog models.EmailLog
+	if err := database.DB.First(&emailLog, emailLogID).Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+		unsubscribePage(c, http.StatusInternalServerError, "Something went wrong. Please try again.", false)
+		return
+	}
+	if emailLog.ID != 0 {
+		if err := unsubscribeCustomer(&emailLog, c.ClientIP(), c.Request.UserAgent(), time.Now()); err != nil {
+			log.Println("Error unsubscribing customer:", err)
+			unsubscribePage(c, http.StatusInternalServerError, "Something went wrong. Please try again.", false)
+			return
+		}
+	}
+
+	unsubscribePage(c, http.StatusOK, "You've been unsubscribed and won't receive these emails again.", false)
+}
+
+// unsubscribeCustomer opts the recipient of an email out: they're
+// unsubscribed, taken out of every campaign and their queued campaign emails
+// are cancelled. Unsubscribing again changes nothing.
+func unsubscribeCustomer(emailLog *models.EmailLog, ip, userAgent string, now time.Time) error {
+	var customer models.Customer
+	if err := database.DB.First(&customer, emailLog.CustomerID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil
+		}
+		return err
+	}
+	if customer.UnsubscribedAt != nil {
+		return nil
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&customer).UpdateColumns(map[string]interface{}{
+			"subscribed":      false,
+			"unsubscribed_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.CampaignCustomer{}).
+			Where("customer_id = ? AND status IN (?)", customer.ID, []string{"", models.EnrollmentActive, models.EnrollmentPaused}).
+			UpdateColumns(map[string]interface{}{
+				"status":       models.EnrollmentExited,
+				"subscribed":   false,
+				"end_date":     now,
+				"next_step_id": 0,
+				"next_step_at": nil,
+			}).Error; err != nil {
+			return err
+		}
+
+		logIDs := tx.Model(&models.EmailLog{}).Select("id").Where("customer_id = ?", customer.ID).SubQuery()
+		if err := tx.Model(&models.QueuedEmail{}).
+			Where("status = ? AND email_log_id IN ?", models.QueueStatusQueued, logIDs).
+			UpdateColumn("status", models.QueueStatusCancelled).Error; err != nil {
+			return err
+		}
+
+		event := models.EngagementEvent{
+			Type:       models.EventUnsubscribe,
+			EmailLogID: emailLog.ID,
+			CustomerID: customer.ID,
+			CampaignID: emailLog.CampaignID,
+			StepID:     emailLog.StepID,
+			IPAddress:  ip,
+			UserAgent:  userAgent,
+			OccurredAt: now,
+		}
+		return tx.Create(&event).Error
+	})
+}
+
+// unsubscribePage serves the page recipients see at their unsubscribe link,
+// with a button to confirm when confirm is set
+func unsubscribePage(c *gin.Context, status int, message string, confirm bool) {
+	form := ""
+	if confirm {
+		form = `<form method="post"><button type="submit">Unsubscribe</button></form>`
+	}
+	page := fmt.Sprintf(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Unsubscribe</title></head>
+<body style="font-family: sans-serif; max-width: 480px; margin: 64px auto; padding: 0 16px; text-align: center;">
+<p>%s</p>%s
+</body></html>`, html.EscapeString(message), form)
+	c.Header("Cache-Control", "no-store")
+	c.Data(status, "text/html; charset=utf-8", []byte(page))
+}
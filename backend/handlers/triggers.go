@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/triggers"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateCampaignTriggerHandler adds an enrollment trigger to a campaign
+// @Summary Create a campaign trigger
+// @Description Enroll customers in the campaign automatically when they're created, get a tag, change lead status, have a custom event posted, or on the anniversary of a date field
+// @Tags Campaigns
+// @Accept json
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Param trigger body models.CampaignTrigger true "Trigger data"
+// @Success 201 {object} models.CampaignTrigger
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /campaigns/{id}/triggers [post]
+func CreateCampaignTriggerHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var campaign models.DripCampaign
+	if err := database.DB.First(&campaign, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	var trigger models.CampaignTrigger
+	if err := c.ShouldBindJSON(&trigger); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	trigger.CampaignID = campaign.ID
+	trigger.Value = strings.TrimSpace(trigger.Value)
+	if msg := triggers.ValidTrigger(&trigger); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := database.DB.Create(&trigger).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create trigger"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, trigger)
+}
+
+// GetCampaignTriggersHandler retrieves the enrollment triggers of a campaign
+// @Summary Get campaign triggers
+// @Description Retrieve the enrollment triggers of a campaign
+// @Tags Campaigns
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Success 200 {array} models.CampaignTrigger
+// @Failure 500 {object} models.ErrorResponse
+// @Router /campaigns/{id}/triggers [get]
+func GetCampaignTriggersHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var campaignTriggers []models.CampaignTrigger
+	if err := database.DB.Where("campaign_id = ?", id).Order("id asc").Find(&campaignTriggers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve triggers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaignTriggers)
+}
+
+// DeleteCampaignTriggerHandler removes an enrollment trigger from a campaign
+// @Summary Delete a campaign trigger
+// @Description Delete an enrollment trigger. Customers it already enrolled stay enrolled.
+// @Tags Campaigns
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Param trigger_id path int true "Trigger ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /campaigns/{id}/triggers/{trigger_id} [delete]
+func DeleteCampaignTriggerHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	triggerID, _ := strconv.Atoi(c.Param("trigger_id"))
+	var trigger models.CampaignTrigger
+	if err := database.DB.Where("campaign_id = ?", id).First(&trigger, triggerID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Trigger not found"})
+		return
+	}
+
+	if err := database.DB.Delete(&trigger).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete trigger"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Trigger deleted successfully"})
+}
+
+// PostEventHandler records a custom event for a customer
+// @Summary Post a customer event
+// @Description Record a custom event such as trial_started for a customer, identified by customer_id or email. Campaigns with a matching event trigger enroll the customer on the next engine run.
+// @Tags Events
+// @Accept json
+// @Produce json
+// @Param event body models.PostEventRequest true "Event data"
+// @Success 202 {object} models.CustomerEvent
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /events [post]
+func PostEventHandler(c *gin.Context) {
+	var req models.PostEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	var customer models.Customer
+	query := database.DB.Where("id = ?", req.CustomerID)
+	if req.CustomerID == 0 {
+		if req.Email == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Customer ID or email is required"})
+			return
+		}
+		query = database.DB.Where("lower(email) = ?", strings.ToLower(strings.TrimSpace(req.Email)))
+	}
+	if err := query.First(&customer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return
+	}
+
+	event := models.CustomerEvent{
+		CustomerID: customer.ID,
+		Type:       models.TriggerEvent,
+		Value:      req.Name,
+		OccurredAt: time.Now(),
+	}
+	if req.OccurredAt != nil {
+		event.OccurredAt = *req.OccurredAt
+	}
+	if len(req.Properties) > 0 {
+		properties, err := json.Marshal(req.Properties)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid properties"})
+			return
+		}
+		event.Properties = string(properties)
+	}
+
+	if err := database.DB.Create(&event).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record event"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, event)
+}
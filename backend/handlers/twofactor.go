@@ -0,0 +1,505 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/auth"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/secrets"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+var errInvalidTwoFactorCode = errors.New("invalid two-factor code")
+
+// LoginTwoFactorHandler completes a login that requires a second factor
+// @Summary Complete two-factor login
+// @Description Exchange a login challenge token and a TOTP or recovery code for a JWT token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} models.TwoFactorTokenResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /login/2fa [post]
+func LoginTwoFactorHandler(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, purpose, err := auth.ParseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+	// A setup challenge is only good until the user has enrolled; after that
+	// it must not replace their secret
+	if purpose == auth.ChallengeTwoFactorSetup && user.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	policy := loginPolicy()
+	if loginThrottled(c, policy, user.Email, &user) {
+		return
+	}
+
+	var recoveryCodes []string
+	if purpose == auth.ChallengeTwoFactorSetup {
+		// The user is required to use 2FA but hasn't enrolled yet: the code
+		// confirms the secret handed out by LoginTwoFactorSetupHandler.
+		recoveryCodes, err = enableTwoFactor(&user, req.Code)
+	} else {
+		err = verifyTwoFactor(&user, req.Code, req.RecoveryCode)
+	}
+	if err == errInvalidTwoFactorCode {
+		recordLoginFailure(c, policy, user.Email, &user, models.LoginReasonInvalidCode)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+		return
+	}
+
+	token, err := auth.GenerateUserToken(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	recordLoginSuccess(c, &user)
+
+	c.JSON(http.StatusOK, models.TwoFactorTokenResponse{Token: token, RecoveryCodes: recoveryCodes})
+}
+
+// LoginTwoFactorSetupHandler starts enrollment for a user who must set up 2FA to log in
+// @Summary Start required two-factor enrollment
+// @Description Generate a TOTP secret for a user whose account requires 2FA but who has not enrolled
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorSetupRequest true "Challenge token"
+// @Success 200 {object} models.TwoFactorEnrollmentResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /login/2fa/setup [post]
+func LoginTwoFactorSetupHandler(c *gin.Context) {
+	var req models.TwoFactorSetupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, purpose, err := auth.ParseChallengeToken(req.ChallengeToken)
+	if err != nil || purpose != auth.ChallengeTwoFactorSetup {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	enrollment, err := startTwoFactorEnrollment(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// EnrollTwoFactorHandler starts TOTP enrollment for the logged-in user
+// @Summary Start two-factor enrollment
+// @Description Generate a TOTP secret and otpauth URI for the authenticated user
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} models.TwoFactorEnrollmentResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/2fa/enroll [post]
+func EnrollTwoFactorHandler(c *gin.Context) {
+	var user models.User
+	if err := database.DB.First(&user, auth.CurrentUserID(c)).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	enrollment, err := startTwoFactorEnrollment(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTwoFactorHandler confirms TOTP enrollment with a code from the authenticator app
+// @Summary Confirm two-factor enrollment
+// @Description Verify a TOTP code against the pending secret, enable 2FA and return recovery codes
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/2fa/confirm [post]
+func ConfirmTwoFactorHandler(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, auth.CurrentUserID(c)).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	codes, err := enableTwoFactor(&user, req.Code)
+	if err == errInvalidTwoFactorCode {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodesHandler replaces the recovery codes of the logged-in user
+// @Summary Regenerate recovery codes
+// @Description Invalidate existing recovery codes and issue a new set
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorCodeRequest true "Current TOTP code"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/2fa/recovery-codes [post]
+func RegenerateRecoveryCodesHandler(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, auth.CurrentUserID(c)).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	err := verifyTwoFactor(&user, req.Code, "")
+	if err == errInvalidTwoFactorCode {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(database.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactorHandler turns off 2FA for the logged-in user
+// @Summary Disable two-factor authentication
+// @Description Disable 2FA for the authenticated user unless an admin requires it
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorDisableRequest true "Password and TOTP code"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/2fa/disable [post]
+func DisableTwoFactorHandler(c *gin.Context) {
+	var req models.TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, auth.CurrentUserID(c)).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if user.TOTPRequired {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for this account"})
+		return
+	}
+
+	if !models.CheckPasswordHash(req.Password, user.Password) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is incorrect"})
+		return
+	}
+
+	err := verifyTwoFactor(&user, req.Code, "")
+	if err == errInvalidTwoFactorCode {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+		return
+	}
+
+	if err := resetTwoFactor(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// ResetUserTwoFactorHandler clears the 2FA enrollment of a user
+// @Summary Reset a user's two-factor authentication
+// @Description Remove a user's TOTP secret and recovery codes so they can enroll again, and sign them out everywhere
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /users/{id}/2fa [delete]
+func ResetUserTwoFactorHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var user models.User
+	if err := database.DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := resetTwoFactor(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset two-factor authentication"})
+		return
+	}
+	// Whoever holds the user's sessions may be why 2FA was reset
+	if err := models.RevokeTokens(database.DB, &user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign the user out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset successfully"})
+}
+
+// UpdateUserTwoFactorRequirementHandler sets whether a user must use 2FA
+// @Summary Require two-factor authentication
+// @Description Enforce or relax the 2FA requirement for a user. Enforcing it for a user who hasn't enrolled signs them out, so they next log in with 2FA.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body models.TwoFactorRequirementRequest true "Requirement"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /users/{id}/2fa [put]
+func UpdateUserTwoFactorRequirementHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var user models.User
+	if err := database.DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req models.TwoFactorRequirementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Model(&user).UpdateColumn("totp_required", req.Required).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update two-factor requirement"})
+		return
+	}
+	// Sessions opened with only a password end, so the user has to log in
+	// with 2FA
+	if req.Required && !user.TOTPRequired && !user.TOTPEnabled {
+		if err := models.RevokeTokens(database.DB, &user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign the user out"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor requirement updated successfully"})
+}
+
+// startTwoFactorEnrollment stores a new pending TOTP secret for the user
+func startTwoFactorEnrollment(user *models.User) (models.TwoFactorEnrollmentResponse, error) {
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return models.TwoFactorEnrollmentResponse{}, err
+	}
+
+	if err := database.DB.Model(user).UpdateColumns(map[string]interface{}{
+		"totp_secret":       secrets.String(secret),
+		"totp_last_counter": 0,
+	}).Error; err != nil {
+		return models.TwoFactorEnrollmentResponse{}, err
+	}
+
+	return models.TwoFactorEnrollmentResponse{
+		Secret: secret,
+		URI:    auth.TOTPURI(user.Email, secret),
+	}, nil
+}
+
+// enableTwoFactor confirms the pending secret with a code and issues recovery codes
+func enableTwoFactor(user *models.User, code string) ([]string, error) {
+	if user.TOTPSecret == "" {
+		return nil, errInvalidTwoFactorCode
+	}
+
+	counter, ok := auth.ValidateTOTP(string(user.TOTPSecret), code, user.TOTPLastCounter, time.Now())
+	if !ok {
+		return nil, errInvalidTwoFactorCode
+	}
+
+	tx := database.DB.Begin()
+	if err := tx.Model(user).UpdateColumns(map[string]interface{}{
+		"totp_enabled":      true,
+		"totp_last_counter": counter,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(tx, user.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return codes, tx.Commit().Error
+}
+
+// verifyTwoFactor checks a TOTP code, or consumes a recovery code when one is given
+func verifyTwoFactor(user *models.User, code, recoveryCode string) error {
+	if !user.TOTPEnabled {
+		return errInvalidTwoFactorCode
+	}
+
+	if recoveryCode != "" {
+		result := database.DB.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, auth.HashRecoveryCode(recoveryCode)).
+			UpdateColumn("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	counter, ok := auth.ValidateTOTP(string(user.TOTPSecret), code, user.TOTPLastCounter, time.Now())
+	if !ok {
+		return errInvalidTwoFactorCode
+	}
+
+	// Only advance the counter if nobody used the same code concurrently
+	result := database.DB.Model(user).
+		Where("totp_last_counter < ?", counter).
+		UpdateColumn("totp_last_counter", counter)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// replaceRecoveryCodes deletes a user's recovery codes and stores a fresh set
+func replaceRecoveryCodes(db *gorm.DB, userID uint) ([]string, error) {
+	codes, hashes, err := auth.GenerateRecoveryCodes(models.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	for _, hash := range hashes {
+		if err := db.Create(&models.RecoveryCode{UserID: userID, CodeHash: hash}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+// resetTwoFactor removes the user's TOTP secret and recovery codes
+func resetTwoFactor(user *models.User) error {
+	tx := database.DB.Begin()
+	if err := tx.Model(user).UpdateColumns(map[string]interface{}{
+		"totp_secret":       "",
+		"totp_enabled":      false,
+		"totp_last_counter": 0,
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
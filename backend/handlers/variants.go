@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/abtest"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateStepVariantHandler adds an A/B test variant to a step
+// @Summary Create a step variant
+// @Description Add a template variant to a step. Once a step has variants, each recipient is deterministically assigned one of them in proportion to their weights.
+// @Tags Steps
+// @Accept json
+// @Produce json
+// @Param id path int true "Step ID"
+// @Param variant body models.StepVariant true "Variant data"
+// @Success 201 {object} models.StepVariant
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /steps/{id}/variants [post]
+func CreateStepVariantHandler(c *gin.Context) {
+	step, ok := findVariantStep(c)
+	if !ok {
+		return
+	}
+
+	var variant models.StepVariant
+	if err := c.ShouldBindJSON(&variant); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	variant.StepID = step.ID
+	if !validateVariant(c, &variant) {
+		return
+	}
+
+	if err := database.DB.Create(&variant).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create variant"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, variant)
+}
+
+// GetStepVariantsHandler retrieves the A/B test variants of a step
+// @Summary Get step variants
+// @Description Retrieve the template variants of a step
+// @Tags Steps
+// @Produce json
+// @Param id path int true "Step ID"
+// @Success 200 {array} models.StepVariant
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /steps/{id}/variants [get]
+func GetStepVariantsHandler(c *gin.Context) {
+	step, ok := findVariantStep(c)
+	if !ok {
+		return
+	}
+
+	var variants []models.StepVariant
+	if err := database.DB.Where("step_id = ?", step.ID).Preload("EmailTemplate").Order("id asc").Find(&variants).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve variants"})
+		return
+	}
+
+	c.JSON(http.StatusOK, variants)
+}
+
+// UpdateStepVariantHandler updates an A/B test variant
+// @Summary Update a step variant
+// @Description Update the name, template or weight of a step variant
+// @Tags Steps
+// @Accept json
+// @Produce json
+// @Param id path int true "Step ID"
+// @Param variant_id path int true "Variant ID"
+// @Param variant body models.StepVariant true "Updated variant data"
+// @Success 200 {object} models.StepVariant
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /steps/{id}/variants/{variant_id} [put]
+func UpdateStepVariantHandler(c *gin.Context) {
+	_, variant, ok := findStepVariant(c)
+	if !ok {
+		return
+	}
+
+	stepID := variant.StepID
+	if err := c.ShouldBindJSON(&variant); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	variant.StepID = stepID
+	if !validateVariant(c, &variant) {
+		return
+	}
+
+	if err := database.DB.Save(&variant).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update variant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, variant)
+}
+
+// DeleteStepVariantHandler deletes an A/B test variant
+// @Summary Delete a step variant
+// @Description Delete a step variant. Deleting the promoted winner restarts the test.
+// @Tags Steps
+// @Produce json
+// @Param id path int true "Step ID"
+// @Param variant_id path int true "Variant ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /steps/{id}/variants/{variant_id} [delete]
+func DeleteStepVariantHandler(c *gin.Context) {
+	step, variant, ok := findStepVariant(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(&variant).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete variant"})
+		return
+	}
+	if step.WinningVariantID == variant.ID {
+		if err := database.DB.Model(&step).UpdateColumns(map[string]interface{}{
+			"winning_variant_id": 0,
+			"promoted_at":        nil,
+		}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset winning variant"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Variant deleted successfully"})
+}
+
+// PromoteStepVariantHandler makes a variant the winner of its step's A/B test
+// @Summary Promote a step variant
+// @Description Make a variant the winner of the step's A/B test, so every later recipient gets it
+// @Tags Steps
+// @Produce json
+// @Param id path int true "Step ID"
+// @Param variant_id path int true "Variant ID"
+// @Success 200 {object} models.Step
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /steps/{id}/variants/{variant_id}/promote [post]
+func PromoteStepVariantHandler(c *gin.Context) {
+	step, variant, ok := findStepVariant(c)
+	if !ok {
+		return
+	}
+
+	if err := abtest.Promote(&step, variant.ID, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to promote variant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, step)
+}
+
+// GetStepABResultsHandler compares the variants of a step
+// @Summary Get A/B test results
+// @Description Retrieve per-variant sends, opens, clicks and replies with rates, the leader on the step's A/B metric and whether its lead is statistically significant
+// @Tags Steps
+// @Produce json
+// @Param id path int true "Step ID"
+// @Success 200 {object} models.ABTestResults
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /steps/{id}/ab-results [get]
+func GetStepABResultsHandler(c *gin.Context) {
+	step, ok := findVariantStep(c)
+	if !ok {
+		return
+	}
+
+	results, err := abtest.Results(&step)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute A/B test results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+func findVariantStep(c *gin.Context) (models.Step, bool) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var step models.Step
+	if err := database.DB.First(&step, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Step not found"})
+		return step, false
+	}
+	return step, true
+}
+
+func findStepVariant(c *gin.Context) (models.Step, models.StepVariant, bool) {
+	var variant models.StepVariant
+	step, ok := findVariantStep(c)
+	if !ok {
+		return step, variant, false
+	}
+
+	variantID, _ := strconv.Atoi(c.Param("variant_id"))
+	if err := database.DB.Where("step_id = ?", step.ID).First(&variant, variantID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
+		return step, variant, false
+	}
+	return step, variant, true
+}
+
+func validateVariant(c *gin.Context, variant *models.StepVariant) bool {
+	if variant.Weight < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Weight can't be negative"})
+		return false
+	}
+	var template models.EmailTemplate
+	if err := database.DB.First(&template, variant.EmailTemplateID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email template not found"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,220 @@
+// Package imap is a minimal IMAP4rev1 client, just enough to poll a mailbox
+// for new messages, download them and flag them as seen.
+package imap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// timeout bounds every network round trip
+	timeout = time.Minute
+
+	// maxLiteral caps the size of a single literal, and so of a fetched message
+	maxLiteral = 50 << 20
+)
+
+// Client is a connection to an IMAP server
+type Client struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// response is an untagged server response, with any literals it carried
+type response struct {
+	line     string
+	literals [][]byte
+}
+
+// Dial connects to addr ("host:port"), using implicit TLS unless plain is set.
+// Plain connections are meant for local test servers.
+func Dial(addr string, plain bool) (*Client, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	var err error
+	if plain {
+		conn, err = dialer.Dial("tcp", addr)
+	} else {
+		host, _, _ := net.SplitHostPort(addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: host})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.readResponse()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting.line, "* OK") && !strings.HasPrefix(greeting.line, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("imap: unexpected greeting %q", greeting.line)
+	}
+	return c, nil
+}
+
+// Close closes the connection without logging out
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Login authenticates with a username and password
+func (c *Client) Login(username, password string) error {
+	_, err := c.command("LOGIN " + quote(username) + " " + quote(password))
+	return err
+}
+
+// Select opens a mailbox for reading and writing
+func (c *Client) Select(mailbox string) error {
+	_, err := c.command("SELECT " + quote(mailbox))
+	return err
+}
+
+// Search returns the UIDs of the messages matching the criteria, e.g. "UNSEEN"
+func (c *Client) Search(criteria string) ([]uint32, error) {
+	responses, err := c.command("UID SEARCH " + criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	var uids []uint32
+	for _, resp := range responses {
+		if !strings.HasPrefix(resp.line, "* SEARCH") {
+			continue
+		}
+		for _, field := range strings.Fields(resp.line)[2:] {
+			uid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("imap: invalid search result %q", resp.line)
+			}
+			uids = append(uids, uint32(uid))
+		}
+	}
+	return uids, nil
+}
+
+// Fetch downloads the full raw message without marking it as seen
+func (c *Client) Fetch(uid uint32) ([]byte, error) {
+	responses, err := c.command(fmt.Sprintf("UID FETCH %d BODY.PEEK[]", uid))
+	if err != nil {
+		return nil, err
+	}
+	for _, resp := range responses {
+		if strings.Contains(resp.line, " FETCH ") && len(resp.literals) > 0 {
+			return resp.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("imap: message %d not found", uid)
+}
+
+// MarkSeen flags a message as seen
+func (c *Client) MarkSeen(uid uint32) error {
+	_, err := c.command(fmt.Sprintf(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid))
+	return err
+}
+
+// Logout ends the session and closes the connection
+func (c *Client) Logout() error {
+	_, err := c.command("LOGOUT")
+	if closeErr := c.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// SearchDate formats a date for SINCE and BEFORE search criteria
+func SearchDate(t time.Time) string {
+	return t.Format("2-Jan-2006")
+}
+
+// command sends a tagged command and collects the untagged responses until
+// the matching completion
+func (c *Client) command(cmd string) ([]response, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%03d", c.tag)
+
+	c.conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := io.WriteString(c.conn, tag+" "+cmd+"\r\n"); err != nil {
+		return nil, err
+	}
+
+	var responses []response
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(resp.line, tag+" ") {
+			responses = append(responses, resp)
+			continue
+		}
+
+		status := strings.TrimPrefix(resp.line, tag+" ")
+		if strings.HasPrefix(status, "OK") {
+			return responses, nil
+		}
+		// Keep credentials out of error messages
+		if strings.HasPrefix(cmd, "LOGIN ") {
+			cmd = "LOGIN"
+		}
+		return nil, fmt.Errorf("imap: %s failed: %s", cmd, status)
+	}
+}
+
+// readResponse reads one response line, following any {n} literals it contains
+func (c *Client) readResponse() (response, error) {
+	var resp response
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return resp, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		resp.line += line
+
+		size, ok := literalSize(line)
+		if !ok {
+			return resp, nil
+		}
+		if size > maxLiteral {
+			return resp, fmt.Errorf("imap: literal of %d bytes is too large", size)
+		}
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return resp, err
+		}
+		resp.literals = append(resp.literals, literal)
+	}
+}
+
+// literalSize parses a trailing "{n}" literal announcement
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndex(line, "{")
+	if open < 0 {
+		return 0, false
+	}
+	size, err := strconv.Atoi(line[open+1 : len(line)-1])
+	if err != nil || size < 0 {
+		return 0, false
+	}
+	return size, true
+}
+
+// quote returns s as an IMAP quoted string
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,226 @@
+// Package inbound polls IMAP mailboxes and hands new messages to handlers such
+// as the bounce processor and reply detection.
+package inbound
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/imap"
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+const (
+	// lookback limits each search to recent mail so old messages in a shared
+	// mailbox aren't rescanned forever
+	lookback = 7 * 24 * time.Hour
+
+	// idleInterval is how often an unconfigured mailbox is checked for settings
+	idleInterval = time.Minute
+
+	// gmailIMAPServer serves the Gmail sending account set up in Settings
+	gmailIMAPServer = "imap.gmail.com:993"
+)
+
+// Mailbox is an IMAP mailbox to poll
+type Mailbox struct {
+	// Addr is the server's "host:port"
+	Addr     string
+	Username string
+	Password string
+	Folder   string
+
+	// Plain disables TLS, for local test servers
+	Plain bool
+
+	// Peek leaves a mailbox that people also read untouched: read messages are
+	// looked at too, and nothing is marked as seen
+	Peek bool
+}
+
+// Handler processes a raw message and reports whether it recognised it.
+// Messages no handler recognises are left unread for a human; errors are
+// treated as temporary and the message is retried on the next poll.
+type Handler func(raw []byte) (bool, error)
+
+// Source returns the mailbox to poll and how long to wait before the next
+// poll. It's called before every poll so settings changes are picked up;
+// ok is false while the mailbox isn't configured.
+type Source func() (mailbox Mailbox, interval time.Duration, ok bool)
+
+// Start polls the mailbox from source until the process exits. Messages that
+// aren't handled are only looked at once per run.
+func Start(name string, source Source, handlers ...Handler) {
+	log.Printf("Starting %s mailbox poller", name)
+	var current Mailbox
+	var lastUID uint32
+	for {
+		mailbox, interval, ok := source()
+		if ok {
+			if mailbox != current {
+				current, lastUID = mailbox, 0
+			}
+			handled, last, err := Poll(mailbox, lastUID, time.Now(), handlers...)
+			lastUID = last
+			if err != nil {
+				log.Printf("Error polling %s mailbox: %v", name, err)
+			} else if handled > 0 {
+				log.Printf("Processed %d messages from %s mailbox", handled, name)
+			}
+		}
+		if interval <= 0 {
+			interval = idleInterval
+		}
+		time.Sleep(interval)
+	}
+}
+
+// SendingAccount is the Source for the Gmail account campaigns are sent from,
+// polled every Settings.EmailPollingSeconds
+func SendingAccount() (Mailbox, time.Duration, bool) {
+	var settings models.Settings
+	if err := database.DB.First(&settings).Error; err != nil {
+		return Mailbox{}, idleInterval, false
+	}
+	if settings.GmailEmail == "" || settings.GmailPassword == "" || settings.EmailPollingSeconds <= 0 {
+		return Mailbox{}, idleInterval, false
+	}
+
+	mailbox := Mailbox{
+		Addr:     gmailIMAPServer,
+		Username: settings.GmailEmail,
+		Password: string(settings.GmailPassword),
+		Folder:   "INBOX",
+		Peek:     true,
+	}
+	return mailbox, time.Duration(settings.EmailPollingSeconds) * time.Second, true
+}
+
+// Poll fetches the messages with a UID above after and passes each to the
+// handlers in turn until one recognises it. Handled messages are recorded so
+// they're never processed twice, and marked as seen unless the mailbox is
+// peeked at. It returns how many messages were handled and the highest UID
+// looked at.
+func Poll(mailbox Mailbox, after uint32, now time.Time, handlers ...Handler) (int, uint32, error) {
+	folder := mailbox.Folder
+	if folder == "" {
+		folder = "INBOX"
+	}
+
+	client, err := imap.Dial(mailbox.Addr, mailbox.Plain)
+	if err != nil {
+		return 0, after, err
+	}
+	defer client.Close()
+
+	if err := client.Login(mailbox.Username, mailbox.Password); err != nil {
+		return 0, after, err
+	}
+	if err := client.Select(folder); err != nil {
+		return 0, after, err
+	}
+
+	criteria := fmt.Sprintf("SINCE %s UID %d:*", imap.SearchDate(now.Add(-lookback)), after+1)
+	if !mailbox.Peek {
+		criteria = "UNSEEN " + criteria
+	}
+	uids, err := client.Search(criteria)
+	if err != nil {
+		return 0, after, err
+	}
+
+	handled := 0
+	last := after
+	for _, uid := range uids {
+		// "n:*" always matches the newest message, even below n
+		if uid <= after {
+			continue
+		}
+		raw, err := client.Fetch(uid)
+		if err != nil {
+			return handled, last, err
+		}
+
+		ok, err := handle(mailbox.Username+"/"+folder, raw, now, handlers)
+		if err != nil {
+			return handled, last, err
+		}
+		if ok {
+			if !mailbox.Peek {
+				if err := client.MarkSeen(uid); err != nil {
+					return handled, last, err
+				}
+			}
+			handled++
+		}
+		if uid > last {
+			last = uid
+		}
+	}
+
+	return handled, last, client.Logout()
+}
+
+// ErrDuplicate is returned by Handle for a message that was handled before,
+// whether it was fetched from a mailbox or posted
+var ErrDuplicate = errors.New("message was already processed")
+
+// handle runs the handlers on a fetched message. Messages handled before are
+// skipped.
+func handle(mailbox string, raw []byte, now time.Time, handlers []Handler) (bool, error) {
+	ok, err := Handle(mailbox, raw, now, handlers...)
+	if err == ErrDuplicate {
+		return false, nil
+	}
+	return ok, err
+}
+
+// Handle runs the handlers on a message that hasn't been handled before and
+// records it once one of them has, with source as its mailbox. Webhooks use
+// it as Poll does, so a message that's both posted and fetched, or posted
+// twice, is only processed once.
+func Handle(source string, raw []byte, now time.Time, handlers ...Handler) (bool, error) {
+	messageID := messageKey(raw)
+
+	var count int
+	if err := database.DB.Model(&models.InboundMessage{}).Where("message_id = ?", messageID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, ErrDuplicate
+	}
+
+	for _, handler := range handlers {
+		ok, err := handler(raw)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			continue
+		}
+
+		record := models.InboundMessage{MessageID: messageID, Mailbox: source, ReceivedAt: now}
+		return true, database.DB.Create(&record).Error
+	}
+	return false, nil
+}
+
+// messageKey identifies a message by its Message-ID, or by a hash of its
+// content when it doesn't have one
+func messageKey(raw []byte) string {
+	if msg, err := mail.ReadMessage(bytes.NewReader(raw)); err == nil {
+		if id := strings.TrimSpace(msg.Header.Get("Message-Id")); id != "" {
+			return id
+		}
+	}
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,384 @@
+// Package lint checks email templates for content that hurts deliverability
+// or rendering: spam-trigger phrases, image-heavy layouts, missing plain-text
+// parts and unsubscribe links, broken merge fields, clipped HTML, URL
+// shorteners and links whose text shows a different address.
+package lint
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/render"
+	"golang.org/x/net/html"
+)
+
+const (
+	// gmailClipBytes is the HTML size beyond which Gmail clips a message
+	// behind a "View entire message" link, hiding the rest and the open pixel
+	gmailClipBytes = 102 * 1024
+
+	// nearClipBytes leaves room for the tracking links and pixel added when sending
+	nearClipBytes = 90 * 1024
+
+	// textPerImage is the least visible text, in characters, each image should
+	// be balanced by
+	textPerImage = 400
+)
+
+// spamPhrases are phrases spam filters commonly score against
+var spamPhrases = []string{
+	"100% free", "100% satisfied", "act now", "additional income", "all new",
+	"apply now", "as seen on", "be your own boss", "best price", "big bucks",
+	"buy direct", "buy now", "call now", "cash bonus", "cheap", "click below",
+	"click here", "congratulations", "dear friend", "double your", "earn $",
+	"earn extra cash", "eliminate debt", "extra income", "fast cash",
+	"financial freedom", "free access", "free gift", "free money", "free trial",
+	"get paid", "guaranteed", "increase sales", "incredible deal",
+	"limited time", "lowest price", "make money", "million dollars",
+	"miracle", "money back", "no catch", "no cost", "no credit check",
+	"no fees", "no obligation", "once in a lifetime", "order now",
+	"risk-free", "risk free", "special promotion", "this isn't spam",
+	"this is not spam", "urgent", "winner", "you have been selected",
+	"you're a winner", "while supplies last", "why pay more", "$$$",
+}
+
+// shorteners are URL shortener hosts, which hide the destination and are
+// widely abused, so filters distrust them
+var shorteners = map[string]bool{
+	"bit.ly": true, "bitly.com": true, "tinyurl.com": true, "goo.gl": true,
+	"t.co": true, "ow.ly": true, "is.gd": true, "buff.ly": true,
+	"rebrand.ly": true, "cutt.ly": true, "shorturl.at": true, "tiny.cc": true,
+	"bl.ink": true, "lnkd.in": true, "rb.gy": true, "t.ly": true, "v.gd": true,
+}
+
+var (
+	fieldPattern    = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*\}\}`)
+	bracesPattern   = regexp.MustCompile(`\{\{[^{}]*\}\}`)
+	singlePattern   = regexp.MustCompile(`(^|[^{])\{\s*([a-zA-Z0-9_]+)\s*\}($|[^}])`)
+	urlPattern      = regexp.MustCompile(`https?://[^\s<>"')]+`)
+	urlLikePattern  = regexp.MustCompile(`(?i)^(https?://)?(www\.)?([a-z0-9-]+\.)+[a-z]{2,}(/\S*)?$`)
+	whitespaceRegex = regexp.MustCompile(`\s+`)
+)
+
+// Template lints an email template
+func Template(template *models.EmailTemplate) []models.LintWarning {
+	l := &linter{known: map[string]bool{}}
+	for _, name := range render.FieldNames() {
+		l.known[name] = true
+	}
+
+	isHTML := render.IsHTML(template.ContentType)
+	var doc document
+	if isHTML {
+		doc = parseHTML(template.Body)
+	} else {
+		doc = document{text: template.Body, links: plainLinks("body", template.Body)}
+	}
+	textBody := strings.TrimSpace(template.TextBody)
+
+	l.spam("subject", template.Subject)
+	l.shouting(template.Subject)
+	l.spam("body", doc.text)
+	if isHTML {
+		l.images(doc)
+		if textBody == "" {
+			l.add(models.LintNoPlainText, "text_body",
+				"The HTML email has no plain-text alternative. Filters score HTML-only mail worse and some readers only show plain text; fill in text_body.")
+		}
+	}
+
+	l.mergeFields("subject", template.Subject)
+	l.mergeFields("body", template.Body)
+	if isHTML && textBody != "" {
+		l.mergeFields("text_body", template.TextBody)
+	}
+
+	l.unsubscribe(template)
+
+	if isHTML {
+		size := len(template.Body)
+		switch {
+		case size > gmailClipBytes:
+			l.add(models.LintOversized, "body", fmt.Sprintf(
+				"The HTML is %d KB. Gmail clips messages over 102 KB, hiding the rest, the unsubscribe link and the open pixel. Trim markup or inline styles.", size/1024))
+		case size > nearClipBytes:
+			l.add(models.LintOversized, "body", fmt.Sprintf(
+				"The HTML is %d KB, close to Gmail's 102 KB clipping limit once tracking links and the open pixel are added.", size/1024))
+		}
+	}
+
+	links := doc.links
+	if isHTML && textBody != "" {
+		links = append(links, plainLinks("text_body", template.TextBody)...)
+	}
+	l.links(links)
+	return l.warnings
+}
+
+type linter struct {
+	known    map[string]bool
+	warnings []models.LintWarning
+	seen     map[string]bool
+}
+
+func (l *linter) add(rule, field, message string) {
+	key := rule + "|" + field + "|" + message
+	if l.seen == nil {
+		l.seen = map[string]bool{}
+	}
+	if l.seen[key] {
+		return
+	}
+	l.seen[key] = true
+	l.warnings = append(l.warnings, models.LintWarning{Rule: rule, Field: field, Message: message})
+}
+
+func (l *linter) spam(field, text string) {
+	lower := strings.ToLower(text)
+	for _, phrase := range spamPhrases {
+		if containsPhrase(lower, phrase) {
+			l.add(models.LintSpamPhrase, field, fmt.Sprintf("%q is a common spam-filter trigger phrase", phrase))
+		}
+	}
+}
+
+// containsPhrase reports whether phrase occurs in text as whole words
+func containsPhrase(text, phrase string) bool {
+	for start := 0; ; {
+		i := strings.Index(text[start:], phrase)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(phrase)
+		before := i == 0 || !isWordByte(text[i-1]) || !isWordByte(phrase[0])
+		after := end == len(text) || !isWordByte(text[end]) || !isWordByte(phrase[len(phrase)-1])
+		if before && after {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func (l *linter) shouting(subject string) {
+	letters, upper := 0, 0
+	for _, r := range fieldPattern.ReplaceAllString(subject, "") {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters >= 10 && upper*10 > letters*7 {
+		l.add(models.LintShouting, "subject", "The subject is mostly capital letters, which spam filters penalize")
+	}
+	if strings.Contains(subject, "!!") || strings.Contains(subject, "??") {
+		l.add(models.LintShouting, "subject", "The subject has repeated punctuation such as !!, which spam filters penalize")
+	}
+}
+
+func (l *linter) images(doc document) {
+	if doc.images == 0 {
+		return
+	}
+	chars := len([]rune(doc.text))
+	if chars < textPerImage*doc.images {
+		l.add(models.LintImageRatio, "body", fmt.Sprintf(
+			"The email has %d image(s) but only %d characters of text. Filters flag image-heavy mail and many clients block images by default; aim for at least %d characters of text per image.",
+			doc.images, chars, textPerImage))
+	}
+	if doc.missingAlt > 0 {
+		l.add(models.LintImageRatio, "body", fmt.Sprintf(
+			"%d image(s) have no alt text, so readers with images blocked see nothing in their place", doc.missingAlt))
+	}
+}
+
+func (l *linter) mergeFields(field, text string) {
+	for _, match := range bracesPattern.FindAllString(text, -1) {
+		groups := fieldPattern.FindStringSubmatch(match)
+		switch {
+		case strings.HasPrefix(match, "{{>"):
+			// Partials are included before sending, and checked when saving
+		case groups == nil:
+			l.add(models.LintMergeField, field, fmt.Sprintf(
+				"%s isn't a valid merge field; names are letters, digits and underscores, such as {{first_name}}", match))
+		case !l.known[groups[1]]:
+			l.add(models.LintMergeField, field, fmt.Sprintf(
+				"{{%s}} isn't a known merge field and will be left empty. Available fields: %s", groups[1], strings.Join(render.FieldNames(), ", ")))
+		}
+	}
+
+	rest := bracesPattern.ReplaceAllString(text, "")
+	if strings.Contains(rest, "{{") || strings.Contains(rest, "}}") {
+		l.add(models.LintMergeField, field, "There are unmatched {{ or }} braces, so a merge field won't be filled in")
+	}
+	for _, groups := range singlePattern.FindAllStringSubmatch(rest, -1) {
+		if l.known[groups[2]] {
+			l.add(models.LintMergeField, field, fmt.Sprintf(
+				"{%s} has single braces and will be sent as is; merge fields use double braces: {{%s}}", groups[2], groups[2]))
+		}
+	}
+}
+
+// unsubscribe looks for the {{unsubscribe_url}} merge field. It's the only
+// link that unsubscribes the customer, since it's signed for each email; a
+// hand-written link to an unsubscribe page doesn't, however it's worded.
+func (l *linter) unsubscribe(template *models.EmailTemplate) {
+	for _, text := range []string{template.Body, template.TextBody} {
+		for _, groups := range fieldPattern.FindAllStringSubmatch(text, -1) {
+			if groups[1] == "unsubscribe_url" {
+				return
+			}
+		}
+	}
+	l.add(models.LintNoUnsubscribe, "body",
+		"There's no {{unsubscribe_url}} link. It's the signed link that unsubscribes the customer and takes them out of their campaigns; other unsubscribe links don't. Gmail and Yahoo require one from bulk senders and recipients who can't opt out mark mail as spam.")
+}
+
+func (l *linter) links(links []link) {
+	for _, link := range links {
+		href := strings.TrimSpace(link.href)
+		if strings.Contains(href, "{{") {
+			continue
+		}
+		target, err := url.Parse(href)
+		if err != nil || target.Host == "" {
+			continue
+		}
+		host := strings.TrimPrefix(strings.ToLower(target.Hostname()), "www.")
+		if shorteners[host] {
+			l.add(models.LintURLShortener, link.field, fmt.Sprintf(
+				"%s uses the URL shortener %s, which hides the destination and is widely abused; link to the destination directly", href, host))
+		}
+
+		text := strings.TrimSpace(link.text)
+		if text == "" || !urlLikePattern.MatchString(text) {
+			continue
+		}
+		shown := text
+		if !strings.Contains(shown, "://") {
+			shown = "http://" + shown
+		}
+		shownURL, err := url.Parse(shown)
+		if err != nil {
+			continue
+		}
+		shownHost := strings.TrimPrefix(strings.ToLower(shownURL.Hostname()), "www.")
+		if shownHost != host {
+			l.add(models.LintMismatchedLink, link.field, fmt.Sprintf(
+				"The link text shows %s but the link goes to %s; filters treat this as phishing. Make the text match the destination or use words instead.", text, host))
+		}
+	}
+}
+
+// document is what the linter needs from an email body
+type document struct {
+	text       string
+	links      []link
+	images     int
+	missingAlt int
+}
+
+type link struct {
+	field string
+	href  string
+	text  string
+}
+
+// parseHTML extracts the visible text, links and images of an HTML body
+func parseHTML(body string) document {
+	var doc document
+	var text strings.Builder
+	var current *link
+	var anchor strings.Builder
+	skip := 0
+
+	tokens := html.NewTokenizer(strings.NewReader(body))
+	for {
+		switch tokens.Next() {
+		case html.ErrorToken:
+			doc.text = strings.TrimSpace(whitespaceRegex.ReplaceAllString(text.String(), " "))
+			return doc
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokens.Token()
+			switch token.Data {
+			case "script", "style", "head", "title":
+				if token.Type == html.StartTagToken {
+					skip++
+				}
+			case "img":
+				doc.images++
+				if !hasAttr(token, "alt") {
+					doc.missingAlt++
+				}
+			case "a":
+				current = &link{field: "body", href: attr(token, "href")}
+				anchor.Reset()
+			case "br", "p", "div", "tr", "li", "h1", "h2", "h3", "td":
+				text.WriteString(" ")
+			}
+
+		case html.EndTagToken:
+			token := tokens.Token()
+			switch token.Data {
+			case "script", "style", "head", "title":
+				if skip > 0 {
+					skip--
+				}
+			case "a":
+				if current != nil {
+					current.text = whitespaceRegex.ReplaceAllString(anchor.String(), " ")
+					doc.links = append(doc.links, *current)
+					current = nil
+				}
+			}
+
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			data := string(tokens.Text())
+			text.WriteString(data)
+			if current != nil {
+				anchor.WriteString(data)
+			}
+		}
+	}
+}
+
+func attr(token html.Token, name string) string {
+	for _, a := range token.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(token html.Token, name string) bool {
+	for _, a := range token.Attr {
+		if a.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// plainLinks finds the URLs in the plain text of field
+func plainLinks(field, text string) []link {
+	var links []link
+	for _, match := range urlPattern.FindAllString(text, -1) {
+		links = append(links, link{field: field, href: strings.TrimRight(match, ".,;:!?")})
+	}
+	return links
+}
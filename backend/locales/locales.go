@@ -0,0 +1,338 @@
+// Package locales picks the language each customer is emailed in and the
+// localization of a template that's sent to them.
+package locales
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/schedule"
+	"github.com/jinzhu/gorm"
+)
+
+// localePattern matches a language code with an optional region, such as
+// "fr", "pt-BR" or "es-419"
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-([A-Z]{2}|[0-9]{3}))?$`)
+
+// Normalize writes a locale the way it's stored: "FR_ca" becomes "fr-CA". It
+// reports false for text that isn't a locale. An empty locale is valid.
+func Normalize(locale string) (string, bool) {
+	locale = strings.TrimSpace(strings.ReplaceAll(locale, "_", "-"))
+	if locale == "" {
+		return "", true
+	}
+	parts := strings.SplitN(locale, "-", 2)
+	parts[0] = strings.ToLower(parts[0])
+	if len(parts) == 2 {
+		parts[1] = strings.ToUpper(parts[1])
+	}
+	locale = strings.Join(parts, "-")
+	return locale, localePattern.MatchString(locale)
+}
+
+// Language returns the language of a locale without its region
+func Language(locale string) string {
+	if i := strings.IndexByte(locale, '-'); i >= 0 {
+		return locale[:i]
+	}
+	return locale
+}
+
+// FromCountry returns the main language of a country, given its name or ISO
+// code. It returns "" for countries it doesn't know, and for those such as
+// Belgium without one main language.
+func FromCountry(country string) string {
+	return countryLanguages[schedule.CountryCode(country)]
+}
+
+// ForCustomer returns the locale a customer is emailed in: their own, the
+// language of their country or defaultLocale
+func ForCustomer(customer *models.Customer, defaultLocale string) string {
+	if locale, ok := Normalize(customer.Locale); ok && locale != "" {
+		return locale
+	}
+	if language := FromCountry(customer.Country); language != "" {
+		return language
+	}
+	return defaultLocale
+}
+
+// Find returns the revision of a template's localization that's sent with
+// a version of the template: the last one saved while the template was at
+// that version or an earlier one. The localization for the locale itself is
+// preferred, or else the one for its language, so "es-MX" customers are sent
+// the "es" localization. It returns nil when there's neither, including for
+// versions older than the translation.
+func Find(templateID uint, locale string, version int) (*models.LocalizationRevision, error) {
+	if locale == "" {
+		return nil, nil
+	}
+	query := database.DB.Where("email_template_id = ? AND locale IN (?)", templateID, candidates(locale))
+	if version > 0 {
+		query = query.Where("template_version <= ?", version)
+	}
+	var found []models.LocalizationRevision
+	if err := query.Order("revision desc").Find(&found).Error; err != nil {
+		return nil, err
+	}
+	var best *models.LocalizationRevision
+	for i := range found {
+		if found[i].Locale == locale {
+			return &found[i], nil
+		}
+		if best == nil {
+			best = &found[i]
+		}
+	}
+	return best, nil
+}
+
+func candidates(locale string) []string {
+	if language := Language(locale); language != locale {
+		return []string{locale, language}
+	}
+	return []string{locale}
+}
+
+// Localize returns a copy of a template with its content in a locale, with
+// the locale and revision of the localization used. The template is
+// returned as it is, with an empty locale, when it has no localization for
+// the locale and the template's version.
+func Localize(template *models.EmailTemplate, locale string) (*models.EmailTemplate, string, int, error) {
+	revision, err := Find(template.ID, locale, template.Version)
+	if err != nil || revision == nil {
+		return template, "", 0, err
+	}
+	localized := *template
+	localized.Subject = revision.Subject
+	localized.Body = revision.Body
+	localized.TextBody = revision.TextBody
+	localized.CompiledBody, localized.CompiledTextBody = "", ""
+	return &localized, revision.Locale, revision.Revision, nil
+}
+
+// Save creates or updates a localization, adding a revision when its
+// content changed. templateVersion is the template's current version and
+// userID the revision's author.
+func Save(localization *models.TemplateLocalization, templateVersion int, userID uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if localization.ID != 0 {
+			// Lock the localization so concurrent saves get distinct revisions
+			var stored models.TemplateLocalization
+			if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&stored, localization.ID).Error; err != nil {
+				return err
+			}
+		}
+		var revisions []models.LocalizationRevision
+		if err := tx.Where("email_template_id = ? AND locale = ?", localization.EmailTemplateID, localization.Locale).
+			Order("revision desc").Limit(1).Find(&revisions).Error; err != nil {
+			return err
+		}
+
+		latest := 0
+		if len(revisions) > 0 {
+			current := revisions[0]
+			latest = current.Revision
+			if current.Subject == localization.Subject && current.Body == localization.Body && current.TextBody == localization.TextBody {
+				localization.Revision = current.Revision
+				return tx.Save(localization).Error
+			}
+		}
+
+		localization.Revision = latest + 1
+		if err := tx.Save(localization).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.LocalizationRevision{
+			EmailTemplateID: localization.EmailTemplateID,
+			Locale:          localization.Locale,
+			Revision:        localization.Revision,
+			Subject:         localization.Subject,
+			Body:            localization.Body,
+			TextBody:        localization.TextBody,
+			TemplateVersion: templateVersion,
+			CreatedBy:       userID,
+		}).Error
+	})
+}
+
+// Delete removes a localization and its revisions, so the locale can be
+// translated again from scratch
+func Delete(localization *models.TemplateLocalization) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("email_template_id = ? AND locale = ?", localization.EmailTemplateID, localization.Locale).
+			Delete(&models.LocalizationRevision{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(localization).Error
+	})
+}
+
+// DeleteForTemplate removes every localization of a deleted template
+func DeleteForTemplate(templateID uint) error {
+	if err := database.DB.Unscoped().Where("email_template_id = ?", templateID).Delete(&models.LocalizationRevision{}).Error; err != nil {
+		return err
+	}
+	return database.DB.Unscoped().Where("email_template_id = ?", templateID).Delete(&models.TemplateLocalization{}).Error
+}
+
+// Backfill gives localizations saved before revisions their first revision
+func Backfill() error {
+	var unrevised []models.TemplateLocalization
+	if err := database.DB.Where("revision = 0 OR revision IS NULL").Find(&unrevised).Error; err != nil {
+		return err
+	}
+	for i := range unrevised {
+		var template models.EmailTemplate
+		if err := database.DB.Unscoped().First(&template, unrevised[i].EmailTemplateID).Error; err != nil {
+			return err
+		}
+		if err := Save(&unrevised[i], template.Version, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Covers reports whether customers with a locale are sent content in their
+// language: the template's own content when it's their language, or one of
+// the translated locales
+func Covers(translated []string, locale, defaultLocale string) bool {
+	if Language(locale) == Language(defaultLocale) {
+		return true
+	}
+	for _, candidate := range candidates(locale) {
+		for _, t := range translated {
+			if t == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Report lists the translations the templates of a campaign's steps are
+// missing for the locales of the customers still going through it. Locales
+// in wanted are checked too, even when no customer has them yet.
+func Report(campaignID uint, steps []models.Step, defaultLocale string, wanted []string) (*models.TranslationReport, error) {
+	report := &models.TranslationReport{
+		CampaignID:    campaignID,
+		DefaultLocale: defaultLocale,
+		Locales:       []models.LocaleAudience{},
+		Templates:     []models.TemplateTranslations{},
+		Complete:      true,
+	}
+
+	var customers []models.Customer
+	if err := database.DB.Joins("JOIN campaign_customers ON campaign_customers.customer_id = customers.id").
+		Where("campaign_customers.campaign_id = ? AND campaign_customers.status IN (?) AND campaign_customers.deleted_at IS NULL",
+			campaignID, []string{models.EnrollmentActive, models.EnrollmentPaused}).
+		Find(&customers).Error; err != nil {
+		return nil, err
+	}
+	audience := map[string]int{}
+	for _, locale := range wanted {
+		if _, ok := audience[locale]; !ok {
+			audience[locale] = 0
+		}
+	}
+	for i := range customers {
+		audience[ForCustomer(&customers[i], defaultLocale)]++
+	}
+	for locale, count := range audience {
+		report.Locales = append(report.Locales, models.LocaleAudience{Locale: locale, Customers: count})
+	}
+	sort.Slice(report.Locales, func(i, j int) bool {
+		if report.Locales[i].Customers != report.Locales[j].Customers {
+			return report.Locales[i].Customers > report.Locales[j].Customers
+		}
+		return report.Locales[i].Locale < report.Locales[j].Locale
+	})
+
+	// Templates in the order the campaign sends them, with the steps that
+	// send each, A/B test variants included
+	var templateIDs []uint
+	stepIDs := map[uint][]uint{}
+	add := func(templateID, stepID uint) {
+		if templateID == 0 {
+			return
+		}
+		if _, ok := stepIDs[templateID]; !ok {
+			templateIDs = append(templateIDs, templateID)
+		}
+		ids := stepIDs[templateID]
+		if len(ids) == 0 || ids[len(ids)-1] != stepID {
+			stepIDs[templateID] = append(ids, stepID)
+		}
+	}
+	for _, step := range steps {
+		if step.Kind() != models.StepEmail {
+			continue
+		}
+		add(step.EmailTemplateID, step.ID)
+		for _, variant := range step.Variants {
+			add(variant.EmailTemplateID, step.ID)
+		}
+	}
+
+	for _, templateID := range templateIDs {
+		var template models.EmailTemplate
+		err := database.DB.First(&template, templateID).Error
+		if gorm.IsRecordNotFoundError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var localizations []models.TemplateLocalization
+		if err := database.DB.Where("email_template_id = ?", templateID).Order("locale asc").
+			Find(&localizations).Error; err != nil {
+			return nil, err
+		}
+		translations := models.TemplateTranslations{
+			EmailTemplateID: template.ID,
+			Name:            template.Name,
+			StepIDs:         stepIDs[templateID],
+			Translated:      []string{},
+			Missing:         []models.LocaleAudience{},
+		}
+		for _, localization := range localizations {
+			translations.Translated = append(translations.Translated, localization.Locale)
+		}
+		for _, locale := range report.Locales {
+			if !Covers(translations.Translated, locale.Locale, defaultLocale) {
+				translations.Missing = append(translations.Missing, locale)
+			}
+		}
+		if len(translations.Missing) > 0 {
+			report.Complete = false
+		}
+		report.Templates = append(report.Templates, translations)
+	}
+	return report, nil
+}
+
+// countryLanguages is the main language of each country, by ISO code.
+// Countries with several main languages, such as Belgium and Canada, are
+// left out; their customers need a locale of their own.
+var countryLanguages = map[string]string{
+	"US": "en", "GB": "en", "IE": "en", "AU": "en", "NZ": "en", "ZA": "en", "NG": "en", "KE": "en",
+	"SG": "en", "PH": "en", "IN": "en", "PK": "en",
+
+	"ES": "es", "MX": "es", "AR": "es", "CL": "es", "CO": "es", "PE": "es", "VE": "es", "EC": "es",
+	"UY": "es", "PY": "es", "BO": "es", "CR": "es", "PA": "es", "DO": "es", "GT": "es", "HN": "es",
+	"SV": "es", "NI": "es", "CU": "es", "PR": "es",
+
+	"FR": "fr", "MC": "fr", "LU": "fr", "SN": "fr", "CI": "fr",
+
+	"DE": "de", "AT": "de", "CH": "de", "LI": "de",
+
+	"IT": "it", "SM": "it", "PT": "pt", "BR": "pt", "AO": "pt", "MZ": "pt", "NL": "nl",
+	"DK": "da", "NO": "nb", "SE": "sv", "FI": "fi", "PL": "pl", "CZ": "cs", "SK": "sk",
+	"HU": "hu", "RO": "ro", "BG": "bg", "GR": "el", "TR": "tr", "UA": "uk", "RU": "ru",
+	"IL": "he", "AE": "ar", "SA": "ar", "EG": "ar", "TH": "th", "VN": "vi", "ID": "id",
+	"MY": "ms", "CN": "zh", "TW": "zh", "HK": "zh", "KR": "ko", "JP": "ja", "BD": "bn",
+}
@@ -0,0 +1,93 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/dkim"
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+// sign adds a DKIM signature for each enabled key of the from address's
+// domain. Messages from domains without keys are sent unsigned.
+func sign(from string, message []byte, now time.Time) ([]byte, error) {
+	domain := strings.ToLower(from[strings.LastIndex(from, "@")+1:])
+
+	var keys []models.DKIMKey
+	if err := database.DB.Where("domain = ? AND disabled = ?", domain, false).Order("id asc").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve DKIM keys: %w", err)
+	}
+	for _, key := range keys {
+		signer, err := dkim.NewSigner(key.Domain, key.Selector, string(key.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("DKIM key %s: %w", key.Selector, err)
+		}
+		if message, err = signer.Sign(message, now); err != nil {
+			return nil, fmt.Errorf("DKIM key %s: %w", key.Selector, err)
+		}
+	}
+	return message, nil
+}
+
+// lookupTXT looks up DNS TXT records. Tests can replace it.
+var lookupTXT = net.DefaultResolver.LookupTXT
+
+// CheckDKIM signs a test message with the key and verifies it against the
+// record published in DNS, as a receiver would
+func CheckDKIM(key *models.DKIMKey) models.DKIMCheck {
+	now := time.Now()
+	from := "dkim-check@" + key.Domain
+	message := crlf([]byte(fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: DKIM self-check\r\nDate: %s\r\nMessage-ID: %s\r\n\r\nThis message checks the DKIM key %s.\n",
+		from, from, now.Format(time.RFC1123Z), NewMessageID(from), key.Selector)))
+
+	signer, err := dkim.NewSigner(key.Domain, key.Selector, string(key.PrivateKey))
+	if err != nil {
+		return models.DKIMCheck{Error: err.Error()}
+	}
+	signed, err := signer.Sign(message, now)
+	if err != nil {
+		return models.DKIMCheck{Error: err.Error()}
+	}
+	check := models.DKIMCheck{Signature: string(signed[:bytes.Index(signed, []byte("\r\n"))])}
+
+	err = dkim.Verify(signed, func(domain, selector string) (string, error) {
+		if domain != key.Domain || selector != key.Selector {
+			return "", fmt.Errorf("no key for %s", dkim.RecordName(domain, selector))
+		}
+		return publishedRecord(dkim.RecordName(domain, selector))
+	})
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.OK = true
+	return check
+}
+
+// publishedRecord looks up the DKIM record published at name
+func publishedRecord(name string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	txt, err := lookupTXT(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	for _, record := range txt {
+		if strings.Contains(record, "p=") {
+			return record, nil
+		}
+	}
+	return "", fmt.Errorf("%s has no DKIM record", name)
+}
+
+// crlf turns bare line feeds into CRLF, the line ending the message has on
+// the wire and so the one it's signed with
+func crlf(message []byte) []byte {
+	message = bytes.ReplaceAll(message, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(message, []byte("\n"), []byte("\r\n"))
+}
@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/4cecoder/drip-campaign/dkim"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/secrets"
+)
+
+func TestCheckDKIM(t *testing.T) {
+	privateKey, publicKey, err := dkim.GenerateKey(dkim.Ed25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := dkim.GenerateKey(dkim.Ed25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := models.DKIMKey{Domain: "example.com", Selector: "s1", Algorithm: dkim.Ed25519, PrivateKey: secrets.String(privateKey), PublicKey: publicKey}
+
+	tests := []struct {
+		name string
+		txt  []string
+		ok   bool
+	}{
+		{"published", []string{"google-site-verification=abc", dkim.Record(dkim.Ed25519, publicKey)}, true},
+		{"not published", nil, false},
+		{"another key", []string{dkim.Record(dkim.Ed25519, otherKey)}, false},
+		{"revoked", []string{"v=DKIM1; k=ed25519; p="}, false},
+	}
+	previous := lookupTXT
+	t.Cleanup(func() { lookupTXT = previous })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookupTXT = func(ctx context.Context, name string) ([]string, error) {
+				if name != "s1._domainkey.example.com" || tt.txt == nil {
+					return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+				}
+				return tt.txt, nil
+			}
+			check := CheckDKIM(&key)
+			if check.OK != tt.ok {
+				t.Errorf("ok = %v, want %v: %s", check.OK, tt.ok, check.Error)
+			}
+			if check.Signature == "" {
+				t.Error("no signature returned")
+			}
+		})
+	}
+}
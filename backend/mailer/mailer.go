@@ -0,0 +1,332 @@
+package mailer
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+const (
+	smtpServer = "smtp.gmail.com"
+	smtpPort   = 587
+)
+
+// Message is an outgoing email. It's sent from SenderIdentityID, or from the
+// Settings account when that's zero.
+type Message struct {
+	To          string
+	Subject     string
+	Body        string
+	ContentType string
+	// TextBody is sent as the plain-text alternative of an HTML Body
+	TextBody         string
+	SenderIdentityID uint
+	Attachments      []Attachment
+	// UnsubscribeURL is sent in the List-Unsubscribe header, so mail clients
+	// show an unsubscribe button that works with one click
+	UnsubscribeURL string
+}
+
+// Attachment is a file sent with a message. Inline attachments, those with a
+// ContentID, are images an HTML body shows with src="cid:<ContentID>".
+type Attachment struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Data        []byte
+}
+
+// sender is where a message comes from and how it's delivered
+type sender struct {
+	fromName string
+	from     string
+	replyTo  string
+	host     string
+	port     int
+	username string
+	password string
+}
+
+// Send delivers a message over SMTP and returns the Message-ID it was sent with
+func Send(msg Message) (string, error) {
+	from, err := senderFor(msg.SenderIdentityID)
+	if err != nil {
+		return "", err
+	}
+	messageID := NewMessageID(from.from)
+
+	fromHeader := from.from
+	if from.fromName != "" {
+		fromHeader = (&mail.Address{Name: from.fromName, Address: from.from}).String()
+	}
+	headers := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMessage-ID: %s\r\n",
+		headerValue(fromHeader), headerValue(msg.To), subjectHeader(msg.Subject), time.Now().Format(time.RFC1123Z), messageID)
+	if from.replyTo != "" {
+		headers += fmt.Sprintf("Reply-To: %s\r\n", headerValue(from.replyTo))
+	}
+	if msg.UnsubscribeURL != "" {
+		headers += fmt.Sprintf("List-Unsubscribe: <%s>\r\nList-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n", headerValue(msg.UnsubscribeURL))
+	}
+	entity := mimeBody(msg)
+	headers += "MIME-Version: 1.0\r\n" + entity.header
+	body, err := sign(from.from, crlf([]byte(headers+"\r\n"+entity.content)), time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	if err := deliver(from, msg.To, body); err != nil {
+		return "", err
+	}
+	return messageID, nil
+}
+
+// headerValue removes the line breaks from a header's value. Subjects are
+// rendered from customer fields, and a first name ending in "\r\nBcc: ..."
+// mustn't add headers.
+func headerValue(value string) string {
+	return strings.Join(strings.FieldsFunc(value, func(r rune) bool { return r == '\r' || r == '\n' }), " ")
+}
+
+// subjectHeader encodes a subject per RFC 2047, so accented subjects such
+// as "¿Hablamos, José?" survive. ASCII subjects are left as they are.
+func subjectHeader(subject string) string {
+	return mime.QEncoding.Encode("utf-8", headerValue(subject))
+}
+
+// senderFor loads the sender identity with the given ID, or the Settings
+// account for zero
+func senderFor(identityID uint) (*sender, error) {
+	if identityID == 0 {
+		var settings models.Settings
+		if err := database.DB.First(&settings).Error; err != nil {
+			return nil, fmt.Errorf("failed to retrieve email settings: %w", err)
+		}
+		return &sender{
+			from:     settings.GmailEmail,
+			host:     smtpServer,
+			port:     smtpPort,
+			username: settings.GmailEmail,
+			password: string(settings.GmailPassword),
+		}, nil
+	}
+
+	var identity models.SenderIdentity
+	if err := database.DB.First(&identity, identityID).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve sender identity %d: %w", identityID, err)
+	}
+	s := &sender{
+		fromName: identity.FromName,
+		from:     identity.FromEmail,
+		replyTo:  identity.ReplyTo,
+		host:     identity.SMTPHost,
+		port:     identity.SMTPPort,
+		username: identity.SMTPUsername,
+		password: string(identity.SMTPPassword),
+	}
+	if s.host == "" {
+		s.host = smtpServer
+	}
+	if s.port == 0 {
+		s.port = smtpPort
+	}
+	if s.username == "" {
+		s.username = s.from
+	}
+	return s, nil
+}
+
+// deliver hands the message to the sender's SMTP server. Port 465 uses
+// implicit TLS; other ports upgrade with STARTTLS when the server offers it.
+func deliver(from *sender, to string, body []byte) error {
+	addr := net.JoinHostPort(from.host, strconv.Itoa(from.port))
+	auth := smtp.PlainAuth("", from.username, from.password, from.host)
+	if from.port != 465 {
+		return smtp.SendMail(addr, auth, from.from, []string{to}, body)
+	}
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: from.host})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, from.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Auth(auth); err != nil {
+		return err
+	}
+	if err := client.Mail(from.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Permanent reports whether a send error means the message will never be
+// accepted, so retrying is pointless: a 5xx SMTP reply. Authentication
+// failures and Gmail's sending quota (5.4.5) are about the account rather
+// than the message and clear up once fixed, so they count as temporary, as do
+// 4xx replies and network errors.
+func Permanent(err error) bool {
+	var reply *textproto.Error
+	if !errors.As(err, &reply) || reply.Code < 500 {
+		return false
+	}
+	switch reply.Code {
+	case 530, 534, 535:
+		return false
+	}
+	return !strings.Contains(reply.Msg, "5.4.5")
+}
+
+// mimePart is a MIME entity: its header lines, each ending in CRLF, and its
+// content
+type mimePart struct {
+	header  string
+	content string
+}
+
+// mimeBody builds a message's MIME structure. An HTML body is sent with its
+// inline images in multipart/related, alongside its plain-text alternative
+// in multipart/alternative, and with other attachments in multipart/mixed:
+//
+//	mixed
+//	  alternative
+//	    text/plain
+//	    related
+//	      text/html
+//	      inline images
+//	  attachments
+func mimeBody(msg Message) mimePart {
+	contentType := strings.TrimSpace(strings.SplitN(msg.ContentType, ";", 2)[0])
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	html := contentType != "text/plain"
+
+	var inline, files []mimePart
+	for _, attachment := range msg.Attachments {
+		switch {
+		case attachment.ContentID == "":
+			files = append(files, attachmentPart(attachment))
+		case html:
+			inline = append(inline, attachmentPart(attachment))
+		}
+	}
+
+	body := textPart(contentType, msg.Body)
+	if len(inline) > 0 {
+		body = multipartOf("related", append([]mimePart{body}, inline...))
+	}
+	if html && msg.TextBody != "" {
+		body = multipartOf("alternative", []mimePart{textPart("text/plain", msg.TextBody), body})
+	}
+	if len(files) > 0 {
+		body = multipartOf("mixed", append([]mimePart{body}, files...))
+	}
+	return body
+}
+
+// textPart encodes text as quoted-printable, which keeps lines under SMTP's
+// 998 character limit so relays don't rewrap them and break the DKIM body
+// hash, and keeps non-ASCII characters intact through 7-bit relays
+func textPart(contentType, text string) mimePart {
+	var content strings.Builder
+	w := quotedprintable.NewWriter(&content)
+	w.Write([]byte(text))
+	w.Close()
+	return mimePart{
+		header: fmt.Sprintf("Content-Type: %s; charset=\"UTF-8\"\r\n", contentType) +
+			"Content-Transfer-Encoding: quoted-printable\r\n",
+		content: content.String(),
+	}
+}
+
+func multipartOf(subtype string, parts []mimePart) mimePart {
+	boundary := newBoundary()
+	var content strings.Builder
+	for _, part := range parts {
+		content.WriteString("--" + boundary + "\r\n" + part.header + "\r\n" + part.content + "\r\n")
+	}
+	content.WriteString("--" + boundary + "--\r\n")
+	return mimePart{
+		header:  fmt.Sprintf("Content-Type: multipart/%s; boundary=\"%s\"\r\n", subtype, boundary),
+		content: content.String(),
+	}
+}
+
+// attachmentPart encodes a file in base64, in lines of 76 characters
+func attachmentPart(attachment Attachment) mimePart {
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	disposition := "attachment"
+	header := ""
+	if attachment.ContentID != "" {
+		disposition = "inline"
+		header = "Content-ID: <" + attachment.ContentID + ">\r\n"
+	}
+	header = "Content-Type: " + mime.FormatMediaType(contentType, map[string]string{"name": attachment.Filename}) + "\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"Content-Disposition: " + mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}) + "\r\n" +
+		header
+
+	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+	var content strings.Builder
+	for len(encoded) > 76 {
+		content.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	content.WriteString(encoded)
+	return mimePart{header: header, content: content.String()}
+}
+
+// newBoundary returns a random MIME multipart boundary
+func newBoundary() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return "drip-" + hex.EncodeToString(buf)
+}
+
+// NewMessageID returns a unique Message-ID in the domain of the from address
+func NewMessageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 && i+1 < len(from) {
+		domain = strings.Trim(from[i+1:], "> ")
+	}
+
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return fmt.Sprintf("<%s.%d@%s>", hex.EncodeToString(buf), time.Now().Unix(), domain)
+}
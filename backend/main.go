@@ -3,11 +3,19 @@ package main
 
 import (
 	"fmt"
+	"github.com/4cecoder/drip-campaign/auth"
+	"github.com/4cecoder/drip-campaign/bounce"
 	"github.com/4cecoder/drip-campaign/config"
 	"github.com/4cecoder/drip-campaign/database"
 	_ "github.com/4cecoder/drip-campaign/docs"
+	"github.com/4cecoder/drip-campaign/engine"
+	"github.com/4cecoder/drip-campaign/inbound"
+	"github.com/4cecoder/drip-campaign/locales"
 	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/reply"
 	"github.com/4cecoder/drip-campaign/routes"
+	"github.com/4cecoder/drip-campaign/sendqueue"
+	"github.com/4cecoder/drip-campaign/templates"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
 	"github.com/joho/godotenv"
@@ -15,6 +23,7 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"log"
 	"os"
+	"time"
 )
 
 // @title Drip Campaign API
@@ -30,6 +39,12 @@ func main() {
 		log.Println("Using default environment variables")
 	}
 
+	// Session tokens and email links are signed with JWT_SECRET, so the
+	// server refuses to start without a real one
+	if err := auth.SetSecret(config.LoadConfig().JWTSecret); err != nil {
+		log.Fatal("Invalid JWT secret: ", err)
+	}
+
 	// Initialize the database connection
 	config.Init()
 	defer func(DB *gorm.DB) {
@@ -39,16 +54,30 @@ func main() {
 		}
 	}(database.DB)
 
+	// Templates saved before versioning get their first version
+	if err := templates.Backfill(); err != nil {
+		log.Println("Failed to backfill template versions:", err)
+	}
+	if err := locales.Backfill(); err != nil {
+		log.Println("Failed to backfill localization revisions:", err)
+	}
+
 	log.Println("Database connection initialized to " + os.Getenv("DB_HOST") + ":" + os.Getenv("DB_PORT") + " with database " + os.Getenv("DB_NAME") + " and user " + os.Getenv("DB_USER") + " successfully")
 
 	// Create a new Gin router
 	router := gin.Default()
 
+	// Only trust X-Forwarded-For from known proxies so clients can't spoof
+	// their IP to get around login throttling
+	if err := router.SetTrustedProxies(config.LoadConfig().TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// Enable CORS for all origins and methods
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-API-Key")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -71,6 +100,31 @@ func main() {
 		}
 	}()
 
+	// Start the drip engine
+	interval := time.Duration(config.LoadConfig().EngineIntervalSeconds) * time.Second
+	go engine.Start(interval)
+
+	// Send queued emails within the rate limits, retrying failures
+	queueInterval := time.Duration(config.LoadConfig().SendQueueIntervalSeconds) * time.Second
+	go sendqueue.Start(queueInterval, sendqueue.NewLimits(config.LoadConfig()), sendqueue.NewBackoff(config.LoadConfig()))
+
+	// Poll the bounce mailbox, if one is configured
+	if cfg := config.LoadConfig(); cfg.BounceIMAPAddr != "" {
+		mailbox := inbound.Mailbox{
+			Addr:     cfg.BounceIMAPAddr,
+			Username: cfg.BounceIMAPUser,
+			Password: cfg.BounceIMAPPassword,
+			Folder:   cfg.BounceIMAPFolder,
+		}
+		pollInterval := time.Duration(cfg.BouncePollSeconds) * time.Second
+		go inbound.Start("bounce", func() (inbound.Mailbox, time.Duration, bool) {
+			return mailbox, pollInterval, true
+		}, bounce.Handle)
+	}
+
+	// Watch the sending account for replies, and for bounces that come back to it
+	go inbound.Start("sending account", inbound.SendingAccount, bounce.Handle, reply.Handle)
+
 	// Register routes
 	routes.RegisterRoutes(router)
 	// Register Swagger route
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// StepVariant is one of the templates an A/B tested step chooses between.
+// Recipients are split between variants in proportion to their weights.
+type StepVariant struct {
+	Model
+	StepID          uint           `json:"step_id" gorm:"index"`
+	Name            string         `json:"name"`
+	EmailTemplateID uint           `json:"email_template_id"`
+	EmailTemplate   *EmailTemplate `json:"email_template,omitempty" gorm:"foreignkey:EmailTemplateID"`
+	Weight          int            `json:"weight"`
+}
+
+// A/B test metrics
+const (
+	ABMetricOpen  = "open"
+	ABMetricClick = "click"
+	ABMetricReply = "reply"
+)
+
+// DefaultABConfidence is the confidence level used when a step doesn't set one
+const DefaultABConfidence = 0.95
+
+// VariantStats is how a variant has performed so far
+type VariantStats struct {
+	VariantID       uint    `json:"variant_id"`
+	Name            string  `json:"name"`
+	EmailTemplateID uint    `json:"email_template_id"`
+	Weight          int     `json:"weight"`
+	Sent            int     `json:"sent"`
+	Opened          int     `json:"opened"`
+	Clicked         int     `json:"clicked"`
+	Replied         int     `json:"replied"`
+	OpenRate        float64 `json:"open_rate"`
+	ClickRate       float64 `json:"click_rate"`
+	ReplyRate       float64 `json:"reply_rate"`
+}
+
+// ComputeRates fills in the rates from the counts
+func (s *VariantStats) ComputeRates() {
+	s.OpenRate = ratio(s.Opened, s.Sent)
+	s.ClickRate = ratio(s.Clicked, s.Sent)
+	s.ReplyRate = ratio(s.Replied, s.Sent)
+}
+
+// ABTestResults compares the variants of a step on its A/B metric. PValue is
+// from a two-proportion z-test of the leader against the runner-up.
+type ABTestResults struct {
+	StepID           uint           `json:"step_id"`
+	Metric           string         `json:"metric"`
+	Confidence       float64        `json:"confidence"`
+	Variants         []VariantStats `json:"variants"`
+	LeaderVariantID  uint           `json:"leader_variant_id"`
+	PValue           float64        `json:"p_value"`
+	Significant      bool           `json:"significant"`
+	WinningVariantID uint           `json:"winning_variant_id"`
+	PromotedAt       *time.Time     `json:"promoted_at"`
+}
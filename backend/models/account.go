@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+const MinPasswordLength = 8
+
+// Invitation is an admin-issued invite for a new user to set their own password
+type Invitation struct {
+	Model
+	Email      string     `json:"email" gorm:"not null"`
+	Role       string     `json:"role" gorm:"not null"`
+	TokenHash  string     `json:"-" gorm:"index"`
+	InvitedBy  uint       `json:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+}
+
+// PasswordResetToken is a single-use token emailed to a user who forgot their password
+type PasswordResetToken struct {
+	Model
+	UserID    uint       `json:"user_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"unique_index"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+type InvitationRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type AcceptInvitationRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
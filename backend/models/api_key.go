@@ -0,0 +1,50 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKey is a long-lived credential for integrations. Only a hash of the key is
+// stored; Prefix is kept in the clear so keys can be told apart in listings.
+type APIKey struct {
+	Model
+	UserID     uint       `json:"user_id" gorm:"index"`
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-" gorm:"unique_index"`
+	Scopes     string     `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// ScopeList returns the key's comma-separated scopes as a slice
+func (k *APIKey) ScopeList() []string {
+	var scopes []string
+	for _, scope := range strings.Split(k.Scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// Active reports whether the key can currently be used
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type CreateAPIKeyResponse struct {
+	Key    string `json:"key"`
+	APIKey APIKey `json:"api_key"`
+}
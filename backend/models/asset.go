@@ -0,0 +1,43 @@
+package models
+
+// Asset is an uploaded file that templates and steps attach or show inline.
+// ContentType is sniffed from the file's contents.
+type Asset struct {
+	Model
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256" gorm:"column:sha256;index"`
+	UploadedBy  uint   `json:"uploaded_by"`
+}
+
+// Attachment attaches an asset to an email template or a step. Inline
+// attachments are images an HTML body shows with src="cid:<content_id>";
+// others are attached as files.
+type Attachment struct {
+	Model
+	EmailTemplateID uint   `json:"email_template_id,omitempty" gorm:"index"`
+	StepID          uint   `json:"step_id,omitempty" gorm:"index"`
+	AssetID         uint   `json:"asset_id" gorm:"index"`
+	Inline          bool   `json:"inline"`
+	ContentID       string `json:"content_id,omitempty"`
+	Asset           *Asset `json:"asset,omitempty" gorm:"-"`
+}
+
+// AttachmentRequest attaches an asset. ContentID defaults to the asset's
+// filename for inline images.
+type AttachmentRequest struct {
+	AssetID   uint   `json:"asset_id" binding:"required"`
+	Inline    bool   `json:"inline"`
+	ContentID string `json:"content_id"`
+}
+
+// QueuedAttachment is a file a queued email carries, stored with it as JSON.
+// ContentID is set for inline images.
+type QueuedAttachment struct {
+	AssetID     uint   `json:"asset_id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	ContentID   string `json:"content_id,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// DNS check statuses. Unknown means the record couldn't be looked up, for
+// example because DNS timed out, so nothing is known to be wrong.
+const (
+	CheckOK      = "ok"
+	CheckWarning = "warning"
+	CheckError   = "error"
+	CheckUnknown = "unknown"
+)
+
+// DNSCheck is the result of inspecting one kind of DNS record of a sending
+// domain. Fix says how to correct a warning or error.
+type DNSCheck struct {
+	Record  string   `json:"record"`
+	Status  string   `json:"status"`
+	Message string   `json:"message"`
+	Fix     string   `json:"fix,omitempty"`
+	Values  []string `json:"values,omitempty"`
+}
+
+// DomainCheck is the result of a sending domain's pre-flight checks.
+// Alignment is ok when mail from the domain can pass DMARC, through an
+// aligned DKIM signature or SPF; campaigns whose from-domain fails it can't
+// be activated.
+type DomainCheck struct {
+	Domain    string     `json:"domain"`
+	Status    string     `json:"status"`
+	Alignment string     `json:"alignment"`
+	Checks    []DNSCheck `json:"checks"`
+}
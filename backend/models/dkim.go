@@ -0,0 +1,52 @@
+package models
+
+import "github.com/4cecoder/drip-campaign/secrets"
+
+// DKIMKey signs the emails sent from addresses in Domain. A domain can have
+// several keys, such as an RSA and an Ed25519 one, and each enabled key adds
+// its own signature.
+type DKIMKey struct {
+	Model
+	Domain    string `json:"domain" gorm:"unique_index:idx_dkim_domain_selector"`
+	Selector  string `json:"selector" gorm:"unique_index:idx_dkim_domain_selector"`
+	Algorithm string `json:"algorithm"`
+	Bits      int    `json:"bits,omitempty"`
+
+	// PrivateKey is a PKCS #8 PEM key. It never leaves the server.
+	PrivateKey secrets.String `json:"-" gorm:"type:text"`
+	PublicKey  string         `json:"public_key" gorm:"type:text"`
+
+	Disabled bool `json:"disabled" gorm:"default:false"`
+
+	// DNSRecord is the TXT record to publish for the key
+	DNSRecord *DNSRecord `json:"dns_record,omitempty" gorm:"-"`
+}
+
+// DKIMKeyRequest asks for a new DKIM key. Algorithm is rsa (the default) or
+// ed25519; Bits is the RSA key size, 2048 by default.
+type DKIMKeyRequest struct {
+	Domain    string `json:"domain"`
+	Selector  string `json:"selector"`
+	Algorithm string `json:"algorithm"`
+	Bits      int    `json:"bits"`
+}
+
+// UpdateDKIMKeyRequest turns signing with a DKIM key on or off
+type UpdateDKIMKeyRequest struct {
+	Disabled bool `json:"disabled"`
+}
+
+// DNSRecord is a DNS record to publish
+type DNSRecord struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// DKIMCheck is the result of signing a test message with a DKIM key and
+// verifying it against the key's public half
+type DKIMCheck struct {
+	OK        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+	Signature string `json:"signature"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// EngagementEvent records something a recipient did with an email
+type EngagementEvent struct {
+	Model
+	Type       string    `json:"type" gorm:"index"`
+	EmailLogID uint      `json:"email_log_id" gorm:"index"`
+	CustomerID uint      `json:"customer_id" gorm:"index"`
+	CampaignID uint      `json:"campaign_id" gorm:"index"`
+	StepID     uint      `json:"step_id"`
+	URL        string    `json:"url"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	Bot        bool      `json:"bot"`
+	BotReason  string    `json:"bot_reason"`
+	Detail     string    `json:"detail"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Engagement event types
+const (
+	EventOpen        = "open"
+	EventClick       = "click"
+	EventUnsubscribe = "unsubscribe"
+	EventHardBounce  = "hard_bounce"
+	EventSoftBounce  = "soft_bounce"
+	EventComplaint   = "complaint"
+	EventReply       = "reply"
+	EventAutoReply   = "auto_reply"
+)
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// InboundMessage records a message fetched from a polled mailbox or posted
+// to an inbound webhook that was handled, so it isn't processed twice
+type InboundMessage struct {
+	Model
+	MessageID  string    `json:"message_id" gorm:"unique_index"`
+	Mailbox    string    `json:"mailbox"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// TimelineEntry is an email or engagement event on a customer's timeline
+type TimelineEntry struct {
+	Type       string    `json:"type"`
+	At         time.Time `json:"at"`
+	EmailLogID uint      `json:"email_log_id,omitempty"`
+	CampaignID uint      `json:"campaign_id,omitempty"`
+	StepID     uint      `json:"step_id,omitempty"`
+	Subject    string    `json:"subject,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+	Bot        bool      `json:"bot,omitempty"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// LintWarning is something in an email template likely to hurt its
+// deliverability or rendering. Field is subject, body or text_body.
+type LintWarning struct {
+	Rule    string `json:"rule"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Lint rules
+const (
+	LintSpamPhrase     = "spam_phrase"
+	LintShouting       = "shouting"
+	LintImageRatio     = "image_ratio"
+	LintNoPlainText    = "no_plain_text"
+	LintMergeField     = "merge_field"
+	LintNoUnsubscribe  = "no_unsubscribe"
+	LintOversized      = "oversized"
+	LintURLShortener   = "url_shortener"
+	LintMismatchedLink = "mismatched_link"
+)
+
+// TemplateLint is the result of linting an email template
+type TemplateLint struct {
+	TemplateID uint          `json:"template_id"`
+	SizeBytes  int           `json:"size_bytes"`
+	Warnings   []LintWarning `json:"warnings"`
+}
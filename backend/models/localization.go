@@ -0,0 +1,67 @@
+package models
+
+// TemplateLocalization is an email template's content in another language.
+// Customers whose locale matches are sent it instead of the template's own
+// content, which is written in the default locale. The content type and
+// layout are the template's.
+type TemplateLocalization struct {
+	Model
+	EmailTemplateID uint   `json:"email_template_id" gorm:"unique_index:idx_template_localization"`
+	Locale          string `json:"locale" gorm:"unique_index:idx_template_localization"`
+	Subject         string `json:"subject"`
+	Body            string `json:"body" gorm:"type:text"`
+	TextBody        string `json:"text_body" gorm:"type:text;default:null"`
+
+	// Revision is the number of the localization's current LocalizationRevision
+	Revision int `json:"revision"`
+
+	// Warnings are the linter's findings, returned when the localization is saved
+	Warnings []LintWarning `json:"warnings,omitempty" gorm:"-"`
+}
+
+// LocalizationRevision is an immutable copy of a localization's content.
+// Every change to a localization adds one, numbered from 1. A step pinned to
+// a template version is sent the last revision saved while the template was
+// at that version or an earlier one.
+type LocalizationRevision struct {
+	Model
+	EmailTemplateID uint   `json:"email_template_id" gorm:"unique_index:idx_localization_revision"`
+	Locale          string `json:"locale" gorm:"unique_index:idx_localization_revision"`
+	Revision        int    `json:"revision" gorm:"unique_index:idx_localization_revision"`
+	Subject         string `json:"subject"`
+	Body            string `json:"body" gorm:"type:text"`
+	TextBody        string `json:"text_body" gorm:"type:text;default:null"`
+
+	// TemplateVersion is the template's version when the revision was saved
+	TemplateVersion int `json:"template_version"`
+
+	// CreatedBy is the user who saved the revision, when known
+	CreatedBy uint `json:"created_by"`
+}
+
+// LocaleAudience is how many customers enrolled in a campaign have a locale
+type LocaleAudience struct {
+	Locale    string `json:"locale"`
+	Customers int    `json:"customers"`
+}
+
+// TemplateTranslations lists the locales a template a campaign sends is and
+// isn't translated into
+type TemplateTranslations struct {
+	EmailTemplateID uint             `json:"email_template_id"`
+	Name            string           `json:"name"`
+	StepIDs         []uint           `json:"step_ids"`
+	Translated      []string         `json:"translated"`
+	Missing         []LocaleAudience `json:"missing"`
+}
+
+// TranslationReport lists the translations a campaign's templates are
+// missing for the locales of its customers. Customers whose locale has no
+// translation are sent the default locale's content.
+type TranslationReport struct {
+	CampaignID    uint                   `json:"campaign_id"`
+	DefaultLocale string                 `json:"default_locale"`
+	Locales       []LocaleAudience       `json:"locales"`
+	Templates     []TemplateTranslations `json:"templates"`
+	Complete      bool                   `json:"complete"`
+}
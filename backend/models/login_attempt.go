@@ -0,0 +1,22 @@
+package models
+
+// LoginAttempt is an audit record of a login attempt
+type LoginAttempt struct {
+	Model
+	Email     string `json:"email" gorm:"index"`
+	UserID    uint   `json:"user_id" gorm:"index"`
+	IPAddress string `json:"ip_address" gorm:"index"`
+	UserAgent string `json:"user_agent"`
+	Success   bool   `json:"success"`
+	Reason    string `json:"reason"`
+}
+
+// Reasons recorded on login attempts
+const (
+	LoginReasonSuccess         = "success"
+	LoginReasonInvalidPassword = "invalid_password"
+	LoginReasonUnknownUser     = "unknown_user"
+	LoginReasonInvalidCode     = "invalid_two_factor_code"
+	LoginReasonLocked          = "account_locked"
+	LoginReasonThrottled       = "throttled"
+)
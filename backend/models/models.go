@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"github.com/4cecoder/drip-campaign/secrets"
 )
 
 type Model struct {
@@ -19,6 +21,61 @@ type DripCampaign struct {
 	StartDate   time.Time `json:"start_date"`
 	EndDate     time.Time `json:"end_date"`
 	Stages      []Stage   `json:"stages" gorm:"foreignkey:CampaignID"`
+
+	// TrackingDisabled turns off open and click tracking for privacy-sensitive lists
+	TrackingDisabled bool `json:"tracking_disabled" gorm:"default:false"`
+
+	// UTM parameters appended to links in the campaign's emails, when set
+	UTMSource   string `json:"utm_source"`
+	UTMMedium   string `json:"utm_medium"`
+	UTMCampaign string `json:"utm_campaign"`
+
+	// Triggers enroll customers automatically; see CampaignTrigger
+	Triggers []CampaignTrigger `json:"triggers,omitempty" gorm:"foreignkey:CampaignID;association_autoupdate:false;association_autocreate:false"`
+
+	// EntryStepID is the first step of a branching flow. Campaigns without one
+	// run their steps in stage order.
+	EntryStepID uint `json:"entry_step_id"`
+
+	// OnReply is what happens to a customer's enrollment when they reply:
+	// exit (the default), pause or continue
+	OnReply string `json:"on_reply" gorm:"default:null"`
+
+	// Sending window in each customer's local time: SendDays is a
+	// comma-separated list of days such as "mon,tue,wed,thu,fri" and the
+	// window runs from SendWindowStart to SendWindowEnd ("09:00" to "17:00").
+	// Emails due outside it wait for the next opening. Timezone is used for
+	// customers whose own timezone isn't known.
+	SendDays        string `json:"send_days" gorm:"default:null"`
+	SendWindowStart string `json:"send_window_start" gorm:"default:null"`
+	SendWindowEnd   string `json:"send_window_end" gorm:"default:null"`
+	Timezone        string `json:"timezone" gorm:"default:null"`
+
+	// Who the campaign's emails come from: SenderIdentityID, or per customer
+	// with a SenderMode of rotate (identities in rotation, in turn) or
+	// assigned_rep (the identity of the customer's AssignedTo user, falling
+	// back to SenderIdentityID). Steps can override it.
+	SenderIdentityID uint   `json:"sender_identity_id"`
+	SenderMode       string `json:"sender_mode" gorm:"default:null"`
+}
+
+// Only campaigns with this status are sent by the engine
+const CampaignStatusActive = "active"
+
+// DripCampaign reply actions
+const (
+	ReplyActionExit     = "exit"
+	ReplyActionPause    = "pause"
+	ReplyActionContinue = "continue"
+)
+
+// ValidReplyAction reports whether action can be used as a campaign's OnReply
+func ValidReplyAction(action string) bool {
+	switch action {
+	case "", ReplyActionExit, ReplyActionPause, ReplyActionContinue:
+		return true
+	}
+	return false
 }
 
 type Stage struct {
@@ -38,6 +95,92 @@ type Step struct {
 	EmailTemplateID uint           `json:"email_template_id"`
 	EmailTemplate   *EmailTemplate `json:"email_template,omitempty" gorm:"foreignkey:EmailTemplateID"`
 	WaitTime        int            `json:"wait_time"`
+
+	// EmailTemplateVersion pins the step to a version of its template; zero
+	// sends the template's current version
+	EmailTemplateVersion int `json:"email_template_version"`
+
+	// WaitUnit is the unit of WaitTime: seconds (the default), minutes, hours,
+	// days or business_days
+	WaitUnit string `json:"wait_unit" gorm:"default:null"`
+
+	// SenderIdentityID overrides the campaign's sender for this step
+	SenderIdentityID uint `json:"sender_identity_id"`
+
+	// Flow fields. Email steps continue to NextStepID, condition steps to
+	// YesStepID or NoStepID. Wait-until steps wait up to WaitTime for their
+	// condition and go to NoStepID on timeout. Goal steps aren't part of the
+	// path; customers who meet their condition leave the campaign. A zero edge
+	// ends the flow, or in campaigns without an EntryStepID continues with the
+	// next step in stage order.
+	Type       string `json:"type" gorm:"default:null"`
+	NextStepID uint   `json:"next_step_id"`
+	YesStepID  uint   `json:"yes_step_id"`
+	NoStepID   uint   `json:"no_step_id"`
+
+	// Condition checked by condition, wait-until and goal steps. Opened and
+	// clicked look at ConditionStepID's email, or the customer's latest one.
+	Condition         string `json:"condition" gorm:"default:null"`
+	ConditionStepID   uint   `json:"condition_step_id"`
+	ConditionField    string `json:"condition_field" gorm:"default:null"`
+	ConditionOperator string `json:"condition_operator" gorm:"default:null"`
+	ConditionValue    string `json:"condition_value" gorm:"default:null"`
+
+	// A/B test. When a step has variants each recipient gets one of them
+	// instead of EmailTemplateID. With ABAutoPromote the leader on ABMetric is
+	// promoted to WinningVariantID, and sent to everyone after that, once every
+	// variant has ABSampleSize sends or ABTestHours have passed since the first
+	// send, provided the difference is significant at ABConfidence.
+	Variants         []StepVariant `json:"variants,omitempty" gorm:"foreignkey:StepID;association_autoupdate:false;association_autocreate:false"`
+	ABAutoPromote    bool          `json:"ab_auto_promote" gorm:"default:false"`
+	ABMetric         string        `json:"ab_metric" gorm:"default:null"`
+	ABSampleSize     int           `json:"ab_sample_size"`
+	ABTestHours      int           `json:"ab_test_hours"`
+	ABConfidence     float64       `json:"ab_confidence"`
+	WinningVariantID uint          `json:"winning_variant_id"`
+	PromotedAt       *time.Time    `json:"promoted_at"`
+}
+
+// Step types. An empty type is an email step.
+const (
+	StepEmail     = "email"
+	StepCondition = "condition"
+	StepWaitUntil = "wait_until"
+	StepGoal      = "goal"
+)
+
+// Step conditions
+const (
+	ConditionOpened  = "opened"
+	ConditionClicked = "clicked"
+	ConditionReplied = "replied"
+	ConditionField   = "field"
+	ConditionTag     = "tag"
+)
+
+// Condition operators. An empty operator means equals.
+const (
+	OperatorEquals      = "equals"
+	OperatorNotEquals   = "not_equals"
+	OperatorContains    = "contains"
+	OperatorNotContains = "not_contains"
+	OperatorEmpty       = "empty"
+	OperatorNotEmpty    = "not_empty"
+)
+
+// Kind returns the step's type, defaulting to email
+func (s Step) Kind() string {
+	if s.Type == "" {
+		return StepEmail
+	}
+	return s.Type
+}
+
+// CampaignFlow is a campaign's step graph
+type CampaignFlow struct {
+	EntryStepID uint     `json:"entry_step_id"`
+	Steps       []Step   `json:"steps"`
+	Errors      []string `json:"errors,omitempty"`
 }
 
 type Customer struct {
@@ -61,8 +204,41 @@ type Customer struct {
 	LeadStatus    string `json:"lead_status" gorm:"default:null"`
 	CreatedBy     uint   `json:"created_by"`
 	AssignedTo    uint   `json:"assigned_to"`
+
+	// Timezone is the customer's IANA timezone, such as "America/Chicago".
+	// When empty it's derived from Country and State.
+	Timezone string `json:"timezone" gorm:"default:null"`
+
+	// Locale is the language the customer is emailed in, such as "es" or
+	// "fr-CA". When empty it's derived from Country.
+	Locale string `json:"locale" gorm:"default:null"`
+
+	// Birthday can be used by anniversary triggers
+	Birthday *time.Time `json:"birthday"`
+
+	// Set when bounces or complaints pass the suppression thresholds; suppressed
+	// customers are never emailed again
+	SuppressedAt      *time.Time `json:"suppressed_at"`
+	SuppressionReason string     `json:"suppression_reason" gorm:"default:null"`
+
+	// Set when the customer unsubscribes with an email's unsubscribe link.
+	// Subscribing them again clears it.
+	UnsubscribedAt *time.Time `json:"unsubscribed_at"`
 }
 
+// OptedOut reports whether the customer unsubscribed or was suppressed, so
+// campaigns mustn't email them
+func (c Customer) OptedOut() bool {
+	return c.SuppressedAt != nil || c.UnsubscribedAt != nil
+}
+
+// Customer suppression reasons
+const (
+	SuppressedHardBounce = "hard_bounce"
+	SuppressedSoftBounce = "soft_bounce"
+	SuppressedComplaint  = "complaint"
+)
+
 type CampaignCustomer struct {
 	Model
 	CampaignID uint      `json:"campaign_id"`
@@ -71,14 +247,53 @@ type CampaignCustomer struct {
 	StartDate  time.Time `json:"start_date"`
 	EndDate    time.Time `json:"end_date"`
 	Subscribed bool      `json:"subscribed" gorm:"default:false"`
+
+	// Progress through the campaign, maintained by the engine
+	CurrentStepID uint       `json:"current_step_id"`
+	LastStepAt    *time.Time `json:"last_step_at"`
+	NextStepID    uint       `json:"next_step_id"`
+	NextStepAt    *time.Time `json:"next_step_at" gorm:"index"`
+
+	// WaitDeadline is when a wait-until step times out
+	WaitDeadline *time.Time `json:"wait_deadline"`
+
+	// SenderIdentityID is the identity the customer was given by a campaign
+	// that rotates senders, so the whole sequence comes from one mailbox
+	SenderIdentityID uint `json:"sender_identity_id"`
 }
 
+// CampaignCustomer statuses
+const (
+	EnrollmentActive    = "active"
+	EnrollmentPaused    = "paused"
+	EnrollmentCompleted = "completed"
+	EnrollmentExited    = "exited"
+)
+
 type EmailTemplate struct {
 	Model
 	Name        string `json:"name"`
 	Subject     string `json:"subject"`
 	Body        string `json:"body"`
-	ContentType string `json:"content_type" description:"Specifies the content type of the email body. Valid values are 'text/plain' for plain text emails and 'text/html' for HTML emails."`
+	ContentType string `json:"content_type" description:"Specifies the content type of the email body. Valid values are 'text/plain' for plain text emails, 'text/html' for HTML emails, and 'text/markdown' or 'text/mjml' for a Markdown or MJML source compiled to HTML."`
+
+	// TextBody is the plain-text alternative of an HTML body, sent alongside it
+	TextBody string `json:"text_body" gorm:"type:text;default:null"`
+
+	// LayoutID is the layout Partial the template's content is placed in
+	LayoutID uint `json:"layout_id"`
+
+	// Version is the number of the template's current TemplateVersion
+	Version int `json:"version"`
+
+	// CompiledBody and CompiledTextBody are the HTML and plain text a
+	// Markdown or MJML body compiles to, with its CSS inlined. They're
+	// compiled again when sent, with the layout and partials as they are then.
+	CompiledBody     string `json:"compiled_body,omitempty" gorm:"type:text;default:null"`
+	CompiledTextBody string `json:"compiled_text_body,omitempty" gorm:"type:text;default:null"`
+
+	// Warnings are the linter's findings, returned when the template is saved
+	Warnings []LintWarning `json:"warnings,omitempty" gorm:"-"`
 }
 
 type EmailLog struct {
@@ -86,19 +301,45 @@ type EmailLog struct {
 	CampaignID      uint      `json:"campaign_id"`
 	CustomerID      uint      `json:"customer_id"`
 	EmailTemplateID uint      `json:"email_template_id"`
+	StepID          uint      `json:"step_id" gorm:"index"`
 	Subject         string    `json:"subject"`
 	Body            string    `json:"body"`
 	SentAt          time.Time `json:"sent_at"`
 	Status          string    `json:"status"`
+	MessageID       string    `json:"message_id" gorm:"index"`
+	VariantID       uint      `json:"variant_id" gorm:"index"`
+
+	SenderIdentityID uint `json:"sender_identity_id" gorm:"index"`
+
+	// TemplateVersion is the version of the template that was sent
+	TemplateVersion int `json:"template_version"`
+
+	// Locale and LocaleRevision are the localization and its revision that
+	// were sent; Locale is empty for the template's own content
+	Locale         string `json:"locale,omitempty" gorm:"default:null"`
+	LocaleRevision int    `json:"locale_revision,omitempty"`
 }
 
+// EmailLog statuses
+const (
+	EmailStatusPending = "pending"
+	EmailStatusSent    = "sent"
+	EmailStatusFailed  = "failed"
+	EmailStatusBounced = "bounced"
+
+	EmailStatusSoftBounced = "soft_bounced"
+	EmailStatusComplained  = "complained"
+)
+
+// Settings holds the account-wide configuration. Secret fields are encrypted at
+// rest and masked in API responses; see the secrets package.
 type Settings struct {
 	Model
-	UserID              uint   `json:"user_id"`
-	CRMAPIKey           string `json:"crm_api_key"`
-	GmailEmail          string `json:"gmail_email"`
-	GmailPassword       string `json:"gmail_password"`
-	EmailPollingSeconds int    `json:"email_polling_seconds"`
+	UserID              uint           `json:"user_id"`
+	CRMAPIKey           secrets.String `json:"crm_api_key" gorm:"type:text"`
+	GmailEmail          string         `json:"gmail_email"`
+	GmailPassword       secrets.String `json:"gmail_password" gorm:"type:text"`
+	EmailPollingSeconds int            `json:"email_polling_seconds"`
 }
 
 type TokenResponse struct {
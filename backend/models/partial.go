@@ -0,0 +1,23 @@
+package models
+
+// Partial is a named snippet templates include with {{> name}}, such as a
+// signature or footer, or a layout that wraps a template's content.
+// Layouts mark where the content goes with {{> content}}. TextBody is used
+// in plain-text bodies; partials without one include Body there too.
+type Partial struct {
+	Model
+	Name        string `json:"name" gorm:"unique_index"`
+	Kind        string `json:"kind"`
+	Description string `json:"description" gorm:"default:null"`
+	Body        string `json:"body" gorm:"type:text"`
+	TextBody    string `json:"text_body" gorm:"type:text;default:null"`
+}
+
+// Partial kinds
+const (
+	PartialSnippet = "partial"
+	PartialLayout  = "layout"
+)
+
+// ContentSlot is the name layouts include a template's content with
+const ContentSlot = "content"
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/4cecoder/drip-campaign/secrets"
+)
+
+// SenderIdentity is a mailbox emails can be sent from. Identities without an
+// SMTP host send through Gmail, like the Settings account.
+type SenderIdentity struct {
+	Model
+	FromName  string `json:"from_name"`
+	FromEmail string `json:"from_email"`
+	ReplyTo   string `json:"reply_to" gorm:"default:null"`
+
+	SMTPHost     string         `json:"smtp_host" gorm:"default:null"`
+	SMTPPort     int            `json:"smtp_port"`
+	SMTPUsername string         `json:"smtp_username" gorm:"default:null"`
+	SMTPPassword secrets.String `json:"smtp_password" gorm:"type:text"`
+
+	// Signature is available to templates as {{signature}}
+	Signature string `json:"signature" gorm:"type:text;default:null"`
+
+	// DailyCap limits the emails sent from the identity in any 24 hours; zero
+	// means no limit
+	DailyCap int `json:"daily_cap"`
+
+	// Warm-up of a new mailbox: WarmupSchedule is a comma-separated list of
+	// daily send caps, such as "50,100,200,400", the first applying on the day
+	// of WarmupStartedAt. Once the list runs out only DailyCap applies.
+	WarmupStartedAt *time.Time `json:"warmup_started_at"`
+	WarmupSchedule  string     `json:"warmup_schedule" gorm:"default:null"`
+
+	// UserID is the rep who owns the mailbox, for campaigns that send as the
+	// customer's assigned rep
+	UserID uint `json:"user_id" gorm:"index"`
+
+	// InRotation identities share the customers of campaigns that rotate
+	// senders, in turn
+	InRotation     bool       `json:"in_rotation" gorm:"default:false"`
+	LastAssignedAt *time.Time `json:"last_assigned_at"`
+}
+
+// DripCampaign sender modes. With no mode the campaign sends from its
+// SenderIdentityID, or from the Settings account when that's zero.
+const (
+	SenderModeRotate      = "rotate"
+	SenderModeAssignedRep = "assigned_rep"
+)
+
+// ValidSenderMode reports whether mode can be used as a campaign's SenderMode
+func ValidSenderMode(mode string) bool {
+	switch mode {
+	case "", SenderModeRotate, SenderModeAssignedRep:
+		return true
+	}
+	return false
+}
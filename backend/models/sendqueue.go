@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+// QueuedEmail is a job in the send queue. Every email goes through the
+// queue, which sends it as fast as the rate limits allow and retries it when
+// sending fails.
+type QueuedEmail struct {
+	Model
+	EmailLogID       uint   `json:"email_log_id" gorm:"index"`
+	SenderIdentityID uint   `json:"sender_identity_id" gorm:"index"`
+	To               string `json:"to"`
+	Domain           string `json:"domain" gorm:"index"`
+	Subject          string `json:"subject"`
+	Body             string `json:"body" gorm:"type:text"`
+	TextBody         string `json:"text_body" gorm:"type:text;default:null"`
+	ContentType      string `json:"content_type" gorm:"default:null"`
+	// Attachments is the JSON list of QueuedAttachments the email carries
+	Attachments string `json:"attachments" gorm:"type:text;default:null"`
+	// UnsubscribeURL is the email's List-Unsubscribe link
+	UnsubscribeURL string     `json:"unsubscribe_url" gorm:"default:null"`
+	Status         string     `json:"status" gorm:"index"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"last_error" gorm:"type:text;default:null"`
+	NextAttempt    *time.Time `json:"next_attempt" gorm:"index"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	SentAt         *time.Time `json:"sent_at" gorm:"index"`
+	MessageID      string     `json:"message_id" gorm:"default:null"`
+}
+
+// QueuedEmail statuses. Failed emails were rejected outright; dead ones ran
+// out of attempts on temporary errors.
+const (
+	QueueStatusQueued    = "queued"
+	QueueStatusSent      = "sent"
+	QueueStatusFailed    = "failed"
+	QueueStatusDead      = "dead"
+	QueueStatusCancelled = "cancelled"
+)
+
+// DomainRateLimit caps how many emails go to one recipient domain, such as
+// gmail.com, per minute, hour and day. Zero means no limit.
+type DomainRateLimit struct {
+	Model
+	Domain    string `json:"domain" gorm:"unique_index"`
+	PerMinute int    `json:"per_minute"`
+	PerHour   int    `json:"per_hour"`
+	PerDay    int    `json:"per_day"`
+}
+
+// RateUsage compares a rate limit with what was sent in the last minute, hour
+// and day
+type RateUsage struct {
+	PerMinute      int `json:"per_minute"`
+	PerHour        int `json:"per_hour"`
+	PerDay         int `json:"per_day"`
+	SentLastMinute int `json:"sent_last_minute"`
+	SentLastHour   int `json:"sent_last_hour"`
+	SentLastDay    int `json:"sent_last_day"`
+}
+
+// DomainQueueStatus is the queue of one recipient domain
+type DomainQueueStatus struct {
+	Domain string `json:"domain"`
+	Queued int    `json:"queued"`
+	RateUsage
+}
+
+// SenderWarmup is where a sender identity is in its warm-up: the day,
+// counting from 1, that day's cap and the emails sent in the last 24 hours
+type SenderWarmup struct {
+	SenderIdentityID uint   `json:"sender_identity_id"`
+	FromEmail        string `json:"from_email"`
+	Day              int    `json:"day"`
+	Limit            int    `json:"limit"`
+	SentLastDay      int    `json:"sent_last_day"`
+}
+
+// SendQueueStatus is the depth of the send queue and how close sending is to
+// its limits. Warmups lists the sender identities warming up today.
+type SendQueueStatus struct {
+	Queued       int                 `json:"queued"`
+	Failed       int                 `json:"failed"`
+	Dead         int                 `json:"dead"`
+	OldestQueued *time.Time          `json:"oldest_queued_at"`
+	Global       RateUsage           `json:"global"`
+	Warmups      []SenderWarmup      `json:"warmups"`
+	Domains      []DomainQueueStatus `json:"domains"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+)
+
+// FunnelStats counts how a set of sent emails performed. Opened includes
+// emails that were clicked, since a click implies an open even when images are
+// blocked. Automated opens and clicks flagged as bots are excluded.
+type FunnelStats struct {
+	Sent         int `json:"sent"`
+	Delivered    int `json:"delivered"`
+	Bounced      int `json:"bounced"`
+	Opened       int `json:"opened"`
+	Clicked      int `json:"clicked"`
+	Unsubscribed int `json:"unsubscribed"`
+
+	DeliveryRate    float64 `json:"delivery_rate"`
+	BounceRate      float64 `json:"bounce_rate"`
+	OpenRate        float64 `json:"open_rate"`
+	ClickRate       float64 `json:"click_rate"`
+	ClickToOpenRate float64 `json:"click_to_open_rate"`
+	UnsubscribeRate float64 `json:"unsubscribe_rate"`
+}
+
+// Add accumulates the counts of other into s
+func (s *FunnelStats) Add(other FunnelStats) {
+	s.Sent += other.Sent
+	s.Delivered += other.Delivered
+	s.Bounced += other.Bounced
+	s.Opened += other.Opened
+	s.Clicked += other.Clicked
+	s.Unsubscribed += other.Unsubscribed
+}
+
+// ComputeRates fills in the rates from the counts
+func (s *FunnelStats) ComputeRates() {
+	s.DeliveryRate = ratio(s.Delivered, s.Sent)
+	s.BounceRate = ratio(s.Bounced, s.Sent)
+	s.OpenRate = ratio(s.Opened, s.Delivered)
+	s.ClickRate = ratio(s.Clicked, s.Delivered)
+	s.ClickToOpenRate = ratio(s.Clicked, s.Opened)
+	s.UnsubscribeRate = ratio(s.Unsubscribed, s.Delivered)
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+type EnrollmentStats struct {
+	Enrolled  int `json:"enrolled"`
+	Active    int `json:"active"`
+	Paused    int `json:"paused"`
+	Completed int `json:"completed"`
+	Exited    int `json:"exited"`
+}
+
+type StepStats struct {
+	StepID uint   `json:"step_id"`
+	Name   string `json:"name"`
+	FunnelStats
+}
+
+type StageStats struct {
+	StageID uint   `json:"stage_id"`
+	Name    string `json:"name"`
+	Order   int    `json:"order"`
+	FunnelStats
+	Steps []StepStats `json:"steps"`
+}
+
+type StatsBucket struct {
+	Start time.Time `json:"start"`
+	FunnelStats
+}
+
+type CampaignStats struct {
+	CampaignID  uint            `json:"campaign_id"`
+	Enrollments EnrollmentStats `json:"enrollments"`
+	Totals      FunnelStats     `json:"totals"`
+	Stages      []StageStats    `json:"stages"`
+	Interval    string          `json:"interval"`
+	Series      []StatsBucket   `json:"series"`
+}
@@ -0,0 +1,35 @@
+package models
+
+// TemplateVersion is an immutable copy of an email template's content. Every
+// change to a template adds one, numbered from 1.
+type TemplateVersion struct {
+	Model
+	EmailTemplateID uint   `json:"email_template_id" gorm:"unique_index:idx_template_version"`
+	Version         int    `json:"version" gorm:"unique_index:idx_template_version"`
+	Name            string `json:"name"`
+	Subject         string `json:"subject"`
+	Body            string `json:"body" gorm:"type:text"`
+	TextBody        string `json:"text_body" gorm:"type:text;default:null"`
+	ContentType     string `json:"content_type" gorm:"default:null"`
+	LayoutID        uint   `json:"layout_id"`
+
+	// CreatedBy is the user who saved the version, when known
+	CreatedBy uint `json:"created_by"`
+	// RestoredFrom is the version this one copies, for restores
+	RestoredFrom int `json:"restored_from,omitempty"`
+}
+
+// TemplateDiff compares two versions of a template. Each field's diff has
+// one line per line of text, prefixed with "  " when unchanged, "- " when
+// only in From and "+ " when only in To.
+type TemplateDiff struct {
+	EmailTemplateID uint     `json:"email_template_id"`
+	From            int      `json:"from"`
+	To              int      `json:"to"`
+	Changed         []string `json:"changed"`
+	Subject         []string `json:"subject,omitempty"`
+	Body            []string `json:"body,omitempty"`
+	TextBody        []string `json:"text_body,omitempty"`
+	ContentType     []string `json:"content_type,omitempty"`
+	LayoutID        []string `json:"layout_id,omitempty"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+)
+
+// CampaignTrigger enrolls customers in a campaign when something happens to
+// them. Value narrows the trigger down: the tag, lead status or event name to
+// match (empty matches any), or the date field of an anniversary trigger.
+type CampaignTrigger struct {
+	Model
+	CampaignID uint   `json:"campaign_id" gorm:"index"`
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+}
+
+// Trigger types, which are also the types of the customer events they match
+const (
+	TriggerCustomerCreated   = "customer_created"
+	TriggerTagAdded          = "tag_added"
+	TriggerLeadStatusChanged = "lead_status_changed"
+	TriggerEvent             = "event"
+	TriggerDateAnniversary   = "date_anniversary"
+)
+
+// Date fields anniversary triggers can use
+const (
+	AnniversaryCreatedAt = "created_at"
+	AnniversaryBirthday  = "birthday"
+)
+
+// CustomerEvent is something that happened to a customer, waiting for the
+// trigger worker. Custom events posted to the API have type "event" and their
+// name as the value.
+type CustomerEvent struct {
+	Model
+	CustomerID  uint       `json:"customer_id" gorm:"index"`
+	Type        string     `json:"type"`
+	Value       string     `json:"value"`
+	Properties  string     `json:"properties" gorm:"type:text"`
+	OccurredAt  time.Time  `json:"occurred_at"`
+	ProcessedAt *time.Time `json:"processed_at" gorm:"index"`
+}
+
+// TriggerFiring records that a trigger enrolled a customer, so it never
+// enrolls them in the campaign again. Key is empty except for anniversaries,
+// which fire once a year.
+type TriggerFiring struct {
+	Model
+	CampaignID uint   `json:"campaign_id" gorm:"unique_index:idx_trigger_firing"`
+	CustomerID uint   `json:"customer_id" gorm:"unique_index:idx_trigger_firing"`
+	Key        string `json:"key" gorm:"unique_index:idx_trigger_firing"`
+	TriggerID  uint   `json:"trigger_id"`
+}
+
+// PostEventRequest is a custom event for a customer, identified by ID or email
+type PostEventRequest struct {
+	CustomerID uint                   `json:"customer_id"`
+	Email      string                 `json:"email"`
+	Name       string                 `json:"name" binding:"required"`
+	Properties map[string]interface{} `json:"properties"`
+	OccurredAt *time.Time             `json:"occurred_at"`
+}
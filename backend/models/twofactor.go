@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+)
+
+const RecoveryCodeCount = 10
+
+// RecoveryCode is a hashed single-use code that can stand in for a TOTP code
+type RecoveryCode struct {
+	Model
+	UserID   uint       `json:"user_id" gorm:"index"`
+	CodeHash string     `json:"-" gorm:"index"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	SetupRequired     bool   `json:"setup_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+type TwoFactorSetupRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+type TwoFactorEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type TwoFactorRequirementRequest struct {
+	Required bool `json:"required"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorTokenResponse struct {
+	Token         string   `json:"token"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
@@ -4,10 +4,13 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/secrets"
 	"github.com/jinzhu/gorm"
 	"golang.org/x/crypto/bcrypt"
 	"log"
 	"strings"
+	"sync"
+	"time"
 )
 
 const AdminRole = "admin"
@@ -18,6 +21,23 @@ type User struct {
 	Email    string `gorm:"unique;not null"`
 	Password string `gorm:"not null"`
 	Role     string `gorm:"not null"`
+
+	// Two-factor authentication. TOTPSecret holds a pending secret until
+	// enrollment is confirmed with a valid code.
+	TOTPSecret      secrets.String `json:"-" gorm:"type:text"`
+	TOTPEnabled     bool           `gorm:"default:false"`
+	TOTPRequired    bool           `gorm:"default:false"`
+	TOTPLastCounter int64          `json:"-"`
+
+	// TokenVersion is carried by the user's session and challenge tokens.
+	// Changing the password increments it, which revokes every token issued
+	// before.
+	TokenVersion int `json:"-" gorm:"default:0"`
+
+	// Login throttling
+	FailedLoginCount  int `gorm:"default:0"`
+	LastFailedLoginAt *time.Time
+	LockedUntil       *time.Time
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
@@ -76,6 +96,24 @@ func CheckPasswordHash(password, hashedPassword string) bool {
 	return err == nil
 }
 
+var dummyPassword struct {
+	once sync.Once
+	hash string
+}
+
+// DummyPasswordHash returns a hash that no password matches, for checking
+// logins to unknown accounts at the same cost as real ones
+func DummyPasswordHash() string {
+	dummyPassword.once.Do(func() {
+		hash, err := HashPassword(GenerateSalt())
+		if err != nil {
+			log.Fatal(err)
+		}
+		dummyPassword.hash = hash
+	})
+	return dummyPassword.hash
+}
+
 func GenerateSalt() string {
 	salt := make([]byte, 16)
 	if _, err := rand.Read(salt); err != nil {
@@ -83,3 +121,30 @@ func GenerateSalt() string {
 	}
 	return base64.StdEncoding.EncodeToString(salt)
 }
+
+// UpdatePassword hashes and stores a new password for the user
+func UpdatePassword(db *gorm.DB, user *User, password string) error {
+	hashedPassword, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	// Tokens issued with the old password stop working
+	if err := db.Model(user).UpdateColumns(map[string]interface{}{
+		"password":      hashedPassword,
+		"token_version": gorm.Expr("token_version + 1"),
+	}).Error; err != nil {
+		return err
+	}
+	user.Password = hashedPassword
+	user.TokenVersion++
+	return nil
+}
+
+// RevokeTokens signs the user out of every session and pending login
+func RevokeTokens(db *gorm.DB, user *User) error {
+	if err := db.Model(user).UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+		return err
+	}
+	user.TokenVersion++
+	return nil
+}
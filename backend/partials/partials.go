@@ -0,0 +1,297 @@
+// Package partials composes email templates from layouts and named partials
+// at send time, so shared headers, footers and signatures live in one place.
+package partials
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+// maxDepth bounds how deeply partials can include each other
+const maxDepth = 10
+
+var (
+	// includePattern matches {{> name}}
+	includePattern = regexp.MustCompile(`\{\{>\s*([a-zA-Z0-9_-]+)\s*\}\}`)
+	namePattern    = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+)
+
+// ValidName reports whether name can be included with {{> name}}
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Includes returns the names of the partials text includes directly
+func Includes(text string) []string {
+	var names []string
+	for _, groups := range includePattern.FindAllStringSubmatch(text, -1) {
+		names = append(names, groups[1])
+	}
+	return names
+}
+
+// Set is the partials available to a template, by name
+type Set map[string]*models.Partial
+
+// Load reads every partial
+func Load() (Set, error) {
+	var all []models.Partial
+	if err := database.DB.Find(&all).Error; err != nil {
+		return nil, err
+	}
+	set := Set{}
+	for i := range all {
+		set[all[i].Name] = &all[i]
+	}
+	return set, nil
+}
+
+// Expand returns a copy of a template with its layout applied and its
+// partials included
+func Expand(template *models.EmailTemplate) (*models.EmailTemplate, error) {
+	set, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return set.Expand(template)
+}
+
+// Expand returns a copy of a template with its layout applied and its
+// partials included, using the partials in the set
+func (s Set) Expand(template *models.EmailTemplate) (*models.EmailTemplate, error) {
+	expanded := *template
+	body, textBody := template.Body, template.TextBody
+
+	if template.LayoutID != 0 {
+		layout := s.byID(template.LayoutID)
+		if layout == nil || layout.Kind != models.PartialLayout {
+			return nil, fmt.Errorf("layout %d doesn't exist", template.LayoutID)
+		}
+		body = fillSlot(layout.Body, body)
+		if layout.TextBody != "" && textBody != "" {
+			textBody = fillSlot(layout.TextBody, textBody)
+		}
+	}
+
+	var err error
+	if expanded.Body, err = s.expand(body, false, nil); err != nil {
+		return nil, err
+	}
+	if expanded.TextBody, err = s.expand(textBody, true, nil); err != nil {
+		return nil, err
+	}
+	return &expanded, nil
+}
+
+// Check reports the first problem with a partial's includes, as it would be
+// saved: includes of partials that don't exist or that include it back.
+// Layouts must have a content slot.
+func (s Set) Check(partial *models.Partial) error {
+	if partial.Kind == models.PartialLayout {
+		if !hasSlot(partial.Body) {
+			return fmt.Errorf("layouts must place the content with {{> %s}}", models.ContentSlot)
+		}
+		if partial.TextBody != "" && !hasSlot(partial.TextBody) {
+			return fmt.Errorf("the layout's text_body must place the content with {{> %s}}", models.ContentSlot)
+		}
+	}
+
+	// Check against the other partials with this one as it would be saved
+	set := Set{}
+	for name, other := range s {
+		if other.ID != partial.ID || partial.ID == 0 {
+			set[name] = other
+		}
+	}
+	set[partial.Name] = partial
+
+	stack := []string{partial.Name}
+	body, textBody := partial.Body, partial.TextBody
+	if partial.Kind == models.PartialLayout {
+		body, textBody = fillSlot(body, ""), fillSlot(textBody, "")
+	}
+	if _, err := set.expand(body, false, stack); err != nil {
+		return err
+	}
+	_, err := set.expand(textBody, true, stack)
+	return err
+}
+
+func (s Set) byID(id uint) *models.Partial {
+	for _, partial := range s {
+		if partial.ID == id {
+			return partial
+		}
+	}
+	return nil
+}
+
+// expand includes the partials text refers to. stack is the chain of
+// partials being included, to catch ones that include each other.
+func (s Set) expand(text string, plain bool, stack []string) (string, error) {
+	if len(stack) > maxDepth {
+		return "", fmt.Errorf("partials are nested more than %d deep: %s", maxDepth, strings.Join(stack, " > "))
+	}
+
+	var err error
+	expanded := includePattern.ReplaceAllStringFunc(text, func(match string) string {
+		if err != nil {
+			return match
+		}
+		name := includePattern.FindStringSubmatch(match)[1]
+		if name == models.ContentSlot {
+			err = fmt.Errorf("{{> %s}} can only be used in layouts", models.ContentSlot)
+			return match
+		}
+		for _, including := range stack {
+			if including == name {
+				err = fmt.Errorf("partials include each other: %s > %s", strings.Join(stack, " > "), name)
+				return match
+			}
+		}
+		partial, ok := s[name]
+		if !ok {
+			err = fmt.Errorf("partial %q doesn't exist", name)
+			return match
+		}
+		if partial.Kind == models.PartialLayout {
+			err = fmt.Errorf("%q is a layout and can't be included as a partial", name)
+			return match
+		}
+
+		body := partial.Body
+		if plain && partial.TextBody != "" {
+			body = partial.TextBody
+		}
+		var included string
+		included, err = s.expand(body, plain, append(append([]string{}, stack...), name))
+		return included
+	})
+	return expanded, err
+}
+
+// slotPattern matches a layout's {{> content}} slot
+var slotPattern = regexp.MustCompile(`\{\{>\s*` + models.ContentSlot + `\s*\}\}`)
+
+func hasSlot(layout string) bool {
+	return slotPattern.MatchString(layout)
+}
+
+func fillSlot(layout, content string) string {
+	return slotPattern.ReplaceAllLiteralString(layout, content)
+}
+
+// Usage lists what includes a partial by name, or uses it as a layout:
+// templates, step-pinned template versions, template translations and other
+// partials
+type Usage struct {
+	Templates     []uint            `json:"templates"`
+	Localizations []LocalizationUse `json:"localizations"`
+	Partials      []string          `json:"partials"`
+}
+
+// LocalizationUse is a template's translation that uses a partial
+type LocalizationUse struct {
+	EmailTemplateID uint   `json:"email_template_id"`
+	Locale          string `json:"locale"`
+}
+
+// InUse reports whether anything uses the partial
+func (u Usage) InUse() bool {
+	return len(u.Templates) > 0 || len(u.Localizations) > 0 || len(u.Partials) > 0
+}
+
+// UsageOf finds what uses a partial
+func UsageOf(partial *models.Partial) (Usage, error) {
+	var usage Usage
+	includes := func(text string) bool {
+		for _, name := range Includes(text) {
+			if name == partial.Name {
+				return true
+			}
+		}
+		return false
+	}
+
+	var templates []models.EmailTemplate
+	if err := database.DB.Find(&templates).Error; err != nil {
+		return usage, err
+	}
+	seen := map[uint]bool{}
+	for _, template := range templates {
+		if template.LayoutID == partial.ID || includes(template.Body) || includes(template.TextBody) {
+			usage.Templates = append(usage.Templates, template.ID)
+			seen[template.ID] = true
+		}
+	}
+
+	// Versions that steps are pinned to are still sent
+	var versions []models.TemplateVersion
+	if err := database.DB.Joins("JOIN steps ON steps.email_template_id = template_versions.email_template_id AND steps.email_template_version = template_versions.version").
+		Where("steps.deleted_at IS NULL").Find(&versions).Error; err != nil {
+		return usage, err
+	}
+	for _, version := range versions {
+		if seen[version.EmailTemplateID] {
+			continue
+		}
+		if version.LayoutID == partial.ID || includes(version.Body) || includes(version.TextBody) {
+			usage.Templates = append(usage.Templates, version.EmailTemplateID)
+			seen[version.EmailTemplateID] = true
+		}
+	}
+
+	// Translations are sent with the template's layout in place of its own
+	// content, and steps pinned to older versions are sent older revisions
+	var localizations []models.TemplateLocalization
+	if err := database.DB.Order("email_template_id asc, locale asc").Find(&localizations).Error; err != nil {
+		return usage, err
+	}
+	localized := map[LocalizationUse]bool{}
+	for _, localization := range localizations {
+		if includes(localization.Body) || includes(localization.TextBody) {
+			use := LocalizationUse{EmailTemplateID: localization.EmailTemplateID, Locale: localization.Locale}
+			usage.Localizations = append(usage.Localizations, use)
+			localized[use] = true
+		}
+	}
+	var pinned []models.Step
+	if err := database.DB.Where("email_template_version > 0").Find(&pinned).Error; err != nil {
+		return usage, err
+	}
+	for _, step := range pinned {
+		var revisions []models.LocalizationRevision
+		if err := database.DB.Where("email_template_id = ? AND template_version <= ?", step.EmailTemplateID, step.EmailTemplateVersion).
+			Order("locale asc, revision desc").Find(&revisions).Error; err != nil {
+			return usage, err
+		}
+		sent := map[string]bool{}
+		for _, revision := range revisions {
+			// Only the latest revision of each locale is sent
+			if sent[revision.Locale] {
+				continue
+			}
+			sent[revision.Locale] = true
+			use := LocalizationUse{EmailTemplateID: revision.EmailTemplateID, Locale: revision.Locale}
+			if !localized[use] && (includes(revision.Body) || includes(revision.TextBody)) {
+				usage.Localizations = append(usage.Localizations, use)
+				localized[use] = true
+			}
+		}
+	}
+
+	var others []models.Partial
+	if err := database.DB.Where("id <> ?", partial.ID).Find(&others).Error; err != nil {
+		return usage, err
+	}
+	for _, other := range others {
+		if includes(other.Body) || includes(other.TextBody) {
+			usage.Partials = append(usage.Partials, other.Name)
+		}
+	}
+	return usage, nil
+}
@@ -0,0 +1,114 @@
+// Package render fills in the merge fields of email templates for a customer.
+package render
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+var fieldPattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*\}\}`)
+
+// Email is a template rendered for one recipient
+type Email struct {
+	Subject     string
+	Body        string
+	TextBody    string
+	ContentType string
+	// Missing lists merge fields used by the template that had no value
+	Missing []string
+}
+
+// IsHTML reports whether a template content type is HTML
+func IsHTML(contentType string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(contentType)), "text/html")
+}
+
+// Fields returns the merge fields available to templates for a customer and
+// the identity the email is sent from, which may be nil. unsubscribe_url is
+// left empty; Template fills it in with the email's signed link.
+func Fields(customer *models.Customer, sender *models.SenderIdentity) map[string]string {
+	if sender == nil {
+		sender = &models.SenderIdentity{}
+	}
+	name := strings.TrimSpace(customer.FirstName + " " + customer.LastName)
+	return map[string]string{
+		"email":           customer.Email,
+		"first_name":      customer.FirstName,
+		"last_name":       customer.LastName,
+		"customer_name":   name,
+		"phone":           customer.Phone,
+		"company":         customer.Company,
+		"address":         customer.Address,
+		"city":            customer.City,
+		"state":           customer.State,
+		"country":         customer.Country,
+		"postal_code":     customer.PostalCode,
+		"unsubscribe_url": "",
+		"sender_name":     sender.FromName,
+		"sender_email":    sender.FromEmail,
+		"signature":       sender.Signature,
+	}
+}
+
+// FieldNames returns the names of the merge fields templates can use
+func FieldNames() []string {
+	var names []string
+	for name := range Fields(&models.Customer{}, nil) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render replaces {{field}} placeholders in text. Values are HTML escaped when
+// escapeHTML is set, with their line breaks kept as <br>. Fields without a value are replaced with an empty string
+// and returned in missing.
+func Render(text string, fields map[string]string, escapeHTML bool) (string, []string) {
+	var missing []string
+	rendered := fieldPattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := fieldPattern.FindStringSubmatch(match)[1]
+		value, ok := fields[name]
+		if !ok {
+			missing = append(missing, name)
+			return ""
+		}
+		if escapeHTML {
+			return strings.ReplaceAll(html.EscapeString(value), "\n", "<br>\n")
+		}
+		return value
+	})
+	return rendered, missing
+}
+
+// Template renders an email template for a customer, sent from sender (nil
+// for the Settings account), with the email's unsubscribe link
+func Template(template *models.EmailTemplate, customer *models.Customer, sender *models.SenderIdentity, unsubscribeURL string) Email {
+	fields := Fields(customer, sender)
+	fields["unsubscribe_url"] = unsubscribeURL
+	subject, missingSubject := Render(template.Subject, fields, false)
+	// A subject is one header line, whatever line breaks the fields have
+	subject = strings.Join(strings.Fields(subject), " ")
+	body, missingBody := Render(template.Body, fields, IsHTML(template.ContentType))
+	var textBody string
+	var missingText []string
+	if IsHTML(template.ContentType) {
+		textBody, missingText = Render(template.TextBody, fields, false)
+	}
+
+	contentType := template.ContentType
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+
+	return Email{
+		Subject:     subject,
+		Body:        body,
+		TextBody:    textBody,
+		ContentType: contentType,
+		Missing:     append(append(missingSubject, missingBody...), missingText...),
+	}
+}
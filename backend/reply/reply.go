@@ -0,0 +1,202 @@
+// Package reply detects replies to campaign emails and stops the customer's
+// drip sequence once they answer.
+package reply
+
+import (
+	"bytes"
+	"io"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/jinzhu/gorm"
+)
+
+// Reply is the part of an incoming message needed to match it to a sent email
+type Reply struct {
+	From       string    `json:"from"`
+	Subject    string    `json:"subject"`
+	MessageID  string    `json:"message_id"`
+	References []string  `json:"references"`
+	Auto       bool      `json:"auto"`
+	Date       time.Time `json:"date"`
+}
+
+// Result is the outcome of processing a reply
+type Result struct {
+	Reply
+	Matched    bool   `json:"matched"`
+	EmailLogID uint   `json:"email_log_id,omitempty"`
+	CustomerID uint   `json:"customer_id,omitempty"`
+	CampaignID uint   `json:"campaign_id,omitempty"`
+	Action     string `json:"action,omitempty"`
+}
+
+var messageIDRegex = regexp.MustCompile(`<[^<>\s]+>`)
+
+// autoReplySubjects are subject prefixes of out-of-office and similar replies
+var autoReplySubjects = []string{
+	"auto:",
+	"automatic reply",
+	"autoreply",
+	"auto-reply",
+	"out of office",
+	"out of the office",
+}
+
+// Parse reads the headers of a raw message
+func Parse(r io.Reader) (Reply, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return Reply{}, err
+	}
+	header := msg.Header
+
+	reply := Reply{
+		Subject:   header.Get("Subject"),
+		MessageID: strings.TrimSpace(header.Get("Message-Id")),
+	}
+	if from, err := mail.ParseAddress(header.Get("From")); err == nil {
+		reply.From = strings.ToLower(from.Address)
+	}
+	if date, err := header.Date(); err == nil {
+		reply.Date = date
+	}
+
+	// In-Reply-To names the message answered; References the whole thread,
+	// oldest first. Prefer the direct parent.
+	seen := map[string]bool{}
+	refs := messageIDRegex.FindAllString(header.Get("In-Reply-To"), -1)
+	threads := messageIDRegex.FindAllString(header.Get("References"), -1)
+	for i := len(threads) - 1; i >= 0; i-- {
+		refs = append(refs, threads[i])
+	}
+	for _, ref := range refs {
+		if !seen[ref] {
+			seen[ref] = true
+			reply.References = append(reply.References, ref)
+		}
+	}
+
+	reply.Auto = isAutoReply(header)
+	return reply, nil
+}
+
+// isAutoReply recognises out-of-office and other automatic responses
+// (RFC 3834 and the common non-standard headers)
+func isAutoReply(header mail.Header) bool {
+	if auto := strings.ToLower(header.Get("Auto-Submitted")); auto != "" && auto != "no" {
+		return true
+	}
+	if header.Get("X-Autoreply") != "" || header.Get("X-Autorespond") != "" {
+		return true
+	}
+	switch strings.ToLower(header.Get("Precedence")) {
+	case "auto_reply", "bulk", "junk", "list":
+		return true
+	}
+
+	subject := strings.ToLower(strings.TrimSpace(header.Get("Subject")))
+	for _, prefix := range autoReplySubjects {
+		if strings.HasPrefix(subject, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle parses a raw message and processes it if it answers a campaign
+// email. It reports whether the message was such a reply.
+func Handle(raw []byte) (bool, error) {
+	reply, err := Parse(bytes.NewReader(raw))
+	if err != nil || len(reply.References) == 0 {
+		return false, nil
+	}
+
+	result, err := Process(reply, time.Now())
+	return result.Matched, err
+}
+
+// Process records a reply on the customer's timeline and applies the
+// campaign's reply action to their enrollment. Automatic replies are recorded
+// but don't change the enrollment.
+func Process(reply Reply, now time.Time) (Result, error) {
+	result := Result{Reply: reply}
+	if len(reply.References) == 0 {
+		return result, nil
+	}
+
+	var emailLog models.EmailLog
+	err := database.DB.Where("message_id IN (?)", reply.References).Order("sent_at desc").First(&emailLog).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	result.Matched = true
+	result.EmailLogID = emailLog.ID
+	result.CustomerID = emailLog.CustomerID
+	result.CampaignID = emailLog.CampaignID
+
+	occurredAt := reply.Date
+	if occurredAt.IsZero() || occurredAt.After(now) {
+		occurredAt = now
+	}
+	event := models.EngagementEvent{
+		Type:       models.EventReply,
+		EmailLogID: emailLog.ID,
+		CustomerID: emailLog.CustomerID,
+		CampaignID: emailLog.CampaignID,
+		StepID:     emailLog.StepID,
+		Detail:     reply.Subject,
+		OccurredAt: occurredAt,
+	}
+	if reply.Auto {
+		event.Type = models.EventAutoReply
+	}
+	if err := database.DB.Create(&event).Error; err != nil {
+		return result, err
+	}
+	if reply.Auto {
+		return result, nil
+	}
+
+	var campaign models.DripCampaign
+	if err := database.DB.First(&campaign, emailLog.CampaignID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return result, nil
+		}
+		return result, err
+	}
+
+	result.Action = campaign.OnReply
+	if result.Action == "" {
+		result.Action = models.ReplyActionExit
+	}
+	return result, applyAction(result.Action, emailLog, now)
+}
+
+// applyAction exits or pauses the customer's enrollment in the campaign
+func applyAction(action string, emailLog models.EmailLog, now time.Time) error {
+	updates := map[string]interface{}{}
+	switch action {
+	case models.ReplyActionExit:
+		updates["status"] = models.EnrollmentExited
+		updates["end_date"] = now
+		updates["next_step_id"] = 0
+		updates["next_step_at"] = nil
+	case models.ReplyActionPause:
+		updates["status"] = models.EnrollmentPaused
+	default:
+		return nil
+	}
+
+	return database.DB.Model(&models.CampaignCustomer{}).
+		Where("campaign_id = ? AND customer_id = ? AND status IN (?)",
+			emailLog.CampaignID, emailLog.CustomerID, []string{"", models.EnrollmentActive}).
+		UpdateColumns(updates).Error
+}
@@ -0,0 +1,227 @@
+package reply
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+func message(headers ...string) string {
+	return strings.Join(headers, "\r\n") + "\r\n\r\nThanks!\r\n"
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		from       string
+		references []string
+		auto       bool
+	}{
+		{
+			name:       "in-reply-to",
+			raw:        message("From: Someone <Someone@Customer.test>", "Subject: Re: Welcome", "In-Reply-To: <a@example.com>"),
+			from:       "someone@customer.test",
+			references: []string{"<a@example.com>"},
+		},
+		{
+			// The parent comes first, then the thread from newest to oldest
+			name: "in-reply-to and references",
+			raw: message("From: someone@customer.test", "Subject: Re: Welcome",
+				"In-Reply-To: <c@example.com>", "References: <a@example.com>\r\n <b@example.com> <c@example.com>"),
+			from:       "someone@customer.test",
+			references: []string{"<c@example.com>", "<b@example.com>", "<a@example.com>"},
+		},
+		{
+			name:       "references only",
+			raw:        message("From: someone@customer.test", "Subject: Re: Welcome", "References: <a@example.com> <b@example.com>"),
+			from:       "someone@customer.test",
+			references: []string{"<b@example.com>", "<a@example.com>"},
+		},
+		{
+			name: "not a reply",
+			raw:  message("From: someone@customer.test", "Subject: Hello"),
+			from: "someone@customer.test",
+		},
+		{
+			name:       "auto-submitted",
+			raw:        message("From: someone@customer.test", "Subject: Re: Welcome", "Auto-Submitted: auto-replied", "In-Reply-To: <a@example.com>"),
+			from:       "someone@customer.test",
+			references: []string{"<a@example.com>"},
+			auto:       true,
+		},
+		{
+			name:       "out of office subject",
+			raw:        message("From: someone@customer.test", "Subject: Out of Office: Welcome", "In-Reply-To: <a@example.com>"),
+			from:       "someone@customer.test",
+			references: []string{"<a@example.com>"},
+			auto:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, err := Parse(strings.NewReader(tt.raw))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if reply.From != tt.from {
+				t.Errorf("from = %q, want %q", reply.From, tt.from)
+			}
+			if !reflect.DeepEqual(reply.References, tt.references) {
+				t.Errorf("references = %v, want %v", reply.References, tt.references)
+			}
+			if reply.Auto != tt.auto {
+				t.Errorf("auto = %v, want %v", reply.Auto, tt.auto)
+			}
+		})
+	}
+}
+
+// openTestDB points database.DB at an in-memory database with the tables
+// replies touch
+func openTestDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.DripCampaign{}, &models.CampaignCustomer{}, &models.EmailLog{}, &models.EngagementEvent{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		db.Close()
+	})
+}
+
+func TestProcess(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	sentAt := now.Add(-48 * time.Hour)
+
+	tests := []struct {
+		name       string
+		onReply    string
+		references []string
+		auto       bool
+		matched    bool
+		emailLog   int
+		action     string
+		status     string
+		event      string
+	}{
+		{
+			name:       "in-reply-to exits by default",
+			references: []string{"<second@example.com>"},
+			matched:    true,
+			emailLog:   1,
+			action:     models.ReplyActionExit,
+			status:     models.EnrollmentExited,
+			event:      models.EventReply,
+		},
+		{
+			name:       "references to an earlier email",
+			onReply:    models.ReplyActionExit,
+			references: []string{"<unknown@customer.test>", "<first@example.com>"},
+			matched:    true,
+			emailLog:   0,
+			action:     models.ReplyActionExit,
+			status:     models.EnrollmentExited,
+			event:      models.EventReply,
+		},
+		{
+			name:       "pause",
+			onReply:    models.ReplyActionPause,
+			references: []string{"<second@example.com>"},
+			matched:    true,
+			emailLog:   1,
+			action:     models.ReplyActionPause,
+			status:     models.EnrollmentPaused,
+			event:      models.EventReply,
+		},
+		{
+			name:       "continue",
+			onReply:    models.ReplyActionContinue,
+			references: []string{"<second@example.com>"},
+			matched:    true,
+			emailLog:   1,
+			action:     models.ReplyActionContinue,
+			status:     models.EnrollmentActive,
+			event:      models.EventReply,
+		},
+		{
+			name:       "automatic reply",
+			onReply:    models.ReplyActionExit,
+			references: []string{"<second@example.com>"},
+			auto:       true,
+			matched:    true,
+			emailLog:   1,
+			status:     models.EnrollmentActive,
+			event:      models.EventAutoReply,
+		},
+		{
+			name:       "unknown thread",
+			onReply:    models.ReplyActionExit,
+			references: []string{"<unknown@customer.test>"},
+			emailLog:   -1,
+			status:     models.EnrollmentActive,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			openTestDB(t)
+			campaign := models.DripCampaign{Name: "Onboarding", OnReply: tt.onReply}
+			database.DB.Create(&campaign)
+			enrollment := models.CampaignCustomer{CampaignID: campaign.ID, CustomerID: 7, Status: models.EnrollmentActive, NextStepID: 3, NextStepAt: &now}
+			database.DB.Create(&enrollment)
+			var logs []models.EmailLog
+			for i, id := range []string{"<first@example.com>", "<second@example.com>"} {
+				at := sentAt.Add(time.Duration(i) * time.Hour)
+				emailLog := models.EmailLog{CampaignID: campaign.ID, CustomerID: 7, StepID: uint(i + 1), MessageID: id, SentAt: at}
+				database.DB.Create(&emailLog)
+				logs = append(logs, emailLog)
+			}
+
+			result, err := Process(Reply{Subject: "Re: Welcome", References: tt.references, Auto: tt.auto}, now)
+			if err != nil {
+				t.Fatalf("Process: %v", err)
+			}
+			if result.Matched != tt.matched {
+				t.Errorf("matched = %v, want %v", result.Matched, tt.matched)
+			}
+			if tt.emailLog >= 0 && result.EmailLogID != logs[tt.emailLog].ID {
+				t.Errorf("email log = %d, want %d", result.EmailLogID, logs[tt.emailLog].ID)
+			}
+			if result.Action != tt.action {
+				t.Errorf("action = %q, want %q", result.Action, tt.action)
+			}
+
+			database.DB.First(&enrollment, enrollment.ID)
+			if enrollment.Status != tt.status {
+				t.Errorf("enrollment status = %q, want %q", enrollment.Status, tt.status)
+			}
+			if tt.status == models.EnrollmentExited && (enrollment.NextStepID != 0 || enrollment.NextStepAt != nil) {
+				t.Errorf("exited enrollment still has a next step: %d at %v", enrollment.NextStepID, enrollment.NextStepAt)
+			}
+
+			var events []models.EngagementEvent
+			database.DB.Find(&events)
+			if tt.event == "" {
+				if len(events) != 0 {
+					t.Errorf("got %d events, want none", len(events))
+				}
+				return
+			}
+			if len(events) != 1 || events[0].Type != tt.event || events[0].CustomerID != 7 {
+				t.Errorf("events = %+v, want one %s for customer 7", events, tt.event)
+			}
+		})
+	}
+}
@@ -11,18 +11,51 @@ func RegisterRoutes(router *gin.Engine) {
 	public := router.Group("/api/v1")
 	{
 		public.POST("/login", handlers.LoginHandler)
+		public.POST("/login/2fa", handlers.LoginTwoFactorHandler)
+		public.POST("/login/2fa/setup", handlers.LoginTwoFactorSetupHandler)
+		public.POST("/password/forgot", handlers.ForgotPasswordHandler)
+		public.POST("/password/reset", handlers.ResetPasswordHandler)
+		public.POST("/invitations/accept", handlers.AcceptInvitationHandler)
+	}
+
+	// Public tracking routes, requested by recipients' mail clients
+	tracking := router.Group("/t")
+	{
+		tracking.GET("/o/:token", handlers.TrackOpenHandler)
+		tracking.GET("/c/:token", handlers.TrackClickHandler)
+		tracking.GET("/u/:token", handlers.UnsubscribePageHandler)
+		tracking.POST("/u/:token", handlers.UnsubscribeHandler)
 	}
 
 	// Routes accessible by users and admins
 	userAndAdmin := router.Group("/api/v1")
-	userAndAdmin.Use(auth.IsUserOrAdmin) // Use IsUserOrAdmin middleware
+	userAndAdmin.Use(auth.IsUserOrAdmin) // Use IsUserOrAdmin middleware, which also accepts scoped API keys
 	{
+		// Account routes
+		userAndAdmin.PUT("/me/password", handlers.ChangePasswordHandler)
+		userAndAdmin.POST("/me/2fa/enroll", handlers.EnrollTwoFactorHandler)
+		userAndAdmin.POST("/me/2fa/confirm", handlers.ConfirmTwoFactorHandler)
+		userAndAdmin.POST("/me/2fa/recovery-codes", handlers.RegenerateRecoveryCodesHandler)
+		userAndAdmin.POST("/me/2fa/disable", handlers.DisableTwoFactorHandler)
+
+		// API key routes
+		userAndAdmin.POST("/api-keys", handlers.CreateAPIKeyHandler)
+		userAndAdmin.GET("/api-keys", handlers.GetAPIKeysHandler)
+		userAndAdmin.DELETE("/api-keys/:id", handlers.RevokeAPIKeyHandler)
+
 		// Campaign routes
 		userAndAdmin.POST("/campaigns", handlers.CreateCampaignHandler)
 		userAndAdmin.GET("/campaigns", handlers.GetCampaignsHandler)
 		userAndAdmin.GET("/campaigns/:id", handlers.GetCampaignHandler)
 		userAndAdmin.PUT("/campaigns/:id", handlers.UpdateCampaignHandler)
 		userAndAdmin.DELETE("/campaigns/:id", handlers.DeleteCampaignHandler)
+		userAndAdmin.GET("/campaigns/:id/stats", handlers.GetCampaignStatsHandler)
+		userAndAdmin.GET("/campaigns/:id/flow", handlers.GetCampaignFlowHandler)
+		userAndAdmin.GET("/campaigns/:id/translations", handlers.GetCampaignTranslationsHandler)
+		userAndAdmin.PUT("/campaigns/:id/flow", handlers.UpdateCampaignFlowHandler)
+		userAndAdmin.GET("/campaigns/:id/triggers", handlers.GetCampaignTriggersHandler)
+		userAndAdmin.POST("/campaigns/:id/triggers", handlers.CreateCampaignTriggerHandler)
+		userAndAdmin.DELETE("/campaigns/:id/triggers/:trigger_id", handlers.DeleteCampaignTriggerHandler)
 
 		// Stage routes
 		userAndAdmin.POST("/stages", handlers.CreateStageHandler)
@@ -37,6 +70,15 @@ func RegisterRoutes(router *gin.Engine) {
 		userAndAdmin.GET("/steps/:id", handlers.GetStepHandler)
 		userAndAdmin.PUT("/steps/:id", handlers.UpdateStepHandler)
 		userAndAdmin.DELETE("/steps/:id", handlers.DeleteStepHandler)
+		userAndAdmin.GET("/steps/:id/variants", handlers.GetStepVariantsHandler)
+		userAndAdmin.POST("/steps/:id/variants", handlers.CreateStepVariantHandler)
+		userAndAdmin.PUT("/steps/:id/variants/:variant_id", handlers.UpdateStepVariantHandler)
+		userAndAdmin.DELETE("/steps/:id/variants/:variant_id", handlers.DeleteStepVariantHandler)
+		userAndAdmin.POST("/steps/:id/variants/:variant_id/promote", handlers.PromoteStepVariantHandler)
+		userAndAdmin.GET("/steps/:id/ab-results", handlers.GetStepABResultsHandler)
+		userAndAdmin.GET("/steps/:id/attachments", handlers.GetStepAttachmentsHandler)
+		userAndAdmin.POST("/steps/:id/attachments", handlers.CreateStepAttachmentHandler)
+		userAndAdmin.DELETE("/steps/:id/attachments/:attachment_id", handlers.DeleteStepAttachmentHandler)
 
 		// Customer routes
 		userAndAdmin.POST("/customers", handlers.CreateCustomerHandler)
@@ -44,6 +86,7 @@ func RegisterRoutes(router *gin.Engine) {
 		userAndAdmin.GET("/customers/:id", handlers.GetCustomerHandler)
 		userAndAdmin.PUT("/customers/:id", handlers.UpdateCustomerHandler)
 		userAndAdmin.DELETE("/customers/:id", handlers.DeleteCustomerHandler)
+		userAndAdmin.GET("/customers/:id/timeline", handlers.GetCustomerTimelineHandler)
 
 		// Campaign customer routes
 		userAndAdmin.POST("/campaign-customers", handlers.CreateCampaignCustomerHandler)
@@ -55,12 +98,71 @@ func RegisterRoutes(router *gin.Engine) {
 		// Send an email route
 		userAndAdmin.POST("/send-email", handlers.SendEmailHandler)
 
+		// Send queue routes
+		userAndAdmin.GET("/send-queue", handlers.GetSendQueueHandler)
+		userAndAdmin.GET("/rate-limits", handlers.GetDomainRateLimitsHandler)
+		userAndAdmin.POST("/rate-limits", handlers.CreateDomainRateLimitHandler)
+		userAndAdmin.PUT("/rate-limits/:id", handlers.UpdateDomainRateLimitHandler)
+		userAndAdmin.DELETE("/rate-limits/:id", handlers.DeleteDomainRateLimitHandler)
+
+		// Customer event routes, for enrollment triggers
+		userAndAdmin.POST("/events", handlers.PostEventHandler)
+
+		// Inbound mail routes, for feeding in bounces and replies from a mail provider's webhook or a script
+		userAndAdmin.POST("/inbound/bounces", handlers.InboundBounceHandler)
+		userAndAdmin.POST("/inbound/replies", handlers.InboundReplyHandler)
+
 		// Email Template routes
 		userAndAdmin.POST("/templates", handlers.CreateEmailTemplateHandler)
 		userAndAdmin.GET("/templates", handlers.GetEmailTemplatesHandler)
 		userAndAdmin.GET("/templates/:id", handlers.GetEmailTemplateHandler)
 		userAndAdmin.PUT("/templates/:id", handlers.UpdateEmailTemplateHandler)
 		userAndAdmin.DELETE("/templates/:id", handlers.DeleteEmailTemplateHandler)
+		userAndAdmin.POST("/templates/:id/lint", handlers.LintEmailTemplateHandler)
+		userAndAdmin.GET("/templates/:id/versions", handlers.GetTemplateVersionsHandler)
+		userAndAdmin.GET("/templates/:id/versions/:version", handlers.GetTemplateVersionHandler)
+		userAndAdmin.POST("/templates/:id/versions/:version/restore", handlers.RestoreTemplateVersionHandler)
+		userAndAdmin.GET("/templates/:id/diff", handlers.DiffTemplateVersionsHandler)
+		userAndAdmin.GET("/templates/:id/attachments", handlers.GetTemplateAttachmentsHandler)
+		userAndAdmin.POST("/templates/:id/attachments", handlers.CreateTemplateAttachmentHandler)
+		userAndAdmin.DELETE("/templates/:id/attachments/:attachment_id", handlers.DeleteTemplateAttachmentHandler)
+		userAndAdmin.GET("/templates/:id/localizations", handlers.GetTemplateLocalizationsHandler)
+		userAndAdmin.GET("/templates/:id/localizations/:locale", handlers.GetTemplateLocalizationHandler)
+		userAndAdmin.PUT("/templates/:id/localizations/:locale", handlers.PutTemplateLocalizationHandler)
+		userAndAdmin.GET("/templates/:id/localizations/:locale/revisions", handlers.GetLocalizationRevisionsHandler)
+		userAndAdmin.DELETE("/templates/:id/localizations/:locale", handlers.DeleteTemplateLocalizationHandler)
+
+		// Partial and layout routes
+		userAndAdmin.POST("/partials", handlers.CreatePartialHandler)
+		userAndAdmin.GET("/partials", handlers.GetPartialsHandler)
+		userAndAdmin.GET("/partials/:id", handlers.GetPartialHandler)
+		userAndAdmin.PUT("/partials/:id", handlers.UpdatePartialHandler)
+		userAndAdmin.DELETE("/partials/:id", handlers.DeletePartialHandler)
+
+		// Asset routes
+		userAndAdmin.POST("/assets", handlers.UploadAssetHandler)
+		userAndAdmin.GET("/assets", handlers.GetAssetsHandler)
+		userAndAdmin.GET("/assets/:id", handlers.GetAssetHandler)
+		userAndAdmin.GET("/assets/:id/content", handlers.DownloadAssetHandler)
+		userAndAdmin.DELETE("/assets/:id", handlers.DeleteAssetHandler)
+
+		// Sender identity routes
+		userAndAdmin.POST("/sender-identities", handlers.CreateSenderIdentityHandler)
+		userAndAdmin.GET("/sender-identities", handlers.GetSenderIdentitiesHandler)
+		userAndAdmin.GET("/sender-identities/:id", handlers.GetSenderIdentityHandler)
+		userAndAdmin.PUT("/sender-identities/:id", handlers.UpdateSenderIdentityHandler)
+		userAndAdmin.DELETE("/sender-identities/:id", handlers.DeleteSenderIdentityHandler)
+
+		// DKIM key routes
+		userAndAdmin.POST("/dkim-keys", handlers.CreateDKIMKeyHandler)
+		userAndAdmin.GET("/dkim-keys", handlers.GetDKIMKeysHandler)
+		userAndAdmin.GET("/dkim-keys/:id", handlers.GetDKIMKeyHandler)
+		userAndAdmin.PUT("/dkim-keys/:id", handlers.UpdateDKIMKeyHandler)
+		userAndAdmin.DELETE("/dkim-keys/:id", handlers.DeleteDKIMKeyHandler)
+		userAndAdmin.POST("/dkim-keys/:id/check", handlers.CheckDKIMKeyHandler)
+
+		// Sending domain routes
+		userAndAdmin.GET("/sending-domains/:domain/check", handlers.CheckSendingDomainHandler)
 
 		// Settings routes
 		userAndAdmin.GET("/settings", handlers.GetSettingsHandler)
@@ -77,5 +179,20 @@ func RegisterRoutes(router *gin.Engine) {
 		adminPrivate.GET("/users/:id", handlers.GetUserHandler)
 		adminPrivate.PUT("/users/:id", handlers.UpdateUserHandler)
 		adminPrivate.DELETE("/users/:id", handlers.DeleteUserHandler)
+		adminPrivate.PUT("/users/:id/2fa", handlers.UpdateUserTwoFactorRequirementHandler)
+		adminPrivate.DELETE("/users/:id/2fa", handlers.ResetUserTwoFactorHandler)
+		adminPrivate.POST("/users/:id/unlock", handlers.UnlockUserHandler)
+		adminPrivate.GET("/login-attempts", handlers.GetLoginAttemptsHandler)
+
+		// Invitation routes
+		adminPrivate.POST("/invitations", handlers.CreateInvitationHandler)
+		adminPrivate.GET("/invitations", handlers.GetInvitationsHandler)
+		adminPrivate.DELETE("/invitations/:id", handlers.DeleteInvitationHandler)
+
+		// Send queue job routes
+		adminPrivate.GET("/send-queue/jobs", handlers.GetSendJobsHandler)
+		adminPrivate.GET("/send-queue/jobs/:id", handlers.GetSendJobHandler)
+		adminPrivate.POST("/send-queue/jobs/:id/retry", handlers.RetrySendJobHandler)
+		adminPrivate.POST("/send-queue/jobs/:id/cancel", handlers.CancelSendJobHandler)
 	}
 }
@@ -0,0 +1,183 @@
+// Package schedule works out when steps are due: wait units, campaign sending
+// windows and the customer's local time.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Embed the timezone database so customers' timezones resolve on hosts
+	// without one installed
+	_ "time/tzdata"
+
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+// Wait units. Steps without a unit wait in seconds, as they always have.
+const (
+	UnitSeconds      = "seconds"
+	UnitMinutes      = "minutes"
+	UnitHours        = "hours"
+	UnitDays         = "days"
+	UnitBusinessDays = "business_days"
+)
+
+// ValidUnit reports whether unit can be used as a step's wait unit
+func ValidUnit(unit string) bool {
+	switch unit {
+	case "", UnitSeconds, UnitMinutes, UnitHours, UnitDays, UnitBusinessDays:
+		return true
+	}
+	return false
+}
+
+// Delay returns the time that is wait units after from. Days and business days
+// keep the time of day in loc across daylight saving changes; business days
+// skip weekends.
+func Delay(from time.Time, wait int, unit string, loc *time.Location) time.Time {
+	switch unit {
+	case UnitMinutes:
+		return from.Add(time.Duration(wait) * time.Minute)
+	case UnitHours:
+		return from.Add(time.Duration(wait) * time.Hour)
+	case UnitDays:
+		return from.In(loc).AddDate(0, 0, wait)
+	case UnitBusinessDays:
+		t := from.In(loc)
+		for added := 0; added < wait; {
+			t = t.AddDate(0, 0, 1)
+			if !weekend(t.Weekday()) {
+				added++
+			}
+		}
+		return t
+	}
+	return from.Add(time.Duration(wait) * time.Second)
+}
+
+func weekend(day time.Weekday) bool {
+	return day == time.Saturday || day == time.Sunday
+}
+
+// Window is the part of the week a campaign may send in, in the recipient's
+// local time. The zero Window is always open.
+type Window struct {
+	days       [7]bool // none means every day
+	start, end int     // minutes since midnight; both zero means all day
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseWindow reads a campaign's sending window: days as a comma-separated
+// list such as "mon,tue,wed,thu,fri" (empty for every day) and start and end
+// as "HH:MM" (both empty for all day)
+func ParseWindow(days, start, end string) (Window, error) {
+	var w Window
+	for _, day := range strings.Split(days, ",") {
+		day = strings.ToLower(strings.TrimSpace(day))
+		if day == "" {
+			continue
+		}
+		if len(day) > 3 {
+			day = day[:3]
+		}
+		weekday, ok := weekdays[day]
+		if !ok {
+			return w, fmt.Errorf("unknown day %q", day)
+		}
+		w.days[weekday] = true
+	}
+
+	if start == "" && end == "" {
+		return w, nil
+	}
+	var err error
+	if w.start, err = parseClock(start); err != nil {
+		return w, err
+	}
+	if w.end, err = parseClock(end); err != nil {
+		return w, err
+	}
+	if w.start >= w.end {
+		return w, fmt.Errorf("window start %s must be before its end %s", start, end)
+	}
+	return w, nil
+}
+
+// CampaignWindow returns the campaign's sending window
+func CampaignWindow(campaign *models.DripCampaign) (Window, error) {
+	return ParseWindow(campaign.SendDays, campaign.SendWindowStart, campaign.SendWindowEnd)
+}
+
+func parseClock(value string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", value)
+	}
+	hours, err1 := strconv.Atoi(parts[0])
+	minutes, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || hours < 0 || minutes < 0 || minutes > 59 || hours > 24 || (hours == 24 && minutes != 0) {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", value)
+	}
+	return hours*60 + minutes, nil
+}
+
+// open reports whether sending is allowed on the day
+func (w Window) open(day time.Weekday) bool {
+	return w.days == [7]bool{} || w.days[day]
+}
+
+// Next returns t if it falls inside the window in loc, otherwise the start of
+// the window's next opening
+func (w Window) Next(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	for i := 0; i < 8; i++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+i, 0, 0, 0, 0, loc)
+		if !w.open(day.Weekday()) {
+			continue
+		}
+		if w.start == 0 && w.end == 0 {
+			if i == 0 {
+				return t
+			}
+			return day
+		}
+
+		// Built from the wall clock so a daylight saving change that day
+		// doesn't move the window
+		opens := time.Date(day.Year(), day.Month(), day.Day(), 0, w.start, 0, 0, loc)
+		closes := time.Date(day.Year(), day.Month(), day.Day(), 0, w.end, 0, 0, loc)
+		if i == 0 && !local.Before(opens) && local.Before(closes) {
+			return t
+		}
+		if local.Before(opens) {
+			return opens
+		}
+	}
+	return t
+}
+
+// ValidateCampaign checks a campaign's sending window and timezone
+func ValidateCampaign(campaign *models.DripCampaign) error {
+	if _, err := CampaignWindow(campaign); err != nil {
+		return err
+	}
+	if !ValidTimezone(campaign.Timezone) {
+		return fmt.Errorf("unknown timezone %q", campaign.Timezone)
+	}
+	return nil
+}
+
+// ValidTimezone reports whether name is empty or an IANA timezone
+func ValidTimezone(name string) bool {
+	if name == "" {
+		return true
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
@@ -0,0 +1,171 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestDelay(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	at := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, newYork)
+	}
+
+	tests := []struct {
+		name string
+		from time.Time
+		wait int
+		unit string
+		want time.Time
+	}{
+		{"seconds by default", at(2026, 10, 19, 9, 0), 90, "", at(2026, 10, 19, 9, 1).Add(30 * time.Second)},
+		{"minutes", at(2026, 10, 19, 9, 0), 45, UnitMinutes, at(2026, 10, 19, 9, 45)},
+		{"hours", at(2026, 10, 19, 9, 0), 3, UnitHours, at(2026, 10, 19, 12, 0)},
+		{"days", at(2026, 10, 19, 9, 0), 2, UnitDays, at(2026, 10, 21, 9, 0)},
+
+		// Daylight saving starts on March 8 and ends on November 1 2026
+		{"hours across the spring change", at(2026, 3, 7, 9, 0), 24, UnitHours, at(2026, 3, 8, 10, 0)},
+		{"days across the spring change", at(2026, 3, 7, 9, 0), 1, UnitDays, at(2026, 3, 8, 9, 0)},
+		{"days across the fall change", at(2026, 10, 31, 9, 0), 2, UnitDays, at(2026, 11, 2, 9, 0)},
+		{"business days across the fall change", at(2026, 10, 30, 9, 0), 1, UnitBusinessDays, at(2026, 11, 2, 9, 0)},
+
+		// October 16 2026 is a Friday
+		{"business day within the week", at(2026, 10, 13, 9, 0), 2, UnitBusinessDays, at(2026, 10, 15, 9, 0)},
+		{"business day over a weekend", at(2026, 10, 16, 9, 0), 1, UnitBusinessDays, at(2026, 10, 19, 9, 0)},
+		{"business week", at(2026, 10, 16, 9, 0), 5, UnitBusinessDays, at(2026, 10, 23, 9, 0)},
+		{"business day from a Saturday", at(2026, 10, 17, 9, 0), 1, UnitBusinessDays, at(2026, 10, 19, 9, 0)},
+		{"business day from a Sunday", at(2026, 10, 18, 9, 0), 1, UnitBusinessDays, at(2026, 10, 19, 9, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Delay(tt.from, tt.wait, tt.unit, newYork); !got.Equal(tt.want) {
+				t.Errorf("Delay = %s, want %s", got.In(newYork), tt.want)
+			}
+		})
+	}
+
+	// Business days are counted in the customer's timezone: Friday 22:00 in
+	// New York is already Saturday in UTC
+	friday := at(2026, 10, 16, 22, 0)
+	if got, want := Delay(friday, 1, UnitBusinessDays, newYork), at(2026, 10, 19, 22, 0); !got.Equal(want) {
+		t.Errorf("Delay = %s, want %s", got.In(newYork), want)
+	}
+	if got, want := Delay(friday, 1, UnitBusinessDays, time.UTC), at(2026, 10, 18, 22, 0); !got.Equal(want) {
+		t.Errorf("Delay in UTC = %s, want %s", got.In(newYork), want)
+	}
+}
+
+func TestWindowNext(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, newYork)
+	}
+	weekdays := mustWindow(t, "mon,tue,wed,thu,fri", "09:00", "17:00")
+
+	tests := []struct {
+		name   string
+		window Window
+		t      time.Time
+		want   time.Time
+	}{
+		{"always open", Window{}, at(10, 18, 3, 0), at(10, 18, 3, 0)},
+		{"inside the window", weekdays, at(10, 19, 10, 30), at(10, 19, 10, 30)},
+		{"as it opens", weekdays, at(10, 19, 9, 0), at(10, 19, 9, 0)},
+		{"before it opens", weekdays, at(10, 19, 7, 15), at(10, 19, 9, 0)},
+		{"as it closes", weekdays, at(10, 19, 17, 0), at(10, 20, 9, 0)},
+		{"overnight", weekdays, at(10, 19, 23, 30), at(10, 20, 9, 0)},
+		{"after midnight", weekdays, at(10, 20, 0, 30), at(10, 20, 9, 0)},
+		{"Friday evening", weekdays, at(10, 23, 18, 0), at(10, 26, 9, 0)},
+		{"closed day", weekdays, at(10, 24, 12, 0), at(10, 26, 9, 0)},
+		{"all day on open days", mustWindow(t, "sat,sun", "", ""), at(10, 21, 12, 0), at(10, 24, 0, 0)},
+		{"all day on an open day", mustWindow(t, "Saturday,Sunday", "", ""), at(10, 24, 12, 0), at(10, 24, 12, 0)},
+		{"until midnight", mustWindow(t, "", "20:00", "24:00"), at(10, 19, 23, 59), at(10, 19, 23, 59)},
+
+		// The window follows the wall clock across daylight saving changes
+		{"day of the spring change", mustWindow(t, "", "09:00", "17:00"), at(3, 8, 3, 30), at(3, 8, 9, 0)},
+		{"night before the spring change", mustWindow(t, "", "09:00", "17:00"), at(3, 7, 20, 0), at(3, 8, 9, 0)},
+		{"day of the fall change", mustWindow(t, "", "09:00", "17:00"), at(11, 1, 0, 30), at(11, 1, 9, 0)},
+		{"closing on the day of the fall change", mustWindow(t, "", "09:00", "17:00"), at(11, 1, 16, 59), at(11, 1, 16, 59)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Next(tt.t, newYork); !got.Equal(tt.want) {
+				t.Errorf("Next = %s, want %s", got.In(newYork), tt.want)
+			}
+		})
+	}
+
+	// The window is in the recipient's time: 22:00 UTC on a Monday is
+	// already Tuesday morning in Tokyo
+	tokyo := mustLoad(t, "Asia/Tokyo")
+	monday := time.Date(2026, 10, 19, 22, 0, 0, 0, time.UTC)
+	if got, want := weekdays.Next(monday, tokyo), time.Date(2026, 10, 20, 9, 0, 0, 0, tokyo); !got.Equal(want) {
+		t.Errorf("Next in Tokyo = %s, want %s", got.In(tokyo), want)
+	}
+}
+
+func mustWindow(t *testing.T, days, start, end string) Window {
+	t.Helper()
+	w, err := ParseWindow(days, start, end)
+	if err != nil {
+		t.Fatalf("ParseWindow(%q, %q, %q): %v", days, start, end, err)
+	}
+	return w
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		days, start, end string
+		valid            bool
+	}{
+		{"", "", "", true},
+		{"mon, Tue ,WED", "", "", true},
+		{"monday,friday", "08:30", "18:00", true},
+		{"", "00:00", "24:00", true},
+		{"funday", "", "", false},
+		{"", "22:00", "06:00", false},
+		{"", "09:00", "09:00", false},
+		{"", "09:00", "", false},
+		{"", "9am", "5pm", false},
+		{"", "09:60", "17:00", false},
+		{"", "09:00", "24:30", false},
+	}
+	for _, tt := range tests {
+		if _, err := ParseWindow(tt.days, tt.start, tt.end); (err == nil) != tt.valid {
+			t.Errorf("ParseWindow(%q, %q, %q) error = %v, want valid %v", tt.days, tt.start, tt.end, err, tt.valid)
+		}
+	}
+}
+
+func TestLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		customer models.Customer
+		campaign *models.DripCampaign
+		want     string
+	}{
+		{"customer's own", models.Customer{Timezone: "Europe/Paris", Country: "US"}, nil, "Europe/Paris"},
+		{"from state", models.Customer{Country: "United States", State: "california"}, nil, "America/Los_Angeles"},
+		{"from country", models.Customer{Country: "deutschland"}, nil, "Europe/Berlin"},
+		{"unknown state", models.Customer{Country: "US", State: "Atlantis"}, nil, "America/New_York"},
+		{"campaign's", models.Customer{Country: "Atlantis"}, &models.DripCampaign{Timezone: "Asia/Tokyo"}, "Asia/Tokyo"},
+		{"invalid own", models.Customer{Timezone: "Mars/Olympus"}, &models.DripCampaign{Timezone: "Asia/Tokyo"}, "Asia/Tokyo"},
+		{"nothing known", models.Customer{}, nil, "UTC"},
+	}
+	for _, tt := range tests {
+		if got := Location(&tt.customer, tt.campaign).String(); got != tt.want {
+			t.Errorf("%s: Location = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package schedule
+
+import (
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+// Location returns the customer's timezone: their own, one derived from their
+// country and state, the campaign's, or UTC
+func Location(customer *models.Customer, campaign *models.DripCampaign) *time.Location {
+	names := []string{customer.Timezone, CustomerTimezone(customer)}
+	if campaign != nil {
+		names = append(names, campaign.Timezone)
+	}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// CustomerTimezone derives a timezone from the customer's country and, for
+// countries spanning several timezones, their state. It returns "" when the
+// country isn't known.
+func CustomerTimezone(customer *models.Customer) string {
+	country := CountryCode(customer.Country)
+	if states, ok := stateTimezones[country]; ok {
+		if tz, ok := states[stateCode(customer.State)]; ok {
+			return tz
+		}
+	}
+	return countryTimezones[country]
+}
+
+// CountryCode normalizes a country name or ISO code to its ISO 3166 alpha-2 code
+func CountryCode(country string) string {
+	country = strings.ToLower(strings.TrimSpace(country))
+	if code, ok := countryNames[country]; ok {
+		return code
+	}
+	return strings.ToUpper(country)
+}
+
+// stateCode normalizes a state name or abbreviation to its abbreviation
+func stateCode(state string) string {
+	state = strings.ToLower(strings.TrimSpace(state))
+	if code, ok := stateNames[state]; ok {
+		return code
+	}
+	return strings.ToUpper(state)
+}
+
+// countryTimezones is the main timezone of each country, by ISO code
+var countryTimezones = map[string]string{
+	"US": "America/New_York", "CA": "America/Toronto", "MX": "America/Mexico_City",
+	"BR": "America/Sao_Paulo", "AR": "America/Argentina/Buenos_Aires", "CL": "America/Santiago",
+	"CO": "America/Bogota", "PE": "America/Lima", "VE": "America/Caracas",
+	"GB": "Europe/London", "IE": "Europe/Dublin", "PT": "Europe/Lisbon", "ES": "Europe/Madrid",
+	"FR": "Europe/Paris", "BE": "Europe/Brussels", "NL": "Europe/Amsterdam", "LU": "Europe/Luxembourg",
+	"DE": "Europe/Berlin", "CH": "Europe/Zurich", "AT": "Europe/Vienna", "IT": "Europe/Rome",
+	"DK": "Europe/Copenhagen", "NO": "Europe/Oslo", "SE": "Europe/Stockholm", "FI": "Europe/Helsinki",
+	"PL": "Europe/Warsaw", "CZ": "Europe/Prague", "SK": "Europe/Bratislava", "HU": "Europe/Budapest",
+	"RO": "Europe/Bucharest", "BG": "Europe/Sofia", "GR": "Europe/Athens", "TR": "Europe/Istanbul",
+	"UA": "Europe/Kyiv", "RU": "Europe/Moscow", "IL": "Asia/Jerusalem", "AE": "Asia/Dubai",
+	"SA": "Asia/Riyadh", "EG": "Africa/Cairo", "ZA": "Africa/Johannesburg", "NG": "Africa/Lagos",
+	"KE": "Africa/Nairobi", "IN": "Asia/Kolkata", "PK": "Asia/Karachi", "BD": "Asia/Dhaka",
+	"TH": "Asia/Bangkok", "VN": "Asia/Ho_Chi_Minh", "MY": "Asia/Kuala_Lumpur", "SG": "Asia/Singapore",
+	"ID": "Asia/Jakarta", "PH": "Asia/Manila", "CN": "Asia/Shanghai", "HK": "Asia/Hong_Kong",
+	"TW": "Asia/Taipei", "KR": "Asia/Seoul", "JP": "Asia/Tokyo", "AU": "Australia/Sydney",
+	"NZ": "Pacific/Auckland",
+}
+
+// stateTimezones is the timezone of each state of countries that span several
+var stateTimezones = map[string]map[string]string{
+	"US": {
+		"CT": "America/New_York", "DE": "America/New_York", "DC": "America/New_York", "FL": "America/New_York",
+		"GA": "America/New_York", "IN": "America/Indiana/Indianapolis", "KY": "America/New_York",
+		"ME": "America/New_York", "MD": "America/New_York", "MA": "America/New_York", "MI": "America/Detroit",
+		"NH": "America/New_York", "NJ": "America/New_York", "NY": "America/New_York", "NC": "America/New_York",
+		"OH": "America/New_York", "PA": "America/New_York", "RI": "America/New_York", "SC": "America/New_York",
+		"VT": "America/New_York", "VA": "America/New_York", "WV": "America/New_York",
+		"AL": "America/Chicago", "AR": "America/Chicago", "IL": "America/Chicago", "IA": "America/Chicago",
+		"KS": "America/Chicago", "LA": "America/Chicago", "MN": "America/Chicago", "MS": "America/Chicago",
+		"MO": "America/Chicago", "NE": "America/Chicago", "ND": "America/Chicago", "OK": "America/Chicago",
+		"SD": "America/Chicago", "TN": "America/Chicago", "TX": "America/Chicago", "WI": "America/Chicago",
+		"AZ": "America/Phoenix", "CO": "America/Denver", "ID": "America/Boise", "MT": "America/Denver",
+		"NM": "America/Denver", "UT": "America/Denver", "WY": "America/Denver",
+		"CA": "America/Los_Angeles", "NV": "America/Los_Angeles", "OR": "America/Los_Angeles",
+		"WA": "America/Los_Angeles", "AK": "America/Anchorage", "HI": "Pacific/Honolulu",
+		"PR": "America/Puerto_Rico",
+	},
+	"CA": {
+		"NL": "America/St_Johns", "NS": "America/Halifax", "NB": "America/Moncton", "PE": "America/Halifax",
+		"QC": "America/Toronto", "ON": "America/Toronto", "MB": "America/Winnipeg", "SK": "America/Regina",
+		"AB": "America/Edmonton", "BC": "America/Vancouver", "YT": "America/Whitehorse",
+		"NT": "America/Yellowknife", "NU": "America/Iqaluit",
+	},
+	"AU": {
+		"NSW": "Australia/Sydney", "ACT": "Australia/Sydney", "VIC": "Australia/Melbourne",
+		"QLD": "Australia/Brisbane", "SA": "Australia/Adelaide", "WA": "Australia/Perth",
+		"TAS": "Australia/Hobart", "NT": "Australia/Darwin",
+	},
+	"BR": {
+		"AC": "America/Rio_Branco", "AM": "America/Manaus", "RO": "America/Porto_Velho",
+		"RR": "America/Boa_Vista", "MT": "America/Cuiaba", "MS": "America/Campo_Grande",
+	},
+	"MX": {
+		"BC": "America/Tijuana", "SON": "America/Hermosillo", "CHH": "America/Chihuahua",
+		"SIN": "America/Mazatlan", "BCS": "America/Mazatlan", "ROO": "America/Cancun",
+	},
+}
+
+// countryNames maps common country names to ISO codes
+var countryNames = map[string]string{
+	"united states": "US", "united states of america": "US", "usa": "US", "u.s.": "US", "u.s.a.": "US", "america": "US",
+	"canada": "CA", "mexico": "MX", "brazil": "BR", "argentina": "AR", "chile": "CL", "colombia": "CO",
+	"peru": "PE", "venezuela": "VE", "united kingdom": "GB", "uk": "GB", "great britain": "GB",
+	"england": "GB", "scotland": "GB", "wales": "GB", "northern ireland": "GB", "ireland": "IE",
+	"portugal": "PT", "spain": "ES", "france": "FR", "belgium": "BE", "netherlands": "NL",
+	"the netherlands": "NL", "holland": "NL", "luxembourg": "LU", "germany": "DE", "switzerland": "CH",
+	"austria": "AT", "italy": "IT", "denmark": "DK", "norway": "NO", "sweden": "SE", "finland": "FI",
+	"poland": "PL", "czech republic": "CZ", "czechia": "CZ", "slovakia": "SK", "hungary": "HU",
+	"romania": "RO", "bulgaria": "BG", "greece": "GR", "turkey": "TR", "ukraine": "UA", "russia": "RU",
+	"israel": "IL", "united arab emirates": "AE", "uae": "AE", "saudi arabia": "SA", "egypt": "EG",
+	"south africa": "ZA", "nigeria": "NG", "kenya": "KE", "india": "IN", "pakistan": "PK",
+	"bangladesh": "BD", "thailand": "TH", "vietnam": "VN", "malaysia": "MY", "singapore": "SG",
+	"indonesia": "ID", "philippines": "PH", "china": "CN", "hong kong": "HK", "taiwan": "TW",
+	"south korea": "KR", "korea": "KR", "japan": "JP", "australia": "AU", "new zealand": "NZ",
+
+	// Names in the countries' own languages
+	"españa": "ES", "espana": "ES", "méxico": "MX", "deutschland": "DE", "österreich": "AT",
+	"osterreich": "AT", "schweiz": "CH", "suisse": "CH", "svizzera": "CH", "belgique": "BE",
+	"belgië": "BE", "nederland": "NL", "italia": "IT", "brasil": "BR", "perú": "PE",
+}
+
+// stateNames maps state and province names to the abbreviations used in
+// stateTimezones
+var stateNames = map[string]string{
+	"alabama": "AL", "alaska": "AK", "arizona": "AZ", "arkansas": "AR", "california": "CA",
+	"colorado": "CO", "connecticut": "CT", "delaware": "DE", "district of columbia": "DC",
+	"florida": "FL", "georgia": "GA", "hawaii": "HI", "idaho": "ID", "illinois": "IL", "indiana": "IN",
+	"iowa": "IA", "kansas": "KS", "kentucky": "KY", "louisiana": "LA", "maine": "ME", "maryland": "MD",
+	"massachusetts": "MA", "michigan": "MI", "minnesota": "MN", "mississippi": "MS", "missouri": "MO",
+	"montana": "MT", "nebraska": "NE", "nevada": "NV", "new hampshire": "NH", "new jersey": "NJ",
+	"new mexico": "NM", "new york": "NY", "north carolina": "NC", "north dakota": "ND", "ohio": "OH",
+	"oklahoma": "OK", "oregon": "OR", "pennsylvania": "PA", "rhode island": "RI",
+	"south carolina": "SC", "south dakota": "SD", "tennessee": "TN", "texas": "TX", "utah": "UT",
+	"vermont": "VT", "virginia": "VA", "washington": "WA", "west virginia": "WV", "wisconsin": "WI",
+	"wyoming": "WY", "puerto rico": "PR",
+	"newfoundland and labrador": "NL", "newfoundland": "NL", "nova scotia": "NS", "new brunswick": "NB",
+	"prince edward island": "PE", "quebec": "QC", "ontario": "ON", "manitoba": "MB",
+	"saskatchewan": "SK", "alberta": "AB", "british columbia": "BC", "yukon": "YT",
+	"northwest territories": "NT", "nunavut": "NU",
+	"new south wales": "NSW", "australian capital territory": "ACT", "victoria": "VIC",
+	"queensland": "QLD", "south australia": "SA", "western australia": "WA", "tasmania": "TAS",
+	"northern territory": "NT",
+}
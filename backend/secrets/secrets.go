@@ -0,0 +1,216 @@
+// Package secrets implements envelope encryption for secret values stored in
+// the database. Every value is encrypted with its own random data key, which is
+// in turn wrapped with a master key taken from the configuration.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	encryptedPrefix = "enc:v1:"
+	masterKeySize   = 32
+)
+
+var (
+	ErrNoMasterKey = errors.New("no master key configured, set MASTER_KEY")
+	ErrUnknownKey  = errors.New("value was encrypted with an unknown master key")
+)
+
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Keyring holds the primary master key used for encryption and any previous
+// keys that are still accepted for decryption during rotation.
+type Keyring struct {
+	primary *masterKey
+	keys    map[string]*masterKey
+}
+
+var (
+	mu      sync.RWMutex
+	keyring = &Keyring{keys: map[string]*masterKey{}}
+)
+
+// NewKeyring builds a keyring from base64 encoded 32 byte master keys.
+// primary may be empty, in which case values can't be encrypted.
+func NewKeyring(primary string, previous []string) (*Keyring, error) {
+	k := &Keyring{keys: map[string]*masterKey{}}
+	for _, encoded := range previous {
+		key, err := parseMasterKey(encoded)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[key.id] = key
+	}
+	if primary != "" {
+		key, err := parseMasterKey(primary)
+		if err != nil {
+			return nil, err
+		}
+		k.primary = key
+		k.keys[key.id] = key
+	}
+	return k, nil
+}
+
+// SetKeyring replaces the keyring used by Encrypt and Decrypt
+func SetKeyring(k *Keyring) {
+	mu.Lock()
+	defer mu.Unlock()
+	keyring = k
+}
+
+// GenerateMasterKey returns a new random base64 encoded master key
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, masterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// IsEncrypted reports whether a stored value is in encrypted form
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// Encrypt encrypts plaintext with a fresh data key wrapped by the primary master key
+func Encrypt(plaintext string) (string, error) {
+	mu.RLock()
+	primary := keyring.primary
+	mu.RUnlock()
+	if primary == nil {
+		return "", ErrNoMasterKey
+	}
+
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	dataAEAD, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := seal(dataAEAD, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	wrappedKey, err := seal(primary.aead, dek, []byte(primary.id))
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedPrefix + primary.id + ":" +
+		base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt reverses Encrypt. Values that aren't encrypted are returned unchanged
+// so rows written before encryption was enabled keep working.
+func Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+
+	mu.RLock()
+	key := keyring.keys[parts[0]]
+	mu.RUnlock()
+	if key == nil {
+		return "", ErrUnknownKey
+	}
+
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+
+	dek, err := open(key.aead, wrappedKey, []byte(key.id))
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataAEAD, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether a stored value isn't encrypted with the primary key
+func NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+	if !IsEncrypted(value) {
+		return true
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return keyring.primary == nil || !strings.HasPrefix(value, encryptedPrefix+keyring.primary.id+":")
+}
+
+func parseMasterKey(encoded string) (*masterKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("master key is not valid base64: %w", err)
+	}
+	if len(raw) != masterKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", masterKeySize, len(raw))
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(raw)
+	return &masterKey{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts data and prepends the random nonce
+func seal(aead cipher.AEAD, data, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, data, additionalData), nil
+}
+
+func open(aead cipher.AEAD, data, additionalData []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
@@ -0,0 +1,184 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// useKeys encrypts with primary and also decrypts with previous until the test ends
+func useKeys(t *testing.T, primary string, previous ...string) {
+	t.Helper()
+	k, err := NewKeyring(primary, previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetKeyring(k)
+	t.Cleanup(func() { SetKeyring(&Keyring{keys: map[string]*masterKey{}}) })
+}
+
+func newMasterKey(t *testing.T) string {
+	t.Helper()
+	key, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	useKeys(t, newMasterKey(t))
+	for _, plaintext := range []string{"hunter2", "", "päss wörd:with:colons"} {
+		encrypted, err := Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		if !IsEncrypted(encrypted) || strings.Contains(encrypted, "hunter2") {
+			t.Errorf("Encrypt(%q) = %q, which isn't encrypted", plaintext, encrypted)
+		}
+		decrypted, err := Decrypt(encrypted)
+		if err != nil {
+			t.Fatalf("Decrypt: %v", err)
+		}
+		if decrypted != plaintext {
+			t.Errorf("Decrypt = %q, want %q", decrypted, plaintext)
+		}
+		if NeedsRotation(encrypted) {
+			t.Errorf("value encrypted with the primary key needs rotation")
+		}
+	}
+
+	// Each value gets its own data key and nonce
+	a, _ := Encrypt("hunter2")
+	b, _ := Encrypt("hunter2")
+	if a == b {
+		t.Error("the same plaintext encrypted to the same value twice")
+	}
+}
+
+func TestDecryptPlaintext(t *testing.T) {
+	useKeys(t, newMasterKey(t))
+	value, err := Decrypt("stored before encryption")
+	if err != nil || value != "stored before encryption" {
+		t.Errorf("Decrypt = %q, %v, want the value unchanged", value, err)
+	}
+	if !NeedsRotation("stored before encryption") {
+		t.Error("plaintext value doesn't need rotation")
+	}
+}
+
+func TestDecryptPreviousKey(t *testing.T) {
+	oldKey, newKey := newMasterKey(t), newMasterKey(t)
+	useKeys(t, oldKey)
+	encrypted, err := Encrypt("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	useKeys(t, newKey, oldKey)
+	decrypted, err := Decrypt(encrypted)
+	if err != nil || decrypted != "hunter2" {
+		t.Errorf("Decrypt = %q, %v, want hunter2", decrypted, err)
+	}
+	if !NeedsRotation(encrypted) {
+		t.Error("value encrypted with the previous key doesn't need rotation")
+	}
+
+	// Once the old key is dropped its values can't be read
+	useKeys(t, newKey)
+	if _, err := Decrypt(encrypted); err != ErrUnknownKey {
+		t.Errorf("err = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	previous := newMasterKey(t)
+	useKeys(t, newMasterKey(t), previous)
+	encrypted, err := Encrypt("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(strings.TrimPrefix(encrypted, encryptedPrefix), ":")
+
+	// flip changes the last byte of a base64 encoded part
+	flip := func(part string) string {
+		raw, _ := base64.RawStdEncoding.DecodeString(part)
+		raw[len(raw)-1] ^= 1
+		return base64.RawStdEncoding.EncodeToString(raw)
+	}
+	// Pointing the value at another key in the keyring fails to unwrap
+	previousKey, _ := parseMasterKey(previous)
+
+	tests := map[string]string{
+		"ciphertext":  encryptedPrefix + parts[0] + ":" + parts[1] + ":" + flip(parts[2]),
+		"wrapped key": encryptedPrefix + parts[0] + ":" + flip(parts[1]) + ":" + parts[2],
+		"key ID":      encryptedPrefix + previousKey.id + ":" + parts[1] + ":" + parts[2],
+		"truncated":   encryptedPrefix + parts[0] + ":" + parts[1] + ":" + parts[2][:8],
+		"malformed":   encryptedPrefix + parts[0] + ":" + parts[2],
+		"not base64":  encryptedPrefix + parts[0] + ":" + parts[1] + ":%%%",
+	}
+	for name, value := range tests {
+		if plaintext, err := Decrypt(value); err == nil {
+			t.Errorf("%s: tampered value decrypted to %q", name, plaintext)
+		}
+	}
+}
+
+func TestNoMasterKey(t *testing.T) {
+	useKeys(t, "")
+	if _, err := Encrypt("hunter2"); err != ErrNoMasterKey {
+		t.Errorf("err = %v, want ErrNoMasterKey", err)
+	}
+	if _, err := NewKeyring("dG9vIHNob3J0", nil); err == nil {
+		t.Error("a short master key was accepted")
+	}
+	if _, err := NewKeyring("not base64!", nil); err == nil {
+		t.Error("an invalid master key was accepted")
+	}
+}
+
+func TestStringJSON(t *testing.T) {
+	type settings struct {
+		Password String `json:"password"`
+	}
+
+	out, err := json.Marshal(settings{Password: "hunter2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"password":"********"}` {
+		t.Errorf("Marshal = %s, want the password masked", out)
+	}
+	if out, _ := json.Marshal(settings{}); string(out) != `{"password":""}` {
+		t.Errorf("Marshal = %s, want an empty password", out)
+	}
+
+	// Sending the mask back keeps the current value, anything else replaces it
+	s := settings{Password: "hunter2"}
+	if err := json.Unmarshal([]byte(`{"password":"********"}`), &s); err != nil || s.Password != "hunter2" {
+		t.Errorf("after unmarshalling the mask password = %q, %v, want hunter2", s.Password, err)
+	}
+	if err := json.Unmarshal([]byte(`{"password":"correct horse"}`), &s); err != nil || s.Password != "correct horse" {
+		t.Errorf("password = %q, %v, want correct horse", s.Password, err)
+	}
+}
+
+func TestStringValueScan(t *testing.T) {
+	useKeys(t, newMasterKey(t))
+	value, err := String("hunter2").Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(value.(string)) {
+		t.Fatalf("Value = %q, want it encrypted", value)
+	}
+
+	var s String
+	if err := s.Scan([]byte(value.(string))); err != nil || s != "hunter2" {
+		t.Errorf("Scan = %q, %v, want hunter2", s, err)
+	}
+	if err := s.Scan(nil); err != nil || s != "" {
+		t.Errorf("Scan(nil) = %q, %v, want empty", s, err)
+	}
+}
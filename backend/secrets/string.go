@@ -0,0 +1,66 @@
+package secrets
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Mask is returned by the API in place of a secret that has a value
+const Mask = "********"
+
+// String is a secret string column. It is encrypted when written to the
+// database, decrypted when read, and write-only in JSON: it marshals to Mask
+// and unmarshalling Mask leaves the current value untouched.
+type String string
+
+// Value implements driver.Valuer
+func (s String) Value() (driver.Value, error) {
+	if s == "" {
+		return "", nil
+	}
+	return Encrypt(string(s))
+}
+
+// Scan implements sql.Scanner
+func (s *String) Scan(src interface{}) error {
+	var value string
+	switch v := src.(type) {
+	case nil:
+		value = ""
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into secrets.String", src)
+	}
+
+	plaintext, err := Decrypt(value)
+	if err != nil {
+		return err
+	}
+	*s = String(plaintext)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (s String) MarshalJSON() ([]byte, error) {
+	if s == "" {
+		return json.Marshal("")
+	}
+	return json.Marshal(Mask)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *String) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == Mask {
+		return nil
+	}
+	*s = String(value)
+	return nil
+}
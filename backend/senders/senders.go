@@ -0,0 +1,92 @@
+// Package senders picks the sender identity each campaign email comes from.
+package senders
+
+import (
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/sendqueue"
+	"github.com/jinzhu/gorm"
+)
+
+// Choose returns the ID of the identity a step's email to the customer is
+// sent from, or zero for the Settings account: the step's identity, then the
+// customer's assigned rep or rotation identity when the campaign uses one,
+// then the campaign's identity. Rotation assignments are saved on the
+// enrollment so every email of the sequence comes from the same mailbox.
+func Choose(campaign *models.DripCampaign, step *models.Step, enrollment *models.CampaignCustomer, customer *models.Customer) (uint, error) {
+	if step.SenderIdentityID != 0 {
+		return step.SenderIdentityID, nil
+	}
+
+	switch campaign.SenderMode {
+	case models.SenderModeAssignedRep:
+		if customer.AssignedTo != 0 {
+			var identities []models.SenderIdentity
+			if err := database.DB.Where("user_id = ?", customer.AssignedTo).Order("id asc").Limit(1).
+				Find(&identities).Error; err != nil {
+				return 0, err
+			}
+			if len(identities) > 0 {
+				return identities[0].ID, nil
+			}
+		}
+
+	case models.SenderModeRotate:
+		if enrollment.SenderIdentityID != 0 {
+			return enrollment.SenderIdentityID, nil
+		}
+		id, err := rotate(time.Now())
+		if err != nil {
+			return 0, err
+		}
+		if id == 0 {
+			break
+		}
+		enrollment.SenderIdentityID = id
+		if err := database.DB.Model(enrollment).UpdateColumn("sender_identity_id", id).Error; err != nil {
+			return 0, err
+		}
+		return id, nil
+	}
+	return campaign.SenderIdentityID, nil
+}
+
+// rotate hands out the identity in rotation that was assigned longest ago,
+// preferring identities below their daily and warm-up caps. When every one is
+// at its cap the queue holds the email until there's room. It returns zero
+// when no identity is in rotation.
+func rotate(now time.Time) (uint, error) {
+	var chosen uint
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the rotation so instances don't hand out the same identity
+		var identities []models.SenderIdentity
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").
+			Where("in_rotation = ?", true).
+			Order("last_assigned_at asc nulls first, id asc").
+			Find(&identities).Error; err != nil {
+			return err
+		}
+
+		if len(identities) == 0 {
+			return nil
+		}
+
+		pick := &identities[0]
+		for i := range identities {
+			allowance, err := sendqueue.IdentityAllowance(&identities[i], now)
+			if err != nil {
+				return err
+			}
+			if allowance == 0 {
+				continue
+			}
+			pick = &identities[i]
+			break
+		}
+		chosen = pick.ID
+		return tx.Model(pick).UpdateColumn("last_assigned_at", now).Error
+	})
+	return chosen, err
+}
@@ -0,0 +1,648 @@
+// Package sendqueue holds outgoing emails and sends them within the global,
+// per-domain and warm-up rate limits. Emails over a limit wait for the next
+// run instead of failing.
+package sendqueue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/assets"
+	"github.com/4cecoder/drip-campaign/config"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/mailer"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// batchSize is how many queued emails a run looks at most
+	batchSize = 100
+
+	// maxRetryDelay caps the backoff between attempts
+	maxRetryDelay = 6 * time.Hour
+
+	// unlimited is the remaining allowance when there's no limit
+	unlimited = -1
+)
+
+// Limits caps how many emails are sent per minute, hour and day. Zero means
+// no limit.
+type Limits struct {
+	PerMinute int
+	PerHour   int
+	PerDay    int
+}
+
+// NewLimits returns the global limits from the configuration
+func NewLimits(cfg *config.Config) Limits {
+	return Limits{
+		PerMinute: cfg.SendLimitPerMinute,
+		PerHour:   cfg.SendLimitPerHour,
+		PerDay:    cfg.SendLimitPerDay,
+	}
+}
+
+// Enqueue adds an email to the queue. emailLogID is the email log the result
+// is recorded on, or zero. The attachments' contents are read when the email
+// is sent.
+func Enqueue(msg mailer.Message, emailLogID uint, attachments []models.QueuedAttachment) (*models.QueuedEmail, error) {
+	var encoded string
+	if len(attachments) > 0 {
+		data, err := json.Marshal(attachments)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(data)
+	}
+	email := models.QueuedEmail{
+		EmailLogID:       emailLogID,
+		SenderIdentityID: msg.SenderIdentityID,
+		To:               msg.To,
+		Domain:           Domain(msg.To),
+		Subject:          msg.Subject,
+		Body:             msg.Body,
+		TextBody:         msg.TextBody,
+		ContentType:      msg.ContentType,
+		Attachments:      encoded,
+		UnsubscribeURL:   msg.UnsubscribeURL,
+		Status:           models.QueueStatusQueued,
+	}
+	if err := database.DB.Create(&email).Error; err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+// Domain returns the lower-cased domain of an email address
+func Domain(address string) string {
+	address = strings.Trim(strings.TrimSpace(address), "<>")
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		return strings.ToLower(address[i+1:])
+	}
+	return ""
+}
+
+// ErrWrongStatus is returned when an email can't be requeued or cancelled in
+// its current status
+var ErrWrongStatus = errors.New("the email's status doesn't allow that")
+
+// Start sends queued emails every interval. It blocks, so call it in a goroutine.
+func Start(interval time.Duration, limits Limits, backoff Backoff) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := RunOnce(time.Now(), limits, backoff); err != nil {
+			log.Println("Error running send queue:", err)
+		}
+		<-ticker.C
+	}
+}
+
+// RunOnce sends the queued emails that are due, oldest first, until a limit
+// is reached. Emails to a domain, or from a sender identity, that is at its
+// limit are skipped so others aren't held up behind them.
+//
+// Each email is claimed with SELECT ... FOR UPDATE SKIP LOCKED and stays
+// locked while it's sent, so several instances can share the queue without
+// sending anything twice. If an instance dies mid-send the lock is released
+// and the email is picked up again.
+func RunOnce(now time.Time, limits Limits, backoff Backoff) error {
+	global, err := globalAllowance(limits, now)
+	if err != nil || global == 0 {
+		return err
+	}
+
+	domains := map[string]int{}
+	senders := map[uint]int{}
+	var fullDomains []string
+	var fullSenders []uint
+	for n := 0; n < batchSize && global != 0; n++ {
+		var email models.QueuedEmail
+		claimed, sent := false, false
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			query := tx.Set("gorm:query_option", "FOR UPDATE SKIP LOCKED").
+				Where("status = ? AND (next_attempt IS NULL OR next_attempt <= ?)", models.QueueStatusQueued, now)
+			if len(fullDomains) > 0 {
+				query = query.Where("domain NOT IN (?)", fullDomains)
+			}
+			if len(fullSenders) > 0 {
+				query = query.Where("sender_identity_id NOT IN (?)", fullSenders)
+			}
+			if err := query.Order("id asc").First(&email).Error; err != nil {
+				if gorm.IsRecordNotFoundError(err) {
+					return nil
+				}
+				return err
+			}
+			claimed = true
+
+			if _, ok := domains[email.Domain]; !ok {
+				allowance, err := domainAllowance(email.Domain, now)
+				if err != nil {
+					return err
+				}
+				domains[email.Domain] = allowance
+			}
+			if _, ok := senders[email.SenderIdentityID]; !ok {
+				allowance, err := senderAllowance(email.SenderIdentityID, now)
+				if err != nil {
+					return err
+				}
+				senders[email.SenderIdentityID] = allowance
+			}
+			if domains[email.Domain] == 0 || senders[email.SenderIdentityID] == 0 {
+				return nil
+			}
+			sent = send(tx, &email, backoff)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			break
+		}
+
+		switch {
+		case domains[email.Domain] == 0:
+			fullDomains = append(fullDomains, email.Domain)
+		case senders[email.SenderIdentityID] == 0:
+			fullSenders = append(fullSenders, email.SenderIdentityID)
+		case sent:
+			global = spend(global)
+			domains[email.Domain] = spend(domains[email.Domain])
+			senders[email.SenderIdentityID] = spend(senders[email.SenderIdentityID])
+		}
+	}
+	return nil
+}
+
+// send delivers a queued email and records the result on it and its email
+// log. Temporary failures are retried with exponential backoff until the
+// email runs out of attempts and is dead-lettered; permanent ones fail it
+// straight away. It reports whether the email was sent.
+func send(tx *gorm.DB, email *models.QueuedEmail, backoff Backoff) bool {
+	var messageID string
+	attachments, err := readAttachments(email)
+	if err == nil {
+		messageID, err = mailer.Send(mailer.Message{
+			To:               email.To,
+			Subject:          email.Subject,
+			Body:             email.Body,
+			TextBody:         email.TextBody,
+			ContentType:      email.ContentType,
+			SenderIdentityID: email.SenderIdentityID,
+			Attachments:      attachments,
+			UnsubscribeURL:   email.UnsubscribeURL,
+		})
+	}
+	now := time.Now()
+	email.Attempts++
+
+	queueUpdate := map[string]interface{}{"attempts": email.Attempts, "last_attempt_at": now}
+	logUpdate := map[string]interface{}{}
+	switch {
+	case err == nil:
+		queueUpdate["status"] = models.QueueStatusSent
+		queueUpdate["sent_at"] = now
+		queueUpdate["message_id"] = messageID
+		logUpdate["status"] = models.EmailStatusSent
+		logUpdate["sent_at"] = now
+		logUpdate["message_id"] = messageID
+	case mailer.Permanent(err) || errors.Is(err, assets.ErrNotFound):
+		log.Printf("Queued email %d to %s was rejected: %v", email.ID, email.To, err)
+		queueUpdate["status"] = models.QueueStatusFailed
+		queueUpdate["last_error"] = err.Error()
+		logUpdate["status"] = models.EmailStatusFailed
+		logUpdate["sent_at"] = now
+	case email.Attempts >= backoff.MaxAttempts:
+		log.Printf("Giving up on queued email %d to %s after %d attempts: %v", email.ID, email.To, email.Attempts, err)
+		queueUpdate["status"] = models.QueueStatusDead
+		queueUpdate["last_error"] = err.Error()
+		logUpdate["status"] = models.EmailStatusFailed
+		logUpdate["sent_at"] = now
+	default:
+		log.Printf("Error sending queued email %d to %s, retrying: %v", email.ID, email.To, err)
+		queueUpdate["last_error"] = err.Error()
+		queueUpdate["next_attempt"] = now.Add(backoff.Delay(email.Attempts))
+	}
+
+	if updateErr := tx.Model(email).UpdateColumns(queueUpdate).Error; updateErr != nil {
+		log.Println("Error updating queued email:", updateErr)
+	}
+	if email.EmailLogID != 0 && len(logUpdate) > 0 {
+		if updateErr := tx.Model(&models.EmailLog{}).Where("id = ?", email.EmailLogID).
+			UpdateColumns(logUpdate).Error; updateErr != nil {
+			log.Println("Error updating email log:", updateErr)
+		}
+	}
+	return err == nil
+}
+
+// readAttachments loads the contents of a queued email's attachments. An
+// attachment whose asset was deleted since is an assets.ErrNotFound.
+func readAttachments(email *models.QueuedEmail) ([]mailer.Attachment, error) {
+	if email.Attachments == "" {
+		return nil, nil
+	}
+	var queued []models.QueuedAttachment
+	if err := json.Unmarshal([]byte(email.Attachments), &queued); err != nil {
+		return nil, fmt.Errorf("invalid attachments: %w", err)
+	}
+
+	var attachments []mailer.Attachment
+	for _, attachment := range queued {
+		var asset models.Asset
+		if err := database.DB.Unscoped().First(&asset, attachment.AssetID).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return nil, fmt.Errorf("attachment %s: %w", attachment.Filename, assets.ErrNotFound)
+			}
+			return nil, err
+		}
+		data, err := assets.Read(&asset)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %s: %w", attachment.Filename, err)
+		}
+		attachments = append(attachments, mailer.Attachment{
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			ContentID:   attachment.ContentID,
+			Data:        data,
+		})
+	}
+	return attachments, nil
+}
+
+// Backoff is how often and how long apart failed emails are retried
+type Backoff struct {
+	MaxAttempts int
+	Base        time.Duration
+	Max         time.Duration
+}
+
+// NewBackoff returns the retry policy from the configuration
+func NewBackoff(cfg *config.Config) Backoff {
+	return Backoff{
+		MaxAttempts: cfg.SendMaxAttempts,
+		Base:        time.Duration(cfg.SendRetryBaseSeconds) * time.Second,
+		Max:         maxRetryDelay,
+	}
+}
+
+// Delay is how long to wait after the given number of failed attempts: Base,
+// doubling with every attempt up to Max, plus up to a tenth of jitter so
+// emails that failed together aren't all retried at once
+func (b Backoff) Delay(attempts int) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempts && delay < b.Max; i++ {
+		delay *= 2
+	}
+	if delay > b.Max {
+		delay = b.Max
+	}
+	if delay >= 10 {
+		delay += time.Duration(rand.Int63n(int64(delay / 10)))
+	}
+	return delay
+}
+
+// Requeue puts a failed, dead-lettered or cancelled email back on the queue
+// with a fresh set of attempts, due right away
+func Requeue(email *models.QueuedEmail) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(email).
+			Where("status IN (?)", []string{models.QueueStatusFailed, models.QueueStatusDead, models.QueueStatusCancelled}).
+			UpdateColumns(map[string]interface{}{
+				"status":       models.QueueStatusQueued,
+				"attempts":     0,
+				"next_attempt": nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWrongStatus
+		}
+		if email.EmailLogID == 0 {
+			return nil
+		}
+		return tx.Model(&models.EmailLog{}).Where("id = ?", email.EmailLogID).
+			UpdateColumn("status", models.EmailStatusPending).Error
+	})
+}
+
+// Cancel takes a queued email off the queue. An email that is being sent at
+// that moment is waited for, and then can't be cancelled.
+func Cancel(email *models.QueuedEmail) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(email).Where("status = ?", models.QueueStatusQueued).
+			UpdateColumn("status", models.QueueStatusCancelled)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWrongStatus
+		}
+		if email.EmailLogID == 0 {
+			return nil
+		}
+		return tx.Model(&models.EmailLog{}).Where("id = ?", email.EmailLogID).
+			UpdateColumn("status", models.EmailStatusFailed).Error
+	})
+}
+
+// globalAllowance is how many more emails can be sent now, across all domains
+func globalAllowance(limits Limits, now time.Time) (int, error) {
+	usage, err := Usage(limits, "", now)
+	if err != nil {
+		return 0, err
+	}
+	return remaining(usage), nil
+}
+
+// domainAllowance is how many more emails can be sent to domain now
+func domainAllowance(domain string, now time.Time) (int, error) {
+	limits, err := DomainLimits(domain)
+	if err != nil {
+		return 0, err
+	}
+	if limits == (Limits{}) {
+		return unlimited, nil
+	}
+	usage, err := Usage(limits, domain, now)
+	if err != nil {
+		return 0, err
+	}
+	return remaining(usage), nil
+}
+
+// senderAllowance is how many more emails can be sent from a sender identity
+// now. The Settings account (zero) has no cap of its own.
+func senderAllowance(identityID uint, now time.Time) (int, error) {
+	if identityID == 0 {
+		return unlimited, nil
+	}
+	var identity models.SenderIdentity
+	if err := database.DB.First(&identity, identityID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			// Sending fails and is retried until the identity is fixed
+			return unlimited, nil
+		}
+		return 0, err
+	}
+	return IdentityAllowance(&identity, now)
+}
+
+// IdentityAllowance is how many more emails a sender identity can send now
+// under its daily cap and today's warm-up cap
+func IdentityAllowance(identity *models.SenderIdentity, now time.Time) (int, error) {
+	_, warmupLimit, err := Warmup(identity, now)
+	if err != nil {
+		return 0, err
+	}
+	if identity.DailyCap <= 0 && warmupLimit == 0 {
+		return unlimited, nil
+	}
+	sent, err := SentBy(identity.ID, now)
+	if err != nil {
+		return 0, err
+	}
+	allowance := unlimited
+	if identity.DailyCap > 0 {
+		allowance = nonNegative(identity.DailyCap - sent)
+	}
+	if warmupLimit > 0 {
+		allowance = tighter(allowance, nonNegative(warmupLimit-sent))
+	}
+	return allowance, nil
+}
+
+// SentBy counts the emails sent from a sender identity in the last 24 hours
+func SentBy(identityID uint, now time.Time) (int, error) {
+	var count int
+	err := database.DB.Model(&models.QueuedEmail{}).
+		Where("status = ? AND sender_identity_id = ? AND sent_at > ?", models.QueueStatusSent, identityID, now.Add(-24*time.Hour)).
+		Count(&count).Error
+	return count, err
+}
+
+// DomainLimits returns the rate limits of a recipient domain
+func DomainLimits(domain string) (Limits, error) {
+	var limit models.DomainRateLimit
+	if err := database.DB.Where("domain = ?", domain).First(&limit).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return Limits{}, nil
+		}
+		return Limits{}, err
+	}
+	return Limits{PerMinute: limit.PerMinute, PerHour: limit.PerHour, PerDay: limit.PerDay}, nil
+}
+
+// Usage counts the emails sent in the last minute, hour and day, to domain or
+// to all domains when it's empty, alongside the limits
+func Usage(limits Limits, domain string, now time.Time) (models.RateUsage, error) {
+	usage := models.RateUsage{PerMinute: limits.PerMinute, PerHour: limits.PerHour, PerDay: limits.PerDay}
+	for _, window := range []struct {
+		length time.Duration
+		count  *int
+	}{
+		{time.Minute, &usage.SentLastMinute},
+		{time.Hour, &usage.SentLastHour},
+		{24 * time.Hour, &usage.SentLastDay},
+	} {
+		query := database.DB.Model(&models.QueuedEmail{}).
+			Where("status = ? AND sent_at > ?", models.QueueStatusSent, now.Add(-window.length))
+		if domain != "" {
+			query = query.Where("domain = ?", domain)
+		}
+		if err := query.Count(window.count).Error; err != nil {
+			return usage, err
+		}
+	}
+	return usage, nil
+}
+
+// remaining is the tightest allowance left under the usage's limits
+func remaining(usage models.RateUsage) int {
+	allowance := unlimited
+	for _, window := range []struct{ limit, sent int }{
+		{usage.PerMinute, usage.SentLastMinute},
+		{usage.PerHour, usage.SentLastHour},
+		{usage.PerDay, usage.SentLastDay},
+	} {
+		if window.limit > 0 {
+			allowance = tighter(allowance, nonNegative(window.limit-window.sent))
+		}
+	}
+	return allowance
+}
+
+func tighter(a, b int) int {
+	if a == unlimited {
+		return b
+	}
+	if b == unlimited || a < b {
+		return a
+	}
+	return b
+}
+
+func spend(allowance int) int {
+	if allowance == unlimited {
+		return unlimited
+	}
+	return allowance - 1
+}
+
+func nonNegative(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// Warmup returns the day of a sender identity's warm-up, counting from 1,
+// and that day's send cap. Both are zero when the identity isn't warming up.
+func Warmup(identity *models.SenderIdentity, now time.Time) (int, int, error) {
+	if identity.WarmupStartedAt == nil {
+		return 0, 0, nil
+	}
+	caps, err := ParseWarmupSchedule(identity.WarmupSchedule)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	day := 0
+	if now.After(*identity.WarmupStartedAt) {
+		day = int(now.Sub(*identity.WarmupStartedAt) / (24 * time.Hour))
+	}
+	if day >= len(caps) {
+		return 0, 0, nil
+	}
+	return day + 1, caps[day], nil
+}
+
+// ParseWarmupSchedule reads a comma-separated list of daily send caps
+func ParseWarmupSchedule(schedule string) ([]int, error) {
+	var caps []int
+	for _, value := range strings.Split(schedule, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid daily cap %q", value)
+		}
+		caps = append(caps, n)
+	}
+	return caps, nil
+}
+
+// Status reports the depth of the queue, per domain, and how much of each
+// rate limit has been used
+func Status(limits Limits, now time.Time) (*models.SendQueueStatus, error) {
+	status := &models.SendQueueStatus{}
+	queued := database.DB.Model(&models.QueuedEmail{}).Where("status = ?", models.QueueStatusQueued)
+	if err := queued.Count(&status.Queued).Error; err != nil {
+		return nil, err
+	}
+	if err := database.DB.Model(&models.QueuedEmail{}).Where("status = ?", models.QueueStatusFailed).
+		Count(&status.Failed).Error; err != nil {
+		return nil, err
+	}
+	if err := database.DB.Model(&models.QueuedEmail{}).Where("status = ?", models.QueueStatusDead).
+		Count(&status.Dead).Error; err != nil {
+		return nil, err
+	}
+
+	var oldest []models.QueuedEmail
+	if err := queued.Order("id asc").Limit(1).Find(&oldest).Error; err != nil {
+		return nil, err
+	}
+	if len(oldest) > 0 {
+		status.OldestQueued = &oldest[0].CreatedAt
+	}
+
+	var err error
+	if status.Global, err = Usage(limits, "", now); err != nil {
+		return nil, err
+	}
+	if status.Warmups, err = warmups(now); err != nil {
+		return nil, err
+	}
+
+	// Every domain with queued emails or a rate limit of its own
+	var depths []struct {
+		Domain string
+		Queued int
+	}
+	if err := queued.Select("domain, count(*) AS queued").Group("domain").Order("domain asc").
+		Scan(&depths).Error; err != nil {
+		return nil, err
+	}
+	var domainLimits []models.DomainRateLimit
+	if err := database.DB.Order("domain asc").Find(&domainLimits).Error; err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, depth := range depths {
+		seen[depth.Domain] = true
+		status.Domains = append(status.Domains, models.DomainQueueStatus{Domain: depth.Domain, Queued: depth.Queued})
+	}
+	for _, limit := range domainLimits {
+		if !seen[limit.Domain] {
+			status.Domains = append(status.Domains, models.DomainQueueStatus{Domain: limit.Domain})
+		}
+	}
+
+	for i := range status.Domains {
+		domain := &status.Domains[i]
+		domainLimits, err := DomainLimits(domain.Domain)
+		if err != nil {
+			return nil, err
+		}
+		if domain.RateUsage, err = Usage(domainLimits, domain.Domain, now); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// warmups reports the sender identities warming up today
+func warmups(now time.Time) ([]models.SenderWarmup, error) {
+	var identities []models.SenderIdentity
+	if err := database.DB.Where("warmup_started_at IS NOT NULL").Order("id asc").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	reports := []models.SenderWarmup{}
+	for i := range identities {
+		day, limit, err := Warmup(&identities[i], now)
+		if err != nil {
+			return nil, err
+		}
+		if limit == 0 {
+			continue
+		}
+		sent, err := SentBy(identities[i].ID, now)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, models.SenderWarmup{
+			SenderIdentityID: identities[i].ID,
+			FromEmail:        identities[i].FromEmail,
+			Day:              day,
+			Limit:            limit,
+			SentLastDay:      sent,
+		})
+	}
+	return reports, nil
+}
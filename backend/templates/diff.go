@@ -0,0 +1,88 @@
+package templates
+
+import "strings"
+
+// maxDiffCells bounds the work of a line diff; bigger inputs are shown as
+// every old line removed and every new line added
+const maxDiffCells = 4_000_000
+
+// Lines diffs two texts line by line, from their longest common subsequence.
+// Lines are prefixed with "  " when unchanged, "- " when removed and "+ "
+// when added.
+func Lines(from, to string) []string {
+	a := strings.Split(from, "\n")
+	b := strings.Split(to, "\n")
+
+	// Unchanged leading and trailing lines don't need the table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var out []string
+	for _, line := range a[:prefix] {
+		out = append(out, "  "+line)
+	}
+	out = append(out, middle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		out = append(out, "  "+line)
+	}
+	return out
+}
+
+func middle(a, b []string) []string {
+	var out []string
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			out = append(out, "- "+line)
+		}
+		for _, line := range b {
+			out = append(out, "+ "+line)
+		}
+		return out
+	}
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int32, len(a)+1)
+	for i := range common {
+		common[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		out = append(out, "+ "+b[j])
+	}
+	return out
+}
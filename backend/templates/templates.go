@@ -0,0 +1,227 @@
+// Package templates saves email templates as a history of immutable
+// versions, and looks up the version a step sends.
+package templates
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/4cecoder/drip-campaign/compile"
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/4cecoder/drip-campaign/partials"
+	"github.com/4cecoder/drip-campaign/render"
+	"github.com/jinzhu/gorm"
+)
+
+// ErrVersionNotFound is returned for a version a template doesn't have
+var ErrVersionNotFound = errors.New("template version not found")
+
+// Save creates or updates a template, adding a version when its content
+// changed. userID is recorded as the version's author.
+func Save(template *models.EmailTemplate, userID uint) error {
+	return save(template, userID, 0)
+}
+
+// Restore makes a copy of an earlier version the template's current version.
+// History is kept: the copy is a new version.
+func Restore(template *models.EmailTemplate, version int, userID uint) error {
+	old, err := Version(template.ID, version)
+	if err != nil {
+		return err
+	}
+	template.Name = old.Name
+	template.Subject = old.Subject
+	template.Body = old.Body
+	template.TextBody = old.TextBody
+	template.ContentType = old.ContentType
+	template.LayoutID = old.LayoutID
+	return save(template, userID, old.Version)
+}
+
+func save(template *models.EmailTemplate, userID uint, restoredFrom int) error {
+	if err := compileSource(template); err != nil {
+		return err
+	}
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		latest := 0
+		var current *models.TemplateVersion
+		if template.ID != 0 {
+			// Lock the template so concurrent saves get distinct version numbers
+			var stored models.EmailTemplate
+			if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&stored, template.ID).Error; err != nil {
+				return err
+			}
+			var versions []models.TemplateVersion
+			if err := tx.Where("email_template_id = ?", template.ID).Order("version desc").Limit(1).
+				Find(&versions).Error; err != nil {
+				return err
+			}
+			if len(versions) > 0 {
+				current = &versions[0]
+				latest = current.Version
+			}
+		}
+
+		if current != nil && sameContent(current, template) && restoredFrom == 0 {
+			template.Version = current.Version
+			return tx.Save(template).Error
+		}
+
+		template.Version = latest + 1
+		if err := tx.Save(template).Error; err != nil {
+			return err
+		}
+		version := snapshot(template)
+		version.CreatedBy = userID
+		version.RestoredFrom = restoredFrom
+		return tx.Create(&version).Error
+	})
+}
+
+// compileSource stores what a Markdown or MJML template compiles to
+func compileSource(template *models.EmailTemplate) error {
+	template.CompiledBody, template.CompiledTextBody = "", ""
+	if !compile.IsSource(template.ContentType) {
+		return nil
+	}
+	compiled, err := compile.Template(template)
+	if err != nil {
+		return err
+	}
+	if template.CompiledBody, err = compile.InlineCSS(compiled.Body); err != nil {
+		return err
+	}
+	template.CompiledTextBody = compiled.TextBody
+	return nil
+}
+
+// Prepare returns a copy of a template as it's sent: a Markdown or MJML body
+// compiled to HTML, its layout and partials included, and its CSS inlined
+func Prepare(template *models.EmailTemplate) (*models.EmailTemplate, error) {
+	compiled, err := compile.Template(template)
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := partials.Expand(compiled)
+	if err != nil {
+		return nil, err
+	}
+	if render.IsHTML(expanded.ContentType) {
+		if expanded.Body, err = compile.InlineCSS(expanded.Body); err != nil {
+			return nil, err
+		}
+	}
+	return expanded, nil
+}
+
+// snapshot copies a template's content into a version
+func snapshot(template *models.EmailTemplate) models.TemplateVersion {
+	return models.TemplateVersion{
+		EmailTemplateID: template.ID,
+		Version:         template.Version,
+		Name:            template.Name,
+		Subject:         template.Subject,
+		Body:            template.Body,
+		TextBody:        template.TextBody,
+		ContentType:     template.ContentType,
+		LayoutID:        template.LayoutID,
+	}
+}
+
+func sameContent(version *models.TemplateVersion, template *models.EmailTemplate) bool {
+	return version.Name == template.Name &&
+		version.Subject == template.Subject &&
+		version.Body == template.Body &&
+		version.TextBody == template.TextBody &&
+		version.ContentType == template.ContentType &&
+		version.LayoutID == template.LayoutID
+}
+
+// Version loads one version of a template
+func Version(templateID uint, version int) (*models.TemplateVersion, error) {
+	var found models.TemplateVersion
+	err := database.DB.Where("email_template_id = ? AND version = ?", templateID, version).First(&found).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrVersionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &found, nil
+}
+
+// ForStep loads the template a step sends: the version it's pinned to, or
+// the template's current version when pinned is zero. The returned template
+// has the version's content and number.
+func ForStep(templateID uint, pinned int) (*models.EmailTemplate, error) {
+	var template models.EmailTemplate
+	if err := database.DB.First(&template, templateID).Error; err != nil {
+		return nil, err
+	}
+	if pinned == 0 || pinned == template.Version {
+		return &template, nil
+	}
+
+	version, err := Version(templateID, pinned)
+	if err != nil {
+		return nil, err
+	}
+	template.Name = version.Name
+	template.Subject = version.Subject
+	template.Body = version.Body
+	template.TextBody = version.TextBody
+	template.ContentType = version.ContentType
+	template.LayoutID = version.LayoutID
+	template.Version = version.Version
+	return &template, nil
+}
+
+// Backfill gives templates saved before versioning their first version
+func Backfill() error {
+	var unversioned []models.EmailTemplate
+	if err := database.DB.Where("version = 0 OR version IS NULL").Find(&unversioned).Error; err != nil {
+		return err
+	}
+	for i := range unversioned {
+		if err := Save(&unversioned[i], 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Diff compares two versions of a template line by line
+func Diff(from, to *models.TemplateVersion) models.TemplateDiff {
+	diff := models.TemplateDiff{
+		EmailTemplateID: to.EmailTemplateID,
+		From:            from.Version,
+		To:              to.Version,
+		Changed:         []string{},
+	}
+	fields := []struct {
+		name     string
+		from, to string
+		out      *[]string
+	}{
+		{"subject", from.Subject, to.Subject, &diff.Subject},
+		{"body", from.Body, to.Body, &diff.Body},
+		{"text_body", from.TextBody, to.TextBody, &diff.TextBody},
+		{"content_type", from.ContentType, to.ContentType, &diff.ContentType},
+	}
+	for _, field := range fields {
+		if field.from == field.to {
+			continue
+		}
+		diff.Changed = append(diff.Changed, field.name)
+		*field.out = Lines(field.from, field.to)
+	}
+	if from.LayoutID != to.LayoutID {
+		diff.Changed = append(diff.Changed, "layout_id")
+		diff.LayoutID = Lines(strconv.FormatUint(uint64(from.LayoutID), 10), strconv.FormatUint(uint64(to.LayoutID), 10))
+	}
+	if from.Name != to.Name {
+		diff.Changed = append(diff.Changed, "name")
+	}
+	return diff
+}
@@ -0,0 +1,120 @@
+package tracking
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/4cecoder/drip-campaign/auth"
+	"github.com/4cecoder/drip-campaign/config"
+	"github.com/4cecoder/drip-campaign/models"
+)
+
+var hrefPattern = regexp.MustCompile(`(?is)(<a\b[^>]*?\bhref\s*=\s*)("[^"]*"|'[^']*')`)
+
+// ClickToken returns the signed token for a tracked link. The destination is
+// part of the signed payload so the redirect endpoint can't be pointed at
+// arbitrary URLs.
+func ClickToken(emailLogID uint, target string) string {
+	payload := strconv.FormatUint(uint64(emailLogID), 36) + "|" + target
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + auth.Sign("click:"+payload)
+}
+
+// ParseClickToken verifies a click token and returns the email log ID and destination URL
+func ParseClickToken(token string) (uint, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("invalid tracking token")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid tracking token")
+	}
+	payload := string(raw)
+	if !auth.VerifySignature("click:"+payload, parts[1]) {
+		return 0, "", fmt.Errorf("invalid tracking token")
+	}
+
+	fields := strings.SplitN(payload, "|", 2)
+	if len(fields) != 2 {
+		return 0, "", fmt.Errorf("invalid tracking token")
+	}
+	id, err := strconv.ParseUint(fields[0], 36, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid tracking token")
+	}
+	if !IsTrackableURL(fields[1]) {
+		return 0, "", fmt.Errorf("invalid destination")
+	}
+	return uint(id), fields[1], nil
+}
+
+// ClickURL returns the public redirect URL for a tracked link
+func ClickURL(emailLogID uint, target string) string {
+	return config.LoadConfig().PublicURL + "/t/c/" + ClickToken(emailLogID, target)
+}
+
+// IsTrackableURL reports whether a link is an absolute http(s) URL that can be redirected to
+func IsTrackableURL(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// AppendUTM adds the campaign's UTM parameters to a link, keeping any already present
+func AppendUTM(link string, campaign *models.DripCampaign) string {
+	params := map[string]string{
+		"utm_source":   campaign.UTMSource,
+		"utm_medium":   campaign.UTMMedium,
+		"utm_campaign": campaign.UTMCampaign,
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	query := u.Query()
+	changed := false
+	for _, key := range []string{"utm_source", "utm_medium", "utm_campaign"} {
+		if params[key] != "" && query.Get(key) == "" {
+			query.Set(key, params[key])
+			changed = true
+		}
+	}
+	if !changed {
+		return link
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// RewriteLinks appends the campaign's UTM parameters to every http(s) link in an
+// HTML body and, unless tracking is disabled, replaces it with a click tracking redirect
+func RewriteLinks(body string, campaign *models.DripCampaign, emailLogID uint) string {
+	return hrefPattern.ReplaceAllStringFunc(body, func(match string) string {
+		groups := hrefPattern.FindStringSubmatch(match)
+		quoted := groups[2]
+		quote := quoted[:1]
+		link := strings.TrimSpace(html.UnescapeString(quoted[1 : len(quoted)-1]))
+
+		// Leave mailto:, tel:, anchors, links built from unfilled merge fields
+		// and unsubscribe links alone
+		if !IsTrackableURL(link) || strings.HasPrefix(link, UnsubscribePrefix()) {
+			return match
+		}
+
+		link = AppendUTM(link, campaign)
+		if !campaign.TrackingDisabled {
+			link = ClickURL(emailLogID, link)
+		}
+		return groups[1] + quote + html.EscapeString(link) + quote
+	})
+}
@@ -0,0 +1,91 @@
+package tracking
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/4cecoder/drip-campaign/auth"
+)
+
+const (
+	testSecret  = "test-secret-that-is-long-enough-to-be-accepted"
+	otherSecret = "another-secret-that-is-long-enough-to-be-accepted"
+)
+
+// withSecret signs with secret until the test ends
+func withSecret(t *testing.T, secret string) {
+	t.Helper()
+	if err := auth.SetSecret(secret); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { auth.SetSecret(testSecret) })
+}
+
+func TestClickToken(t *testing.T) {
+	withSecret(t, testSecret)
+	token := ClickToken(42, "https://example.com/pricing?plan=pro")
+
+	id, target, err := ParseClickToken(token)
+	if err != nil {
+		t.Fatalf("ParseClickToken: %v", err)
+	}
+	if id != 42 || target != "https://example.com/pricing?plan=pro" {
+		t.Errorf("got %d %q, want 42 https://example.com/pricing?plan=pro", id, target)
+	}
+}
+
+// TestClickTokenForged checks the redirect can't be pointed at a URL that
+// wasn't signed with the configured secret
+func TestClickTokenForged(t *testing.T) {
+	withSecret(t, otherSecret)
+	forged := ClickToken(42, "https://attacker.test/phish")
+
+	withSecret(t, testSecret)
+	signature := strings.SplitN(ClickToken(42, "https://example.com/"), ".", 2)[1]
+	payload := base64.RawURLEncoding.EncodeToString([]byte("16|https://attacker.test/phish"))
+
+	tests := map[string]string{
+		"signed with another key": forged,
+		"destination swapped":     payload + "." + signature,
+		"unsigned":                payload,
+		"empty signature":         payload + ".",
+		"open token":              OpenToken(42),
+		"unsubscribe token":       UnsubscribeToken(42),
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, target, err := ParseClickToken(token); err == nil {
+				t.Errorf("token accepted, redirecting to %q", target)
+			}
+		})
+	}
+}
+
+func TestClickTokenDestination(t *testing.T) {
+	withSecret(t, testSecret)
+	for _, target := range []string{"javascript:alert(1)", "mailto:someone@example.com", "/relative", "https://"} {
+		if _, _, err := ParseClickToken(ClickToken(42, target)); err == nil {
+			t.Errorf("%q was accepted as a destination", target)
+		}
+	}
+}
+
+func TestOpenAndUnsubscribeTokens(t *testing.T) {
+	withSecret(t, testSecret)
+	if id, err := ParseOpenToken(OpenToken(7) + ".gif"); err != nil || id != 7 {
+		t.Errorf("ParseOpenToken = %d, %v, want 7", id, err)
+	}
+	if id, err := ParseUnsubscribeToken(UnsubscribeToken(7)); err != nil || id != 7 {
+		t.Errorf("ParseUnsubscribeToken = %d, %v, want 7", id, err)
+	}
+
+	open, unsubscribe := OpenToken(7), UnsubscribeToken(7)
+	withSecret(t, otherSecret)
+	if _, err := ParseOpenToken(open); err == nil {
+		t.Error("open token signed with another key was accepted")
+	}
+	if _, err := ParseUnsubscribeToken(unsubscribe); err == nil {
+		t.Error("unsubscribe token signed with another key was accepted")
+	}
+}
@@ -0,0 +1,97 @@
+// Package tracking builds the signed open and click tracking links embedded in
+// outgoing emails and classifies the requests they receive.
+package tracking
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/auth"
+	"github.com/4cecoder/drip-campaign/config"
+)
+
+// Pixel is a transparent 1x1 GIF
+var Pixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// OpenToken returns the signed token identifying an email log in its open pixel URL
+func OpenToken(emailLogID uint) string {
+	id := strconv.FormatUint(uint64(emailLogID), 36)
+	return id + "." + auth.Sign("open:"+id)
+}
+
+// ParseOpenToken verifies an open token and returns the email log ID
+func ParseOpenToken(token string) (uint, error) {
+	parts := strings.Split(strings.TrimSuffix(token, ".gif"), ".")
+	if len(parts) != 2 || !auth.VerifySignature("open:"+parts[0], parts[1]) {
+		return 0, fmt.Errorf("invalid tracking token")
+	}
+	id, err := strconv.ParseUint(parts[0], 36, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid tracking token")
+	}
+	return uint(id), nil
+}
+
+// OpenPixelURL returns the public URL of the open tracking pixel for an email log
+func OpenPixelURL(emailLogID uint) string {
+	return config.LoadConfig().PublicURL + "/t/o/" + OpenToken(emailLogID) + ".gif"
+}
+
+// InjectPixel adds the open tracking pixel to an HTML body, just before </body> when present
+func InjectPixel(body, pixelURL string) string {
+	img := `<img src="` + pixelURL + `" width="1" height="1" alt="" style="display:block;width:1px;height:1px;border:0;" />`
+
+	if i := strings.LastIndex(strings.ToLower(body), "</body>"); i >= 0 {
+		return body[:i] + img + body[i:]
+	}
+	return body + img
+}
+
+// prefetchWindow is how soon after sending an open is assumed to be automated
+const prefetchWindow = 5 * time.Second
+
+// scannerAgents are substrings of user agents used by link scanners and prefetchers
+var scannerAgents = []string{
+	"bot", "crawler", "spider", "curl", "wget", "python-requests", "go-http-client",
+	"barracuda", "mimecast", "proofpoint", "symantec", "forcepoint", "trendmicro",
+	"microsoft office protection", "safelinks",
+}
+
+// ClassifyOpen flags opens that most likely weren't made by a person reading the email
+func ClassifyOpen(userAgent string, sentAt, openedAt time.Time) (bool, string) {
+	// Apple Mail Privacy Protection prefetches images through Apple's proxy with a bare user agent
+	if strings.ToLower(strings.TrimSpace(userAgent)) == "mozilla/5.0" {
+		return true, "apple_mail_privacy_protection"
+	}
+	return classify(userAgent, sentAt, openedAt)
+}
+
+// ClassifyClick flags clicks that most likely came from a link scanner rather than a person
+func ClassifyClick(userAgent string, sentAt, clickedAt time.Time) (bool, string) {
+	return classify(userAgent, sentAt, clickedAt)
+}
+
+func classify(userAgent string, sentAt, at time.Time) (bool, string) {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return true, "empty_user_agent"
+	}
+
+	for _, agent := range scannerAgents {
+		if strings.Contains(ua, agent) {
+			return true, "scanner"
+		}
+	}
+
+	if !sentAt.IsZero() && at.Sub(sentAt) < prefetchWindow {
+		return true, "prefetch"
+	}
+
+	return false, ""
+}
@@ -0,0 +1,41 @@
+package tracking
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/4cecoder/drip-campaign/auth"
+	"github.com/4cecoder/drip-campaign/config"
+)
+
+// UnsubscribeToken returns the signed token identifying an email log in its
+// unsubscribe link
+func UnsubscribeToken(emailLogID uint) string {
+	id := strconv.FormatUint(uint64(emailLogID), 36)
+	return id + "." + auth.Sign("unsubscribe:"+id)
+}
+
+// ParseUnsubscribeToken verifies an unsubscribe token and returns the email log ID
+func ParseUnsubscribeToken(token string) (uint, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || !auth.VerifySignature("unsubscribe:"+parts[0], parts[1]) {
+		return 0, fmt.Errorf("invalid unsubscribe token")
+	}
+	id, err := strconv.ParseUint(parts[0], 36, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid unsubscribe token")
+	}
+	return uint(id), nil
+}
+
+// UnsubscribeURL returns the public unsubscribe link of an email log. It's
+// both the {{unsubscribe_url}} merge field and the List-Unsubscribe header.
+func UnsubscribeURL(emailLogID uint) string {
+	return UnsubscribePrefix() + UnsubscribeToken(emailLogID)
+}
+
+// UnsubscribePrefix is what every unsubscribe link starts with
+func UnsubscribePrefix() string {
+	return config.LoadConfig().PublicURL + "/t/u/"
+}
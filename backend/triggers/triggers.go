@@ -0,0 +1,239 @@
+// Package triggers records what happens to customers and enrolls them in the
+// campaigns whose triggers match.
+package triggers
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/4cecoder/drip-campaign/database"
+	"github.com/4cecoder/drip-campaign/models"
+	"github.com/jinzhu/gorm"
+)
+
+// batchSize is how many pending events a run processes at most
+const batchSize = 500
+
+// ValidTrigger checks a trigger's type and value
+func ValidTrigger(trigger *models.CampaignTrigger) string {
+	switch trigger.Type {
+	case models.TriggerCustomerCreated, models.TriggerTagAdded, models.TriggerLeadStatusChanged:
+	case models.TriggerEvent:
+		if strings.TrimSpace(trigger.Value) == "" {
+			return "Event triggers need the event name as their value"
+		}
+	case models.TriggerDateAnniversary:
+		if trigger.Value != models.AnniversaryCreatedAt && trigger.Value != models.AnniversaryBirthday {
+			return "Anniversary triggers need created_at or birthday as their value"
+		}
+	default:
+		return "Type must be customer_created, tag_added, lead_status_changed, event or date_anniversary"
+	}
+	return ""
+}
+
+// CustomerCreated records the events of a new customer
+func CustomerCreated(customer *models.Customer) error {
+	events := []models.CustomerEvent{{Type: models.TriggerCustomerCreated}}
+	for _, tag := range splitTags(customer.Tags) {
+		events = append(events, models.CustomerEvent{Type: models.TriggerTagAdded, Value: tag})
+	}
+	if customer.LeadStatus != "" {
+		events = append(events, models.CustomerEvent{Type: models.TriggerLeadStatusChanged, Value: customer.LeadStatus})
+	}
+	return record(customer.ID, events)
+}
+
+// CustomerUpdated records the tags added to a customer and changes to their
+// lead status
+func CustomerUpdated(before, after *models.Customer) error {
+	var events []models.CustomerEvent
+
+	had := map[string]bool{}
+	for _, tag := range splitTags(before.Tags) {
+		had[strings.ToLower(tag)] = true
+	}
+	for _, tag := range splitTags(after.Tags) {
+		if !had[strings.ToLower(tag)] {
+			events = append(events, models.CustomerEvent{Type: models.TriggerTagAdded, Value: tag})
+		}
+	}
+
+	if after.LeadStatus != "" && !strings.EqualFold(before.LeadStatus, after.LeadStatus) {
+		events = append(events, models.CustomerEvent{Type: models.TriggerLeadStatusChanged, Value: after.LeadStatus})
+	}
+	return record(after.ID, events)
+}
+
+func record(customerID uint, events []models.CustomerEvent) error {
+	now := time.Now()
+	for _, event := range events {
+		event.CustomerID = customerID
+		event.OccurredAt = now
+		if err := database.DB.Create(&event).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunOnce processes the pending customer events and today's anniversaries,
+// enrolling customers in the active campaigns whose triggers match
+func RunOnce(now time.Time) error {
+	var triggers []models.CampaignTrigger
+	err := database.DB.
+		Select("campaign_triggers.*").
+		Joins("JOIN drip_campaigns ON drip_campaigns.id = campaign_triggers.campaign_id AND drip_campaigns.deleted_at IS NULL").
+		Where("drip_campaigns.status = ?", models.CampaignStatusActive).
+		Find(&triggers).Error
+	if err != nil {
+		return err
+	}
+
+	if err := processEvents(triggers, now); err != nil {
+		return err
+	}
+
+	for i := range triggers {
+		if triggers[i].Type != models.TriggerDateAnniversary {
+			continue
+		}
+		if err := processAnniversaries(&triggers[i], now); err != nil {
+			log.Printf("Error processing anniversary trigger %d: %v", triggers[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// processEvents matches pending events against the triggers and marks them processed
+func processEvents(triggers []models.CampaignTrigger, now time.Time) error {
+	var events []models.CustomerEvent
+	if err := database.DB.Where("processed_at IS NULL").Order("id asc").Limit(batchSize).Find(&events).Error; err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		for i := range triggers {
+			if !matches(&triggers[i], &event) {
+				continue
+			}
+			if err := enroll(&triggers[i], event.CustomerID, "", now); err != nil {
+				return err
+			}
+		}
+
+		if err := database.DB.Model(&event).UpdateColumn("processed_at", now).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matches(trigger *models.CampaignTrigger, event *models.CustomerEvent) bool {
+	if trigger.Type != event.Type {
+		return false
+	}
+	return trigger.Value == "" || strings.EqualFold(strings.TrimSpace(trigger.Value), event.Value)
+}
+
+// processAnniversaries enrolls the customers whose date field falls on today,
+// once a year. Customers born on the 29th of February celebrate on the 28th
+// in other years.
+func processAnniversaries(trigger *models.CampaignTrigger, now time.Time) error {
+	column := "created_at"
+	if trigger.Value == models.AnniversaryBirthday {
+		column = "birthday"
+	}
+
+	today := now.UTC()
+	days := []int{today.Day()}
+	if today.Month() == time.February && today.Day() == 28 && !isLeap(today.Year()) {
+		days = append(days, 29)
+	}
+	startOfDay := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+
+	var customers []models.Customer
+	err := database.DB.
+		Where("EXTRACT(MONTH FROM "+column+") = ? AND EXTRACT(DAY FROM "+column+") IN (?)", int(today.Month()), days).
+		Where(column+" < ?", startOfDay).
+		Find(&customers).Error
+	if err != nil {
+		return err
+	}
+
+	key := strconv.Itoa(today.Year())
+	for _, customer := range customers {
+		if err := enroll(trigger, customer.ID, key, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enroll adds the customer to the trigger's campaign unless the campaign's
+// triggers already enrolled them (for this key), they're already enrolled or
+// they've opted out
+func enroll(trigger *models.CampaignTrigger, customerID uint, key string, now time.Time) error {
+	var customer models.Customer
+	if err := database.DB.First(&customer, customerID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil
+		}
+		return err
+	}
+	if customer.OptedOut() {
+		return nil
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var count int
+		if err := tx.Model(&models.TriggerFiring{}).
+			Where(`campaign_id = ? AND customer_id = ? AND "key" = ?`, trigger.CampaignID, customerID, key).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		firing := models.TriggerFiring{CampaignID: trigger.CampaignID, CustomerID: customerID, Key: key, TriggerID: trigger.ID}
+		if err := tx.Create(&firing).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.CampaignCustomer{}).
+			Where("campaign_id = ? AND customer_id = ? AND status IN (?)",
+				trigger.CampaignID, customerID, []string{"", models.EnrollmentActive, models.EnrollmentPaused}).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		enrollment := models.CampaignCustomer{
+			CampaignID: trigger.CampaignID,
+			CustomerID: customerID,
+			Status:     models.EnrollmentActive,
+			StartDate:  now,
+			Subscribed: customer.Subscribed,
+		}
+		return tx.Create(&enrollment).Error
+	})
+}
+
+func splitTags(tags string) []string {
+	var out []string
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}